@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-management-system/models"
+)
+
+// GenesisHash 哈希链中第一条事件的上一条哈希
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// chainVersion 哈希与签名内容的格式版本，格式变化时递增
+const chainVersion = "audit-chain-v1"
+
+// timeLayout 计算哈希时使用的时间格式，与 audit_events.occurred_at 的微秒精度一致
+const timeLayout = "2006-01-02T15:04:05.000000Z"
+
+// Timestamp 把时间规整为数据库保存的精度，写入前调用以保证读出后哈希不变
+func Timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// EventHash 计算事件的哈希，覆盖 e.PrevHash、e.Seq 和除 ID 外的全部内容
+func EventHash(e *models.AuditEvent) string {
+	h := sha256.New()
+	writeFields(h,
+		chainVersion,
+		e.PrevHash,
+		strconv.FormatInt(e.Seq, 10),
+		strconv.Itoa(e.OrgID),
+		Timestamp(e.OccurredAt).Format(timeLayout),
+		strconv.Itoa(e.ActorID),
+		e.ActorName,
+		strconv.Itoa(e.ImpersonatorID),
+		e.ImpersonatorName,
+		e.Action,
+		e.TargetType,
+		strconv.Itoa(e.TargetID),
+		e.TargetName,
+		string(e.Before),
+		string(e.After),
+		e.IP,
+		e.UserAgent,
+		e.RequestID,
+		e.Outcome,
+		e.Error,
+	)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CheckpointMessage 返回检查点被签名的内容
+func CheckpointMessage(cp *models.AuditCheckpoint) []byte {
+	var b strings.Builder
+	writeFields(&b,
+		chainVersion+"-checkpoint",
+		strconv.FormatInt(cp.Seq, 10),
+		strconv.FormatInt(cp.EventID, 10),
+		cp.EventHash,
+		cp.KeyID,
+		Timestamp(cp.CreatedAt).Format(timeLayout),
+	)
+	return []byte(b.String())
+}
+
+// writeFields 依次写入带长度前缀的字段，避免字段边界产生歧义
+func writeFields(w io.Writer, fields ...string) {
+	for _, f := range fields {
+		io.WriteString(w, strconv.Itoa(len(f))+":"+f+"\n")
+	}
+}
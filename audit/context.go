@@ -0,0 +1,40 @@
+// Package audit 在服务层记录审计事件时传递请求信息（客户端地址、User-Agent、请求ID、所在组织）
+package audit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"user-management-system/logger"
+	"user-management-system/tenant"
+)
+
+// Meta 审计事件中与请求有关的信息
+type Meta struct {
+	OrgID     int    // 请求所在的组织，0 表示未解析（事件归入默认组织）
+	IP        string // 客户端 IP
+	UserAgent string // 客户端 User-Agent
+	RequestID string // 请求 ID（见 middleware.RequestID）
+}
+
+// maxUserAgent User-Agent 的最大保存长度，与 audit_events.user_agent 列一致
+const maxUserAgent = 255
+
+// FromRequest 从请求中提取审计信息
+func FromRequest(r *http.Request) Meta {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgent {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgent], "")
+	}
+	return Meta{
+		OrgID:     tenant.ID(r.Context()),
+		IP:        ip,
+		UserAgent: userAgent,
+		RequestID: logger.RequestID(r.Context()),
+	}
+}
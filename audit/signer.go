@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"user-management-system/config"
+	"user-management-system/logger"
+	"user-management-system/models"
+)
+
+// Signer 使用本地 Ed25519 私钥为哈希链检查点签名
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// PublicKey 用于验证检查点签名的公钥，验证时不需要私钥
+type PublicKey struct {
+	key   ed25519.PublicKey
+	keyID string
+}
+
+// LoadOrCreateSigner 从 path 读取 PEM 格式的私钥；文件不存在时生成新的密钥对，
+// 私钥写入 path（仅所有者可读），公钥写入 path+".pub" 供验证使用
+func LoadOrCreateSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createSigner(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取审计签名密钥失败: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("审计签名密钥 %s 不是 PEM 格式的私钥", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析审计签名密钥失败: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("审计签名密钥 %s 不是 Ed25519 私钥", path)
+	}
+	return newSigner(key), nil
+}
+
+// createSigner 生成新的密钥对并写入文件
+func createSigner(path string) (*Signer, error) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成审计签名密钥失败: %w", err)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("创建审计签名密钥目录失败: %w", err)
+	}
+	// O_EXCL 防止多个进程同时启动时互相覆盖密钥
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return LoadOrCreateSigner(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("写入审计签名密钥失败: %w", err)
+	}
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: privDER}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("写入审计签名密钥失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("写入审计签名密钥失败: %w", err)
+	}
+
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	if err := os.WriteFile(path+".pub", pubPEM, 0644); err != nil {
+		return nil, fmt.Errorf("写入审计签名公钥失败: %w", err)
+	}
+	return newSigner(key), nil
+}
+
+// newSigner 根据私钥创建签名器
+func newSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{key: key, keyID: keyID(key.Public().(ed25519.PublicKey))}
+}
+
+// KeyID 返回签名公钥的指纹
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey 返回对应的公钥
+func (s *Signer) PublicKey() *PublicKey {
+	return &PublicKey{key: s.key.Public().(ed25519.PublicKey), keyID: s.keyID}
+}
+
+// Sign 为检查点设置 KeyID 并签名
+func (s *Signer) Sign(cp *models.AuditCheckpoint) {
+	cp.KeyID = s.keyID
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, CheckpointMessage(cp)))
+}
+
+// LoadPublicKey 从 path 读取 PEM 格式的 Ed25519 公钥
+func LoadPublicKey(path string) (*PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取审计签名公钥失败: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("审计签名公钥 %s 不是 PEM 格式的公钥", path)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析审计签名公钥失败: %w", err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("审计签名公钥 %s 不是 Ed25519 公钥", path)
+	}
+	return &PublicKey{key: key, keyID: keyID(key)}, nil
+}
+
+// KeyID 返回公钥的指纹
+func (k *PublicKey) KeyID() string {
+	return k.keyID
+}
+
+// Verify 检查检查点的签名是否由该公钥对应的私钥生成
+func (k *PublicKey) Verify(cp *models.AuditCheckpoint) bool {
+	if cp.KeyID != k.keyID {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(k.key, CheckpointMessage(cp), sig)
+}
+
+// keyID 公钥指纹：公钥 SHA-256 的前8字节（十六进制）
+func keyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+var (
+	defaultSigner     *Signer
+	defaultSignerOnce sync.Once
+)
+
+// DefaultSigner 返回根据配置加载的全局签名器（只加载一次）
+// 未配置 AuditSigningKeyPath 或密钥无法加载时返回 nil，此时不生成检查点
+func DefaultSigner() *Signer {
+	defaultSignerOnce.Do(func() {
+		path := config.GetConfig().AuditSigningKeyPath
+		if path == "" {
+			logger.Warn("未配置审计签名密钥，审计日志不会生成签名检查点")
+			return
+		}
+		signer, err := LoadOrCreateSigner(path)
+		if err != nil {
+			logger.Error("加载审计签名密钥失败，审计日志不会生成签名检查点", "path", path, "error", err)
+			return
+		}
+		defaultSigner = signer
+		logger.Info("审计签名密钥已加载", "path", path, "key_id", signer.KeyID())
+	})
+	return defaultSigner
+}
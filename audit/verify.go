@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+
+	"user-management-system/models"
+)
+
+// ChainSource 验证哈希链时读取事件和检查点的来源（由审计事件仓库实现）
+type ChainSource interface {
+	ChainHead() (*models.AuditChainHead, error)
+	EachInChain(fn func(*models.AuditEvent) error) error
+	GetCheckpoints() ([]*models.AuditCheckpoint, error)
+}
+
+// Break 哈希链中第一处断开的位置
+type Break struct {
+	Seq     int64  // 出问题的序号（事件或检查点）
+	EventID int64  // 出问题的事件 ID，未知时为 0
+	Reason  string // 断开的原因
+}
+
+// String 返回断开位置的描述
+func (b *Break) String() string {
+	if b.EventID != 0 {
+		return fmt.Sprintf("序号 %d（事件 ID %d）：%s", b.Seq, b.EventID, b.Reason)
+	}
+	return fmt.Sprintf("序号 %d：%s", b.Seq, b.Reason)
+}
+
+// Report 哈希链的验证结果
+type Report struct {
+	Events         int64                   // 已验证的事件数
+	Checkpoints    int                     // 已验证签名的检查点数
+	LastCheckpoint *models.AuditCheckpoint // 最后一个已验证的检查点
+	Head           *models.AuditChainHead
+	Broken         *Break // 第一处断开的位置，链条完整时为 nil
+}
+
+// errStop 找到断开位置后停止遍历
+var errStop = errors.New("stop")
+
+// Verify 从第一条事件开始逐条复算哈希，检查序号连续、每条事件链接到前一条，
+// 并在经过检查点时验证签名；最后比对链条末端，发现末尾事件被删除的情况
+// key 为 nil 时只检查哈希链，不验证检查点签名
+func Verify(src ChainSource, key *PublicKey) (*Report, error) {
+	head, err := src.ChainHead()
+	if err != nil {
+		return nil, fmt.Errorf("读取哈希链末端失败: %w", err)
+	}
+	checkpoints, err := src.GetCheckpoints()
+	if err != nil {
+		return nil, fmt.Errorf("读取检查点失败: %w", err)
+	}
+
+	report := &Report{Head: head}
+	fail := func(seq, eventID int64, format string, args ...interface{}) error {
+		report.Broken = &Break{Seq: seq, EventID: eventID, Reason: fmt.Sprintf(format, args...)}
+		return errStop
+	}
+
+	// 检查点按序号排列，遍历到对应的事件时验证
+	next := 0
+	prevSeq, prevHash := int64(0), GenesisHash
+	err = src.EachInChain(func(e *models.AuditEvent) error {
+		if e.Seq != prevSeq+1 {
+			return fail(prevSeq+1, 0, "缺少事件（下一条事件的序号为 %d），事件可能被删除", e.Seq)
+		}
+		if e.PrevHash != prevHash {
+			return fail(e.Seq, e.ID, "记录的上一条哈希与上一条事件的哈希不一致，事件可能被删除或替换")
+		}
+		if EventHash(e) != e.Hash {
+			return fail(e.Seq, e.ID, "事件内容与哈希不一致，事件可能被修改")
+		}
+
+		for next < len(checkpoints) && checkpoints[next].Seq <= e.Seq {
+			cp := checkpoints[next]
+			if cp.Seq < e.Seq {
+				return fail(cp.Seq, cp.EventID, "检查点对应的事件不存在")
+			}
+			if cp.EventID != e.ID || cp.EventHash != e.Hash {
+				return fail(cp.Seq, e.ID, "事件与签名检查点记录的哈希不一致，事件可能被修改")
+			}
+			if key != nil {
+				if !key.Verify(cp) {
+					return fail(cp.Seq, e.ID, "检查点签名无效（签名密钥 %s，验证公钥 %s）", cp.KeyID, key.KeyID())
+				}
+				report.Checkpoints++
+				report.LastCheckpoint = cp
+			}
+			next++
+		}
+
+		report.Events++
+		prevSeq, prevHash = e.Seq, e.Hash
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, fmt.Errorf("读取审计事件失败: %w", err)
+	}
+	if report.Broken != nil {
+		return report, nil
+	}
+
+	// 链条末端之后不应再有检查点，末端记录也应与最后一条事件一致
+	if next < len(checkpoints) {
+		cp := checkpoints[next]
+		fail(cp.Seq, cp.EventID, "检查点对应的事件不存在，末尾事件可能被删除")
+		return report, nil
+	}
+	if head.Seq != prevSeq || head.Hash != prevHash {
+		fail(prevSeq+1, head.EventID, "哈希链末端记录为序号 %d，但最后一条事件为序号 %d，末尾事件可能被删除", head.Seq, prevSeq)
+	}
+	return report, nil
+}
@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"user-management-system/models"
+)
+
+// memoryChain 内存中的哈希链，实现 ChainSource
+type memoryChain struct {
+	events      []*models.AuditEvent
+	checkpoints []*models.AuditCheckpoint
+	head        models.AuditChainHead
+}
+
+func (c *memoryChain) ChainHead() (*models.AuditChainHead, error) {
+	head := c.head
+	return &head, nil
+}
+
+func (c *memoryChain) EachInChain(fn func(*models.AuditEvent) error) error {
+	for _, e := range c.events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *memoryChain) GetCheckpoints() ([]*models.AuditCheckpoint, error) {
+	return c.checkpoints, nil
+}
+
+// testSigner 使用固定种子的签名器，seed 不同时密钥不同
+func testSigner(seed byte) *Signer {
+	return newSigner(ed25519.NewKeyFromSeed([]byte(strings.Repeat(string(rune(seed)), ed25519.SeedSize))))
+}
+
+// newChain 生成 n 条相互链接的事件，并由 signer 在 checkpointSeqs 对应的事件处签名检查点
+func newChain(n int, signer *Signer, checkpointSeqs ...int64) *memoryChain {
+	c := &memoryChain{}
+	prevHash := GenesisHash
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		e := &models.AuditEvent{
+			ID:         int64(100 + i),
+			Seq:        int64(i),
+			PrevHash:   prevHash,
+			OccurredAt: start.Add(time.Duration(i) * time.Minute),
+			ActorID:    1,
+			ActorName:  "alice",
+			Action:     "user.update",
+			TargetType: "user",
+			TargetID:   i,
+			Outcome:    "success",
+		}
+		e.Hash = EventHash(e)
+		c.events = append(c.events, e)
+		prevHash = e.Hash
+	}
+	for _, seq := range checkpointSeqs {
+		e := c.events[seq-1]
+		cp := &models.AuditCheckpoint{Seq: e.Seq, EventID: e.ID, EventHash: e.Hash, CreatedAt: e.OccurredAt}
+		signer.Sign(cp)
+		c.checkpoints = append(c.checkpoints, cp)
+	}
+	// 与数据库初始化时一致，没有事件时末端为创世哈希
+	c.head = models.AuditChainHead{Hash: GenesisHash}
+	if n > 0 {
+		last := c.events[n-1]
+		c.head = models.AuditChainHead{Seq: last.Seq, EventID: last.ID, Hash: last.Hash}
+	}
+	return c
+}
+
+func TestVerifyIntactChain(t *testing.T) {
+	signer := testSigner(1)
+	chain := newChain(10, signer, 4, 8)
+
+	report, err := Verify(chain, signer.PublicKey())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if report.Broken != nil {
+		t.Fatalf("完整的链条被判定为断开: %s", report.Broken)
+	}
+	if report.Events != 10 || report.Checkpoints != 2 || report.LastCheckpoint.Seq != 8 {
+		t.Errorf("Events = %d, Checkpoints = %d, LastCheckpoint = %+v", report.Events, report.Checkpoints, report.LastCheckpoint)
+	}
+
+	// 空链
+	report, err = Verify(newChain(0, signer), signer.PublicKey())
+	if err != nil || report.Broken != nil || report.Events != 0 {
+		t.Errorf("空链: %+v, %v", report, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	signer := testSigner(1)
+
+	tests := []struct {
+		name    string
+		tamper  func(c *memoryChain)
+		key     *PublicKey
+		seq     int64  // 期望的断开位置
+		summary string // 期望出现在原因中的文字
+	}{
+		{
+			name:    "修改事件内容",
+			tamper:  func(c *memoryChain) { c.events[4].TargetName = "mallory" },
+			seq:     5,
+			summary: "修改",
+		},
+		{
+			// 修改后重新计算本条哈希，下一条事件的链接随之断开
+			name: "修改事件并重算哈希",
+			tamper: func(c *memoryChain) {
+				c.events[4].Action = "user.delete"
+				c.events[4].Hash = EventHash(c.events[4])
+			},
+			seq:     6,
+			summary: "上一条哈希",
+		},
+		{
+			name:    "删除中间的事件",
+			tamper:  func(c *memoryChain) { c.events = append(c.events[:4:4], c.events[5:]...) },
+			seq:     5,
+			summary: "缺少事件",
+		},
+		{
+			// 删除后重新编号并补齐链接，仍然与签名检查点不一致
+			name: "删除事件后重建后续链条",
+			tamper: func(c *memoryChain) {
+				c.events = append(c.events[:2:2], c.events[3:]...)
+				prevHash := c.events[1].Hash
+				for _, e := range c.events[2:] {
+					e.Seq--
+					e.PrevHash = prevHash
+					e.Hash = EventHash(e)
+					prevHash = e.Hash
+				}
+				last := c.events[len(c.events)-1]
+				c.head = models.AuditChainHead{Seq: last.Seq, EventID: last.ID, Hash: last.Hash}
+			},
+			seq:     4,
+			summary: "检查点",
+		},
+		{
+			name:    "截断末尾的事件",
+			tamper:  func(c *memoryChain) { c.events = c.events[:7] },
+			seq:     8,
+			summary: "末尾事件可能被删除",
+		},
+		{
+			// 同时改写了末端记录，仍然会被签名检查点发现
+			name: "截断末尾并改写末端记录",
+			tamper: func(c *memoryChain) {
+				c.events = c.events[:7]
+				last := c.events[6]
+				c.head = models.AuditChainHead{Seq: last.Seq, EventID: last.ID, Hash: last.Hash}
+			},
+			seq:     8,
+			summary: "检查点对应的事件不存在",
+		},
+		{
+			name:    "上一条哈希的链接被替换",
+			tamper:  func(c *memoryChain) { c.events[2].PrevHash = c.events[0].Hash },
+			seq:     3,
+			summary: "上一条哈希",
+		},
+		{
+			name: "检查点签名被篡改",
+			tamper: func(c *memoryChain) {
+				sig, _ := base64.StdEncoding.DecodeString(c.checkpoints[0].Signature)
+				sig[0] ^= 0xff
+				c.checkpoints[0].Signature = base64.StdEncoding.EncodeToString(sig)
+			},
+			seq:     4,
+			summary: "签名无效",
+		},
+		{
+			name:    "检查点签名不是 base64",
+			tamper:  func(c *memoryChain) { c.checkpoints[1].Signature = "not base64!" },
+			seq:     8,
+			summary: "签名无效",
+		},
+		{
+			name:    "使用其他密钥验证",
+			tamper:  func(c *memoryChain) {},
+			key:     testSigner(2).PublicKey(),
+			seq:     4,
+			summary: "签名无效",
+		},
+		{
+			// 攻击者用自己的密钥重新签名，KeyID 与验证公钥不一致
+			name:    "检查点被其他密钥重新签名",
+			tamper:  func(c *memoryChain) { testSigner(2).Sign(c.checkpoints[1]) },
+			seq:     8,
+			summary: "签名无效",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newChain(10, signer, 4, 8)
+			tt.tamper(chain)
+			key := tt.key
+			if key == nil {
+				key = signer.PublicKey()
+			}
+
+			report, err := Verify(chain, key)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if report.Broken == nil {
+				t.Fatal("篡改后的链条未被发现")
+			}
+			if report.Broken.Seq != tt.seq || !strings.Contains(report.Broken.Reason, tt.summary) {
+				t.Errorf("断开位置为 %s，期望序号 %d 且原因包含 %q", report.Broken, tt.seq, tt.summary)
+			}
+		})
+	}
+}
+
+func TestVerifyWithoutKeySkipsSignatures(t *testing.T) {
+	signer := testSigner(1)
+	chain := newChain(5, signer, 3)
+	chain.checkpoints[0].Signature = "invalid"
+
+	report, err := Verify(chain, nil)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if report.Broken != nil || report.Checkpoints != 0 {
+		t.Errorf("不验证签名时 Broken = %v, Checkpoints = %d", report.Broken, report.Checkpoints)
+	}
+
+	// 不验证签名时仍然检查检查点记录的哈希
+	chain.checkpoints[0].EventHash = GenesisHash
+	if report, _ := Verify(chain, nil); report.Broken == nil || report.Broken.Seq != 3 {
+		t.Errorf("检查点哈希不一致未被发现: %+v", report.Broken)
+	}
+}
@@ -0,0 +1,69 @@
+// auditverify 验证审计日志的哈希链：从第一条事件开始逐条复算哈希，检查每条事件都链接到前一条，
+// 用公钥验证经过的签名检查点，并报告第一处断开的位置。
+//
+// 用法：
+//
+//	go run ./cmd/auditverify
+//	go run ./cmd/auditverify -pub /secure/audit_signing.key.pub
+//
+// 链条完整时退出码为 0，发现断开时为 1，无法完成验证时为 2。
+// 公钥应从数据库服务器以外的位置获取，否则能改写数据库和密钥文件的人也能伪造检查点。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"user-management-system/audit"
+	"user-management-system/config"
+	"user-management-system/database"
+	"user-management-system/repository/mysql"
+)
+
+func main() {
+	pubPath := flag.String("pub", config.GetConfig().AuditSigningKeyPath+".pub", "验证检查点签名的公钥文件")
+	noSignature := flag.Bool("no-signature", false, "只检查哈希链，不验证检查点签名")
+	flag.Parse()
+
+	var key *audit.PublicKey
+	if !*noSignature {
+		var err error
+		if key, err = audit.LoadPublicKey(*pubPath); err != nil {
+			log.Printf("%v", err)
+			os.Exit(2)
+		}
+	}
+
+	if err := database.InitDB(); err != nil {
+		log.Printf("数据库初始化失败: %v", err)
+		os.Exit(2)
+	}
+	defer database.CloseDB()
+
+	report, err := audit.Verify(mysql.NewAuditRepository(database.GetDB()), key)
+	if err != nil {
+		log.Printf("验证失败: %v", err)
+		database.CloseDB()
+		os.Exit(2)
+	}
+
+	fmt.Printf("已验证事件: %d\n", report.Events)
+	if key != nil {
+		fmt.Printf("已验证检查点: %d（公钥指纹 %s）\n", report.Checkpoints, key.KeyID())
+		if cp := report.LastCheckpoint; cp != nil {
+			fmt.Printf("最后的检查点: 序号 %d，签名于 %s\n", cp.Seq, cp.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+			if unsigned := report.Events - cp.Seq; unsigned > 0 && report.Broken == nil {
+				fmt.Printf("最后的检查点之后还有 %d 条事件尚未签名\n", unsigned)
+			}
+		}
+	}
+
+	if report.Broken != nil {
+		fmt.Printf("哈希链断开: %s\n", report.Broken)
+		database.CloseDB()
+		os.Exit(1)
+	}
+	fmt.Println("哈希链完整")
+}
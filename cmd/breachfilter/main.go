@@ -0,0 +1,125 @@
+// breachfilter 从本地下载的 HIBP 泄露密码语料生成布隆过滤器文件，
+// 生成的文件通过 config.PasswordBreachBloomPath 配置给密码策略使用。
+//
+// 用法：
+//
+//	go run ./cmd/breachfilter -in pwned-passwords-sha1-ordered-by-hash.txt -out data/breached.bloom
+//	go run ./cmd/breachfilter -in ./hibp-ranges -out data/breached.bloom -fp 0.0001 -min-count 10
+//
+// -in 可以是 "SHA1:COUNT" 格式的单个文件，也可以是按5位前缀拆分的目录（每个文件为 "SUFFIX:COUNT"）。
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"user-management-system/password"
+)
+
+func main() {
+	in := flag.String("in", "", "HIBP 语料文件或按前缀拆分的目录")
+	out := flag.String("out", "breached.bloom", "输出的布隆过滤器文件")
+	fp := flag.Float64("fp", 0.001, "期望误判率")
+	minCount := flag.Int64("min-count", 1, "只收录出现次数不少于该值的哈希")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 第一遍：统计元素个数以确定过滤器大小
+	var total uint64
+	err := eachHash(*in, *minCount, func([20]byte) { total++ })
+	if err != nil {
+		log.Fatalf("读取语料失败: %v", err)
+	}
+	if total == 0 {
+		log.Fatalf("语料中没有符合条件的哈希")
+	}
+
+	filter := password.NewBloomFilter(total, *fp)
+	log.Printf("共 %d 条哈希，过滤器大小 %.1f MB，哈希函数 %d 个",
+		total, float64(filter.SizeBytes())/1024/1024, filter.HashFunctions())
+
+	// 第二遍：写入过滤器
+	if err := eachHash(*in, *minCount, filter.Add); err != nil {
+		log.Fatalf("读取语料失败: %v", err)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("创建输出文件失败: %v", err)
+	}
+	if _, err := filter.WriteTo(file); err != nil {
+		file.Close()
+		log.Fatalf("写入布隆过滤器失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatalf("关闭输出文件失败: %v", err)
+	}
+	log.Printf("已写入 %s", *out)
+}
+
+// eachHash 遍历语料中出现次数不少于 minCount 的全部哈希
+func eachHash(path string, minCount int64, fn func([20]byte)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return scanFile(path, "", minCount, fn)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		prefix := strings.ToUpper(strings.TrimSuffix(entry.Name(), ".txt"))
+		if len(prefix) != 5 {
+			continue
+		}
+		if err := scanFile(filepath.Join(path, entry.Name()), prefix, minCount, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanFile 逐行读取语料文件，prefix 非空时表示文件中只保存了哈希后缀
+func scanFile(path, prefix string, minCount int64, fn func([20]byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		hash, count, ok := password.ParseCorpusLine(scanner.Text())
+		if !ok || count < minCount {
+			continue
+		}
+
+		var digest [20]byte
+		raw, err := hex.DecodeString(prefix + hash)
+		if err != nil || len(raw) != len(digest) {
+			return fmt.Errorf("%s 第 %d 行格式错误", path, lineNo)
+		}
+		copy(digest[:], raw)
+		fn(digest)
+	}
+	return scanner.Err()
+}
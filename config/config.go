@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type Config struct {
 	DBHost     string
 	DBPort     string
@@ -7,6 +9,103 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	ServerPort string
+
+	// 日志
+	LogLevel  string // debug、info、warn 或 error
+	LogFormat string // json 或 text
+	LogOutput string // stdout（标准输出）、file（日志文件）或 both（两者）
+	LogDir    string // 日志文件目录
+
+	// 日志文件轮转：当前日志写入 LogDir/app.log，轮转后的文件为 app-<轮转时间>.log（压缩后为 .log.gz）
+	LogMaxSizeMB      int           // 单个日志文件的最大大小（MB），超过后轮转（0表示不按大小轮转）
+	LogRotateInterval time.Duration // 按时间轮转的周期，按本地时间对齐（0表示不按时间轮转）
+	LogMaxBackups     int           // 保留的轮转文件个数（0表示不限）
+	LogMaxAge         time.Duration // 轮转文件的保留时间（0表示不限）
+	LogCompress       bool          // 是否用 gzip 压缩轮转后的文件
+
+	// BaseURL 站点对外访问地址，用于生成邮件中的链接
+	BaseURL string
+
+	// 邮件发送配置（SMTPHost 为空时邮件只写入日志）
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// EmailVerificationTTL 邮箱验证链接有效期
+	EmailVerificationTTL time.Duration
+
+	// 注册方式：open（开放注册）、invite（仅限邀请）、closed（关闭注册）、approval（注册后需管理员审批）
+	RegistrationMode string
+	InvitationTTL    time.Duration // 注册邀请的有效期
+
+	// 管理员创建用户、重置密码
+	PasswordSetupLinkTTL    time.Duration // 邀请或重置密码链接的有效期
+	TemporaryPasswordLength int           // 生成的一次性临时密码长度
+
+	// 密码策略（注册、重置、修改密码统一使用）
+	PasswordMinLength          int    // 最少字符数
+	PasswordMaxLength          int    // 最多字节数，不超过 bcrypt 的72字节上限
+	PasswordRequireUpper       bool   // 必须包含大写字母
+	PasswordRequireLower       bool   // 必须包含小写字母
+	PasswordRequireDigit       bool   // 必须包含数字
+	PasswordRequireSymbol      bool   // 必须包含特殊符号
+	PasswordMinCharClasses     int    // 至少包含几类字符（0或1表示不限制）
+	PasswordPassphraseLength   int    // 达到该长度的长句式密码不受字符类别限制
+	PasswordRejectPersonalInfo bool   // 拒绝包含用户名或邮箱的密码
+	PasswordDictionaryPath     string // 本地常见密码字典文件，为空时使用内置字典
+	PasswordMinStrength        int    // 最低强度评分（0-4，0表示不限制）
+
+	// 密码哈希：新密码使用 PasswordHashAlgorithm，旧算法或旧参数的哈希在登录成功后自动升级
+	PasswordHashAlgorithm string // argon2id 或 bcrypt
+	BcryptCost            int    // bcrypt 成本因子（4-31）
+	Argon2Memory          uint32 // argon2id 内存（KiB）
+	Argon2Iterations      uint32 // argon2id 迭代次数
+	Argon2Parallelism     uint8  // argon2id 并行度
+
+	// 密码历史与有效期
+	PasswordHistorySize int           // 不允许重复使用最近 N 个密码（0表示不限制）
+	PasswordMaxAge      time.Duration // 所有用户的密码有效期（0表示永不过期）
+	AdminPasswordMaxAge time.Duration // 管理员的密码有效期（0表示与 PasswordMaxAge 相同）
+
+	// 泄露密码检查（均为空时不检查，同时配置时优先使用布隆过滤器）
+	PasswordBreachBloomPath  string // 由 cmd/breachfilter 生成的布隆过滤器文件
+	PasswordBreachCorpusPath string // HIBP 语料：排序后的 SHA1:COUNT 文件或按前缀拆分的目录
+
+	// 软删除：删除的用户先进入回收站，超过保留期后由后台任务永久删除
+	SoftDeleteRetention       time.Duration // 回收站保留期（0表示永不自动清除）
+	SoftDeletePurgeInterval   time.Duration // 后台清除任务的执行间隔
+	SoftDeleteReserveUsername bool          // 回收站中的用户是否继续占用用户名
+	SoftDeleteReserveEmail    bool          // 回收站中的用户是否继续占用邮箱
+
+	// 多组织：请求所在的组织依次按子域名、路径前缀 /o/{标识}/、会话中记录的组织解析
+	TenantBaseDomain string // 子域名解析的基础域名，如 userhub.example.com 时 acme.userhub.example.com 进入 acme 组织；为空时不按子域名解析
+
+	// 双人审批：以下敏感操作先保存为变更申请，由另一位管理员批准后才生效
+	ChangeApprovalActions []string      // promote_admin（提升为管理员）、delete_admin（删除管理员），为空时不需要审批
+	ChangeApprovalTTL     time.Duration // 变更申请的有效期，过期未处理的申请自动失效
+
+	// 审计日志：事件组成哈希链，并定期用本地密钥为链条末端签名生成检查点（用 cmd/auditverify 验证）
+	AuditSigningKeyPath     string        // Ed25519 私钥文件，不存在时自动生成（公钥写入同名 .pub 文件）；为空时不生成检查点
+	AuditCheckpointEvery    int           // 每写入多少条事件生成一个检查点（0表示不按条数生成）
+	AuditCheckpointInterval time.Duration // 后台任务生成检查点的间隔，链条末端已签名时跳过（0表示不启动）
+
+	// 分布式追踪：为 HTTP 请求、服务方法和 SQL 查询记录 span，通过 traceparent 请求头与上下游关联
+	TraceExporter    string  // none（不启用）、stdout（输出到标准输出）或 otlp（OTLP/HTTP JSON）
+	TraceEndpoint    string  // OTLP 接收地址
+	TraceSampleRatio float64 // 没有上游追踪信息时的采样比例（0～1）
+	TraceServiceName string  // 导出的服务名
+
+	// 运行指标：/metrics 默认需要 Bearer 令牌，MetricsToken 为空且没有显式允许匿名访问时不输出指标
+	MetricsToken          string // 访问 /metrics 需要的 Bearer 令牌；持有该令牌也可以查看健康检查详情
+	MetricsAllowAnonymous bool   // 允许不带令牌访问 /metrics（只应在网络层已限制访问时开启）
+
+	// 健康检查：/healthz（存活）只检查进程自身，/readyz（就绪）同时检查数据库、会话存储、邮件发送和日志目录磁盘空间
+	HealthCheckTimeout  time.Duration // 单项检查的超时时间
+	HealthCacheTTL      time.Duration // 检查结果的缓存时间，缓存期内的探测不重复检查
+	HealthMinFreeDiskMB int           // 日志目录所在磁盘的最少可用空间（MB），低于时就绪检查为 degraded（0表示不检查）
+	ShutdownDrainDelay  time.Duration // 收到关闭信号后，就绪检查失败到停止接收请求之间的等待时间，应略大于负载均衡的探测周期（0表示不等待）
 }
 
 func GetConfig() *Config {
@@ -17,5 +116,85 @@ func GetConfig() *Config {
 		DBPassword: "123456",
 		DBName:     "user_management",
 		ServerPort: "8080",
+
+		LogLevel:  "info",
+		LogFormat: "json",
+		LogOutput: "both",
+		LogDir:    "logs",
+
+		LogMaxSizeMB:      100,
+		LogRotateInterval: 24 * time.Hour,
+		LogMaxBackups:     30,
+		LogMaxAge:         30 * 24 * time.Hour,
+		LogCompress:       true,
+
+		BaseURL: "http://localhost:8080",
+
+		SMTPHost:     "",
+		SMTPPort:     "587",
+		SMTPUsername: "",
+		SMTPPassword: "",
+		MailFrom:     "UserHub <no-reply@userhub.local>",
+
+		EmailVerificationTTL: 24 * time.Hour,
+
+		RegistrationMode: "open",
+		InvitationTTL:    7 * 24 * time.Hour,
+
+		PasswordSetupLinkTTL:    72 * time.Hour,
+		TemporaryPasswordLength: 16,
+
+		PasswordMinLength:          8,
+		PasswordMaxLength:          72,
+		PasswordRequireUpper:       false,
+		PasswordRequireLower:       false,
+		PasswordRequireDigit:       false,
+		PasswordRequireSymbol:      false,
+		PasswordMinCharClasses:     2,
+		PasswordPassphraseLength:   16,
+		PasswordRejectPersonalInfo: true,
+		PasswordDictionaryPath:     "",
+		PasswordMinStrength:        2,
+
+		PasswordHashAlgorithm: "argon2id",
+		BcryptCost:            10,
+		Argon2Memory:          64 * 1024,
+		Argon2Iterations:      3,
+		Argon2Parallelism:     2,
+
+		PasswordHistorySize: 5,
+		PasswordMaxAge:      0,
+		AdminPasswordMaxAge: 90 * 24 * time.Hour,
+
+		PasswordBreachBloomPath:  "",
+		PasswordBreachCorpusPath: "",
+
+		SoftDeleteRetention:       30 * 24 * time.Hour,
+		SoftDeletePurgeInterval:   time.Hour,
+		SoftDeleteReserveUsername: true,
+		SoftDeleteReserveEmail:    false,
+
+		TenantBaseDomain: "",
+
+		// 默认不需要审批：申请人不能批准自己的申请，只有一位管理员时开启会导致无法再提升或删除管理员
+		ChangeApprovalActions: nil,
+		ChangeApprovalTTL:     48 * time.Hour,
+
+		AuditSigningKeyPath:     "data/audit_signing.key",
+		AuditCheckpointEvery:    100,
+		AuditCheckpointInterval: time.Hour,
+
+		TraceExporter:    "none",
+		TraceEndpoint:    "http://localhost:4318/v1/traces",
+		TraceSampleRatio: 1,
+		TraceServiceName: "user-management-system",
+
+		MetricsToken:          "",
+		MetricsAllowAnonymous: false,
+
+		HealthCheckTimeout:  2 * time.Second,
+		HealthCacheTTL:      5 * time.Second,
+		HealthMinFreeDiskMB: 100,
+		ShutdownDrainDelay:  5 * time.Second,
 	}
 }
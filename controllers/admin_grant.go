@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+	"user-management-system/tenant"
+)
+
+// AdminGrantController 委派管理授权控制器
+type AdminGrantController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	grantService  services.AdminGrantService
+	roleService   services.RoleService
+	groupService  services.GroupService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewAdminGrantController 创建委派管理授权控制器
+func NewAdminGrantController(application *app.App) *AdminGrantController {
+	return &AdminGrantController{
+		app: application,
+	}
+}
+
+// getGrantService 延迟初始化委派授权服务，以及选择授权角色和范围所需的角色、用户组服务
+func (c *AdminGrantController) getGrantService() services.AdminGrantService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建服务
+		svc := services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		})
+		c.grantService = svc.AdminGrantService
+		c.roleService = svc.RoleService
+		c.groupService = svc.GroupService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("委派授权服务已初始化", "controller", "AdminGrantController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.grantService
+}
+
+// getSessionHelper 获取会话助手
+func (c *AdminGrantController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getGrantService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getOrgService 返回限定在当前请求所在组织内、在审计事件中记录当前请求信息的委派授权服务，方法调用记录在当前请求的追踪中
+func (c *AdminGrantController) getOrgService(r *http.Request) services.AdminGrantService {
+	return c.getGrantService().ForOrganization(tenant.ID(r.Context())).ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// getOptions 返回创建授权时可以选择的角色和当前组织的用户组
+func (c *AdminGrantController) getOptions(r *http.Request) ([]*models.Role, []*models.Group, error) {
+	c.getGrantService()
+
+	c.mu.RLock()
+	roleService, groupService := c.roleService, c.groupService
+	c.mu.RUnlock()
+
+	roles, err := roleService.WithContext(r.Context()).GetRoles()
+	if err != nil {
+		return nil, nil, err
+	}
+	groups, err := groupService.ForOrganization(tenant.ID(r.Context())).WithContext(r.Context()).GetGroups()
+	if err != nil {
+		return nil, nil, err
+	}
+	return roles, groups, nil
+}
+
+// RenderGrantsPage 渲染委派管理页面
+func (c *AdminGrantController) RenderGrantsPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	grants, err := c.getOrgService(r).GetAdminGrants()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	roles, groups, err := c.getOptions(r)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.render(w, r, "views/grants.html", struct {
+		CurrentUser *models.User
+		Grants      []*models.AdminGrant
+		Roles       []*models.Role
+		Groups      []groupRow
+		Actions     []string
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Grants:      grants,
+		Roles:       roles,
+		Groups:      groupTree(groups),
+		Actions:     models.DelegablePermissions,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   c.csrfToken(r),
+	})
+}
+
+// HandleCreateGrant 处理创建委派授权的请求
+func (c *AdminGrantController) HandleCreateGrant(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	input := &services.AdminGrantInput{
+		Role:        r.FormValue("role"),
+		Permissions: r.Form["permissions"],
+	}
+	switch r.FormValue("scope_type") {
+	case "role":
+		input.ScopeRole = r.FormValue("scope_role")
+	case "group":
+		groupID, err := strconv.Atoi(r.FormValue("scope_group_id"))
+		if err != nil {
+			errors.HandleError(w, r, errors.NewValidationError("", "无效的用户组ID"))
+			return
+		}
+		input.ScopeGroupID = groupID
+	}
+
+	details := describeGrantInput(input)
+	if _, err := c.getOrgService(r).CreateAdminGrant(currentUser, input); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建委派授权", details, err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "创建委派授权", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "已授权角色 "+models.RoleLabel(input.Role))
+	http.Redirect(w, r, "/grants", http.StatusSeeOther)
+}
+
+// HandleDeleteGrant 处理删除委派授权的请求
+func (c *AdminGrantController) HandleDeleteGrant(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("grant_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的授权ID"))
+		return
+	}
+
+	details := fmt.Sprintf("授权ID: %d", id)
+	if err := c.getOrgService(r).DeleteAdminGrant(currentUser, id); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "删除委派授权", details, err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "删除委派授权", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "委派授权已删除")
+	http.Redirect(w, r, "/grants", http.StatusSeeOther)
+}
+
+// HandleAPIGrants 通过 API 列出或创建委派授权
+// GET /api/grants 返回当前组织的授权；POST /api/grants 的请求体为 services.AdminGrantInput
+func (c *AdminGrantController) HandleAPIGrants(w http.ResponseWriter, r *http.Request) {
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		grants, err := c.getOrgService(r).GetAdminGrants()
+		if err != nil {
+			errors.HandleError(w, r, err)
+			return
+		}
+		if grants == nil {
+			grants = []*models.AdminGrant{}
+		}
+		writeJSON(w, http.StatusOK, grants)
+	case http.MethodPost:
+		var input services.AdminGrantInput
+		if err := decodeJSON(r, &input); err != nil {
+			errors.HandleError(w, r, err)
+			return
+		}
+
+		details := describeGrantInput(&input)
+		grant, err := c.getOrgService(r).CreateAdminGrant(currentUser, &input)
+		if err != nil {
+			logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建委派授权", details, err)
+			errors.HandleError(w, r, err)
+			return
+		}
+		logger.UserActionContext(r.Context(), currentUser.Username, "创建委派授权", details, true)
+		writeJSON(w, http.StatusCreated, grant)
+	default:
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+	}
+}
+
+// HandleAPIDeleteGrant 通过 API 删除委派授权
+// POST /api/grants/delete，请求体为 {"id": 1}
+func (c *AdminGrantController) HandleAPIDeleteGrant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	var req struct {
+		ID int `json:"id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	details := fmt.Sprintf("授权ID: %d", req.ID)
+	if err := c.getOrgService(r).DeleteAdminGrant(currentUser, req.ID); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "删除委派授权", details, err)
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "删除委派授权", details, true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// describeGrantInput 生成委派授权的日志描述
+func describeGrantInput(input *services.AdminGrantInput) string {
+	scope := "角色 " + input.ScopeRole
+	if input.ScopeGroupID != 0 {
+		scope = fmt.Sprintf("用户组ID %d", input.ScopeGroupID)
+	}
+	return fmt.Sprintf("角色: %s, 操作: %s, 范围: %s", input.Role, strings.Join(input.Permissions, ","), scope)
+}
+
+// parsePost 检查请求方法并解析表单，返回当前用户；失败时已写入错误响应
+func (c *AdminGrantController) parsePost(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return nil, false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return nil, false
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// csrfToken 获取模板使用的CSRF令牌
+func (c *AdminGrantController) csrfToken(r *http.Request) string {
+	csrfToken, err := c.getSessionHelper().GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		return ""
+	}
+	return csrfToken
+}
+
+// render 使用布局模板渲染页面
+func (c *AdminGrantController) render(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", page)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// redirectWithError 将可以修正的错误作为提示带回委派管理页面，内部错误直接返回错误响应
+func (c *AdminGrantController) redirectWithError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, "/grants", http.StatusSeeOther)
+}
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"user-management-system/app"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+	"user-management-system/tenant"
+)
+
+// auditDateLayout 审计日志筛选表单中的日期格式
+const auditDateLayout = "2006-01-02"
+
+// auditOption 审计日志筛选表单中的选项
+type auditOption struct {
+	Name  string
+	Label string
+}
+
+// auditTargetTypes 审计日志筛选表单中的操作对象类型
+var auditTargetTypes = []auditOption{
+	{models.AuditTargetUser, "用户"},
+	{models.AuditTargetInvitation, "邀请"},
+	{models.AuditTargetRole, "角色"},
+	{models.AuditTargetGroup, "用户组"},
+	{models.AuditTargetOrganization, "组织"},
+	{models.AuditTargetGrant, "委派授权"},
+	{models.AuditTargetChangeRequest, "变更申请"},
+}
+
+// auditCSVHeader 导出CSV的表头，与 auditCSVRecord 的字段顺序一致
+var auditCSVHeader = []string{
+	"id", "occurred_at", "organization_id", "actor_id", "actor", "impersonator_id", "impersonator",
+	"action", "target_type", "target_id", "target_name", "before", "after",
+	"ip", "user_agent", "request_id", "outcome", "error", "seq", "prev_hash", "hash",
+}
+
+// AuditController 审计日志控制器，管理员在这里查询和导出审计事件
+type AuditController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	auditService  services.AuditService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewAuditController 创建审计日志控制器
+func NewAuditController(application *app.App) *AuditController {
+	return &AuditController{
+		app: application,
+	}
+}
+
+// getAuditService 延迟初始化审计服务
+func (c *AuditController) getAuditService() services.AuditService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建审计服务
+		c.auditService = services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		}).AuditService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("审计服务已初始化", "controller", "AuditController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.auditService
+}
+
+// getSessionHelper 获取会话助手
+func (c *AuditController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getAuditService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getOrgService 返回限定在当前请求所在组织内的审计服务，方法调用记录在当前请求的追踪中
+func (c *AuditController) getOrgService(r *http.Request) services.AuditService {
+	return c.getAuditService().ForOrganization(tenant.ID(r.Context())).WithContext(r.Context())
+}
+
+// RenderAuditPage 渲染审计日志页面，筛选条件和页码来自查询参数
+func (c *AuditController) RenderAuditPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	filter, err := auditFilterFromQuery(r.URL.Query())
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+
+	result, err := c.getOrgService(r).GetAuditEvents(filter, page, services.DefaultAuditPageSize)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.render(w, r, "views/audit.html", struct {
+		CurrentUser *models.User
+		Page        *services.AuditPage
+		Filter      *models.AuditFilter
+		FromDate    string
+		ToDate      string
+		Actions     []models.AuditAction
+		TargetTypes []auditOption
+		PrevURL     string
+		NextURL     string
+		ExportCSV   string
+		ExportJSON  string
+	}{
+		CurrentUser: currentUser,
+		Page:        result,
+		Filter:      filter,
+		FromDate:    formatAuditDate(filter.From, 0),
+		ToDate:      formatAuditDate(filter.To, -1),
+		Actions:     models.AuditActions,
+		TargetTypes: auditTargetTypes,
+		PrevURL:     auditURL("/audit", filter, "page", strconv.Itoa(result.Page-1)),
+		NextURL:     auditURL("/audit", filter, "page", strconv.Itoa(result.Page+1)),
+		ExportCSV:   auditURL("/audit/export", filter, "format", "csv"),
+		ExportJSON:  auditURL("/audit/export", filter, "format", "json"),
+	})
+}
+
+// HandleAPIAuditEvents 通过 API 分页查询审计事件
+// GET /api/audit?actor=&action=&target_type=&target_id=&outcome=&from=&to=&page=&page_size=
+func (c *AuditController) HandleAPIAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	filter, err := auditFilterFromQuery(r.URL.Query())
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+
+	result, err := c.getOrgService(r).GetAuditEvents(filter, page, pageSize)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// HandleExportAudit 按筛选条件导出全部审计事件，format 为 csv（默认）或 json
+// GET /audit/export?format=csv
+func (c *AuditController) HandleExportAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	filter, err := auditFilterFromQuery(r.URL.Query())
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		errors.HandleError(w, r, errors.NewValidationError("format", "导出格式只能是 csv 或 json"))
+		return
+	}
+
+	filename := "audit-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// 事件逐条写入响应，开始写入后出错只能记录日志
+	var count int
+	if format == "json" {
+		err = c.exportJSON(w, r, filter, &count)
+	} else {
+		err = c.exportCSV(w, r, filter, &count)
+	}
+	details := "格式: " + format + ", 条件: " + auditFilterQuery(filter).Encode() + ", 条数: " + strconv.Itoa(count)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "导出审计日志", details, err)
+		return
+	}
+	logger.UserActionContext(r.Context(), currentUser.Username, "导出审计日志", details, true)
+}
+
+// exportCSV 以CSV格式写出审计事件
+func (c *AuditController) exportCSV(w http.ResponseWriter, r *http.Request, filter *models.AuditFilter, count *int) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	// 写入BOM，便于表格软件识别UTF-8编码
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(auditCSVHeader); err != nil {
+		return err
+	}
+	err := c.getOrgService(r).ExportAuditEvents(filter, func(e *models.AuditEvent) error {
+		*count++
+		return cw.Write(auditCSVRecord(e))
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// exportJSON 以JSON数组格式写出审计事件
+func (c *AuditController) exportJSON(w http.ResponseWriter, r *http.Request, filter *models.AuditFilter, count *int) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+
+	err := c.getOrgService(r).ExportAuditEvents(filter, func(e *models.AuditEvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if *count > 0 {
+			if _, err := w.Write([]byte(",\n")); err != nil {
+				return err
+			}
+		}
+		*count++
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("]\n"))
+	return err
+}
+
+// auditCSVRecord 把审计事件转换为一行CSV
+func auditCSVRecord(e *models.AuditEvent) []string {
+	optionalID := func(id int) string {
+		if id == 0 {
+			return ""
+		}
+		return strconv.Itoa(id)
+	}
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		e.OccurredAt.Format(time.RFC3339Nano),
+		strconv.Itoa(e.OrgID),
+		optionalID(e.ActorID),
+		csvText(e.ActorName),
+		optionalID(e.ImpersonatorID),
+		csvText(e.ImpersonatorName),
+		e.Action,
+		e.TargetType,
+		optionalID(e.TargetID),
+		csvText(e.TargetName),
+		string(e.Before),
+		string(e.After),
+		e.IP,
+		csvText(e.UserAgent),
+		csvText(e.RequestID),
+		e.Outcome,
+		csvText(e.Error),
+		strconv.FormatInt(e.Seq, 10),
+		e.PrevHash,
+		e.Hash,
+	}
+}
+
+// csvText 防止用户可控的文本在表格软件中被当作公式执行
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// auditFilterFromQuery 从查询参数解析审计日志的筛选条件
+// from 和 to 可以是日期（to 当天包含在内）或 RFC3339 格式的时间
+func auditFilterFromQuery(q url.Values) (*models.AuditFilter, error) {
+	filter := &models.AuditFilter{
+		Actor:      strings.TrimSpace(q.Get("actor")),
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		Outcome:    q.Get("outcome"),
+	}
+
+	if v := strings.TrimSpace(q.Get("target_id")); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return nil, errors.NewValidationError("target_id", "无效的对象ID")
+		}
+		filter.TargetID = id
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(q.Get("from"), false); err != nil {
+		return nil, errors.NewValidationError("from", "无效的起始时间")
+	}
+	if filter.To, err = parseAuditTime(q.Get("to"), true); err != nil {
+		return nil, errors.NewValidationError("to", "无效的截止时间")
+	}
+	return filter, nil
+}
+
+// parseAuditTime 解析筛选时间；endOfDay 为 true 时日期表示当天结束（即次日零点）
+func parseAuditTime(v string, endOfDay bool) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(auditDateLayout, v, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// formatAuditDate 把筛选时间格式化为日期输入框的值，offsetDays 用于还原截止日期
+func formatAuditDate(t time.Time, offsetDays int) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.AddDate(0, 0, offsetDays).Format(auditDateLayout)
+}
+
+// auditFilterQuery 把筛选条件转换回查询参数，用于分页和导出链接
+func auditFilterQuery(filter *models.AuditFilter) url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("actor", filter.Actor)
+	set("action", filter.Action)
+	set("target_type", filter.TargetType)
+	if filter.TargetID > 0 {
+		q.Set("target_id", strconv.Itoa(filter.TargetID))
+	}
+	set("outcome", filter.Outcome)
+	if !filter.From.IsZero() {
+		q.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		q.Set("to", filter.To.Format(time.RFC3339))
+	}
+	return q
+}
+
+// auditURL 生成保留当前筛选条件的链接，并额外设置一个查询参数
+func auditURL(path string, filter *models.AuditFilter, key, value string) string {
+	q := auditFilterQuery(filter)
+	q.Set(key, value)
+	return path + "?" + q.Encode()
+}
+
+// render 使用布局模板渲染页面
+func (c *AuditController) render(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", page)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
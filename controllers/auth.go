@@ -2,18 +2,22 @@ package controllers
 
 import (
 	"html/template"
-	"log"
 	"net/http"
 	"sync"
 
 	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/config"
 	"user-management-system/errors"
 	"user-management-system/logger"
+	"user-management-system/metrics"
 	"user-management-system/models"
+	"user-management-system/password"
 	_ "user-management-system/repository/interfaces"
 	"user-management-system/repository/mysql"
 	"user-management-system/services"
 	"user-management-system/session"
+	"user-management-system/tenant"
 )
 
 // AuthController 认证控制器
@@ -40,12 +44,15 @@ func (c *AuthController) getUserService() services.UserService {
 		userRepo := mysql.NewUserRepository(c.app.GetDB())
 
 		// 创建用户服务
-		c.userService = services.NewUserService(userRepo)
+		c.userService = services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		}).UserService
 
 		// 创建会话助手
 		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
 
-		logger.Info("AuthController: 用户服务已初始化")
+		logger.Debug("用户服务已初始化", "controller", "AuthController")
 	})
 
 	c.mu.RLock()
@@ -63,6 +70,22 @@ func (c *AuthController) getSessionHelper() *session.Helper {
 	return c.sessionHelper
 }
 
+// getRequestService 返回不限定组织、在审计事件中记录当前请求信息的用户服务，方法调用记录在当前请求的追踪中
+func (c *AuthController) getRequestService(r *http.Request) services.UserService {
+	return c.getUserService().ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// getOrgService 返回限定在当前请求所在组织内、在审计事件中记录当前请求信息的用户服务，方法调用记录在当前请求的追踪中
+func (c *AuthController) getOrgService(r *http.Request) services.UserService {
+	return c.getUserService().ForOrganization(tenant.ID(r.Context())).ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// loginNotices 登录页可以显示的提示，通过 notice 查询参数指定
+var loginNotices = map[string]string{
+	"password_set":     "密码已设置，请使用新密码登录",
+	"pending_approval": "注册成功，账户需要管理员审批后才能登录",
+}
+
 // RenderLoginPage 渲染登录页面
 func (c *AuthController) RenderLoginPage(w http.ResponseWriter, r *http.Request) {
 	// 使用延迟初始化的会话助手
@@ -76,19 +99,24 @@ func (c *AuthController) RenderLoginPage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// 从其他流程跳转回来时给出提示
+	success := loginNotices[r.URL.Query().Get("notice")]
+
 	// 准备传递给模板的数据
 	data := struct {
 		CurrentUser *models.User
 		Error       string
+		Success     string
 	}{
 		CurrentUser: nil,
 		Error:       "",
+		Success:     success,
 	}
 
 	// 解析模板文件
 	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/login.html")
 	if err != nil {
-		log.Printf("模板解析错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/login.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 		return
 	}
@@ -96,7 +124,7 @@ func (c *AuthController) RenderLoginPage(w http.ResponseWriter, r *http.Request)
 	// 执行模板渲染
 	err = tmpl.ExecuteTemplate(w, "layout", data)
 	if err != nil {
-		log.Printf("模板执行错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/login.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 	}
 }
@@ -133,11 +161,12 @@ func (c *AuthController) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	remember := r.FormValue("remember") == "on"
 
 	// 使用延迟初始化的服务层验证用户
-	userService := c.getUserService()
+	userService := c.getRequestService(r)
 	user, err := userService.AuthenticateUser(username, password)
 	if err != nil {
 		// 记录登录失败
-		logger.UserAction(username, "登录", "IP: "+r.RemoteAddr, false)
+		logger.UserActionContext(r.Context(), username, "登录", "IP: "+r.RemoteAddr, false)
+		metrics.Logins.Inc(metrics.LoginFailure)
 
 		// 渲染登录页面并显示错误信息
 		appErr, _ := errors.IsAppError(err)
@@ -145,6 +174,7 @@ func (c *AuthController) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		data := struct {
 			CurrentUser *models.User
 			Error       string
+			Success     string
 		}{
 			CurrentUser: nil,
 			Error:       appErr.Message,
@@ -164,13 +194,36 @@ func (c *AuthController) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// 使用会话管理器创建会话
 	sessionHelper := c.getSessionHelper()
-	if err := sessionHelper.Login(w, r, user.ID, remember); err != nil {
+	sess, err := sessionHelper.Login(w, r, user.ID, remember)
+	if err != nil {
 		errors.HandleError(w, r, errors.NewInternalError(err))
 		return
 	}
 
+	// 记录最近登录时间（失败不影响登录）
+	if err := userService.RecordLogin(user.ID); err != nil {
+		logger.FromContext(r.Context()).Warn("记录登录时间失败", "user", user.Username, "error", err)
+	}
+
 	// 记录登录成功
-	logger.UserAction(user.Username, "登录", "IP: "+r.RemoteAddr, true)
+	logger.UserActionContext(r.Context(), user.Username, "登录", "IP: "+r.RemoteAddr, true)
+	metrics.Logins.Inc(metrics.LoginSuccess)
+
+	// 使用管理员设置的临时密码登录时必须先修改密码
+	if user.MustChangePassword {
+		session.RequirePasswordChange(sess, "您正在使用管理员设置的临时密码，请设置新密码后继续")
+		logger.UserActionContext(r.Context(), user.Username, "临时密码登录", "登录后强制修改密码", true)
+		http.Redirect(w, r, "/password/change", http.StatusSeeOther)
+		return
+	}
+
+	// 密码已过期时必须先修改密码
+	if userService.IsPasswordExpired(user) {
+		session.RequirePasswordChange(sess, "您的密码已过期，请设置新密码后继续")
+		logger.UserActionContext(r.Context(), user.Username, "密码过期", "登录后强制修改密码", true)
+		http.Redirect(w, r, "/password/change", http.StatusSeeOther)
+		return
+	}
 
 	// 登录成功后，重定向到用户列表页面
 	http.Redirect(w, r, "/users", http.StatusSeeOther)
@@ -197,43 +250,35 @@ func (c *AuthController) HandleRegister(w http.ResponseWriter, r *http.Request)
 
 	// 从表单中获取数据
 	username := r.FormValue("username")
-	password := r.FormValue("password")
+	plainPassword := r.FormValue("password")
 	email := r.FormValue("email")
+	invitationToken := r.FormValue("invitation")
 
 	// 使用延迟初始化的服务层注册用户
-	userService := c.getUserService()
-	err = userService.RegisterUser(username, password, email)
+	userService := c.getOrgService(r)
+	user, err := userService.RegisterUser(username, plainPassword, email, invitationToken)
 	if err != nil {
 		// 记录注册失败
-		logger.UserAction(username, "注册", "邮箱: "+email+", IP: "+r.RemoteAddr, false)
+		logger.UserActionContext(r.Context(), username, "注册", "邮箱: "+email+", IP: "+r.RemoteAddr, false)
 
 		// 渲染注册页面并显示错误信息
-		appErr, _ := errors.IsAppError(err)
-
-		data := struct {
-			CurrentUser *models.User
-			Error       string
-		}{
-			CurrentUser: nil,
-			Error:       appErr.Message,
-		}
-
-		tmpl, parseErr := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/register.html")
-		if parseErr != nil {
-			errors.HandleError(w, r, errors.NewInternalError(parseErr))
+		appErr, ok := errors.IsAppError(err)
+		if !ok || appErr.Type == errors.InternalError {
+			errors.HandleError(w, r, err)
 			return
 		}
-
-		if execErr := tmpl.ExecuteTemplate(w, "layout", data); execErr != nil {
-			errors.HandleError(w, r, errors.NewInternalError(execErr))
-		}
+		c.renderRegister(w, r, invitationToken, appErr.Message)
 		return
 	}
 
 	// 记录注册成功
-	logger.UserAction(username, "注册", "邮箱: "+email+", IP: "+r.RemoteAddr, true)
+	logger.UserActionContext(r.Context(), username, "注册", "邮箱: "+user.Email+", IP: "+r.RemoteAddr, true)
 
 	// 注册成功后，重定向到登录页面
+	if user.Status == models.StatusPending {
+		http.Redirect(w, r, "/login?notice=pending_approval", http.StatusSeeOther)
+		return
+	}
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
@@ -249,14 +294,14 @@ func (c *AuthController) HandleLogout(w http.ResponseWriter, r *http.Request) {
 
 	// 记录登出
 	if currentUser != nil {
-		logger.UserAction(currentUser.Username, "登出", "IP: "+r.RemoteAddr, true)
+		logger.UserActionContext(r.Context(), currentUser.Username, "登出", "IP: "+r.RemoteAddr, true)
 	}
 
 	// 清除会话后，重定向到登录页面
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
-// RenderRegisterPage 渲染注册页面（保持原有实现）
+// RenderRegisterPage 渲染注册页面
 func (c *AuthController) RenderRegisterPage(w http.ResponseWriter, r *http.Request) {
 	sessionHelper := c.getSessionHelper()
 
@@ -268,19 +313,56 @@ func (c *AuthController) RenderRegisterPage(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	c.renderRegister(w, r, r.URL.Query().Get("invitation"), "")
+}
+
+// renderRegister 按注册方式渲染注册页面，errMsg 为上一次提交的错误
+func (c *AuthController) renderRegister(w http.ResponseWriter, r *http.Request, invitationToken, errMsg string) {
+	mode := config.GetConfig().RegistrationMode
+
+	// 仅限邀请或带有邀请链接时先校验邀请，邀请无效则不显示注册表单
+	var invitation *models.Invitation
+	invitationError := ""
+	if mode != models.RegistrationClosed && (invitationToken != "" || mode == models.RegistrationInvite) {
+		inv, err := c.getUserService().WithContext(r.Context()).VerifyInvitation(invitationToken)
+		if err != nil {
+			appErr, ok := errors.IsAppError(err)
+			if !ok || appErr.Type == errors.InternalError {
+				errors.HandleError(w, r, err)
+				return
+			}
+			invitationError = appErr.Message
+		}
+		invitation = inv
+	}
+
 	// 准备传递给模板的数据
 	data := struct {
-		CurrentUser *models.User
-		Error       string
+		CurrentUser     *models.User
+		Error           string
+		PasswordRules   []string
+		Closed          bool
+		Approval        bool
+		Invitation      *models.Invitation
+		InvitationToken string
+		InvitationError string
+		Organization    *models.Organization
 	}{
-		CurrentUser: nil,
-		Error:       "",
+		CurrentUser:     nil,
+		Error:           errMsg,
+		PasswordRules:   password.DefaultPolicy().Requirements(),
+		Closed:          mode == models.RegistrationClosed,
+		Approval:        mode == models.RegistrationApproval,
+		Invitation:      invitation,
+		InvitationToken: invitationToken,
+		InvitationError: invitationError,
+		Organization:    tenant.FromContext(r.Context()),
 	}
 
 	// 解析注册页面所需的模板文件
 	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/register.html")
 	if err != nil {
-		log.Printf("模板解析错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/register.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 		return
 	}
@@ -288,7 +370,79 @@ func (c *AuthController) RenderRegisterPage(w http.ResponseWriter, r *http.Reque
 	// 执行模板渲染
 	err = tmpl.ExecuteTemplate(w, "layout", data)
 	if err != nil {
-		log.Printf("模板执行错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/register.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// RenderPasswordSetupPage 渲染通过邮件链接设置密码的页面（邀请或管理员重置）
+func (c *AuthController) RenderPasswordSetupPage(w http.ResponseWriter, r *http.Request) {
+	c.renderPasswordSetup(w, r, r.URL.Query().Get("token"), "")
+}
+
+// HandlePasswordSetup 处理通过邮件链接设置密码
+func (c *AuthController) HandlePasswordSetup(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+	token := r.FormValue("token")
+	newPassword := r.FormValue("new_password")
+	confirmPassword := r.FormValue("confirm_password")
+
+	if newPassword != confirmPassword {
+		c.renderPasswordSetup(w, r, token, "两次输入的密码不一致")
+		return
+	}
+
+	user, err := c.getRequestService(r).CompletePasswordSetup(token, newPassword)
+	if err != nil {
+		appErr, ok := errors.IsAppError(err)
+		if !ok || appErr.Type == errors.InternalError {
+			errors.HandleError(w, r, err)
+			return
+		}
+		c.renderPasswordSetup(w, r, token, appErr.Message)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), user.Username, "通过链接设置密码", "IP: "+r.RemoteAddr, true)
+	http.Redirect(w, r, "/login?notice=password_set", http.StatusSeeOther)
+}
+
+// renderPasswordSetup 渲染设置密码页面，errMsg 为上一次提交的错误
+func (c *AuthController) renderPasswordSetup(w http.ResponseWriter, r *http.Request, token, errMsg string) {
+	user, record, err := c.getUserService().WithContext(r.Context()).VerifyPasswordSetupToken(token)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	data := struct {
+		CurrentUser   *models.User
+		Username      string
+		Invite        bool
+		Token         string
+		Error         string
+		PasswordRules []string
+	}{
+		CurrentUser:   nil,
+		Username:      user.Username,
+		Invite:        record.Purpose == models.PasswordTokenInvite,
+		Token:         token,
+		Error:         errMsg,
+		PasswordRules: password.DefaultPolicy().Requirements(),
+	}
+
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/password_setup.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/password_setup.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/password_setup.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 	}
 }
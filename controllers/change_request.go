@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+	"user-management-system/tenant"
+)
+
+// ChangeRequestController 双人审批控制器，管理员在这里处理需要第二位管理员批准的变更申请
+type ChangeRequestController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	changeService services.ChangeRequestService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewChangeRequestController 创建双人审批控制器
+func NewChangeRequestController(application *app.App) *ChangeRequestController {
+	return &ChangeRequestController{
+		app: application,
+	}
+}
+
+// getChangeService 延迟初始化审批服务
+func (c *ChangeRequestController) getChangeService() services.ChangeRequestService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建审批服务
+		c.changeService = services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		}).ChangeRequestService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("审批服务已初始化", "controller", "ChangeRequestController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.changeService
+}
+
+// getSessionHelper 获取会话助手
+func (c *ChangeRequestController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getChangeService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getOrgService 返回限定在当前请求所在组织内、在审计事件中记录当前请求信息的审批服务，方法调用记录在当前请求的追踪中
+func (c *ChangeRequestController) getOrgService(r *http.Request) services.ChangeRequestService {
+	return c.getChangeService().ForOrganization(tenant.ID(r.Context())).ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// RenderChangeRequestsPage 渲染审批收件箱，待审批的申请在前，其余作为历史记录
+func (c *ChangeRequestController) RenderChangeRequestsPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	requests, err := c.getOrgService(r).GetChangeRequests()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	var pending, history []*models.ChangeRequest
+	for _, req := range requests {
+		if req.IsPending() {
+			pending = append(pending, req)
+		} else {
+			history = append(history, req)
+		}
+	}
+
+	c.render(w, r, "views/change_requests.html", struct {
+		CurrentUser *models.User
+		Pending     []*models.ChangeRequest
+		History     []*models.ChangeRequest
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Pending:     pending,
+		History:     history,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   c.csrfToken(r),
+	})
+}
+
+// HandleApproveChangeRequest 处理批准变更申请的请求
+func (c *ChangeRequestController) HandleApproveChangeRequest(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("request_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的申请ID"))
+		return
+	}
+
+	req, err := c.approve(r, currentUser, id)
+	if err != nil {
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "success", "已批准："+req.ActionLabel()+" "+req.TargetName)
+	http.Redirect(w, r, "/change-requests", http.StatusSeeOther)
+}
+
+// HandleRejectChangeRequest 处理拒绝（申请人撤回）变更申请的请求
+func (c *ChangeRequestController) HandleRejectChangeRequest(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("request_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的申请ID"))
+		return
+	}
+
+	req, err := c.reject(r, currentUser, id)
+	if err != nil {
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	message := "已拒绝："
+	if req.RequestedBy == currentUser.ID {
+		message = "已撤回："
+	}
+	c.getSessionHelper().SetFlash(r, "success", message+req.ActionLabel()+" "+req.TargetName)
+	http.Redirect(w, r, "/change-requests", http.StatusSeeOther)
+}
+
+// HandleAPIChangeRequests 通过 API 列出当前组织的变更申请
+// GET /api/change-requests
+func (c *ChangeRequestController) HandleAPIChangeRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	requests, err := c.getOrgService(r).GetChangeRequests()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	if requests == nil {
+		requests = []*models.ChangeRequest{}
+	}
+	writeJSON(w, http.StatusOK, requests)
+}
+
+// HandleAPIApproveChangeRequest 通过 API 批准变更申请
+// POST /api/change-requests/approve，请求体为 {"id": 1}
+func (c *ChangeRequestController) HandleAPIApproveChangeRequest(w http.ResponseWriter, r *http.Request) {
+	c.handleAPIDecision(w, r, c.approve)
+}
+
+// HandleAPIRejectChangeRequest 通过 API 拒绝（申请人撤回）变更申请
+// POST /api/change-requests/reject，请求体为 {"id": 1}
+func (c *ChangeRequestController) HandleAPIRejectChangeRequest(w http.ResponseWriter, r *http.Request) {
+	c.handleAPIDecision(w, r, c.reject)
+}
+
+// handleAPIDecision 解析 API 请求并执行 decide，返回处理后的申请
+func (c *ChangeRequestController) handleAPIDecision(w http.ResponseWriter, r *http.Request,
+	decide func(*http.Request, *models.User, int) (*models.ChangeRequest, error)) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	var body struct {
+		ID int `json:"id"`
+	}
+	if err := decodeJSON(r, &body); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	req, err := decide(r, currentUser, body.ID)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+// approve 批准变更申请并记录操作日志；被删除的管理员立即踢下线
+func (c *ChangeRequestController) approve(r *http.Request, currentUser *models.User, id int) (*models.ChangeRequest, error) {
+	details := fmt.Sprintf("申请ID: %d", id)
+	req, err := c.getOrgService(r).ApproveChangeRequest(currentUser, id)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "批准变更申请", details, err)
+		return nil, err
+	}
+
+	if req.Action == models.ChangeActionDeleteAdmin {
+		c.app.GetSessionManager().DestroyUserSessions(req.TargetID, "")
+	}
+	logger.UserActionContext(r.Context(), currentUser.Username, "批准变更申请", describeChangeRequest(req), true)
+	return req, nil
+}
+
+// reject 拒绝变更申请并记录操作日志
+func (c *ChangeRequestController) reject(r *http.Request, currentUser *models.User, id int) (*models.ChangeRequest, error) {
+	details := fmt.Sprintf("申请ID: %d", id)
+	req, err := c.getOrgService(r).RejectChangeRequest(currentUser, id)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "拒绝变更申请", details, err)
+		return nil, err
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "拒绝变更申请", describeChangeRequest(req), true)
+	return req, nil
+}
+
+// describeChangeRequest 生成变更申请的日志描述
+func describeChangeRequest(req *models.ChangeRequest) string {
+	return fmt.Sprintf("申请ID: %d, 操作: %s, 目标用户: %s (ID: %d), 申请人: %s",
+		req.ID, req.Action, req.TargetName, req.TargetID, req.RequesterName)
+}
+
+// parsePost 检查请求方法并解析表单，返回当前用户；失败时已写入错误响应
+func (c *ChangeRequestController) parsePost(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return nil, false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return nil, false
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// csrfToken 获取模板使用的CSRF令牌
+func (c *ChangeRequestController) csrfToken(r *http.Request) string {
+	csrfToken, err := c.getSessionHelper().GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		return ""
+	}
+	return csrfToken
+}
+
+// render 使用布局模板渲染页面
+func (c *ChangeRequestController) render(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", page)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// redirectWithError 将可以修正的错误作为提示带回审批页面，内部错误直接返回错误响应
+func (c *ChangeRequestController) redirectWithError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, "/change-requests", http.StatusSeeOther)
+}
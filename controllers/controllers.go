@@ -5,24 +5,47 @@ import (
 	"strings"
 
 	"user-management-system/app"
+	"user-management-system/models"
 )
 
 // Controllers 控制器集合
 type Controllers struct {
-	Auth *AuthController
-	User *UserController
+	Auth          *AuthController
+	User          *UserController
+	Profile       *ProfileController
+	Invitation    *InvitationController
+	Role          *RoleController
+	Group         *GroupController
+	Organization  *OrganizationController
+	AdminGrant    *AdminGrantController
+	ChangeRequest *ChangeRequestController
+	Audit         *AuditController
+	Metrics       *MetricsController
+	Health        *HealthController
 }
 
 // NewControllers 创建控制器集合
 // 注意：不再在这里初始化服务，而是让每个控制器自己管理
 func NewControllers(application *app.App) *Controllers {
 	return &Controllers{
-		Auth: NewAuthController(application),
-		User: NewUserController(application),
+		Auth:          NewAuthController(application),
+		User:          NewUserController(application),
+		Profile:       NewProfileController(application),
+		Invitation:    NewInvitationController(application),
+		Role:          NewRoleController(application),
+		Group:         NewGroupController(application),
+		Organization:  NewOrganizationController(application),
+		AdminGrant:    NewAdminGrantController(application),
+		ChangeRequest: NewChangeRequestController(application),
+		Audit:         NewAuditController(application),
+		Metrics:       NewMetricsController(application),
+		Health:        NewHealthController(application),
 	}
 }
 
 // funcMap 定义模板函数
 var funcMap = template.FuncMap{
-	"upper": strings.ToUpper,
+	"upper":           strings.ToUpper,
+	"roleLabel":       models.RoleLabel,
+	"permissionLabel": models.PermissionLabel,
 }
@@ -0,0 +1,694 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+	"user-management-system/tenant"
+)
+
+// GroupController 用户组管理控制器
+type GroupController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	groupService  services.GroupService
+	userService   services.UserService
+	roleService   services.RoleService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewGroupController 创建用户组管理控制器
+func NewGroupController(application *app.App) *GroupController {
+	return &GroupController{
+		app: application,
+	}
+}
+
+// getGroupService 延迟初始化用户组服务，以及选择成员和角色所需的用户、角色服务
+func (c *GroupController) getGroupService() services.GroupService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建服务
+		svc := services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		})
+		c.groupService = svc.GroupService
+		c.userService = svc.UserService
+		c.roleService = svc.RoleService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("用户组服务已初始化", "controller", "GroupController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.groupService
+}
+
+// getSessionHelper 获取会话助手
+func (c *GroupController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getGroupService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getOrgService 返回限定在当前请求所在组织内、在审计事件中记录当前请求信息的用户组服务，方法调用记录在当前请求的追踪中
+func (c *GroupController) getOrgService(r *http.Request) services.GroupService {
+	return c.getGroupService().ForOrganization(tenant.ID(r.Context())).ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// getOrgUserService 返回限定在当前请求所在组织内的用户服务，方法调用记录在当前请求的追踪中
+func (c *GroupController) getOrgUserService(r *http.Request) services.UserService {
+	// 确保服务已初始化
+	c.getGroupService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.userService.ForOrganization(tenant.ID(r.Context())).WithContext(r.Context())
+}
+
+// getRoleService 返回在当前请求的追踪中记录方法调用的角色服务
+func (c *GroupController) getRoleService(r *http.Request) services.RoleService {
+	// 确保服务已初始化
+	c.getGroupService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.roleService.WithContext(r.Context())
+}
+
+// groupRow 按层级排列的用户组，用于在页面中缩进展示
+type groupRow struct {
+	*models.Group
+	Parent string // 上级组名称
+	Depth  int    // 层级，顶级组为0
+}
+
+// Indent 返回与层级对应的缩进前缀
+func (g groupRow) Indent() string {
+	return strings.Repeat("　", g.Depth)
+}
+
+// groupTree 将用户组按层级排列：每个组紧跟在其上级组之后，同级按名称排序
+func groupTree(groups []*models.Group) []groupRow {
+	byID := make(map[int]*models.Group, len(groups))
+	children := make(map[int][]*models.Group)
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+	var roots []*models.Group
+	for _, group := range groups {
+		if group.ParentID != nil && byID[*group.ParentID] != nil {
+			children[*group.ParentID] = append(children[*group.ParentID], group)
+		} else {
+			roots = append(roots, group)
+		}
+	}
+
+	rows := make([]groupRow, 0, len(groups))
+	visited := make(map[int]bool, len(groups))
+	var walk func(group *models.Group, depth int)
+	walk = func(group *models.Group, depth int) {
+		if visited[group.ID] {
+			return
+		}
+		visited[group.ID] = true
+		row := groupRow{Group: group, Depth: depth}
+		if group.ParentID != nil && byID[*group.ParentID] != nil {
+			row.Parent = byID[*group.ParentID].Name
+		}
+		rows = append(rows, row)
+		for _, child := range children[group.ID] {
+			walk(child, depth+1)
+		}
+	}
+	// 仓库按名称返回用户组，因此同级的组保持名称顺序
+	for _, group := range roots {
+		walk(group, 0)
+	}
+	return rows
+}
+
+// RenderGroupsPage 渲染用户组列表页面
+func (c *GroupController) RenderGroupsPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	groupService := c.getOrgService(r)
+	groups, err := groupService.GetGroups()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	roles, err := c.getRoleService(r).GetRoles()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.render(w, r, "views/groups.html", struct {
+		CurrentUser *models.User
+		Groups      []groupRow
+		Roles       []*models.Role
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Groups:      groupTree(groups),
+		Roles:       roles,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   c.csrfToken(r),
+	})
+}
+
+// RenderEditGroupPage 渲染编辑用户组页面，包括组的成员
+func (c *GroupController) RenderEditGroupPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户组ID"))
+		return
+	}
+
+	groupService := c.getOrgService(r)
+	group, err := groupService.GetGroup(id)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	groups, err := groupService.GetGroups()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	roles, err := c.getRoleService(r).GetRoles()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	members, err := groupService.GetGroupMembers(id)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	users, err := c.getOrgUserService(r).GetAllUsers()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	// 可以加入的用户：尚不是该组直接成员的用户
+	isMember := make(map[int]bool, len(members))
+	for _, member := range members {
+		isMember[member.ID] = true
+	}
+	candidates := make([]*models.User, 0, len(users))
+	for _, user := range users {
+		if !isMember[user.ID] {
+			candidates = append(candidates, user)
+		}
+	}
+
+	// 上级组不能是组本身（移动到下级组之下由服务层拒绝）
+	parents := make([]groupRow, 0, len(groups))
+	for _, row := range groupTree(groups) {
+		if row.ID != group.ID {
+			parents = append(parents, row)
+		}
+	}
+
+	c.render(w, r, "views/group_edit.html", struct {
+		CurrentUser *models.User
+		Group       *models.Group
+		Parents     []groupRow
+		Roles       []*models.Role
+		Members     []*models.User
+		Candidates  []*models.User
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Group:       group,
+		Parents:     parents,
+		Roles:       roles,
+		Members:     members,
+		Candidates:  candidates,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   c.csrfToken(r),
+	})
+}
+
+// HandleCreateGroup 处理创建用户组的请求
+func (c *GroupController) HandleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	input, err := groupInputFromForm(r)
+	if err != nil {
+		c.redirectWithError(w, r, "/groups", err)
+		return
+	}
+	details := fmt.Sprintf("用户组: %s, 角色: %s", input.Name, strings.Join(input.Roles, ","))
+	group, err := c.getOrgService(r).CreateGroup(currentUser, input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建用户组", details, err)
+		c.redirectWithError(w, r, "/groups", err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "创建用户组", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "用户组 "+group.Name+" 已创建")
+	http.Redirect(w, r, fmt.Sprintf("/groups/edit?id=%d", group.ID), http.StatusSeeOther)
+}
+
+// HandleUpdateGroup 处理修改用户组的请求
+func (c *GroupController) HandleUpdateGroup(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("group_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户组ID"))
+		return
+	}
+	back := fmt.Sprintf("/groups/edit?id=%d", id)
+
+	input, err := groupInputFromForm(r)
+	if err != nil {
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+	details := fmt.Sprintf("用户组ID: %d, 名称: %s, 上级组ID: %d, 角色: %s",
+		id, input.Name, input.ParentID, strings.Join(input.Roles, ","))
+	group, err := c.getOrgService(r).UpdateGroup(currentUser, id, input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "修改用户组", details, err)
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "修改用户组", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "用户组 "+group.Name+" 已保存")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// HandleDeleteGroup 处理删除用户组的请求
+func (c *GroupController) HandleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("group_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户组ID"))
+		return
+	}
+
+	details := fmt.Sprintf("用户组ID: %d", id)
+	if err := c.getOrgService(r).DeleteGroup(currentUser, id); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "删除用户组", details, err)
+		c.redirectWithError(w, r, "/groups", err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "删除用户组", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "用户组已删除")
+	http.Redirect(w, r, "/groups", http.StatusSeeOther)
+}
+
+// HandleAddMembers 处理将用户加入组的请求
+// 表单字段：group_id、user_ids（可多个）
+func (c *GroupController) HandleAddMembers(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("group_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户组ID"))
+		return
+	}
+	userIDs, err := parseIDs(r.Form["user_ids"])
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	back := fmt.Sprintf("/groups/edit?id=%d", id)
+	details := fmt.Sprintf("用户组ID: %d, 用户ID: %v", id, userIDs)
+	if err := c.getOrgService(r).AddGroupMembers(currentUser, id, userIDs); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "添加组成员", details, err)
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "添加组成员", details, true)
+	c.getSessionHelper().SetFlash(r, "success", fmt.Sprintf("已添加 %d 个成员", len(userIDs)))
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// HandleRemoveMember 处理将用户移出组的请求
+func (c *GroupController) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("group_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户组ID"))
+		return
+	}
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+		return
+	}
+
+	back := fmt.Sprintf("/groups/edit?id=%d", id)
+	details := fmt.Sprintf("用户组ID: %d, 用户ID: %d", id, userID)
+	if err := c.getOrgService(r).RemoveGroupMember(currentUser, id, userID); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "移除组成员", details, err)
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "移除组成员", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "成员已移出用户组")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// HandleAPIGroups 通过 API 列出或创建用户组
+// GET /api/groups 返回全部用户组；POST /api/groups 的请求体为 services.GroupInput
+func (c *GroupController) HandleAPIGroups(w http.ResponseWriter, r *http.Request) {
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		groups, err := c.getOrgService(r).GetGroups()
+		if err != nil {
+			errors.HandleError(w, r, err)
+			return
+		}
+		if groups == nil {
+			groups = []*models.Group{}
+		}
+		writeJSON(w, http.StatusOK, groups)
+	case http.MethodPost:
+		var input services.GroupInput
+		if err := decodeJSON(r, &input); err != nil {
+			errors.HandleError(w, r, err)
+			return
+		}
+
+		details := fmt.Sprintf("用户组: %s, 角色: %s", input.Name, strings.Join(input.Roles, ","))
+		group, err := c.getOrgService(r).CreateGroup(currentUser, &input)
+		if err != nil {
+			logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建用户组", details, err)
+			errors.HandleError(w, r, err)
+			return
+		}
+		logger.UserActionContext(r.Context(), currentUser.Username, "创建用户组", details, true)
+		writeJSON(w, http.StatusCreated, group)
+	default:
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+	}
+}
+
+// HandleAPIUpdateGroup 通过 API 修改用户组
+// POST /api/groups/update，请求体为 {"id": 1, "name": "...", "description": "...", "parent_id": 0, "roles": [...]}
+func (c *GroupController) HandleAPIUpdateGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int `json:"id"`
+		services.GroupInput
+	}
+	currentUser, ok := c.decodeAPIRequest(w, r, &req)
+	if !ok {
+		return
+	}
+
+	details := fmt.Sprintf("用户组ID: %d, 名称: %s, 上级组ID: %d, 角色: %s",
+		req.ID, req.Name, req.ParentID, strings.Join(req.Roles, ","))
+	group, err := c.getOrgService(r).UpdateGroup(currentUser, req.ID, &req.GroupInput)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "修改用户组", details, err)
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "修改用户组", details, true)
+	writeJSON(w, http.StatusOK, group)
+}
+
+// HandleAPIDeleteGroup 通过 API 删除用户组
+// POST /api/groups/delete，请求体为 {"id": 1}
+func (c *GroupController) HandleAPIDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int `json:"id"`
+	}
+	currentUser, ok := c.decodeAPIRequest(w, r, &req)
+	if !ok {
+		return
+	}
+
+	details := fmt.Sprintf("用户组ID: %d", req.ID)
+	if err := c.getOrgService(r).DeleteGroup(currentUser, req.ID); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "删除用户组", details, err)
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "删除用户组", details, true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAPIGroupMembers 通过 API 列出、添加或移除组成员
+// GET /api/groups/members?id=1 返回直接成员；
+// POST /api/groups/members 的请求体为 {"group_id": 1, "action": "add|remove", "user_ids": [2, 3]}
+func (c *GroupController) HandleAPIGroupMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			errors.HandleError(w, r, errors.NewValidationError("", "无效的用户组ID"))
+			return
+		}
+		members, err := c.getOrgService(r).GetGroupMembers(id)
+		if err != nil {
+			errors.HandleError(w, r, err)
+			return
+		}
+		if members == nil {
+			members = []*models.User{}
+		}
+		writeJSON(w, http.StatusOK, members)
+		return
+	}
+
+	var req struct {
+		GroupID int    `json:"group_id"`
+		Action  string `json:"action"`
+		UserIDs []int  `json:"user_ids"`
+	}
+	currentUser, ok := c.decodeAPIRequest(w, r, &req)
+	if !ok {
+		return
+	}
+
+	groupService := c.getOrgService(r)
+	details := fmt.Sprintf("用户组ID: %d, 用户ID: %v", req.GroupID, req.UserIDs)
+	var action string
+	var err error
+	switch req.Action {
+	case "add":
+		action = "添加组成员"
+		err = groupService.AddGroupMembers(currentUser, req.GroupID, req.UserIDs)
+	case "remove":
+		action = "移除组成员"
+		for _, userID := range req.UserIDs {
+			if err = groupService.RemoveGroupMember(currentUser, req.GroupID, userID); err != nil {
+				break
+			}
+		}
+	default:
+		errors.HandleError(w, r, errors.NewValidationError("action", "无效的操作"))
+		return
+	}
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, action, details, err)
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, action, details, true)
+	members, err := groupService.GetGroupMembers(req.GroupID)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	if members == nil {
+		members = []*models.User{}
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+// groupInputFromForm 从表单读取用户组内容
+func groupInputFromForm(r *http.Request) (*services.GroupInput, error) {
+	input := &services.GroupInput{
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Roles:       r.Form["roles"],
+	}
+	if parent := r.FormValue("parent_id"); parent != "" {
+		parentID, err := strconv.Atoi(parent)
+		if err != nil {
+			return nil, errors.NewValidationError("parent_id", "无效的上级组")
+		}
+		input.ParentID = parentID
+	}
+	return input, nil
+}
+
+// parseIDs 解析表单中的多个ID
+func parseIDs(values []string) ([]int, error) {
+	ids := make([]int, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.NewValidationError("", "无效的用户ID")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// decodeAPIRequest 检查请求方法并解析 JSON 请求体，返回当前用户；失败时已写入错误响应
+func (c *GroupController) decodeAPIRequest(w http.ResponseWriter, r *http.Request, v interface{}) (*models.User, bool) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return nil, false
+	}
+
+	if err := decodeJSON(r, v); err != nil {
+		errors.HandleError(w, r, err)
+		return nil, false
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// parsePost 检查请求方法并解析表单，返回当前用户；失败时已写入错误响应
+func (c *GroupController) parsePost(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return nil, false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return nil, false
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// csrfToken 获取模板使用的CSRF令牌
+func (c *GroupController) csrfToken(r *http.Request) string {
+	csrfToken, err := c.getSessionHelper().GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		return ""
+	}
+	return csrfToken
+}
+
+// render 使用布局模板渲染页面
+func (c *GroupController) render(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", page)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// redirectWithError 将可以修正的错误作为提示带回 target 页面，内部错误直接返回错误响应
+func (c *GroupController) redirectWithError(w http.ResponseWriter, r *http.Request, target string, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/config"
+	"user-management-system/health"
+	"user-management-system/logger"
+	"user-management-system/mail"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/session"
+)
+
+// HealthController 存活与就绪检查
+type HealthController struct {
+	app           *app.App
+	registry      *health.Registry
+	sessionHelper *session.Helper
+	once          sync.Once // 确保检查项只注册一次
+}
+
+// NewHealthController 创建健康检查控制器
+func NewHealthController(application *app.App) *HealthController {
+	return &HealthController{
+		app: application,
+	}
+}
+
+// getRegistry 延迟注册依赖数据库、会话和邮件配置的检查项，返回全局注册表
+func (c *HealthController) getRegistry() *health.Registry {
+	c.once.Do(func() {
+		cfg := config.GetConfig()
+
+		checks := []health.Check{
+			{Name: "database", Func: health.DatabaseCheck(c.app.GetDB()), Critical: true},
+			{Name: "session_store", Func: health.SessionStoreCheck(c.app.GetSessionManager()), Critical: true, Liveness: true},
+			{Name: "mail", Func: health.MailCheck(mail.NewMailer(cfg))},
+		}
+		if cfg.HealthMinFreeDiskMB > 0 && cfg.LogOutput != "stdout" {
+			checks = append(checks, health.Check{
+				Name: "log_disk",
+				Func: health.DiskSpaceCheck(cfg.LogDir, uint64(cfg.HealthMinFreeDiskMB)<<20),
+			})
+		}
+		health.Default.Register(checks...)
+		c.registry = health.Default
+
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), mysql.NewUserRepository(c.app.GetDB()))
+
+		logger.Debug("健康检查已注册", "controller", "HealthController")
+	})
+	return c.registry
+}
+
+// ServeLiveness 存活检查（/healthz）：只检查进程自身，失败时应重启进程
+func (c *HealthController) ServeLiveness(w http.ResponseWriter, r *http.Request) {
+	if !allowGetOrHead(w, r) {
+		return
+	}
+	c.writeReport(w, r, c.getRegistry().Liveness(r.Context()))
+}
+
+// ServeReadiness 就绪检查（/readyz、/health）：关键依赖不可用或正在关闭时返回 503
+func (c *HealthController) ServeReadiness(w http.ResponseWriter, r *http.Request) {
+	if !allowGetOrHead(w, r) {
+		return
+	}
+	c.writeReport(w, r, c.getRegistry().Readiness(r.Context()))
+}
+
+// writeReport 输出检查结果：超级管理员或持有 MetricsToken 的请求得到 JSON 详情，其他请求只得到状态
+func (c *HealthController) writeReport(w http.ResponseWriter, r *http.Request, report *health.Report) {
+	status := http.StatusOK
+	if report.Status == health.StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !c.canViewDetails(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			fmt.Fprintln(w, "OK")
+		} else {
+			fmt.Fprintln(w, "UNAVAILABLE")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.FromContext(r.Context()).Warn("输出健康检查结果失败", "error", err)
+	}
+}
+
+// canViewDetails 检查请求能否查看检查详情：错误信息可能包含内部地址，只对可以管理所有组织的超级管理员公开，
+// 组织管理员（同样拥有 users:manage）不能查看
+func (c *HealthController) canViewDetails(r *http.Request) bool {
+	if token := config.GetConfig().MetricsToken; token != "" && hasBearerToken(r, token) {
+		return true
+	}
+
+	// 没有会话的探测请求不会查询数据库
+	currentUser, err := c.sessionHelper.GetCurrentUser(r)
+	return err == nil && currentUser.HasPermission(models.PermOrgsManage)
+}
+
+// allowGetOrHead 只允许 GET 和 HEAD 请求
+func allowGetOrHead(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+	return false
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/config"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+	"user-management-system/tenant"
+)
+
+// InvitationController 注册邀请控制器
+type InvitationController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	userService   services.UserService
+	roleService   services.RoleService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewInvitationController 创建注册邀请控制器
+func NewInvitationController(application *app.App) *InvitationController {
+	return &InvitationController{
+		app: application,
+	}
+}
+
+// getUserService 延迟初始化用户服务
+func (c *InvitationController) getUserService() services.UserService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建服务
+		svc := services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		})
+		c.userService = svc.UserService
+		c.roleService = svc.RoleService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("用户服务已初始化", "controller", "InvitationController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.userService
+}
+
+// getSessionHelper 获取会话助手
+func (c *InvitationController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getUserService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getOrgService 返回限定在当前请求所在组织内、在审计事件中记录当前请求信息的用户服务，方法调用记录在当前请求的追踪中
+func (c *InvitationController) getOrgService(r *http.Request) services.UserService {
+	return c.getUserService().ForOrganization(tenant.ID(r.Context())).ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// getRoleService 返回在当前请求的追踪中记录方法调用的角色服务
+func (c *InvitationController) getRoleService(r *http.Request) services.RoleService {
+	// 确保服务已初始化
+	c.getUserService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.roleService.WithContext(r.Context())
+}
+
+// RenderInvitationsPage 渲染邀请管理页面
+func (c *InvitationController) RenderInvitationsPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	invitations, err := c.getOrgService(r).GetInvitations()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	roles, err := c.getRoleService(r).GetRoles()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	csrfToken, err := sessionHelper.GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		csrfToken = ""
+	}
+
+	data := struct {
+		CurrentUser *models.User
+		Invitations []*models.Invitation
+		Roles       []*models.Role
+		InviteOnly  bool
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Invitations: invitations,
+		Roles:       roles,
+		InviteOnly:  config.GetConfig().RegistrationMode == models.RegistrationInvite,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   csrfToken,
+	}
+
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/invitations.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/invitations.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/invitations.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// HandleCreateInvitation 处理发出邀请的请求
+func (c *InvitationController) HandleCreateInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+	email := r.FormValue("email")
+	role := r.FormValue("role")
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	details := fmt.Sprintf("邮箱: %s, 角色: %s", email, role)
+	if _, err := c.getOrgService(r).CreateInvitation(currentUser, email, role); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "发出邀请", details, err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "发出邀请", details, true)
+	sessionHelper.SetFlash(r, "success", "邀请已发送至 "+email)
+	http.Redirect(w, r, "/invitations", http.StatusSeeOther)
+}
+
+// HandleResendInvitation 处理重新发送邀请的请求
+func (c *InvitationController) HandleResendInvitation(w http.ResponseWriter, r *http.Request) {
+	c.handleInvitationAction(w, r, "重新发送邀请", func(actor *models.User, id int) (string, error) {
+		invitation, err := c.getOrgService(r).ResendInvitation(actor, id)
+		if err != nil {
+			return "", err
+		}
+		return "邀请已重新发送至 " + invitation.Email, nil
+	})
+}
+
+// HandleRevokeInvitation 处理撤销邀请的请求
+func (c *InvitationController) HandleRevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	c.handleInvitationAction(w, r, "撤销邀请", func(actor *models.User, id int) (string, error) {
+		if err := c.getOrgService(r).RevokeInvitation(actor, id); err != nil {
+			return "", err
+		}
+		return "邀请已撤销", nil
+	})
+}
+
+// handleInvitationAction 处理针对单个邀请的操作，action 返回成功提示
+func (c *InvitationController) handleInvitationAction(w http.ResponseWriter, r *http.Request, name string,
+	action func(actor *models.User, id int) (string, error)) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("invitation_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的邀请ID"))
+		return
+	}
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	details := fmt.Sprintf("邀请ID: %d", id)
+	message, err := action(currentUser, id)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, name, details, err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, name, details, true)
+	sessionHelper.SetFlash(r, "success", message)
+	http.Redirect(w, r, "/invitations", http.StatusSeeOther)
+}
+
+// redirectWithError 将可以修正的错误作为提示带回邀请页，内部错误直接返回错误响应
+func (c *InvitationController) redirectWithError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, "/invitations", http.StatusSeeOther)
+}
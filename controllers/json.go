@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"user-management-system/errors"
+)
+
+// writeJSON 以 JSON 格式写入响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		errors.NewInternalError(err).LogError()
+	}
+}
+
+// decodeJSON 解析 JSON 请求体
+func decodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errors.NewValidationError("", "无法解析请求体")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/config"
+	"user-management-system/logger"
+	"user-management-system/metrics"
+	"user-management-system/repository/interfaces"
+	"user-management-system/repository/mysql"
+)
+
+// MetricsController 以 Prometheus 文本格式输出运行指标
+type MetricsController struct {
+	app      *app.App
+	registry *metrics.Registry
+	once     sync.Once // 确保依赖应用的指标只注册一次
+	warnOnce sync.Once // 未配置令牌的警告只记录一次
+}
+
+// NewMetricsController 创建指标控制器
+func NewMetricsController(application *app.App) *MetricsController {
+	return &MetricsController{
+		app: application,
+	}
+}
+
+// getRegistry 延迟注册依赖数据库和会话的指标，返回全局注册表
+func (c *MetricsController) getRegistry() *metrics.Registry {
+	c.once.Do(func() {
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+		sessionManager := c.app.GetSessionManager()
+
+		metrics.Default.MustRegister(
+			metrics.NewGaugeFunc("userhub_active_sessions", "未过期的登录会话数", func() float64 {
+				return float64(sessionManager.ActiveCount())
+			}),
+			usersByRoleCollector(userRepo),
+			metrics.NewDBStatsCollector(c.app.GetDB()),
+		)
+		c.registry = metrics.Default
+
+		logger.Debug("指标已注册", "controller", "MetricsController")
+	})
+	return c.registry
+}
+
+// usersByRoleCollector 每次抓取时按角色统计用户数，查询失败时只记录日志、不输出该指标
+func usersByRoleCollector(userRepo interfaces.UserRepository) metrics.Collector {
+	return metrics.CollectorFunc(func() []*metrics.Family {
+		counts, err := userRepo.CountGroupByRole()
+		if err != nil {
+			logger.Warn("统计各角色用户数失败", "error", err)
+			return nil
+		}
+
+		f := &metrics.Family{Name: "userhub_users", Help: "直接持有各角色的用户数（不含已删除的用户）", Type: metrics.TypeGauge}
+		roles := make([]string, 0, len(counts))
+		for role := range counts {
+			roles = append(roles, role)
+		}
+		sort.Strings(roles)
+		for _, role := range roles {
+			f.Samples = append(f.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "role", Value: role}},
+				Value:  float64(counts[role]),
+			})
+		}
+		return []*metrics.Family{f}
+	})
+}
+
+// ServeMetrics 输出指标，要求 Authorization: Bearer <MetricsToken>
+// 未配置令牌时拒绝所有请求，除非配置了 MetricsAllowAnonymous
+func (c *MetricsController) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := config.GetConfig()
+	switch {
+	case cfg.MetricsToken != "":
+		if !hasBearerToken(r, cfg.MetricsToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "未授权", http.StatusUnauthorized)
+			return
+		}
+	case !cfg.MetricsAllowAnonymous:
+		c.warnOnce.Do(func() {
+			logger.FromContext(r.Context()).Warn("未配置 MetricsToken，/metrics 已关闭；如需匿名访问请配置 MetricsAllowAnonymous")
+		})
+		http.Error(w, "指标接口未启用", http.StatusForbidden)
+		return
+	}
+
+	c.getRegistry().Handler().ServeHTTP(w, r)
+}
+
+// hasBearerToken 检查请求是否带有 Authorization: Bearer <token>
+func hasBearerToken(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+)
+
+// OrganizationController 组织管理控制器，只有拥有 orgs:manage 权限的超级管理员可以访问
+type OrganizationController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	orgService    services.OrganizationService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewOrganizationController 创建组织管理控制器
+func NewOrganizationController(application *app.App) *OrganizationController {
+	return &OrganizationController{
+		app: application,
+	}
+}
+
+// getOrganizationService 延迟初始化组织服务
+// 组织管理跨越所有组织，因此使用不限定组织的服务
+func (c *OrganizationController) getOrganizationService() services.OrganizationService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建组织服务
+		c.orgService = services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		}).OrganizationService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("组织服务已初始化", "controller", "OrganizationController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.orgService
+}
+
+// getSessionHelper 获取会话助手
+func (c *OrganizationController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getOrganizationService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getRequestService 返回在审计事件中记录当前请求信息的组织服务，方法调用记录在当前请求的追踪中
+func (c *OrganizationController) getRequestService(r *http.Request) services.OrganizationService {
+	return c.getOrganizationService().ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// RenderOrganizationsPage 渲染组织列表页面
+func (c *OrganizationController) RenderOrganizationsPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	orgs, err := c.getOrganizationService().WithContext(r.Context()).GetOrganizations()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.render(w, r, "views/organizations.html", struct {
+		CurrentUser   *models.User
+		Organizations []*models.Organization
+		Flash         *session.Flash
+		CSRFToken     string
+	}{
+		CurrentUser:   currentUser,
+		Organizations: orgs,
+		Flash:         sessionHelper.PopFlash(r),
+		CSRFToken:     c.csrfToken(r),
+	})
+}
+
+// RenderEditOrganizationPage 渲染编辑组织页面，包括组织的成员
+func (c *OrganizationController) RenderEditOrganizationPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的组织ID"))
+		return
+	}
+
+	orgService := c.getOrganizationService().WithContext(r.Context())
+	org, err := orgService.GetOrganization(id)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	members, err := orgService.GetOrganizationMembers(id)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.render(w, r, "views/organization_edit.html", struct {
+		CurrentUser  *models.User
+		Organization *models.Organization
+		Members      []*models.User
+		Flash        *session.Flash
+		CSRFToken    string
+	}{
+		CurrentUser:  currentUser,
+		Organization: org,
+		Members:      members,
+		Flash:        sessionHelper.PopFlash(r),
+		CSRFToken:    c.csrfToken(r),
+	})
+}
+
+// HandleCreateOrganization 处理创建组织的请求
+func (c *OrganizationController) HandleCreateOrganization(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	input := organizationInputFromForm(r)
+	details := fmt.Sprintf("标识: %s, 名称: %s", input.Slug, input.Name)
+	org, err := c.getRequestService(r).CreateOrganization(currentUser, input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建组织", details, err)
+		c.redirectWithError(w, r, "/organizations", err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "创建组织", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "组织 "+org.Name+" 已创建")
+	http.Redirect(w, r, fmt.Sprintf("/organizations/edit?id=%d", org.ID), http.StatusSeeOther)
+}
+
+// HandleUpdateOrganization 处理修改组织的请求
+func (c *OrganizationController) HandleUpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("organization_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的组织ID"))
+		return
+	}
+	back := fmt.Sprintf("/organizations/edit?id=%d", id)
+
+	input := organizationInputFromForm(r)
+	details := fmt.Sprintf("组织ID: %d, 标识: %s, 名称: %s", id, input.Slug, input.Name)
+	org, err := c.getRequestService(r).UpdateOrganization(currentUser, id, input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "修改组织", details, err)
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "修改组织", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "组织 "+org.Name+" 已保存")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// HandleDeleteOrganization 处理删除组织的请求
+func (c *OrganizationController) HandleDeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("organization_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的组织ID"))
+		return
+	}
+
+	details := fmt.Sprintf("组织ID: %d", id)
+	if err := c.getRequestService(r).DeleteOrganization(currentUser, id); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "删除组织", details, err)
+		c.redirectWithError(w, r, "/organizations", err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "删除组织", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "组织已删除")
+	http.Redirect(w, r, "/organizations", http.StatusSeeOther)
+}
+
+// HandleAddMember 处理按用户名将用户加入组织的请求
+func (c *OrganizationController) HandleAddMember(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("organization_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的组织ID"))
+		return
+	}
+	username := r.FormValue("username")
+
+	back := fmt.Sprintf("/organizations/edit?id=%d", id)
+	details := fmt.Sprintf("组织ID: %d, 用户: %s", id, username)
+	if err := c.getRequestService(r).AddOrganizationMember(currentUser, id, username); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "添加组织成员", details, err)
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "添加组织成员", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "已将 "+username+" 加入组织")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// HandleRemoveMember 处理将用户移出组织的请求
+func (c *OrganizationController) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("organization_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的组织ID"))
+		return
+	}
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+		return
+	}
+
+	back := fmt.Sprintf("/organizations/edit?id=%d", id)
+	details := fmt.Sprintf("组织ID: %d, 用户ID: %d", id, userID)
+	if err := c.getRequestService(r).RemoveOrganizationMember(currentUser, id, userID); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "移除组织成员", details, err)
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "移除组织成员", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "成员已移出组织")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// organizationInputFromForm 从表单读取组织的标识和名称
+func organizationInputFromForm(r *http.Request) *services.OrganizationInput {
+	return &services.OrganizationInput{
+		Slug: r.FormValue("slug"),
+		Name: r.FormValue("name"),
+	}
+}
+
+// parsePost 检查请求方法并解析表单，返回当前用户；失败时已写入错误响应
+func (c *OrganizationController) parsePost(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return nil, false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return nil, false
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// csrfToken 获取模板使用的CSRF令牌
+func (c *OrganizationController) csrfToken(r *http.Request) string {
+	csrfToken, err := c.getSessionHelper().GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		return ""
+	}
+	return csrfToken
+}
+
+// render 使用布局模板渲染页面
+func (c *OrganizationController) render(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", page)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// redirectWithError 将可以修正的错误作为提示带回 target 页面，内部错误直接返回错误响应
+func (c *OrganizationController) redirectWithError(w http.ResponseWriter, r *http.Request, target string, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
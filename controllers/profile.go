@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"html/template"
+	"net/http"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/password"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+)
+
+// ProfileController 个人资料控制器
+type ProfileController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	userService   services.UserService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewProfileController 创建个人资料控制器
+func NewProfileController(application *app.App) *ProfileController {
+	return &ProfileController{
+		app: application,
+	}
+}
+
+// getUserService 延迟初始化用户服务
+func (c *ProfileController) getUserService() services.UserService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建用户服务
+		c.userService = services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		}).UserService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("用户服务已初始化", "controller", "ProfileController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.userService
+}
+
+// getSessionHelper 获取会话助手
+func (c *ProfileController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getUserService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getRequestService 返回不限定组织、在审计事件中记录当前请求信息的用户服务，方法调用记录在当前请求的追踪中
+func (c *ProfileController) getRequestService(r *http.Request) services.UserService {
+	return c.getUserService().ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// RenderProfilePage 渲染个人资料页面
+func (c *ProfileController) RenderProfilePage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	// 查询是否有待验证的邮箱变更
+	pendingEmail, err := c.getUserService().WithContext(r.Context()).GetPendingEmailChange(currentUser.ID)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	csrfToken, err := sessionHelper.GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		csrfToken = ""
+	}
+
+	data := struct {
+		CurrentUser   *models.User
+		PendingEmail  *models.EmailVerification
+		Flash         *session.Flash
+		CSRFToken     string
+		PasswordRules []string
+	}{
+		CurrentUser:   currentUser,
+		PendingEmail:  pendingEmail,
+		Flash:         sessionHelper.PopFlash(r),
+		CSRFToken:     csrfToken,
+		PasswordRules: password.DefaultPolicy().Requirements(),
+	}
+
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/profile.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/profile.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/profile.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// HandleChangeEmail 处理修改邮箱请求（发送验证邮件）
+func (c *ProfileController) HandleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+	email := r.FormValue("email")
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	userService := c.getRequestService(r)
+	if err := userService.RequestEmailChange(currentUser, currentUser.ID, email); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "申请修改邮箱", "新邮箱: "+email, err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "申请修改邮箱", "新邮箱: "+email, true)
+	sessionHelper.SetFlash(r, "success", "验证邮件已发送至 "+email+"，请查收并点击链接完成修改")
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// HandleVerifyEmail 处理邮件中的验证链接
+func (c *ProfileController) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	userService := c.getRequestService(r)
+	user, err := userService.ConfirmEmailChange(token)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), user.Username, "验证新邮箱", "新邮箱: "+user.Email, true)
+
+	// 已登录时回到个人资料页，否则去登录
+	sessionHelper := c.getSessionHelper()
+	if _, err := sessionHelper.RequireLogin(r); err == nil {
+		sessionHelper.SetFlash(r, "success", "邮箱已更新为 "+user.Email)
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// HandleChangePassword 处理修改密码请求
+func (c *ProfileController) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+	currentPassword := r.FormValue("current_password")
+	newPassword := r.FormValue("new_password")
+	confirmPassword := r.FormValue("confirm_password")
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	if newPassword != confirmPassword {
+		c.redirectWithError(w, r, errors.NewValidationError("confirm_password", "两次输入的新密码不一致"))
+		return
+	}
+
+	userService := c.getRequestService(r)
+	if err := userService.ChangePassword(currentUser, currentUser.ID, currentPassword, newPassword); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "修改密码", "", err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	// 密码修改后让其他设备上的会话失效
+	revoked := sessionHelper.LogoutOtherSessions(r, currentUser.ID)
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "修改密码", "", true)
+	if revoked > 0 {
+		sessionHelper.SetFlash(r, "success", "密码已修改，其他设备上的登录已失效")
+	} else {
+		sessionHelper.SetFlash(r, "success", "密码已修改")
+	}
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// RenderRequiredPasswordChangePage 渲染强制修改密码页面（密码过期等情况）
+func (c *ProfileController) RenderRequiredPasswordChangePage(w http.ResponseWriter, r *http.Request) {
+	c.renderRequiredPasswordChange(w, r, "")
+}
+
+// HandleRequiredPasswordChange 处理强制修改密码
+func (c *ProfileController) HandleRequiredPasswordChange(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+	currentPassword := r.FormValue("current_password")
+	newPassword := r.FormValue("new_password")
+	confirmPassword := r.FormValue("confirm_password")
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	if newPassword != confirmPassword {
+		c.renderRequiredPasswordChange(w, r, "两次输入的新密码不一致")
+		return
+	}
+
+	userService := c.getRequestService(r)
+	if err := userService.ChangePassword(currentUser, currentUser.ID, currentPassword, newPassword); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "强制修改密码", "", err)
+		appErr, ok := errors.IsAppError(err)
+		if !ok || appErr.Type == errors.InternalError {
+			errors.HandleError(w, r, err)
+			return
+		}
+		c.renderRequiredPasswordChange(w, r, appErr.Message)
+		return
+	}
+
+	sessionHelper.ClearPasswordChange(r)
+	sessionHelper.LogoutOtherSessions(r, currentUser.ID)
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "强制修改密码", "", true)
+	sessionHelper.SetFlash(r, "success", "密码已修改")
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// renderRequiredPasswordChange 渲染强制修改密码页面，errMsg 为上一次提交的错误
+func (c *ProfileController) renderRequiredPasswordChange(w http.ResponseWriter, r *http.Request, errMsg string) {
+	sessionHelper := c.getSessionHelper()
+
+	sess, err := sessionHelper.RequireLogin(r)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	reason, required := session.PasswordChangeReason(sess)
+	if !required {
+		// 没有被要求修改密码时使用个人资料页中的普通修改流程
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+		return
+	}
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	csrfToken, err := sessionHelper.GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		csrfToken = ""
+	}
+
+	data := struct {
+		CurrentUser   *models.User
+		Reason        string
+		Error         string
+		CSRFToken     string
+		PasswordRules []string
+	}{
+		CurrentUser:   currentUser,
+		Reason:        reason,
+		Error:         errMsg,
+		CSRFToken:     csrfToken,
+		PasswordRules: password.DefaultPolicy().Requirements(),
+	}
+
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/password_change.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/password_change.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/password_change.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// redirectWithError 将用户可以修正的错误作为提示带回个人资料页，内部错误直接返回错误响应
+func (c *ProfileController) redirectWithError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/mysql"
+	"user-management-system/services"
+	"user-management-system/session"
+)
+
+// RoleController 角色管理控制器
+type RoleController struct {
+	app           *app.App
+	sessionHelper *session.Helper
+	roleService   services.RoleService
+	once          sync.Once    // 确保服务只初始化一次
+	mu            sync.RWMutex // 保护并发访问
+}
+
+// NewRoleController 创建角色管理控制器
+func NewRoleController(application *app.App) *RoleController {
+	return &RoleController{
+		app: application,
+	}
+}
+
+// getRoleService 延迟初始化角色服务
+func (c *RoleController) getRoleService() services.RoleService {
+	c.once.Do(func() {
+		// 创建用户仓库
+		userRepo := mysql.NewUserRepository(c.app.GetDB())
+
+		// 创建角色服务
+		c.roleService = services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		}).RoleService
+
+		// 创建会话助手
+		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
+
+		logger.Debug("角色服务已初始化", "controller", "RoleController")
+	})
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.roleService
+}
+
+// getSessionHelper 获取会话助手
+func (c *RoleController) getSessionHelper() *session.Helper {
+	// 确保服务已初始化
+	c.getRoleService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionHelper
+}
+
+// getRequestService 返回在审计事件中记录当前请求信息的角色服务，方法调用记录在当前请求的追踪中
+func (c *RoleController) getRequestService(r *http.Request) services.RoleService {
+	return c.getRoleService().ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// RenderRolesPage 渲染角色列表页面
+func (c *RoleController) RenderRolesPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	roles, err := c.getRoleService().WithContext(r.Context()).GetRoles()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.render(w, r, "views/roles.html", struct {
+		CurrentUser *models.User
+		Roles       []*models.Role
+		Permissions []models.Permission
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Roles:       roles,
+		Permissions: models.Permissions,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   c.csrfToken(r),
+	})
+}
+
+// RenderEditRolePage 渲染修改角色页面
+func (c *RoleController) RenderEditRolePage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的角色ID"))
+		return
+	}
+	role, err := c.getRoleService().WithContext(r.Context()).GetRole(id)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.render(w, r, "views/role_edit.html", struct {
+		CurrentUser *models.User
+		Role        *models.Role
+		Permissions []models.Permission
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Role:        role,
+		Permissions: models.Permissions,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   c.csrfToken(r),
+	})
+}
+
+// HandleCreateRole 处理创建角色的请求
+func (c *RoleController) HandleCreateRole(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	input := roleInputFromForm(r)
+	details := fmt.Sprintf("角色: %s, 权限: %s", input.Name, strings.Join(input.Permissions, ","))
+	role, err := c.getRequestService(r).CreateRole(currentUser, input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建角色", details, err)
+		c.redirectWithError(w, r, "/roles", err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "创建角色", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "角色 "+role.Name+" 已创建")
+	http.Redirect(w, r, "/roles", http.StatusSeeOther)
+}
+
+// HandlePreviewRole 展示修改或删除角色的影响范围，由管理员确认后再提交
+// 表单字段 action 为 update 时需要同时提交修改后的角色内容
+func (c *RoleController) HandlePreviewRole(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("role_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的角色ID"))
+		return
+	}
+
+	var preview *services.RoleChangePreview
+	back := "/roles"
+	switch r.FormValue("action") {
+	case "update":
+		back = fmt.Sprintf("/roles/edit?id=%d", id)
+		preview, err = c.getRoleService().WithContext(r.Context()).PreviewRoleUpdate(currentUser, id, roleInputFromForm(r))
+	case "delete":
+		preview, err = c.getRoleService().WithContext(r.Context()).PreviewRoleDelete(currentUser, id)
+	default:
+		err = errors.NewValidationError("action", "无效的操作")
+	}
+	if err != nil {
+		c.redirectWithError(w, r, back, err)
+		return
+	}
+
+	c.render(w, r, "views/role_preview.html", struct {
+		CurrentUser *models.User
+		Preview     *services.RoleChangePreview
+		Back        string
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Preview:     preview,
+		Back:        back,
+		CSRFToken:   c.csrfToken(r),
+	})
+}
+
+// HandleUpdateRole 处理修改角色的请求
+func (c *RoleController) HandleUpdateRole(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("role_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的角色ID"))
+		return
+	}
+
+	input := roleInputFromForm(r)
+	details := fmt.Sprintf("角色ID: %d, 标识: %s, 权限: %s", id, input.Name, strings.Join(input.Permissions, ","))
+	role, err := c.getRequestService(r).UpdateRole(currentUser, id, input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "修改角色", details, err)
+		c.redirectWithError(w, r, fmt.Sprintf("/roles/edit?id=%d", id), err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "修改角色", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "角色 "+role.Name+" 已保存")
+	http.Redirect(w, r, "/roles", http.StatusSeeOther)
+}
+
+// HandleDeleteRole 处理删除角色的请求
+func (c *RoleController) HandleDeleteRole(w http.ResponseWriter, r *http.Request) {
+	currentUser, ok := c.parsePost(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("role_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的角色ID"))
+		return
+	}
+
+	details := fmt.Sprintf("角色ID: %d", id)
+	if err := c.getRequestService(r).DeleteRole(currentUser, id); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "删除角色", details, err)
+		c.redirectWithError(w, r, "/roles", err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "删除角色", details, true)
+	c.getSessionHelper().SetFlash(r, "success", "角色已删除")
+	http.Redirect(w, r, "/roles", http.StatusSeeOther)
+}
+
+// roleInputFromForm 从表单读取角色内容
+func roleInputFromForm(r *http.Request) *services.RoleInput {
+	return &services.RoleInput{
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Permissions: r.Form["permissions"],
+	}
+}
+
+// parsePost 检查请求方法并解析表单，返回当前用户；失败时已写入错误响应
+func (c *RoleController) parsePost(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return nil, false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return nil, false
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// csrfToken 获取模板使用的CSRF令牌
+func (c *RoleController) csrfToken(r *http.Request) string {
+	csrfToken, err := c.getSessionHelper().GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		return ""
+	}
+	return csrfToken
+}
+
+// render 使用布局模板渲染页面
+func (c *RoleController) render(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", page)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", page, "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// redirectWithError 将可以修正的错误作为提示带回 target 页面，内部错误直接返回错误响应
+func (c *RoleController) redirectWithError(w http.ResponseWriter, r *http.Request, target string, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
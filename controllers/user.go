@@ -3,18 +3,23 @@ package controllers
 import (
 	"fmt"
 	"html/template"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"user-management-system/app"
+	"user-management-system/audit"
+	"user-management-system/config"
 	"user-management-system/errors"
 	"user-management-system/logger"
 	"user-management-system/models"
+	"user-management-system/rbac"
 	"user-management-system/repository/mysql"
 	"user-management-system/services"
 	"user-management-system/session"
+	"user-management-system/tenant"
 )
 
 // UserController 用户管理控制器
@@ -22,6 +27,8 @@ type UserController struct {
 	app           *app.App
 	sessionHelper *session.Helper
 	userService   services.UserService
+	roleService   services.RoleService
+	groupService  services.GroupService
 	once          sync.Once    // 确保服务只初始化一次
 	mu            sync.RWMutex // 保护并发访问
 }
@@ -40,13 +47,19 @@ func (c *UserController) getUserService() services.UserService {
 		// 创建用户仓库
 		userRepo := mysql.NewUserRepository(c.app.GetDB())
 
-		// 创建用户服务
-		c.userService = services.NewUserService(userRepo)
+		// 创建服务
+		svc := services.NewService(&services.ServiceDependencies{
+			DB:             c.app.GetDB(),
+			UserRepository: userRepo,
+		})
+		c.userService = svc.UserService
+		c.roleService = svc.RoleService
+		c.groupService = svc.GroupService
 
 		// 创建会话助手
 		c.sessionHelper = session.NewHelper(c.app.GetSessionManager(), userRepo)
 
-		logger.Info("UserController: 用户服务已初始化")
+		logger.Debug("用户服务已初始化", "controller", "UserController")
 	})
 
 	c.mu.RLock()
@@ -64,6 +77,31 @@ func (c *UserController) getSessionHelper() *session.Helper {
 	return c.sessionHelper
 }
 
+// getOrgService 返回限定在当前请求所在组织内、在审计事件中记录当前请求信息的用户服务，方法调用记录在当前请求的追踪中
+func (c *UserController) getOrgService(r *http.Request) services.UserService {
+	return c.getUserService().ForOrganization(tenant.ID(r.Context())).ForRequest(audit.FromRequest(r)).WithContext(r.Context())
+}
+
+// getRoleService 返回在当前请求的追踪中记录方法调用的角色服务
+func (c *UserController) getRoleService(r *http.Request) services.RoleService {
+	// 确保服务已初始化
+	c.getUserService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.roleService.WithContext(r.Context())
+}
+
+// getGroupService 返回限定在当前请求所在组织内的用户组服务，方法调用记录在当前请求的追踪中
+func (c *UserController) getGroupService(r *http.Request) services.GroupService {
+	// 确保服务已初始化
+	c.getUserService()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.groupService.ForOrganization(tenant.ID(r.Context())).WithContext(r.Context())
+}
+
 // RenderHomePage 渲染首页
 func (c *UserController) RenderHomePage(w http.ResponseWriter, r *http.Request) {
 	// 获取当前用户
@@ -78,13 +116,13 @@ func (c *UserController) RenderHomePage(w http.ResponseWriter, r *http.Request)
 
 	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/index.html")
 	if err != nil {
-		log.Printf("模板解析错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/index.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 		return
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		log.Printf("模板执行错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/index.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 	}
 }
@@ -101,11 +139,21 @@ func (c *UserController) RenderUsersPage(w http.ResponseWriter, r *http.Request)
 	}
 
 	// 记录查看用户列表操作
-	logger.UserAction(currentUser.Username, "查看用户列表", "", true)
+	logger.UserActionContext(r.Context(), currentUser.Username, "查看用户列表", "", true)
 
-	// 获取所有用户
-	userService := c.getUserService()
-	users, err := userService.GetAllUsers()
+	// 获取所有用户，指定 group 参数时只显示该组及其下级组的成员
+	userService := c.getOrgService(r)
+	selectedGroup := 0
+	var users []*models.User
+	if group := r.URL.Query().Get("group"); group != "" {
+		if selectedGroup, err = strconv.Atoi(group); err != nil {
+			errors.HandleError(w, r, errors.NewValidationError("group", "无效的用户组ID"))
+			return
+		}
+		users, err = c.getGroupService(r).GetUsersInGroup(selectedGroup)
+	} else {
+		users, err = userService.GetAllUsers()
+	}
 	if err != nil {
 		errors.HandleError(w, r, err)
 		return
@@ -114,29 +162,59 @@ func (c *UserController) RenderUsersPage(w http.ResponseWriter, r *http.Request)
 	// 获取CSRF令牌
 	csrfToken, err := sessionHelper.GetCSRFTokenForTemplate(r)
 	if err != nil {
-		log.Printf("获取CSRF令牌失败: %v", err)
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
 		csrfToken = "" // 继续处理，但不使用CSRF保护
 	}
 
+	roles, err := c.getRoleService(r).GetRoles()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	groups, err := c.getGroupService(r).GetGroups()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	// 每个用户允许的操作，不允许的操作不显示
+	actions, err := userService.GetUserActions(currentUser, users)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
 	data := struct {
-		CurrentUser *models.User
-		Users       []*models.User
-		CSRFToken   string
+		CurrentUser   *models.User
+		Users         []*models.User
+		Actions       map[int]services.UserActions
+		Roles         []*models.Role
+		Groups        []groupRow
+		SelectedGroup int
+		ShowActions   bool
+		Flash         *session.Flash
+		CSRFToken     string
 	}{
-		CurrentUser: currentUser,
-		Users:       users,
-		CSRFToken:   csrfToken,
+		CurrentUser:   currentUser,
+		Users:         users,
+		Actions:       actions,
+		Roles:         roles,
+		Groups:        groupTree(groups),
+		SelectedGroup: selectedGroup,
+		ShowActions: hasAnyPermission(currentUser, models.PermUsersUpdate, models.PermUsersStatus,
+			models.PermUsersResetPassword, models.PermUsersDelete),
+		Flash:     sessionHelper.PopFlash(r),
+		CSRFToken: csrfToken,
 	}
 
 	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/users.html")
 	if err != nil {
-		log.Printf("模板解析错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/users.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 		return
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		log.Printf("模板执行错误: %v", err)
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/users.html", "error", err)
 		errors.HandleError(w, r, errors.NewInternalError(err))
 	}
 }
@@ -180,26 +258,18 @@ func (c *UserController) HandleDeleteUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// 获取要删除的用户信息（用于日志记录）
-	userService := c.getUserService()
-	targetUser, _ := userService.GetUserByID(userID)
-	targetUsername := ""
-	if targetUser != nil {
-		targetUsername = targetUser.Username
-	}
-
 	//删除用户
-	if err := userService.DeleteUser(userID); err != nil {
-		// 记录删除失败
-		logger.UserActionWithError(currentUser.Username, "删除用户",
-			fmt.Sprintf("目标用户: %s (ID: %d)", targetUsername, userID), err)
+	username, pending, err := c.deleteUser(r, currentUser, userID)
+	if err != nil {
 		errors.HandleError(w, r, err)
 		return
 	}
 
-	// 记录删除成功
-	logger.UserAction(currentUser.Username, "删除用户",
-		fmt.Sprintf("目标用户: %s (ID: %d)", targetUsername, userID), true)
+	if pending != nil {
+		sessionHelper.SetFlash(r, "success", "删除管理员 "+username+" 需要另一位管理员批准，已提交审批申请")
+	} else {
+		sessionHelper.SetFlash(r, "success", "用户 "+username+" 已移入回收站")
+	}
 	//重新定向到用户列表
 	http.Redirect(w, r, "/users", http.StatusSeeOther)
 }
@@ -229,7 +299,7 @@ func (c *UserController) HandleUpdateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 	email := r.FormValue("email")
-	role := r.FormValue("role")
+	roles := r.Form["roles"]
 
 	//获取当前用户
 	sessionHelper := c.getSessionHelper()
@@ -239,28 +309,891 @@ func (c *UserController) HandleUpdateUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	//获取更新的用户信息 (记录日志)
-	userService := c.getUserService()
-	targetUser, _ := userService.GetUserByID(userID)
-	targetUsername := ""
-	if targetUser != nil {
-		targetUsername = targetUser.Username
+	//更新用户
+	pending, err := c.updateUser(r, currentUser, userID, email, roles)
+	if err != nil {
+		c.redirectWithError(w, r, err)
+		return
+	}
+	if pending != nil {
+		sessionHelper.SetFlash(r, "success", "将 "+pending.TargetName+" 提升为管理员需要另一位管理员批准，已提交审批申请")
 	}
 
-	//更新用户
-	if err := userService.UpdateUser(userID, email, role); err != nil {
-		// 记录更新失败
-		logger.UserActionWithError(currentUser.Username, "更新用户",
-			fmt.Sprintf("目标用户: %s (ID: %d)", targetUsername, userID), err)
+	// 重定向到用户列表
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// HandleUpdateStatus 处理修改账户状态请求（启用、停用、锁定、解锁）
+func (c *UserController) HandleUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	//解析表单
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+
+	//获取表单数据
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+		return
+	}
+	status := r.FormValue("status")
+	reason := r.FormValue("reason")
+
+	//获取当前用户
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	if err := c.changeStatus(r, currentUser, userID, status, reason); err != nil {
 		errors.HandleError(w, r, err)
 		return
 	}
 
-	// 记录更新成功
-	logger.UserAction(currentUser.Username, "更新用户",
-		fmt.Sprintf("目标用户: %s (ID: %d), 邮箱: %s, 角色: %s",
-			targetUsername, userID, email, role), true)
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
 
-	// 重定向到用户列表
+// HandleStartImpersonation 处理开始模拟登录的请求，当前会话改为以目标用户的身份继续
+func (c *UserController) HandleStartImpersonation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+		return
+	}
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	userService := c.getOrgService(r)
+	details := fmt.Sprintf("管理员: %s (ID: %d), 被模拟用户: %s (ID: %d)",
+		currentUser.Username, currentUser.ID, targetUsername(userService, userID), userID)
+
+	target, err := userService.StartImpersonation(currentUser, userID, func(target *models.User) error {
+		return sessionHelper.StartImpersonation(r, target.ID)
+	})
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "开始模拟登录", details, err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "开始模拟登录", details, true)
+	sessionHelper.SetFlash(r, "success", "你正在以 "+target.Username+" 的身份浏览")
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// HandleStopImpersonation 处理结束模拟登录的请求，会话恢复为管理员本人
+func (c *UserController) HandleStopImpersonation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+	if !currentUser.IsImpersonated() {
+		http.Redirect(w, r, "/users", http.StatusSeeOther)
+		return
+	}
+
+	impersonator := currentUser.Impersonator
+	details := fmt.Sprintf("管理员: %s (ID: %d), 被模拟用户: %s (ID: %d)",
+		impersonator.Username, impersonator.ID, currentUser.Username, currentUser.ID)
+
+	err = c.getOrgService(r).StopImpersonation(currentUser, func() error {
+		_, err := sessionHelper.StopImpersonation(r)
+		return err
+	})
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), impersonator.Username, "结束模拟登录", details, err)
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), impersonator.Username, "结束模拟登录", details, true)
+	sessionHelper.SetFlash(r, "success", "已结束模拟登录")
 	http.Redirect(w, r, "/users", http.StatusSeeOther)
 }
+
+// trashEntry 回收站列表中的一行
+type trashEntry struct {
+	*models.User
+	PurgeAt *time.Time // 将被永久删除的时间，为空表示不会自动清除
+}
+
+// RenderTrashPage 渲染回收站页面
+func (c *UserController) RenderTrashPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	userService := c.getOrgService(r)
+	users, err := userService.GetDeletedUsers()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	entries := make([]trashEntry, 0, len(users))
+	for _, user := range users {
+		entries = append(entries, trashEntry{User: user, PurgeAt: userService.PurgeTime(user)})
+	}
+
+	csrfToken, err := sessionHelper.GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		csrfToken = ""
+	}
+
+	data := struct {
+		CurrentUser *models.User
+		Users       []trashEntry
+		Flash       *session.Flash
+		CSRFToken   string
+	}{
+		CurrentUser: currentUser,
+		Users:       entries,
+		Flash:       sessionHelper.PopFlash(r),
+		CSRFToken:   csrfToken,
+	}
+
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/trash.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/trash.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/trash.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// HandleRestoreUser 处理从回收站恢复用户的请求
+func (c *UserController) HandleRestoreUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	//解析表单
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+		return
+	}
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	details := fmt.Sprintf("目标用户ID: %d", userID)
+	if err := c.getOrgService(r).RestoreUser(currentUser, userID); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "恢复用户", details, err)
+
+		// 用户名或邮箱冲突等可处理的错误提示在回收站页面上
+		appErr, ok := errors.IsAppError(err)
+		if !ok || appErr.Type == errors.InternalError {
+			errors.HandleError(w, r, err)
+			return
+		}
+		sessionHelper.SetFlash(r, "error", appErr.Message)
+		http.Redirect(w, r, "/users/trash", http.StatusSeeOther)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "恢复用户", details, true)
+	sessionHelper.SetFlash(r, "success", "用户已恢复")
+	http.Redirect(w, r, "/users/trash", http.StatusSeeOther)
+}
+
+// RenderPermissionsPage 渲染用户的有效权限页面，列出每项权限来自哪个角色或用户组
+func (c *UserController) RenderPermissionsPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	id := currentUser.ID
+	if value := r.URL.Query().Get("id"); value != "" {
+		if id, err = strconv.Atoi(value); err != nil {
+			errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+			return
+		}
+	}
+
+	effective, err := c.effectivePermissions(r, currentUser, id)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	data := struct {
+		CurrentUser *models.User
+		Effective   *services.EffectivePermissions
+	}{
+		CurrentUser: currentUser,
+		Effective:   effective,
+	}
+
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/user_permissions.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/user_permissions.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/user_permissions.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// RenderApprovalsPage 渲染注册审批页面
+func (c *UserController) RenderApprovalsPage(w http.ResponseWriter, r *http.Request) {
+	sessionHelper := c.getSessionHelper()
+
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	users, err := c.getOrgService(r).GetPendingUsers()
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	csrfToken, err := sessionHelper.GetCSRFTokenForTemplate(r)
+	if err != nil {
+		logger.FromContext(r.Context()).Warn("获取CSRF令牌失败", "error", err)
+		csrfToken = ""
+	}
+
+	data := struct {
+		CurrentUser  *models.User
+		Users        []*models.User
+		ApprovalMode bool
+		Flash        *session.Flash
+		CSRFToken    string
+	}{
+		CurrentUser:  currentUser,
+		Users:        users,
+		ApprovalMode: config.GetConfig().RegistrationMode == models.RegistrationApproval,
+		Flash:        sessionHelper.PopFlash(r),
+		CSRFToken:    csrfToken,
+	}
+
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles("views/layout.html", "views/approvals.html")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("模板解析错误", "page", "views/approvals.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		logger.FromContext(r.Context()).Error("模板执行错误", "page", "views/approvals.html", "error", err)
+		errors.HandleError(w, r, errors.NewInternalError(err))
+	}
+}
+
+// HandleApprovals 处理批量通过或拒绝注册申请的请求
+// 表单字段：action（approve/reject）、user_ids（可多个）、reason（拒绝原因，可选）
+func (c *UserController) HandleApprovals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	//解析表单
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+
+	ids := make([]int, 0, len(r.Form["user_ids"]))
+	for _, value := range r.Form["user_ids"] {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+			return
+		}
+		ids = append(ids, id)
+	}
+	action := r.FormValue("action")
+	reason := r.FormValue("reason")
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	userService := c.getOrgService(r)
+	var (
+		result   *services.ApprovalResult
+		name     string
+		doneVerb string
+	)
+	switch action {
+	case "approve":
+		name, doneVerb = "通过注册申请", "通过"
+		result, err = userService.ApproveUsers(currentUser, ids)
+	case "reject":
+		name, doneVerb = "拒绝注册申请", "拒绝"
+		result, err = userService.RejectUsers(currentUser, ids, reason)
+	default:
+		err = errors.NewValidationError("action", "无效的审批操作")
+	}
+
+	details := fmt.Sprintf("目标用户ID: %v", ids)
+	if reason != "" {
+		details += ", 原因: " + reason
+	}
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "审批注册申请", details, err)
+		appErr, ok := errors.IsAppError(err)
+		if !ok || appErr.Type == errors.InternalError {
+			errors.HandleError(w, r, err)
+			return
+		}
+		sessionHelper.SetFlash(r, "error", appErr.Message)
+		http.Redirect(w, r, "/users/approvals", http.StatusSeeOther)
+		return
+	}
+
+	for _, user := range result.Processed {
+		logger.UserActionContext(r.Context(), currentUser.Username, name, fmt.Sprintf("目标用户: %s (ID: %d)", user.Username, user.ID), true)
+	}
+
+	if len(result.Failed) > 0 {
+		messages := make([]string, 0, len(result.Failed))
+		for _, failure := range result.Failed {
+			messages = append(messages, failure.Message)
+			logger.UserActionContext(r.Context(), currentUser.Username, name, fmt.Sprintf("目标用户ID: %d, %s", failure.UserID, failure.Message), false)
+		}
+		sessionHelper.SetFlash(r, "error", fmt.Sprintf("已%s %d 个申请，%d 个未处理：%s",
+			doneVerb, len(result.Processed), len(result.Failed), strings.Join(messages, "；")))
+	} else {
+		sessionHelper.SetFlash(r, "success", fmt.Sprintf("已%s %d 个注册申请", doneVerb, len(result.Processed)))
+	}
+	http.Redirect(w, r, "/users/approvals", http.StatusSeeOther)
+}
+
+// HandleCreateUser 处理管理员创建用户的表单
+func (c *UserController) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	//解析表单
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+
+	input := &services.CreateUserInput{
+		Username:     r.FormValue("username"),
+		Email:        r.FormValue("email"),
+		Roles:        r.Form["roles"],
+		Status:       r.FormValue("status"),
+		PasswordMode: r.FormValue("password_mode"),
+		Password:     r.FormValue("password"),
+	}
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	details := fmt.Sprintf("用户名: %s, 邮箱: %s, 角色: %s, 密码方式: %s",
+		input.Username, input.Email, strings.Join(input.Roles, ","), input.PasswordMode)
+
+	user, setup, err := c.getOrgService(r).CreateUser(currentUser, input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建用户", details, err)
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "创建用户", details, true)
+	sessionHelper.SetFlash(r, "success", "用户 "+user.Username+" 已创建"+describePasswordSetup(setup))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// HandleResetPassword 处理管理员强制重置用户密码的请求
+func (c *UserController) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	//解析表单
+	if err := r.ParseForm(); err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无法解析表单"))
+		return
+	}
+
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+		return
+	}
+	mode := r.FormValue("mode")
+
+	sessionHelper := c.getSessionHelper()
+	currentUser, err := sessionHelper.GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	setup, err := c.resetPassword(r, currentUser, userID, mode)
+	if err != nil {
+		c.redirectWithError(w, r, err)
+		return
+	}
+
+	sessionHelper.SetFlash(r, "success", "密码已重置，该用户的所有登录已失效"+describePasswordSetup(setup))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// HandleAPICreateUser 管理员通过 API 创建用户
+// POST /api/users，请求体为 services.CreateUserInput
+func (c *UserController) HandleAPICreateUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	var input services.CreateUserInput
+	if err := decodeJSON(r, &input); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	details := fmt.Sprintf("用户名: %s, 邮箱: %s, 角色: %s, 密码方式: %s",
+		input.Username, input.Email, strings.Join(input.Roles, ","), input.PasswordMode)
+
+	user, setup, err := c.getOrgService(r).CreateUser(currentUser, &input)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "创建用户", details, err)
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	logger.UserActionContext(r.Context(), currentUser.Username, "创建用户", details, true)
+	writeJSON(w, http.StatusCreated, struct {
+		User *models.User `json:"user"`
+		*services.PasswordSetup
+	}{user, setup})
+}
+
+// HandleAPIResetPassword 管理员通过 API 强制重置用户密码
+// POST /api/users/reset-password，请求体为 {"user_id": 1, "mode": "temporary|link"}
+func (c *UserController) HandleAPIResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	var req struct {
+		UserID int    `json:"user_id"`
+		Mode   string `json:"mode"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	setup, err := c.resetPassword(r, currentUser, req.UserID, req.Mode)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, setup)
+}
+
+// HandleAPIUpdateUser 通过 API 修改用户的邮箱和角色
+// POST /api/users/update，请求体为 {"user_id": 1, "email": "...", "roles": ["user"]}，roles 为空时不修改角色
+// 修改需要审批时返回 202 和提交的变更申请
+func (c *UserController) HandleAPIUpdateUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	var req struct {
+		UserID int      `json:"user_id"`
+		Email  string   `json:"email"`
+		Roles  []string `json:"roles"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	pending, err := c.updateUser(r, currentUser, req.UserID, req.Email, req.Roles)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	if pending != nil {
+		// 修改尚未生效，返回等待审批的申请
+		writeJSON(w, http.StatusAccepted, pending)
+		return
+	}
+	user, err := c.getOrgService(r).GetUserByID(req.UserID)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// HandleAPIDeleteUser 通过 API 删除用户（移入回收站）
+// POST /api/users/delete，请求体为 {"user_id": 1}，删除需要审批时返回 202 和提交的变更申请
+func (c *UserController) HandleAPIDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	var req struct {
+		UserID int `json:"user_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	_, pending, err := c.deleteUser(r, currentUser, req.UserID)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	if pending != nil {
+		// 删除尚未生效，返回等待审批的申请
+		writeJSON(w, http.StatusAccepted, pending)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAPIUpdateStatus 通过 API 修改账户状态
+// POST /api/users/status，请求体为 {"user_id": 1, "status": "locked", "reason": "..."}
+func (c *UserController) HandleAPIUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	var req struct {
+		UserID int    `json:"user_id"`
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	if err := c.changeStatus(r, currentUser, req.UserID, req.Status, req.Reason); err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAPIUserPermissions 通过 API 获取用户的有效权限及其来源
+// GET /api/users/permissions?id=2，省略 id 时返回当前用户的有效权限
+func (c *UserController) HandleAPIUserPermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.HandleError(w, r, errors.NewAppError(
+			errors.ValidationError,
+			"方法不允许",
+			nil,
+		))
+		return
+	}
+
+	currentUser, err := c.getSessionHelper().GetCurrentUser(r)
+	if err != nil {
+		errors.HandleError(w, r, errors.NewUnauthorizedError(""))
+		return
+	}
+
+	id := currentUser.ID
+	if value := r.URL.Query().Get("id"); value != "" {
+		if id, err = strconv.Atoi(value); err != nil {
+			errors.HandleError(w, r, errors.NewValidationError("", "无效的用户ID"))
+			return
+		}
+	}
+
+	effective, err := c.effectivePermissions(r, currentUser, id)
+	if err != nil {
+		errors.HandleError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, effective)
+}
+
+// effectivePermissions 获取用户的有效权限，查看其他用户时只能查看当前组织的成员
+// 查看自己时不限定组织，管理组织的管理员不一定是当前组织的成员
+func (c *UserController) effectivePermissions(r *http.Request, currentUser *models.User, id int) (*services.EffectivePermissions, error) {
+	if id == currentUser.ID {
+		return c.getUserService().WithContext(r.Context()).GetEffectivePermissions(currentUser, id)
+	}
+	return c.getOrgService(r).GetEffectivePermissions(currentUser, id)
+}
+
+// targetUsername 获取目标用户的用户名（用于日志记录），用户不存在时返回空串
+func targetUsername(userService services.UserService, userID int) string {
+	if targetUser, _ := userService.GetUserByID(userID); targetUser != nil {
+		return targetUser.Username
+	}
+	return ""
+}
+
+// updateUser 修改用户的邮箱和角色，同时记录操作日志
+// 修改需要审批时返回提交的变更申请，此时修改尚未生效
+func (c *UserController) updateUser(r *http.Request, currentUser *models.User, userID int, email string, roles []string) (*models.ChangeRequest, error) {
+	userService := c.getOrgService(r)
+	username := targetUsername(userService, userID)
+
+	pending, err := userService.UpdateUser(currentUser, userID, email, roles)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "更新用户",
+			fmt.Sprintf("目标用户: %s (ID: %d)", username, userID), err)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("目标用户: %s (ID: %d), 邮箱: %s, 角色: %s",
+		username, userID, email, strings.Join(roles, ","))
+	if pending != nil {
+		logger.UserActionContext(r.Context(), currentUser.Username, "提交变更申请",
+			fmt.Sprintf("申请ID: %d, 操作: %s, %s", pending.ID, pending.Action, details), true)
+		return pending, nil
+	}
+	logger.UserActionContext(r.Context(), currentUser.Username, "更新用户", details, true)
+	return nil, nil
+}
+
+// deleteUser 删除用户并让其所有会话失效，同时记录操作日志，返回被删除用户的用户名
+// 删除需要审批时返回提交的变更申请，此时用户尚未被删除
+func (c *UserController) deleteUser(r *http.Request, currentUser *models.User, userID int) (string, *models.ChangeRequest, error) {
+	userService := c.getOrgService(r)
+	username := targetUsername(userService, userID)
+	details := fmt.Sprintf("目标用户: %s (ID: %d)", username, userID)
+
+	pending, err := userService.DeleteUser(currentUser, userID)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "删除用户", details, err)
+		return "", nil, err
+	}
+	if pending != nil {
+		logger.UserActionContext(r.Context(), currentUser.Username, "提交变更申请",
+			fmt.Sprintf("申请ID: %d, 操作: %s, %s", pending.ID, pending.Action, details), true)
+		return username, pending, nil
+	}
+
+	// 已删除的用户立即踢下线
+	c.app.GetSessionManager().DestroyUserSessions(userID, "")
+	logger.UserActionContext(r.Context(), currentUser.Username, "删除用户", details, true)
+	return username, nil, nil
+}
+
+// changeStatus 修改账户状态，非正常状态的账户立即踢下线，同时记录操作日志
+func (c *UserController) changeStatus(r *http.Request, currentUser *models.User, userID int, status, reason string) error {
+	userService := c.getOrgService(r)
+	details := fmt.Sprintf("目标用户: %s (ID: %d), 状态: %s, 原因: %s",
+		targetUsername(userService, userID), userID, models.StatusLabel(status), reason)
+
+	if err := userService.ChangeStatus(currentUser, userID, status, reason); err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "修改账户状态", details, err)
+		return err
+	}
+
+	if status != models.StatusActive {
+		c.app.GetSessionManager().DestroyUserSessions(userID, "")
+	}
+	logger.UserActionContext(r.Context(), currentUser.Username, "修改账户状态", details, true)
+	return nil
+}
+
+// resetPassword 重置密码并让目标用户的所有会话失效，同时记录操作日志
+func (c *UserController) resetPassword(r *http.Request, currentUser *models.User, userID int, mode string) (*services.PasswordSetup, error) {
+	userService := c.getOrgService(r)
+	details := fmt.Sprintf("目标用户: %s (ID: %d), 方式: %s", targetUsername(userService, userID), userID, mode)
+
+	setup, err := userService.ForcePasswordReset(currentUser, userID, mode)
+	if err != nil {
+		logger.UserActionWithErrorContext(r.Context(), currentUser.Username, "重置密码", details, err)
+		return nil, err
+	}
+
+	c.app.GetSessionManager().DestroyUserSessions(userID, "")
+	logger.UserActionContext(r.Context(), currentUser.Username, "重置密码", details, true)
+	return setup, nil
+}
+
+// redirectWithError 将用户可以修正的错误作为提示带回用户列表，内部错误直接返回错误响应
+func (c *UserController) redirectWithError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	c.getSessionHelper().SetFlash(r, "error", appErr.Message)
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// describePasswordSetup 生成告知管理员如何把密码交给用户的提示
+func describePasswordSetup(setup *services.PasswordSetup) string {
+	switch {
+	case setup == nil:
+		return ""
+	case setup.TemporaryPassword != "":
+		return "。临时密码：" + setup.TemporaryPassword + "（只显示这一次，用户首次登录后需要修改）"
+	case setup.LinkSentTo != "":
+		return "。设置密码链接已发送至 " + setup.LinkSentTo
+	}
+	return ""
+}
+
+// hasAnyPermission 检查用户是否拥有（或通过委派获得了）任意一个指定权限，用于决定页面上是否显示操作入口
+func hasAnyPermission(user *models.User, permissions ...string) bool {
+	for _, permission := range permissions {
+		if rbac.CanSome(user, permission) {
+			return true
+		}
+	}
+	return false
+}
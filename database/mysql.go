@@ -3,10 +3,12 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	"time"
 	
+	"user-management-system/audit"
 	"user-management-system/config"
+	"user-management-system/logger"
+	"user-management-system/models"
 	_ "github.com/go-sql-driver/mysql"
 )
 
@@ -43,7 +45,7 @@ func InitDB() error {
 	}
 	
 	DB = db
-	log.Println("数据库连接成功")
+	logger.Info("数据库连接成功", "host", cfg.DBHost, "database", cfg.DBName)
 	
 	// 创建表（如果不存在）
 	if err := createTables(db); err != nil {
@@ -58,34 +60,571 @@ func InitDB() error {
 func CloseDB() {
 	if DB != nil {
 		if err := DB.Close(); err != nil {
-			log.Printf("关闭数据库连接失败: %v", err)
+			logger.Error("关闭数据库连接失败", "error", err)
 		} else {
-			log.Println("数据库连接已关闭")
+			logger.Info("数据库连接已关闭")
 		}
 	}
 }
 
 // createTables 创建必要的数据库表
 func createTables(db *sql.DB) error {
-	query := `
+	for _, query := range tableQueries {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	// 为已存在的旧表补充新增的列
+	for _, c := range columnMigrations {
+		if err := addColumnIfNotExists(db, c.table, c.column, c.definition); err != nil {
+			return fmt.Errorf("为表 %s 添加列 %s 失败: %w", c.table, c.column, err)
+		}
+	}
+
+	// 调整索引（先创建新索引再删除被替代的旧索引，避免中间出现没有唯一约束的窗口）
+	for _, idx := range indexMigrations {
+		if err := addIndexIfNotExists(db, idx.table, idx.name, idx.definition); err != nil {
+			return fmt.Errorf("为表 %s 添加索引 %s 失败: %w", idx.table, idx.name, err)
+		}
+	}
+	for _, idx := range droppedIndexes {
+		if err := dropIndexIfExists(db, idx.table, idx.name); err != nil {
+			return fmt.Errorf("删除表 %s 的索引 %s 失败: %w", idx.table, idx.name, err)
+		}
+	}
+
+	// 写入内置角色，并把旧版 users.role 列中的角色迁移到 user_roles
+	if err := seedRoles(db); err != nil {
+		return fmt.Errorf("初始化角色失败: %w", err)
+	}
+
+	// 写入默认组织，并把尚未归属任何组织的用户、用户组和邀请归入默认组织
+	if err := seedOrganizations(db); err != nil {
+		return fmt.Errorf("初始化组织失败: %w", err)
+	}
+
+	// 把旧版全局的角色分配改为按组织分配
+	if err := migrateUserRoles(db); err != nil {
+		return fmt.Errorf("迁移用户角色失败: %w", err)
+	}
+
+	// 初始化审计哈希链的末端
+	if _, err := db.Exec(`INSERT IGNORE INTO audit_chain (id, last_hash) VALUES (1, ?)`, audit.GenesisHash); err != nil {
+		return fmt.Errorf("初始化审计哈希链失败: %w", err)
+	}
+
+	return nil
+}
+
+// tableQueries 建表语句，按依赖顺序执行
+var tableQueries = []string{
+	`
 	CREATE TABLE IF NOT EXISTS users (
 		id INT AUTO_INCREMENT PRIMARY KEY,
-		username VARCHAR(50) UNIQUE NOT NULL,
+		username VARCHAR(50) NOT NULL,
 		password VARCHAR(255) NOT NULL,
-		email VARCHAR(100) UNIQUE NOT NULL,
-		role VARCHAR(20) DEFAULT 'user',
+		email VARCHAR(100) NOT NULL,
+		role VARCHAR(20) DEFAULT 'user', -- 旧版的单一角色，已迁移到 user_roles，不再读写
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		INDEX idx_username (username),
 		INDEX idx_email (email),
 		INDEX idx_role (role)
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
 	`
-	
-	_, err := db.Exec(query)
+	CREATE TABLE IF NOT EXISTS email_verifications (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		email VARCHAR(100) NOT NULL,
+		token_hash CHAR(64) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		UNIQUE INDEX idx_token_hash (token_hash),
+		INDEX idx_user_id (user_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS password_history (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		password_hash VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_user_created (user_id, created_at),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS invitations (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		email VARCHAR(100) NOT NULL,
+		role VARCHAR(20) NOT NULL DEFAULT 'user',
+		token_hash CHAR(64) NOT NULL,
+		invited_by INT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		accepted_at TIMESTAMP NULL DEFAULT NULL,
+		accepted_user_id INT NULL DEFAULT NULL,
+		revoked_at TIMESTAMP NULL DEFAULT NULL,
+		UNIQUE INDEX idx_token_hash (token_hash),
+		INDEX idx_email (email)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS roles (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(32) NOT NULL,
+		description VARCHAR(255) NOT NULL DEFAULT '',
+		is_system TINYINT(1) NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE INDEX idx_name (name)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS role_permissions (
+		role_id INT NOT NULL,
+		permission VARCHAR(64) NOT NULL,
+		PRIMARY KEY (role_id, permission),
+		FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS user_roles (
+		user_id INT NOT NULL,
+		organization_id INT NOT NULL DEFAULT 0, -- 角色在哪个组织中生效，0 表示平台级角色（见 migrateUserRoles）
+		role_id INT NOT NULL,
+		PRIMARY KEY (user_id, organization_id, role_id),
+		INDEX idx_role_id (role_id),
+		INDEX idx_organization_id (organization_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS password_tokens (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		purpose VARCHAR(20) NOT NULL,
+		token_hash CHAR(64) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		UNIQUE INDEX idx_token_hash (token_hash),
+		INDEX idx_user_id (user_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 用户组（GROUPS 是 MySQL 8 的保留字，因此表名为 user_groups）
+	`
+	CREATE TABLE IF NOT EXISTS user_groups (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(64) NOT NULL,
+		description VARCHAR(255) NOT NULL DEFAULT '',
+		parent_id INT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE INDEX idx_name (name),
+		INDEX idx_parent_id (parent_id),
+		FOREIGN KEY (parent_id) REFERENCES user_groups(id) ON DELETE SET NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS group_members (
+		group_id INT NOT NULL,
+		user_id INT NOT NULL,
+		PRIMARY KEY (group_id, user_id),
+		INDEX idx_user_id (user_id),
+		FOREIGN KEY (group_id) REFERENCES user_groups(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS group_roles (
+		group_id INT NOT NULL,
+		role_id INT NOT NULL,
+		PRIMARY KEY (group_id, role_id),
+		INDEX idx_role_id (role_id),
+		FOREIGN KEY (group_id) REFERENCES user_groups(id) ON DELETE CASCADE,
+		FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 组织（租户）：用户通过 organization_members 属于一个或多个组织
+	`
+	CREATE TABLE IF NOT EXISTS organizations (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		slug VARCHAR(32) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE INDEX idx_slug (slug)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS organization_members (
+		organization_id INT NOT NULL,
+		user_id INT NOT NULL,
+		PRIMARY KEY (organization_id, user_id),
+		INDEX idx_user_id (user_id),
+		FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 委派管理授权：持有 role_id 的用户可以对范围内（持有 scope_role_id 的用户或 scope_group_id 及其下级组的成员）的组织成员执行授权的操作
+	`
+	CREATE TABLE IF NOT EXISTS admin_grants (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		organization_id INT NOT NULL,
+		role_id INT NOT NULL,
+		scope_role_id INT NULL,
+		scope_group_id INT NULL,
+		created_by INT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_organization_id (organization_id),
+		INDEX idx_role_id (role_id),
+		FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+		FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+		FOREIGN KEY (scope_role_id) REFERENCES roles(id) ON DELETE CASCADE,
+		FOREIGN KEY (scope_group_id) REFERENCES user_groups(id) ON DELETE CASCADE,
+		FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS admin_grant_permissions (
+		grant_id INT NOT NULL,
+		permission VARCHAR(64) NOT NULL,
+		PRIMARY KEY (grant_id, permission),
+		FOREIGN KEY (grant_id) REFERENCES admin_grants(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 等待第二位管理员审批的敏感变更；email 和 roles（逗号分隔）只用于 promote_admin
+	`
+	CREATE TABLE IF NOT EXISTS change_requests (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		organization_id INT NOT NULL,
+		action VARCHAR(32) NOT NULL,
+		target_id INT NOT NULL,
+		email VARCHAR(100) NOT NULL DEFAULT '',
+		roles VARCHAR(512) NOT NULL DEFAULT '',
+		requested_by INT NULL,
+		decided_by INT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		approved_at TIMESTAMP NULL DEFAULT NULL,
+		rejected_at TIMESTAMP NULL DEFAULT NULL,
+		INDEX idx_organization_id (organization_id),
+		INDEX idx_target_id (target_id),
+		FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+		FOREIGN KEY (target_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (requested_by) REFERENCES users(id) ON DELETE SET NULL,
+		FOREIGN KEY (decided_by) REFERENCES users(id) ON DELETE SET NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 审计事件：只追加不修改；不引用用户和组织表，用户被永久删除或组织被删除后记录仍然保留
+	// 全部事件按 seq 组成哈希链，修改前后的值按写入时的原文保存（JSON 列会重新排版，导致哈希无法复算）
+	`
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		seq BIGINT NOT NULL,
+		organization_id INT NOT NULL,
+		occurred_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+		actor_id INT NULL,
+		actor_name VARCHAR(50) NOT NULL DEFAULT '',
+		impersonator_id INT NULL,
+		impersonator_name VARCHAR(50) NOT NULL DEFAULT '',
+		action VARCHAR(64) NOT NULL,
+		target_type VARCHAR(32) NOT NULL DEFAULT '',
+		target_id INT NULL,
+		target_name VARCHAR(100) NOT NULL DEFAULT '',
+		before_value MEDIUMTEXT NULL,
+		after_value MEDIUMTEXT NULL,
+		ip VARCHAR(45) NOT NULL DEFAULT '',
+		user_agent VARCHAR(255) NOT NULL DEFAULT '',
+		request_id VARCHAR(64) NOT NULL DEFAULT '',
+		outcome VARCHAR(16) NOT NULL,
+		error VARCHAR(255) NOT NULL DEFAULT '',
+		prev_hash CHAR(64) NOT NULL,
+		hash CHAR(64) NOT NULL,
+		UNIQUE KEY uk_seq (seq),
+		INDEX idx_org_occurred (organization_id, occurred_at),
+		INDEX idx_actor_name (actor_name),
+		INDEX idx_action (action),
+		INDEX idx_target (target_type, target_id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 审计哈希链的末端：只有 id = 1 一行，写入事件时加锁以串行化，并用于发现末尾事件被删除
+	`
+	CREATE TABLE IF NOT EXISTS audit_chain (
+		id TINYINT PRIMARY KEY,
+		last_seq BIGINT NOT NULL DEFAULT 0,
+		last_event_id BIGINT NOT NULL DEFAULT 0,
+		last_hash CHAR(64) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 审计哈希链的签名检查点
+	`
+	CREATE TABLE IF NOT EXISTS audit_checkpoints (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		seq BIGINT NOT NULL,
+		event_id BIGINT NOT NULL,
+		event_hash CHAR(64) NOT NULL,
+		key_id CHAR(16) NOT NULL,
+		signature VARCHAR(128) NOT NULL,
+		created_at TIMESTAMP(6) NOT NULL,
+		UNIQUE KEY uk_seq (seq)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+	// 组的传递闭包：每个组与其自身及所有上级组各对应一行，在组的层级变化时整体重建
+	`
+	CREATE TABLE IF NOT EXISTS group_closure (
+		ancestor_id INT NOT NULL,
+		descendant_id INT NOT NULL,
+		PRIMARY KEY (ancestor_id, descendant_id),
+		INDEX idx_descendant_id (descendant_id),
+		FOREIGN KEY (ancestor_id) REFERENCES user_groups(id) ON DELETE CASCADE,
+		FOREIGN KEY (descendant_id) REFERENCES user_groups(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`,
+}
+
+// columnMigration 描述一个需要补充到已有表中的列
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations 在初始建表之后新增的列
+var columnMigrations = []columnMigration{
+	{"users", "last_login_at", "TIMESTAMP NULL DEFAULT NULL"},
+	{"users", "password_changed_at", "TIMESTAMP NULL DEFAULT NULL"},
+	{"users", "status", "VARCHAR(20) NOT NULL DEFAULT 'active'"},
+	{"users", "status_reason", "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{"users", "status_changed_at", "TIMESTAMP NULL DEFAULT NULL"},
+	{"users", "deleted_at", "TIMESTAMP NULL DEFAULT NULL"},
+	// 未删除时为1、已删除时为NULL，用于让唯一索引忽略已删除的用户
+	{"users", "alive", "TINYINT AS (IF(deleted_at IS NULL, 1, NULL)) VIRTUAL"},
+	{"users", "must_change_password", "TINYINT(1) NOT NULL DEFAULT 0"},
+	// 用户组和邀请所属的组织，旧数据为NULL，由 seedOrganizations 归入默认组织
+	{"user_groups", "organization_id", "INT NULL DEFAULT NULL"},
+	{"invitations", "organization_id", "INT NULL DEFAULT NULL"},
+}
+
+// indexMigration 描述一个需要调整的索引
+type indexMigration struct {
+	table      string
+	name       string
+	definition string
+}
+
+// indexMigrations 需要补充的索引
+// 数据库只保证未删除用户之间的唯一性，已删除用户是否占用用户名和邮箱由配置决定（见 config.SoftDelete*）
+var indexMigrations = []indexMigration{
+	{"users", "uniq_username_alive", "UNIQUE INDEX uniq_username_alive (username, alive)"},
+	{"users", "uniq_email_alive", "UNIQUE INDEX uniq_email_alive (email, alive)"},
+	{"users", "idx_deleted_at", "INDEX idx_deleted_at (deleted_at)"},
+	// 用户组名称只需在组织内唯一
+	{"user_groups", "uniq_org_name", "UNIQUE INDEX uniq_org_name (organization_id, name)"},
+	{"invitations", "idx_organization_id", "INDEX idx_organization_id (organization_id)"},
+}
+
+// droppedIndexes 需要删除的旧索引（只用到 table 和 name）
+// 早期版本在 username、email 列上直接声明了 UNIQUE，软删除后改由上面的组合唯一索引代替
+var droppedIndexes = []indexMigration{
+	{table: "users", name: "username"},
+	{table: "users", name: "email"},
+	{table: "user_groups", name: "idx_name"},
+}
+
+// seedRole 描述一个内置角色
+type seedRole struct {
+	name        string
+	description string
+	permissions []string
+}
+
+// seedRoleList 内置角色，与引入角色表之前的行为一致：管理员可以做任何事，普通用户只能查看用户列表
+// 管理员角色始终拥有全部权限，新增的权限会在启动时自动授予；其他内置角色的权限只在首次创建时写入
+var seedRoleList = []seedRole{
+	{models.RoleAdmin, "系统管理员，拥有全部权限", nil},
+	{models.RoleOrgAdmin, "组织管理员，管理所在组织的用户、用户组和邀请", []string{
+		models.PermUsersView, models.PermUsersCreate, models.PermUsersUpdate, models.PermUsersDelete,
+		models.PermUsersRestore, models.PermUsersStatus, models.PermUsersResetPassword,
+		models.PermUsersApprove, models.PermUsersManage, models.PermInvitationsManage, models.PermGroupsManage,
+		models.PermAuditView,
+	}},
+	{models.RoleUser, "普通用户", []string{models.PermUsersView}},
+}
+
+// seedRoles 写入内置角色及其权限，并在 user_roles 为空时按旧版 users.role 列为已有用户分配角色
+func seedRoles(db *sql.DB) error {
+	for _, role := range seedRoleList {
+		result, err := db.Exec(`INSERT IGNORE INTO roles (name, description, is_system) VALUES (?, ?, 1)`,
+			role.name, role.description)
+		if err != nil {
+			return err
+		}
+		created, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		permissions := role.permissions
+		if role.name == models.RoleAdmin {
+			permissions = make([]string, 0, len(models.Permissions))
+			for _, p := range models.Permissions {
+				permissions = append(permissions, p.Name)
+			}
+		} else if created == 0 {
+			continue
+		}
+
+		for _, permission := range permissions {
+			_, err := db.Exec(`
+				INSERT IGNORE INTO role_permissions (role_id, permission)
+				SELECT id, ? FROM roles WHERE name = ?
+			`, permission, role.name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// 只在 user_roles 从未写入过数据时迁移一次，避免覆盖之后在界面上调整过的角色
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM user_roles`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := db.Exec(`
+		INSERT IGNORE INTO user_roles (user_id, role_id)
+		SELECT u.id, r.id FROM users u
+		JOIN roles r ON r.name = COALESCE(NULLIF(u.role, ''), ?)
+	`, models.RoleUser)
+	return err
+}
+
+// seedOrganizations 写入默认组织，并把尚未归属任何组织的用户、用户组和邀请归入默认组织
+// 升级前只有一个隐含的租户，迁移后原有数据全部位于默认组织中，行为与升级前一致
+func seedOrganizations(db *sql.DB) error {
+	_, err := db.Exec(`INSERT IGNORE INTO organizations (slug, name) VALUES (?, ?)`,
+		models.DefaultOrganization, "默认组织")
+	if err != nil {
+		return err
+	}
+
+	var orgID int
+	if err := db.QueryRow(`SELECT id FROM organizations WHERE slug = ?`, models.DefaultOrganization).Scan(&orgID); err != nil {
+		return err
+	}
+
+	queries := []string{
+		`INSERT IGNORE INTO organization_members (organization_id, user_id)
+		 SELECT ?, u.id FROM users u
+		 WHERE NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.user_id = u.id)`,
+		`UPDATE user_groups SET organization_id = ? WHERE organization_id IS NULL`,
+		`UPDATE invitations SET organization_id = ? WHERE organization_id IS NULL`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query, orgID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateUserRoles 让角色只在分配它的组织中生效（在 seedOrganizations 之后执行）
+// 旧版 user_roles 没有 organization_id 列，角色在用户所属的每个组织中都生效。迁移时为该表加上组织列，
+// 拥有 orgs:manage 权限的角色保留为平台级（organization_id 为 0，在所有组织中生效），
+// 其他角色复制到用户所属的每个组织。应用只为拥有 orgs:manage 的角色写入平台级分配，
+// 因此数据迁移部分每次启动都可以安全地重复执行，中途失败后下次启动会继续完成
+func migrateUserRoles(db *sql.DB) error {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'user_roles' AND column_name = 'organization_id'
+	`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		_, err := db.Exec(`ALTER TABLE user_roles
+			ADD COLUMN organization_id INT NOT NULL DEFAULT 0 AFTER user_id,
+			DROP PRIMARY KEY,
+			ADD PRIMARY KEY (user_id, organization_id, role_id),
+			ADD INDEX idx_organization_id (organization_id)`)
+		if err != nil {
+			return err
+		}
+	}
+
+	platformRoles := `SELECT role_id FROM role_permissions WHERE permission = ?`
+	_, err = db.Exec(`
+		INSERT IGNORE INTO user_roles (user_id, organization_id, role_id)
+		SELECT ur.user_id, om.organization_id, ur.role_id FROM user_roles ur
+		JOIN organization_members om ON om.user_id = ur.user_id
+		WHERE ur.organization_id = 0 AND ur.role_id NOT IN (`+platformRoles+`)
+	`, models.PermOrgsManage)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM user_roles WHERE organization_id = 0 AND role_id NOT IN (`+platformRoles+`)`,
+		models.PermOrgsManage)
 	return err
 }
 
+// addColumnIfNotExists 当列不存在时执行 ALTER TABLE 添加该列
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) error {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?
+	`
+	if err := db.QueryRow(query, table, column).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// indexExists 检查索引是否存在
+func indexExists(db *sql.DB, table, name string) (bool, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?
+	`
+	if err := db.QueryRow(query, table, name).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
 
+// addIndexIfNotExists 当索引不存在时执行 ALTER TABLE 添加该索引
+func addIndexIfNotExists(db *sql.DB, table, name, definition string) error {
+	exists, err := indexExists(db, table, name)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD %s", table, definition))
+	return err
+}
+
+// dropIndexIfExists 当索引存在时删除该索引
+func dropIndexIfExists(db *sql.DB, table, name string) error {
+	exists, err := indexExists(db, table, name)
+	if err != nil || !exists {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s DROP INDEX %s", table, name))
+	return err
+}
 
 // GetDB 获取数据库连接实例
 func GetDB() *sql.DB {
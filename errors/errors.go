@@ -1,9 +1,13 @@
 package errors
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"runtime/debug"
+
+	"user-management-system/logger"
 )
 
 // ErrorType 错误类型
@@ -123,14 +127,22 @@ func (e *AppError) HTTPStatusCode() int {
 	}
 }
 
-// LogError 记录错误日志
+// LogError 记录错误日志：内部错误为 error 级别，其余（用户可以修正的错误）为 warn 级别
 func (e *AppError) LogError() {
+	e.LogErrorContext(context.Background())
+}
+
+// LogErrorContext 与 LogError 相同，在请求中时通过请求范围的日志记录器输出（带有请求 ID、用户和路由）
+func (e *AppError) LogErrorContext(ctx context.Context) {
+	l := logger.FromContext(ctx)
+	args := []any{"type", e.TypeString(), "message", e.Message}
 	if e.Internal != nil {
-		log.Printf("[错误] 类型: %s, 消息: %s, 内部错误: %v",
-			e.TypeString(), e.Message, e.Internal)
+		args = append(args, "error", e.Internal)
+	}
+	if e.Type == InternalError {
+		l.Error("请求处理失败", args...)
 	} else {
-		log.Printf("[错误] 类型: %s, 消息: %s",
-			e.TypeString(), e.Message)
+		l.Warn("请求处理失败", args...)
 	}
 }
 
@@ -170,17 +182,28 @@ func HandleError(w http.ResponseWriter, r *http.Request, err error) {
 	}
 
 	// 记录错误日志
-	appErr.LogError()
+	appErr.LogErrorContext(r.Context())
+
+	// 响应中带上请求 ID，用户反馈问题时可以据此找到对应的日志
+	requestID := logger.RequestID(r.Context())
 
 	// 根据请求类型返回不同格式的响应
 	if isAPIRequest(r) {
 		// API请求返回JSON
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appErr.HTTPStatusCode())
-		fmt.Fprintf(w, `{"error":"%s"}`, appErr.Message)
+		body := map[string]string{"error": appErr.Message}
+		if requestID != "" {
+			body["request_id"] = requestID
+		}
+		json.NewEncoder(w).Encode(body)
 	} else {
 		// 普通请求返回HTML错误页面
-		http.Error(w, appErr.Message, appErr.HTTPStatusCode())
+		message := appErr.Message
+		if requestID != "" {
+			message = fmt.Sprintf("%s（请求ID: %s）", message, requestID)
+		}
+		http.Error(w, message, appErr.HTTPStatusCode())
 	}
 }
 
@@ -194,7 +217,7 @@ func RecoverMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("[PANIC] %v", err)
+				logger.FromContext(r.Context()).Error("请求处理发生panic", "panic", err, "method", r.Method, "path", r.URL.Path, "stack", string(debug.Stack()))
 				appErr := NewInternalError(fmt.Errorf("panic: %v", err))
 				HandleError(w, r, appErr)
 			}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"user-management-system/mail"
+	"user-management-system/session"
+)
+
+// DatabaseCheck 检查数据库连接是否可用（Ping）
+func DatabaseCheck(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// SessionStoreCheck 检查会话存储能否在超时前响应（内存存储被长时间锁住时请求都会挂起）
+func SessionStoreCheck(manager *session.Manager) func(ctx context.Context) error {
+	return manager.Ping
+}
+
+// MailCheck 检查邮件发送通道是否可用；只写日志的发送器总是可用
+func MailCheck(mailer mail.Mailer) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if checker, ok := mailer.(mail.HealthChecker); ok {
+			return checker.CheckHealth(ctx)
+		}
+		return nil
+	}
+}
+
+// DiskSpaceCheck 检查 dir 所在磁盘的可用空间不少于 minFree 字节
+func DiskSpaceCheck(dir string, minFree uint64) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+		free, err := freeDiskSpace(dir)
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("可用空间 %d MB，低于 %d MB", free>>20, minFree>>20)
+		}
+		return nil
+	}
+}
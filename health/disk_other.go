@@ -0,0 +1,8 @@
+//go:build !unix
+
+package health
+
+// freeDiskSpace 其他平台不检查磁盘空间
+func freeDiskSpace(path string) (uint64, error) {
+	return 0, ErrSkipped
+}
@@ -0,0 +1,216 @@
+// Package health 存活（liveness）与就绪（readiness）检查
+//
+// 检查项注册到 Registry 中：存活检查只包括进程自身的状态（失败时应重启进程），
+// 就绪检查还包括数据库等依赖（失败时负载均衡不再转发请求）。检查结果按 CacheTTL 缓存，
+// 频繁的探测不会每次都访问数据库；进入优雅关闭后就绪检查直接失败。
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"user-management-system/config"
+)
+
+// 检查状态
+const (
+	StatusOK          = "ok"          // 所有检查都通过
+	StatusDegraded    = "degraded"    // 只有非关键检查失败，仍可以处理请求
+	StatusUnavailable = "unavailable" // 关键检查失败或正在关闭
+	StatusSkipped     = "skipped"     // 当前环境不支持该检查
+)
+
+// ErrSkipped 检查函数在当前环境不适用时返回，结果记为 skipped，不影响整体状态
+var ErrSkipped = errors.New("当前环境不支持该检查")
+
+// Check 一个检查项
+type Check struct {
+	Name     string                          // 检查项名称，如 database
+	Func     func(ctx context.Context) error // 检查函数，返回 nil 表示通过
+	Timeout  time.Duration                   // 单次检查的超时时间，为0时使用注册表的默认值
+	Critical bool                            // 失败时是否判定为不可用；非关键检查失败只判定为 degraded
+	Liveness bool                            // 是否同时作为存活检查（只应包括不依赖外部服务的检查）
+}
+
+// Result 一个检查项的结果
+type Result struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  float64   `json:"duration_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report 一次检查的汇总
+type Report struct {
+	Status       string             `json:"status"`
+	ShuttingDown bool               `json:"shutting_down,omitempty"`
+	Checks       map[string]*Result `json:"checks"`
+}
+
+// registered 注册的检查项及其缓存的结果
+type registered struct {
+	check Check
+
+	mu     sync.Mutex // 同一检查项同时只执行一次，并发的请求等待并共享结果
+	result *Result
+}
+
+// Default 全局注册表，超时和缓存时间来自配置
+var Default = NewRegistry(config.GetConfig().HealthCheckTimeout, config.GetConfig().HealthCacheTTL)
+
+// Registry 检查项注册表
+type Registry struct {
+	mu       sync.RWMutex
+	checks   []*registered
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	shuttingDown atomic.Bool
+	now          func() time.Time
+}
+
+// NewRegistry 创建注册表，timeout 为检查的默认超时时间，cacheTTL 为结果的缓存时间
+func NewRegistry(timeout, cacheTTL time.Duration) *Registry {
+	return &Registry{timeout: timeout, cacheTTL: cacheTTL, now: time.Now}
+}
+
+// Register 注册检查项，同名的检查项会被替换
+func (r *Registry) Register(checks ...Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range checks {
+		replaced := false
+		for i, existing := range r.checks {
+			if existing.check.Name == c.Name {
+				r.checks[i] = &registered{check: c}
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			r.checks = append(r.checks, &registered{check: c})
+		}
+	}
+	sort.Slice(r.checks, func(i, j int) bool {
+		return r.checks[i].check.Name < r.checks[j].check.Name
+	})
+}
+
+// SetShuttingDown 标记进入优雅关闭，之后就绪检查直接失败，负载均衡不再转发新的请求
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// ShuttingDown 是否正在关闭
+func (r *Registry) ShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// Liveness 执行存活检查
+func (r *Registry) Liveness(ctx context.Context) *Report {
+	return r.run(ctx, true)
+}
+
+// Readiness 执行全部检查；正在关闭时状态为不可用
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	report := r.run(ctx, false)
+	if r.ShuttingDown() {
+		report.Status = StatusUnavailable
+		report.ShuttingDown = true
+	}
+	return report
+}
+
+// run 并发执行检查项并汇总状态
+func (r *Registry) run(ctx context.Context, livenessOnly bool) *Report {
+	r.mu.RLock()
+	var checks []*registered
+	for _, c := range r.checks {
+		if !livenessOnly || c.check.Liveness {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]*Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *registered) {
+			defer wg.Done()
+			results[i] = r.result(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]*Result, len(checks))}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.check.Name] = res
+		if res.Status != StatusUnavailable {
+			continue
+		}
+		if c.check.Critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// result 返回检查项的结果，缓存未过期时直接使用缓存
+func (r *Registry) result(ctx context.Context, c *registered) *Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.result != nil && r.now().Sub(c.result.CheckedAt) < r.cacheTTL {
+		return c.result
+	}
+
+	timeout := c.check.Timeout
+	if timeout <= 0 {
+		timeout = r.timeout
+	}
+	// 检查不随探测请求取消：结果会被缓存给其他请求使用
+	checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	start := r.now()
+	err := runCheck(checkCtx, c.check.Func)
+	res := &Result{
+		Status:    StatusOK,
+		Critical:  c.check.Critical,
+		Duration:  float64(r.now().Sub(start).Microseconds()) / 1000,
+		CheckedAt: start,
+	}
+	switch {
+	case errors.Is(err, ErrSkipped):
+		res.Status = StatusSkipped
+	case err != nil:
+		res.Status = StatusUnavailable
+		res.Error = err.Error()
+	}
+	c.result = res
+	return res
+}
+
+// runCheck 执行检查函数，函数没有遵守 ctx 的超时时也按时返回
+func runCheck(ctx context.Context, fn func(context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("检查超时: %w", ctx.Err())
+	}
+}
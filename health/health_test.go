@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadinessStatus(t *testing.T) {
+	failing := func(ctx context.Context) error { return errors.New("down") }
+	ok := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"all ok", []Check{{Name: "db", Func: ok, Critical: true}, {Name: "mail", Func: ok}}, StatusOK},
+		{"non-critical failure", []Check{{Name: "db", Func: ok, Critical: true}, {Name: "mail", Func: failing}}, StatusDegraded},
+		{"critical failure", []Check{{Name: "db", Func: failing, Critical: true}, {Name: "mail", Func: ok}}, StatusUnavailable},
+		{"skipped", []Check{{Name: "disk", Func: func(ctx context.Context) error { return ErrSkipped }}}, StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(time.Second, 0)
+			r.Register(tt.checks...)
+			if got := r.Readiness(context.Background()).Status; got != tt.want {
+				t.Errorf("status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLivenessOnlyRunsLivenessChecks(t *testing.T) {
+	r := NewRegistry(time.Second, 0)
+	r.Register(
+		Check{Name: "db", Func: func(ctx context.Context) error { return errors.New("down") }, Critical: true},
+		Check{Name: "sessions", Func: func(ctx context.Context) error { return nil }, Critical: true, Liveness: true},
+	)
+
+	report := r.Liveness(context.Background())
+	if report.Status != StatusOK {
+		t.Errorf("status = %q, want %q", report.Status, StatusOK)
+	}
+	if _, ok := report.Checks["db"]; ok || len(report.Checks) != 1 {
+		t.Errorf("checks = %v, want only sessions", report.Checks)
+	}
+}
+
+func TestResultsAreCached(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	r := NewRegistry(time.Second, 5*time.Second)
+	r.now = func() time.Time { return now }
+
+	var calls atomic.Int32
+	r.Register(Check{Name: "db", Critical: true, Func: func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}})
+
+	for i := 0; i < 10; i++ {
+		r.Readiness(context.Background())
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls within TTL = %d, want 1", got)
+	}
+
+	now = now.Add(5 * time.Second)
+	r.Readiness(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls after TTL = %d, want 2", got)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	r := NewRegistry(20*time.Millisecond, 0)
+	block := make(chan struct{})
+	defer close(block)
+	r.Register(Check{Name: "db", Critical: true, Func: func(ctx context.Context) error {
+		<-block // 不遵守 ctx 的检查也按时返回
+		return nil
+	}})
+
+	report := r.Readiness(context.Background())
+	if report.Status != StatusUnavailable {
+		t.Errorf("status = %q, want %q", report.Status, StatusUnavailable)
+	}
+	if res := report.Checks["db"]; res.Error == "" {
+		t.Errorf("error = %q, want timeout", res.Error)
+	}
+}
+
+func TestShuttingDownFailsReadinessOnly(t *testing.T) {
+	r := NewRegistry(time.Second, 0)
+	r.Register(Check{Name: "sessions", Liveness: true, Critical: true, Func: func(ctx context.Context) error { return nil }})
+	r.SetShuttingDown()
+
+	ready := r.Readiness(context.Background())
+	if ready.Status != StatusUnavailable || !ready.ShuttingDown {
+		t.Errorf("readiness = %+v, want unavailable while shutting down", ready)
+	}
+	if live := r.Liveness(context.Background()); live.Status != StatusOK {
+		t.Errorf("liveness = %q, want %q", live.Status, StatusOK)
+	}
+}
+
+func TestRegisterReplacesByName(t *testing.T) {
+	r := NewRegistry(time.Second, 0)
+	r.Register(Check{Name: "db", Critical: true, Func: func(ctx context.Context) error { return errors.New("down") }})
+	r.Register(Check{Name: "db", Critical: true, Func: func(ctx context.Context) error { return nil }})
+
+	report := r.Readiness(context.Background())
+	if report.Status != StatusOK || len(report.Checks) != 1 {
+		t.Errorf("report = %+v, want the replacement check only", report)
+	}
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// requestKey 请求信息在上下文中的键
+type requestKey struct{}
+
+// Request 一次 HTTP 请求的日志信息，由请求 ID 中间件放入请求上下文
+// 用户和路由在后续中间件中确定后补充，之后通过 FromContext 取得的日志记录器都会带上这些字段
+type Request struct {
+	mu     sync.RWMutex
+	id     string
+	userID int
+	route  string
+	trace  string
+	logger *slog.Logger
+}
+
+// NewRequestContext 返回带有请求信息的上下文，日志记录器附加 request_id 字段
+func NewRequestContext(ctx context.Context, requestID string) (context.Context, *Request) {
+	req := &Request{id: requestID}
+	return context.WithValue(ctx, requestKey{}, req), req
+}
+
+// RequestFromContext 返回上下文中的请求信息，不在请求中时返回 nil
+func RequestFromContext(ctx context.Context) *Request {
+	req, _ := ctx.Value(requestKey{}).(*Request)
+	return req
+}
+
+// RequestID 返回上下文中的请求 ID，不在请求中时返回空串
+func RequestID(ctx context.Context) string {
+	if req := RequestFromContext(ctx); req != nil {
+		return req.id
+	}
+	return ""
+}
+
+// SetRequestUser 记录发起请求的用户，之后的请求日志附加 user_id 字段
+func SetRequestUser(ctx context.Context, userID int) {
+	if req := RequestFromContext(ctx); req != nil {
+		req.mu.Lock()
+		req.userID = userID
+		req.logger = nil
+		req.mu.Unlock()
+	}
+}
+
+// SetRequestRoute 记录请求匹配的路由，之后的请求日志附加 route 字段
+func SetRequestRoute(ctx context.Context, route string) {
+	if req := RequestFromContext(ctx); req != nil {
+		req.mu.Lock()
+		req.route = route
+		req.logger = nil
+		req.mu.Unlock()
+	}
+}
+
+// SetRequestTrace 记录请求的追踪 ID，之后的请求日志附加 trace_id 字段，便于从日志跳转到追踪
+func SetRequestTrace(ctx context.Context, traceID string) {
+	if req := RequestFromContext(ctx); req != nil {
+		req.mu.Lock()
+		req.trace = traceID
+		req.logger = nil
+		req.mu.Unlock()
+	}
+}
+
+// UserID 返回发起请求的用户 ID，未登录时为 0
+func (r *Request) UserID() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.userID
+}
+
+// Route 返回请求匹配的路由，尚未匹配时为空串
+func (r *Request) Route() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.route
+}
+
+// Logger 返回附加了请求 ID、用户、路由和追踪 ID 字段的日志记录器
+func (r *Request) Logger() *slog.Logger {
+	r.mu.RLock()
+	l := r.logger
+	r.mu.RUnlock()
+	if l != nil {
+		return l
+	}
+
+	args := []any{"request_id", r.id}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.userID != 0 {
+		args = append(args, "user_id", r.userID)
+	}
+	if r.route != "" {
+		args = append(args, "route", r.route)
+	}
+	if r.trace != "" {
+		args = append(args, "trace_id", r.trace)
+	}
+	r.logger = L().With(args...)
+	return r.logger
+}
+
+// FromContext 返回请求范围的日志记录器；不在请求中时返回全局日志记录器
+func FromContext(ctx context.Context) *slog.Logger {
+	if req := RequestFromContext(ctx); req != nil {
+		return req.Logger()
+	}
+	return L()
+}
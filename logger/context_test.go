@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+// captureLogs 把全局日志记录器换成写入缓冲区的 JSON 记录器，测试结束后恢复
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	mu.Lock()
+	old := current
+	current = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level}))
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		current = old
+		mu.Unlock()
+	})
+	return &buf
+}
+
+func TestUserActionContextCarriesRequestFields(t *testing.T) {
+	buf := captureLogs(t)
+	ctx, _ := NewRequestContext(context.Background(), "req-1")
+	SetRequestUser(ctx, 42)
+
+	tests := []struct {
+		name  string
+		log   func()
+		level string
+	}{
+		{"成功", func() { UserActionContext(ctx, "alice", "删除用户", "bob", true) }, "INFO"},
+		{"失败", func() { UserActionContext(ctx, "alice", "删除用户", "bob", false) }, "WARN"},
+		{"出错", func() { UserActionWithErrorContext(ctx, "alice", "删除用户", "bob", errors.New("boom")) }, "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.log()
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("解析日志失败: %v（%s）", err, buf.String())
+			}
+			if entry["level"] != tt.level {
+				t.Errorf("level = %v，期望 %s", entry["level"], tt.level)
+			}
+			if entry["request_id"] != "req-1" || entry["user_id"] != float64(42) {
+				t.Errorf("日志应附加请求 ID 和用户，实际为 %v", entry)
+			}
+			if entry["user"] != "alice" || entry["action"] != "删除用户" {
+				t.Errorf("日志缺少操作信息: %v", entry)
+			}
+		})
+	}
+}
+
+func TestUserActionWithoutRequest(t *testing.T) {
+	buf := captureLogs(t)
+	UserAction("system", "清理回收站", "", true)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("解析日志失败: %v", err)
+	}
+	if _, ok := entry["request_id"]; ok {
+		t.Errorf("不在请求中的日志不应有请求 ID: %v", entry)
+	}
+}
@@ -1,135 +1,182 @@
-package logger // 定义包名为 logger
+// Package logger 基于 log/slog 的全局结构化日志
+//
+// 日志按级别（debug、info、warn、error）输出，附加的信息以键值对形式记录：
+//
+//	logger.Info("用户登录", "user", username, "ip", ip)
+//
+// 输出格式（json 或 text）和输出位置（stdout、file 或 both）由 Init 决定；
+// Init 之前以及 Init 失败时日志以文本格式写到标准错误。
+// Init 同时把标准库 log 包的输出接入同一个日志，第三方库的日志不会被分散到别处。
+package logger
 
 import (
-	"fmt"        // 用于格式化字符串
-	"log"        // 核心日志记录功能
-	"os"         // 操作系统接口，用于文件操作
-	"path/filepath" // 路径操作
-	"sync"       // 并发原语，如互斥锁和 Once
-	"time"       // 时间操作，用于日期格式化和计算时间间隔
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"user-management-system/config"
 )
 
-var (
-	fileLogger *Logger  // 全局的日志记录器实例
-	once       sync.Once // 确保 Init 函数只被调用一次
+// 输出格式
+const (
+	FormatJSON = "json"
+	FormatText = "text"
 )
 
-// Logger 日志记录器结构体
-type Logger struct {
-	mu       sync.Mutex // 互斥锁，用于保护对文件和 logger 的并发访问
-	file     *os.File   // 当前打开的日志文件句柄
-	logger   *log.Logger // 标准库的 log.Logger 实例
-	filePath string     // 当前日志文件的完整路径
-}
-
-// Init 初始化日志记录器
-// logDir: 日志文件存储的目录
-func Init(logDir string) error {
-	var err error // 用于捕获 Init 过程中可能发生的错误
-	once.Do(func() { // 确保 Init 函数内的代码只执行一次
-		// 确保日志目录存在，如果不存在则创建
-		// 0755 是目录的权限模式：所有者读写执行，组用户和其他用户只读执行
-		if err = os.MkdirAll(logDir, 0755); err != nil {
-			return // 如果创建目录失败，直接返回错误
-		}
-
-		// 生成日志文件名，格式为 "app_YYYY-MM-DD.log"
-		fileName := fmt.Sprintf("app_%s.log", time.Now().Format("2006-01-02"))
-		// 拼接日志目录和文件名，得到完整的日志文件路径
-		filePath := filepath.Join(logDir, fileName)
-
-		// 打开或创建日志文件
-		// os.O_CREATE: 如果文件不存在则创建
-		// os.O_APPEND: 写入时追加到文件末尾
-		// os.O_WRONLY: 只写模式
-		// 0644 是文件的权限模式：所有者读写，组用户和其他用户只读
-		file, openErr := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-		if openErr != nil {
-			err = openErr // 如果打开文件失败，记录错误
-			return
-		}
+// 输出位置
+const (
+	OutputStdout = "stdout" // 只输出到标准输出
+	OutputFile   = "file"   // 只写入日志目录中的文件
+	OutputBoth   = "both"   // 同时输出到标准输出和文件
+)
 
-		// 初始化全局的 fileLogger 实例
-		fileLogger = &Logger{
-			file:     file,                                 // 设置文件句柄
-			logger:   log.New(file, "", log.LstdFlags), // 创建一个新的 log.Logger，输出到文件，不带前缀，包含标准日期时间
-			filePath: filePath,                             // 设置文件路径
-		}
+// Options 日志配置
+type Options struct {
+	Level  string // debug、info、warn 或 error，为空时为 info
+	Format string // json 或 text，为空时为 json
+	Output string // stdout、file 或 both，为空时为 both
+	Dir    string // 日志文件目录（输出到文件时使用）
 
-		// 启动一个 goroutine，用于每天轮转日志文件
-		go fileLogger.rotateDaily(logDir)
-	})
+	Rotation Rotation // 日志文件的轮转和保留策略
+}
 
-	return err // 返回初始化过程中可能发生的错误
+// OptionsFromConfig 根据应用配置生成日志配置
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		Level:  cfg.LogLevel,
+		Format: cfg.LogFormat,
+		Output: cfg.LogOutput,
+		Dir:    cfg.LogDir,
+		Rotation: Rotation{
+			MaxSize:    int64(cfg.LogMaxSizeMB) << 20,
+			Interval:   cfg.LogRotateInterval,
+			MaxBackups: cfg.LogMaxBackups,
+			MaxAge:     cfg.LogMaxAge,
+			Compress:   cfg.LogCompress,
+		},
+	}
 }
 
-// rotateDaily 每天轮转日志文件
-// logDir: 日志文件存储的目录
-func (l *Logger) rotateDaily(logDir string) {
-	for { // 无限循环，持续进行日志轮转
-		// 计算到明天凌晨的时间点
-		now := time.Now()
-		// 获取明天凌晨 0 点 0 分 0 秒的时间
-		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
-		// 计算从现在到明天凌晨的时间间隔
-		duration := tomorrow.Sub(now)
+var (
+	mu    sync.RWMutex
+	file  *rotatingFile        // 输出到文件时的日志文件
+	level = new(slog.LevelVar) // 当前的最低级别，可以在运行中调整
 
-		// 等待到明天凌晨，这个 goroutine 会在这里阻塞
-		time.Sleep(duration)
+	// current 当前使用的日志记录器
+	current = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+)
 
-		// 达到明天凌晨后，开始轮转操作
-		l.mu.Lock() // 获取互斥锁，防止在轮转过程中有其他协程写入日志
+// Init 按 opts 初始化全局日志记录器，可以重复调用以重新配置
+func Init(opts Options) error {
+	lvl, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
 
-		// 关闭旧的日志文件
-		if l.file != nil {
-			l.file.Close()
+	var sinks []io.Writer
+	var newFile *rotatingFile
+	switch opts.Output {
+	case OutputStdout:
+		sinks = append(sinks, os.Stdout)
+	case OutputFile, OutputBoth, "":
+		if newFile, err = openRotatingFile(opts.Dir, opts.Rotation); err != nil {
+			return err
 		}
-
-		// 创建新的日志文件
-		fileName := fmt.Sprintf("app_%s.log", time.Now().Format("2006-01-02"))
-		filePath := filepath.Join(logDir, fileName)
-		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
-			// 如果创建新文件失败，打印错误信息（这里用标准的 log 包打印到 stderr）
-			log.Printf("创建新日志文件失败: %v", err)
-			l.mu.Unlock() // 释放锁
-			continue      // 继续下一次循环，等待下一个轮转周期
+		if opts.Output != OutputFile {
+			sinks = append(sinks, os.Stdout)
+		}
+		sinks = append(sinks, newFile)
+	default:
+		return fmt.Errorf("未知的日志输出位置: %s", opts.Output)
+	}
+	w := io.MultiWriter(sinks...)
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch opts.Format {
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	default:
+		if newFile != nil {
+			newFile.Close()
 		}
+		return fmt.Errorf("未知的日志格式: %s", opts.Format)
+	}
+
+	level.Set(lvl)
+	l := slog.New(handler)
 
-		// 更新 Logger 结构体中的文件句柄、logger 实例和文件路径
-		l.file = file
-		l.logger = log.New(file, "", log.LstdFlags)
-		l.filePath = filePath
+	mu.Lock()
+	oldFile := file
+	current, file = l, newFile
+	mu.Unlock()
 
-		l.mu.Unlock() // 释放互斥锁
+	// 标准库 log 包的输出以 info 级别写入同一个日志
+	slog.SetDefault(l)
+
+	if oldFile != nil {
+		oldFile.Close()
 	}
+	return nil
 }
 
-// Info 记录信息日志
-func Info(format string, v ...interface{}) {
-	if fileLogger != nil { // 检查日志记录器是否已初始化
-		fileLogger.mu.Lock()         // 获取互斥锁，确保并发安全
-		defer fileLogger.mu.Unlock() // 函数执行完毕后释放锁
-		fileLogger.logger.Printf("[INFO] "+format, v...) // 写入信息日志
+// ParseLevel 解析日志级别名称，为空时为 info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("未知的日志级别: %s", name)
 	}
 }
 
-// Error 记录错误日志
-func Error(format string, v ...interface{}) {
-	if fileLogger != nil {
-		fileLogger.mu.Lock()
-		defer fileLogger.mu.Unlock()
-		fileLogger.logger.Printf("[ERROR] "+format, v...) // 写入错误日志
-	}
+// SetLevel 调整最低日志级别
+func SetLevel(l slog.Level) {
+	level.Set(l)
 }
 
-// Warning 记录警告日志
-func Warning(format string, v ...interface{}) {
-	if fileLogger != nil {
-		fileLogger.mu.Lock()
-		defer fileLogger.mu.Unlock()
-		fileLogger.logger.Printf("[WARNING] "+format, v...) // 写入警告日志
-	}
+// L 返回全局日志记录器，用于 With 附加固定字段等 slog 的完整功能
+func L() *slog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Enabled 检查指定级别的日志是否会被输出，用于避免构造开销较大的字段
+func Enabled(l slog.Level) bool {
+	return L().Enabled(context.Background(), l)
+}
+
+// Debug 记录调试日志，args 为交替出现的键和值
+func Debug(msg string, args ...any) {
+	L().Debug(msg, args...)
+}
+
+// Info 记录信息日志，args 为交替出现的键和值
+func Info(msg string, args ...any) {
+	L().Info(msg, args...)
+}
+
+// Warn 记录警告日志，args 为交替出现的键和值
+func Warn(msg string, args ...any) {
+	L().Warn(msg, args...)
+}
+
+// Error 记录错误日志，args 为交替出现的键和值
+func Error(msg string, args ...any) {
+	L().Error(msg, args...)
 }
 
 // UserAction 记录用户操作日志
@@ -138,31 +185,55 @@ func Warning(format string, v ...interface{}) {
 // details: 操作详情
 // success: 操作是否成功
 func UserAction(username, action, details string, success bool) {
-	status := "成功"
-	if !success {
-		status = "失败"
+	UserActionContext(context.Background(), username, action, details, success)
+}
+
+// UserActionContext 与 UserAction 相同，使用 ctx 中请求范围的日志记录器，日志附加请求 ID、用户、路由和追踪 ID
+func UserActionContext(ctx context.Context, username, action, details string, success bool) {
+	if success {
+		FromContext(ctx).Info("用户操作", "user", username, "action", action, "details", details, "result", "成功")
+	} else {
+		FromContext(ctx).Warn("用户操作", "user", username, "action", action, "details", details, "result", "失败")
 	}
-	// 调用 Info 函数记录用户操作日志，格式化输出
-	Info("用户操作 - 用户: %s, 操作: %s, 详情: %s, 结果: %s", username, action, details, status)
 }
 
 // UserActionWithError 记录用户操作日志（包含错误详情）
 func UserActionWithError(username, action, details string, err error) {
-    if err != nil {
-        // 如果有错误，记录详细错误信息
-        Error("用户操作失败 - 用户: %s, 操作: %s, 详情: %s, 错误: %v", 
-            username, action, details, err)
-    } else {
-        // 成功的情况
-        Info("用户操作成功 - 用户: %s, 操作: %s, 详情: %s", 
-            username, action, details)
-    }
-}
-
-// Close 关闭日志文件
+	UserActionWithErrorContext(context.Background(), username, action, details, err)
+}
+
+// UserActionWithErrorContext 与 UserActionWithError 相同，使用 ctx 中请求范围的日志记录器
+func UserActionWithErrorContext(ctx context.Context, username, action, details string, err error) {
+	if err != nil {
+		FromContext(ctx).Error("用户操作失败", "user", username, "action", action, "details", details, "error", err)
+	} else {
+		FromContext(ctx).Info("用户操作成功", "user", username, "action", action, "details", details)
+	}
+}
+
+// Reopen 重新打开日志文件，用于外部工具（如 logrotate）移走日志文件之后，通常在收到 SIGHUP 时调用
+// 没有输出到文件时什么也不做
+func Reopen() error {
+	mu.RLock()
+	f := file
+	mu.RUnlock()
+
+	if f == nil {
+		return nil
+	}
+	return f.Reopen()
+}
+
+// Close 关闭日志文件，之后的日志只写到标准错误
 func Close() {
-	// 在程序退出时调用，确保关闭打开的日志文件
-	if fileLogger != nil && fileLogger.file != nil {
-		fileLogger.file.Close()
+	mu.Lock()
+	f := file
+	file = nil
+	current = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	mu.Unlock()
+
+	slog.SetDefault(current)
+	if f != nil {
+		f.Close()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,346 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志文件命名：当前写入的文件为 app.log，轮转后的文件为 app-<轮转时间>.log，压缩后再加 .gz
+const (
+	activeFileName   = "app.log"
+	backupPrefix     = "app-"
+	backupExt        = ".log"
+	compressedExt    = ".gz"
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+)
+
+// Rotation 日志文件的轮转和保留策略
+type Rotation struct {
+	MaxSize    int64         // 单个文件的最大字节数，超过后轮转（0表示不按大小轮转）
+	Interval   time.Duration // 按时间轮转的周期，按本地时间对齐，如 24h 在每天零点轮转（0表示不按时间轮转）
+	MaxBackups int           // 保留的轮转文件个数（0表示不限）
+	MaxAge     time.Duration // 轮转文件的保留时间（0表示不限）
+	Compress   bool          // 是否用 gzip 压缩轮转后的文件
+
+	// Now 返回当前时间，为空时为 time.Now；可以替换为假时钟，不必真的等到轮转时间
+	Now func() time.Time
+}
+
+// rotatingFile 写入日志目录中的 app.log，按大小和时间轮转
+// 轮转后的压缩和清理在后台进行，不阻塞写日志
+type rotatingFile struct {
+	mu     sync.Mutex
+	dir    string
+	policy Rotation
+
+	file   *os.File
+	size   int64     // 当前文件的字节数
+	period time.Time // 当前文件所属时间周期的起点
+
+	mill chan struct{} // 通知后台执行压缩和清理
+	done sync.WaitGroup
+}
+
+// openRotatingFile 在 dir 中打开 app.log（追加写入），目录不存在时创建
+func openRotatingFile(dir string, policy Rotation) (*rotatingFile, error) {
+	if dir == "" {
+		dir = "logs"
+	}
+	if policy.Now == nil {
+		policy.Now = time.Now
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	f := &rotatingFile{dir: dir, policy: policy, mill: make(chan struct{}, 1)}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	f.done.Add(1)
+	go f.millLoop()
+	// 启动时处理上次运行遗留的未压缩或过期文件
+	f.triggerMill()
+	return f, nil
+}
+
+// path 返回当前日志文件的路径
+func (f *rotatingFile) path() string {
+	return filepath.Join(f.dir, activeFileName)
+}
+
+// open 打开当前日志文件，调用方需持有锁（初始化时除外）
+// 已有文件的时间周期按其修改时间计算，进程重启后不会把上一周期的文件继续写下去
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.file, f.size = file, info.Size()
+	f.period = f.periodStart(f.policy.Now())
+	if f.size > 0 {
+		f.period = f.periodStart(info.ModTime())
+	}
+	return nil
+}
+
+// periodStart 返回 t 所在时间周期的起点（按本地时间对齐）
+func (f *rotatingFile) periodStart(t time.Time) time.Time {
+	if f.policy.Interval <= 0 {
+		return time.Time{}
+	}
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(f.policy.Interval).Add(-shift)
+}
+
+// Write 写入一条日志；超过大小或进入新的时间周期时先轮转，轮转失败时继续写入当前文件
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "轮转日志文件失败: %v\n", err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// shouldRotate 判断写入 n 字节前是否需要轮转；空文件不会因为单条日志过大而轮转
+func (f *rotatingFile) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.policy.MaxSize > 0 && f.size+n > f.policy.MaxSize {
+		return true
+	}
+	return f.policy.Interval > 0 && !f.periodStart(f.policy.Now()).Equal(f.period)
+}
+
+// rotate 把当前文件改名为带轮转时间的备份文件并打开新的 app.log，调用方需持有锁
+func (f *rotatingFile) rotate() error {
+	name := backupPrefix + f.policy.Now().Format(backupTimeFormat)
+	backup := filepath.Join(f.dir, name+backupExt)
+	// 同一毫秒内多次轮转时加序号，避免覆盖
+	for i := 1; fileExists(backup) || fileExists(backup+compressedExt); i++ {
+		backup = filepath.Join(f.dir, fmt.Sprintf("%s.%d%s", name, i, backupExt))
+	}
+
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := os.Rename(f.path(), backup); err != nil {
+		// 改名失败时重新打开原文件继续写入
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.triggerMill()
+	return nil
+}
+
+// Reopen 关闭并重新打开 app.log，供外部 logrotate 移走文件后调用（SIGHUP）
+func (f *rotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.open()
+}
+
+// Close 关闭日志文件，等待后台的压缩和清理完成
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	if f.file == nil {
+		f.mu.Unlock()
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	close(f.mill)
+	f.mu.Unlock()
+
+	f.done.Wait()
+	return err
+}
+
+// triggerMill 通知后台处理轮转文件，已有待处理的通知时不重复发送
+func (f *rotatingFile) triggerMill() {
+	select {
+	case f.mill <- struct{}{}:
+	default:
+	}
+}
+
+// millLoop 后台压缩和清理轮转文件，直到文件关闭
+func (f *rotatingFile) millLoop() {
+	defer f.done.Done()
+	for range f.mill {
+		if err := f.millOnce(); err != nil {
+			fmt.Fprintf(os.Stderr, "处理轮转日志文件失败: %v\n", err)
+		}
+	}
+}
+
+// backupFile 日志目录中的一个轮转文件
+type backupFile struct {
+	name       string
+	rotatedAt  time.Time
+	compressed bool
+}
+
+// millOnce 按保留策略删除多余和过期的轮转文件，再压缩剩余的未压缩文件
+func (f *rotatingFile) millOnce() error {
+	backups, err := f.listBackups()
+	if err != nil {
+		return err
+	}
+
+	var keep []backupFile
+	cutoff := time.Time{}
+	if f.policy.MaxAge > 0 {
+		cutoff = f.policy.Now().Add(-f.policy.MaxAge)
+	}
+	for i, b := range backups {
+		expired := !cutoff.IsZero() && b.rotatedAt.Before(cutoff)
+		if expired || (f.policy.MaxBackups > 0 && i >= f.policy.MaxBackups) {
+			if err := os.Remove(filepath.Join(f.dir, b.name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		keep = append(keep, b)
+	}
+
+	if !f.policy.Compress {
+		return nil
+	}
+	for _, b := range keep {
+		if b.compressed {
+			continue
+		}
+		if err := compressFile(filepath.Join(f.dir, b.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listBackups 列出日志目录中的轮转文件，按轮转时间从新到旧排列
+func (f *rotatingFile) listBackups() ([]backupFile, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if b, ok := parseBackupName(e.Name()); ok {
+			backups = append(backups, b)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+// parseBackupName 从文件名解析轮转时间，不是轮转文件时返回 false
+// 旧版本按日期命名的日志文件（app_YYYY-MM-DD.log）不是轮转文件，不会被清理或压缩
+func parseBackupName(name string) (backupFile, bool) {
+	b := backupFile{name: name}
+	base := name
+	if strings.HasSuffix(base, compressedExt) {
+		b.compressed = true
+		base = strings.TrimSuffix(base, compressedExt)
+	}
+	if !strings.HasSuffix(base, backupExt) {
+		return b, false
+	}
+	base = strings.TrimSuffix(base, backupExt)
+
+	if !strings.HasPrefix(base, backupPrefix) {
+		return b, false
+	}
+	stamp := strings.TrimPrefix(base, backupPrefix)
+	// 去掉同一毫秒内轮转时附加的序号
+	if len(stamp) > len(backupTimeFormat) {
+		stamp = stamp[:len(backupTimeFormat)]
+	}
+	var err error
+	b.rotatedAt, err = time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+	return b, err == nil
+}
+
+// compressFile 把 path 压缩为 path.gz 后删除原文件；先写临时文件，中途失败不会留下不完整的 .gz
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + compressedExt + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+compressedExt)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("压缩日志文件 %s 失败: %w", path, err)
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+// fileExists 检查文件是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
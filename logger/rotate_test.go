@@ -0,0 +1,326 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 测试用的时钟，后台的清理任务也会读取，因此加锁
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// openTestFile 在临时目录中打开轮转文件，测试结束时关闭
+func openTestFile(t *testing.T, policy Rotation) (*rotatingFile, string) {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := openRotatingFile(dir, policy)
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, dir
+}
+
+// mustWrite 写入一条日志
+func mustWrite(t *testing.T, f *rotatingFile, s string) {
+	t.Helper()
+	if _, err := f.Write([]byte(s)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+// mustMill 同步执行一次压缩和清理
+func mustMill(t *testing.T, f *rotatingFile) {
+	t.Helper()
+	if err := f.millOnce(); err != nil {
+		t.Fatalf("millOnce: %v", err)
+	}
+}
+
+// listDir 返回目录中的文件名（已排序）
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// assertFiles 检查目录中恰好有 want 中的文件
+func assertFiles(t *testing.T, dir string, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+}
+
+// readFile 读取文件内容
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return string(data)
+}
+
+// createBackup 创建一个在 at 时轮转的备份文件
+func createBackup(t *testing.T, dir string, at time.Time, content string) string {
+	t.Helper()
+	name := backupPrefix + at.Format(backupTimeFormat) + backupExt
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return name
+}
+
+func TestRotateBySize(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxSize: 10, Now: clock.Now})
+
+	mustWrite(t, f, "first\n")
+	mustWrite(t, f, "second\n") // 6+7 > 10，写入前轮转
+
+	backup := backupPrefix + clock.Now().Format(backupTimeFormat) + backupExt
+	assertFiles(t, dir, activeFileName, backup)
+	if got := readFile(t, filepath.Join(dir, backup)); got != "first\n" {
+		t.Errorf("backup = %q, want %q", got, "first\n")
+	}
+	if got := readFile(t, filepath.Join(dir, activeFileName)); got != "second\n" {
+		t.Errorf("app.log = %q, want %q", got, "second\n")
+	}
+}
+
+func TestRotateBySizeSameMillisecond(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxSize: 1, Now: clock.Now})
+
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		mustWrite(t, f, line)
+	}
+
+	// 同一时刻的两次轮转不会互相覆盖
+	if got := len(listDir(t, dir)); got != 3 {
+		t.Fatalf("files = %v, want app.log and 2 backups", listDir(t, dir))
+	}
+}
+
+func TestOversizedEntryDoesNotRotateEmptyFile(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxSize: 4, Now: clock.Now})
+
+	mustWrite(t, f, "longer than max\n")
+
+	assertFiles(t, dir, activeFileName)
+}
+
+func TestRotateByInterval(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{Interval: 24 * time.Hour, Now: clock.Now})
+
+	mustWrite(t, f, "day one\n")
+	clock.Advance(13 * time.Hour) // 23:00，仍在同一天
+	mustWrite(t, f, "day one again\n")
+	if got := listDir(t, dir); len(got) != 1 {
+		t.Fatalf("rotated within the same period: %v", got)
+	}
+
+	clock.Advance(2 * time.Hour) // 次日 01:00
+	mustWrite(t, f, "day two\n")
+
+	backup := backupPrefix + clock.Now().Format(backupTimeFormat) + backupExt
+	if got := readFile(t, filepath.Join(dir, backup)); got != "day one\nday one again\n" {
+		t.Errorf("backup = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, activeFileName)); got != "day two\n" {
+		t.Errorf("app.log = %q, want %q", got, "day two\n")
+	}
+}
+
+func TestPeriodStartAlignsToLocalTime(t *testing.T) {
+	f := &rotatingFile{policy: Rotation{Interval: 24 * time.Hour}}
+	at := time.Date(2026, 3, 1, 0, 30, 0, 0, time.Local)
+
+	want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	if got := f.periodStart(at); !got.Equal(want) {
+		t.Errorf("periodStart(%v) = %v, want %v", at, got, want)
+	}
+}
+
+func TestMillPrunesByMaxBackups(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxBackups: 2, Now: clock.Now})
+
+	var names []string
+	for i := 1; i <= 4; i++ {
+		names = append(names, createBackup(t, dir, clock.Now().Add(-time.Duration(i)*time.Hour), "x"))
+	}
+	mustMill(t, f)
+
+	// 只保留最新的两个
+	assertFiles(t, dir, activeFileName, names[0], names[1])
+}
+
+func TestMillPrunesByMaxAge(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxAge: 7 * 24 * time.Hour, Now: clock.Now})
+
+	recent := createBackup(t, dir, clock.Now().Add(-6*24*time.Hour), "recent")
+	createBackup(t, dir, clock.Now().Add(-8*24*time.Hour), "old")
+	mustMill(t, f)
+
+	assertFiles(t, dir, activeFileName, recent)
+
+	// 时间推移后较新的文件同样过期
+	clock.Advance(2 * 24 * time.Hour)
+	mustMill(t, f)
+	assertFiles(t, dir, activeFileName)
+}
+
+func TestMillKeepsLegacyAndUnrelatedFiles(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxBackups: 1, MaxAge: time.Hour, Compress: true, Now: clock.Now})
+
+	kept := []string{"app_2025-06-12.log", "other.log", "app-notatime.log"}
+	for _, name := range kept {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustMill(t, f)
+
+	for _, name := range kept {
+		if got := readFile(t, filepath.Join(dir, name)); got != "keep" {
+			t.Errorf("%s = %q, want untouched", name, got)
+		}
+	}
+}
+
+func TestMillCompressesBackups(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxSize: 10, Compress: true, Now: clock.Now})
+
+	mustWrite(t, f, "rotated line\n")
+	mustWrite(t, f, "current\n")
+	mustMill(t, f)
+
+	backup := backupPrefix + clock.Now().Format(backupTimeFormat) + backupExt + compressedExt
+	assertFiles(t, dir, activeFileName, backup)
+
+	file, err := os.Open(filepath.Join(dir, backup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read gzip: %v", err)
+	}
+	if string(data) != "rotated line\n" {
+		t.Errorf("decompressed = %q, want %q", data, "rotated line\n")
+	}
+
+	// 已压缩的文件仍按保留策略参与清理
+	if b, ok := parseBackupName(backup); !ok || !b.compressed {
+		t.Errorf("parseBackupName(%q) = %+v, %v", backup, b, ok)
+	}
+}
+
+func TestCloseWaitsForMill(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
+	f, err := openRotatingFile(dir, Rotation{MaxSize: 10, Compress: true, Now: clock.Now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustWrite(t, f, "rotated line\n")
+	mustWrite(t, f, "current\n")
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for _, name := range listDir(t, dir) {
+		if name != activeFileName && !strings.HasSuffix(name, compressedExt) {
+			t.Errorf("%s not compressed after Close", name)
+		}
+	}
+	if _, err := f.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("Write after Close = %v, want os.ErrClosed", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{Now: clock.Now})
+
+	mustWrite(t, f, "before\n")
+	// 模拟外部 logrotate 移走文件
+	moved := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(filepath.Join(dir, activeFileName), moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	mustWrite(t, f, "after\n")
+
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("moved file = %q, want %q", got, "before\n")
+	}
+	if got := readFile(t, filepath.Join(dir, activeFileName)); got != "after\n" {
+		t.Errorf("app.log = %q, want %q", got, "after\n")
+	}
+}
+
+func TestReopenKeepsSizeOfExistingFile(t *testing.T) {
+	clock := newFakeClock()
+	f, dir := openTestFile(t, Rotation{MaxSize: 10, Now: clock.Now})
+
+	mustWrite(t, f, "12345678\n")
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	mustWrite(t, f, "next\n")
+
+	// 重新打开后仍然按已有的大小判断轮转
+	if got := len(listDir(t, dir)); got != 2 {
+		t.Errorf("files = %v, want app.log and 1 backup", listDir(t, dir))
+	}
+}
@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"user-management-system/config"
+	"user-management-system/logger"
+)
+
+// Message 一封待发送的邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// HealthChecker 可以检查发送通道是否可用的 Mailer（用于就绪检查）
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// NewMailer 根据配置创建邮件发送器
+// 未配置 SMTP 服务器时返回只写日志的实现，方便本地开发
+func NewMailer(cfg *config.Config) Mailer {
+	if cfg.SMTPHost == "" {
+		return &logMailer{from: cfg.MailFrom}
+	}
+	return &smtpMailer{
+		addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		host:     cfg.SMTPHost,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		from:     cfg.MailFrom,
+	}
+}
+
+// logMailer 把邮件内容写入日志而不真正发送
+type logMailer struct {
+	from string
+}
+
+// Send 将邮件写入日志
+func (m *logMailer) Send(msg *Message) error {
+	logger.Info("邮件(未发送)", "from", m.from, "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// smtpMailer 通过 SMTP 服务器发送邮件
+type smtpMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// Send 通过 SMTP 发送邮件
+func (m *smtpMailer) Send(msg *Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	if err := smtp.SendMail(m.addr, auth, envelopeAddress(m.from), []string{msg.To}, m.build(msg)); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
+
+// CheckHealth 连接 SMTP 服务器并读取欢迎信息，不发送邮件
+func (m *smtpMailer) CheckHealth(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return fmt.Errorf("SMTP 服务器没有正常响应: %w", err)
+	}
+	return client.Quit()
+}
+
+// build 组装邮件原文
+func (m *smtpMailer) build(msg *Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + m.from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + encodeHeader(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// envelopeAddress 从 "名称 <地址>" 中取出地址部分
+func envelopeAddress(from string) string {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		return strings.TrimSuffix(from[i+1:], ">")
+	}
+	return from
+}
+
+// encodeHeader 对包含非 ASCII 字符的邮件头进行 MIME 编码
+func encodeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.BEncoding.Encode("UTF-8", s)
+		}
+	}
+	return s
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,24 +11,52 @@ import (
 	"user-management-system/app"
 	"user-management-system/config"
 	"user-management-system/database"
-	"user-management-system/errors"
+	"user-management-system/health"
 	"user-management-system/logger"
 	"user-management-system/router"
+	"user-management-system/services"
+	"user-management-system/tracing"
 )
 
 func main() {
-	// 初始化日志记录器
-	if err := logger.Init("logs"); err != nil {
-		log.Fatalf("日志初始化失败: %v", err)
+	// 获取配置
+	cfg := config.GetConfig()
+
+	// 初始化日志记录器（失败时日志仍以文本格式写到标准错误）
+	if err := logger.Init(logger.OptionsFromConfig(cfg)); err != nil {
+		logger.Error("日志初始化失败", "error", err)
+		os.Exit(1)
 	}
 	defer logger.Close()
 
 	logger.Info("应用程序启动中...")
 
+	// 初始化追踪（未启用时 span 不会被记录）
+	if err := tracing.Init(tracing.OptionsFromConfig(cfg)); err != nil {
+		logger.Error("追踪初始化失败", "error", err)
+		os.Exit(1)
+	}
+	if tracing.Enabled() {
+		logger.Info("追踪已启用", "exporter", cfg.TraceExporter, "sample_ratio", cfg.TraceSampleRatio)
+	}
+
+	// 收到 SIGHUP 时重新打开日志文件，配合外部 logrotate 使用
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := logger.Reopen(); err != nil {
+				logger.Error("重新打开日志文件失败", "error", err)
+				continue
+			}
+			logger.Info("日志文件已重新打开")
+		}
+	}()
+
 	// 初始化数据库连接
 	if err := database.InitDB(); err != nil {
-		logger.Error("数据库初始化失败: %v", err)
-		log.Fatalf("数据库初始化失败: %v", err)
+		logger.Error("数据库初始化失败", "error", err)
+		os.Exit(1)
 	}
 	defer database.CloseDB()
 
@@ -40,20 +67,31 @@ func main() {
 		2*time.Hour,
 	)
 
+	// 启动回收站清除任务
+	stopPurge := services.StartPurgeJob(
+		services.NewServiceWithDB(database.GetDB()).UserService,
+		cfg.SoftDeletePurgeInterval,
+	)
+	defer stopPurge()
+
+	// 启动审计检查点任务
+	stopCheckpoint := services.StartAuditCheckpointJob(
+		services.NewServiceWithDB(database.GetDB()).AuditService,
+		cfg.AuditCheckpointInterval,
+	)
+	defer stopCheckpoint()
+
 	// 创建路由器
 	r := router.NewRouter(application)
 	handler := r.Setup()
 
-	// 获取配置
-	cfg := config.GetConfig()
-
 	// 创建服务器
 	server := &http.Server{
 		Addr:         "0.0.0.0:" + cfg.ServerPort,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
-		Handler:      errors.RecoverMiddleware(handler),
+		Handler:      handler,
 	}
 
 	// 创建通道监听终止信号
@@ -62,28 +100,39 @@ func main() {
 
 	// 启动服务器
 	go func() {
-		logger.Info("服务器启动在 http://localhost:%s", cfg.ServerPort)
-		log.Printf("服务器启动在 http://localhost:%s\n", cfg.ServerPort)
+		logger.Info("服务器启动", "addr", server.Addr, "url", "http://localhost:"+cfg.ServerPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("服务器启动失败: %v", err)
-			log.Fatalf("服务器启动失败: %v", err)
+			logger.Error("服务器启动失败", "error", err)
+			logger.Close()
+			os.Exit(1)
 		}
 	}()
 
 	// 等待终止信号
 	<-done
 	logger.Info("收到关闭信号，服务器正在关闭...")
-	log.Println("服务器正在关闭...")
+
+	// 就绪检查先失败，等待负载均衡摘除实例后再停止接收请求
+	health.Default.SetShuttingDown()
+	if delay := cfg.ShutdownDrainDelay; delay > 0 {
+		logger.Info("等待负载均衡摘除实例", "delay", delay)
+		time.Sleep(delay)
+	}
 
 	// 优雅关闭（给予5秒时间完成正在处理的请求）
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		logger.Error("服务器关闭失败: %v", err)
-		log.Printf("服务器关闭失败: %v", err)
+		logger.Error("服务器关闭失败", "error", err)
+	}
+
+	// 导出尚未发送的追踪数据
+	traceCtx, traceCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer traceCancel()
+	if err := tracing.Shutdown(traceCtx); err != nil {
+		logger.Warn("导出剩余的追踪数据失败", "error", err)
 	}
 
 	logger.Info("服务器已停止")
-	log.Println("服务器已停止")
 }
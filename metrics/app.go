@@ -0,0 +1,27 @@
+package metrics
+
+// 应用指标，名称以 userhub_ 开头
+var (
+	// HTTPRequests 处理完成的 HTTP 请求数，route 为匹配的路由模式（而不是实际路径），避免标签值过多
+	HTTPRequests = NewCounterVec("userhub_http_requests_total", "处理完成的 HTTP 请求数", "method", "route", "status")
+
+	// HTTPRequestDuration HTTP 请求的处理耗时（秒）
+	HTTPRequestDuration = NewHistogramVec("userhub_http_request_duration_seconds", "HTTP 请求的处理耗时（秒）", DefaultBuckets, "method", "route")
+
+	// Logins 登录尝试次数，result 为 success 或 failure
+	Logins = NewCounterVec("userhub_logins_total", "登录尝试次数", "result")
+)
+
+// 登录结果
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
+
+func init() {
+	// 登录计数从0开始输出，第一次失败之前也能计算比率
+	Logins.Add(0, LoginSuccess)
+	Logins.Add(0, LoginFailure)
+
+	Default.MustRegister(HTTPRequests, HTTPRequestDuration, Logins, NewGoCollector())
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"database/sql"
+	"runtime"
+)
+
+// NewGoCollector 返回 Go 运行时指标：goroutine 数、内存和 GC 统计
+// 指标名与 Prometheus 官方客户端一致，可以直接使用现成的仪表盘
+func NewGoCollector() Collector {
+	return CollectorFunc(func() []*Family {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+
+		gauge := func(name, help string, v float64) *Family {
+			return &Family{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: v}}}
+		}
+		return []*Family{
+			gauge("go_goroutines", "当前的 goroutine 数", float64(runtime.NumGoroutine())),
+			gauge("go_threads", "创建的操作系统线程数", float64(threadCount())),
+			gauge("go_gomaxprocs", "GOMAXPROCS 设置", float64(runtime.GOMAXPROCS(0))),
+			{Name: "go_info", Help: "Go 版本", Type: TypeGauge, Samples: []Sample{{Labels: []Label{{Name: "version", Value: runtime.Version()}}, Value: 1}}},
+			gauge("go_memstats_alloc_bytes", "已分配且仍在使用的堆内存字节数", float64(ms.Alloc)),
+			{Name: "go_memstats_alloc_bytes_total", Help: "累计分配的堆内存字节数", Type: TypeCounter, Samples: []Sample{{Value: float64(ms.TotalAlloc)}}},
+			gauge("go_memstats_sys_bytes", "从操作系统获得的内存字节数", float64(ms.Sys)),
+			gauge("go_memstats_heap_alloc_bytes", "堆上已分配且仍在使用的字节数", float64(ms.HeapAlloc)),
+			gauge("go_memstats_heap_inuse_bytes", "正在使用的堆内存段字节数", float64(ms.HeapInuse)),
+			gauge("go_memstats_heap_objects", "已分配的堆对象数", float64(ms.HeapObjects)),
+			gauge("go_memstats_stack_inuse_bytes", "栈使用的字节数", float64(ms.StackInuse)),
+			{Name: "go_memstats_mallocs_total", Help: "累计分配的对象数", Type: TypeCounter, Samples: []Sample{{Value: float64(ms.Mallocs)}}},
+			{Name: "go_memstats_frees_total", Help: "累计释放的对象数", Type: TypeCounter, Samples: []Sample{{Value: float64(ms.Frees)}}},
+			gauge("go_memstats_next_gc_bytes", "触发下一次 GC 的堆大小", float64(ms.NextGC)),
+			gauge("go_memstats_last_gc_time_seconds", "上一次 GC 完成的时间（Unix 秒）", float64(ms.LastGC)/1e9),
+			{Name: "go_gc_cycles_total", Help: "完成的 GC 次数", Type: TypeCounter, Samples: []Sample{{Value: float64(ms.NumGC)}}},
+			{Name: "go_gc_pause_seconds_total", Help: "GC 停顿的累计时间（秒）", Type: TypeCounter, Samples: []Sample{{Value: float64(ms.PauseTotalNs) / 1e9}}},
+		}
+	})
+}
+
+// threadCount 返回创建的操作系统线程数
+func threadCount() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
+
+// NewDBStatsCollector 返回数据库连接池指标（sql.DB.Stats）
+func NewDBStatsCollector(db *sql.DB) Collector {
+	return CollectorFunc(func() []*Family {
+		stats := db.Stats()
+
+		gauge := func(name, help string, v float64) *Family {
+			return &Family{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: v}}}
+		}
+		counter := func(name, help string, v float64) *Family {
+			return &Family{Name: name, Help: help, Type: TypeCounter, Samples: []Sample{{Value: v}}}
+		}
+		return []*Family{
+			gauge("db_max_open_connections", "连接池允许的最大连接数（0表示不限）", float64(stats.MaxOpenConnections)),
+			gauge("db_open_connections", "当前打开的连接数", float64(stats.OpenConnections)),
+			gauge("db_in_use_connections", "正在使用的连接数", float64(stats.InUse)),
+			gauge("db_idle_connections", "空闲的连接数", float64(stats.Idle)),
+			counter("db_wait_count_total", "等待空闲连接的累计次数", float64(stats.WaitCount)),
+			counter("db_wait_duration_seconds_total", "等待空闲连接的累计时间（秒）", stats.WaitDuration.Seconds()),
+			counter("db_max_idle_closed_total", "因超过最大空闲连接数而关闭的连接数", float64(stats.MaxIdleClosed)),
+			counter("db_max_idle_time_closed_total", "因超过最大空闲时间而关闭的连接数", float64(stats.MaxIdleTimeClosed)),
+			counter("db_max_lifetime_closed_total", "因超过最大存活时间而关闭的连接数", float64(stats.MaxLifetimeClosed)),
+		}
+	})
+}
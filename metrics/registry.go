@@ -0,0 +1,176 @@
+// Package metrics 以 Prometheus 文本格式输出运行指标
+//
+// 只实现了本项目需要的部分：计数器、直方图和在抓取时计算的仪表盘，
+// 不依赖外部的客户端库；输出格式见 https://prometheus.io/docs/instrumenting/exposition_formats/
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 指标类型
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Label 指标的一个标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample 指标的一个取值；Name 为空时使用所属指标的名称（直方图的 _bucket、_sum、_count 需要单独指定）
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+}
+
+// Family 同名的一组取值
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector 在每次抓取时提供当前的指标
+type Collector interface {
+	Collect() []*Family
+}
+
+// CollectorFunc 函数形式的 Collector
+type CollectorFunc func() []*Family
+
+// Collect 实现 Collector 接口
+func (f CollectorFunc) Collect() []*Family {
+	return f()
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default 全局注册表，/metrics 输出的就是其中的指标
+var Default = NewRegistry()
+
+// MustRegister 注册 collectors
+func (r *Registry) MustRegister(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Gather 收集所有指标，按名称排序；同名的指标合并为一组
+func (r *Registry) Gather() []*Family {
+	r.mu.RLock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	byName := make(map[string]*Family)
+	var families []*Family
+	for _, c := range collectors {
+		for _, f := range c.Collect() {
+			if existing, ok := byName[f.Name]; ok {
+				existing.Samples = append(existing.Samples, f.Samples...)
+				continue
+			}
+			byName[f.Name] = f
+			families = append(families, f)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
+
+// Write 以 Prometheus 文本格式写出所有指标
+func (r *Registry) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			name := s.Name
+			if name == "" {
+				name = f.Name
+			}
+			bw.WriteString(name)
+			writeLabels(bw, s.Labels)
+			bw.WriteByte(' ')
+			bw.WriteString(formatValue(s.Value))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// ContentType 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler 返回输出注册表中指标的 HTTP 处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.Write(w)
+	})
+}
+
+// writeLabels 写出 {name="value",...}，没有标签时什么也不写
+func writeLabels(w *bufio.Writer, labels []Label) {
+	if len(labels) == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(l.Name)
+		w.WriteString(`="`)
+		w.WriteString(labelValueEscaper.Replace(l.Value))
+		w.WriteByte('"')
+	}
+	w.WriteByte('}')
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// escapeHelp 转义说明文字中的反斜杠和换行
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// formatValue 按文本格式的约定输出数值
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
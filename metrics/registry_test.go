@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// render 以文本格式输出 collectors 的指标
+func render(t *testing.T, collectors ...Collector) string {
+	t.Helper()
+	r := NewRegistry()
+	r.MustRegister(collectors...)
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounterExposition(t *testing.T) {
+	c := NewCounterVec("logins_total", "登录次数", "result")
+	c.Inc("success")
+	c.Inc("success")
+	c.Add(0.5, "failure")
+
+	want := `# HELP logins_total 登录次数
+# TYPE logins_total counter
+logins_total{result="failure"} 0.5
+logins_total{result="success"} 2
+`
+	if got := render(t, c); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterWithoutLabels(t *testing.T) {
+	c := NewCounterVec("events_total", "事件数")
+	c.Inc()
+
+	want := "# HELP events_total 事件数\n# TYPE events_total counter\nevents_total 1\n"
+	if got := render(t, c); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	h := NewHistogramVec("duration_seconds", "耗时", []float64{1, 0.1}, "route")
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a") // 等于上界时计入该分桶
+	h.Observe(3, "/a")   // 超过所有上界时只计入 +Inf
+
+	want := `# HELP duration_seconds 耗时
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 2
+duration_seconds_bucket{route="/a",le="1"} 2
+duration_seconds_bucket{route="/a",le="+Inf"} 3
+duration_seconds_sum{route="/a"} 3.15
+duration_seconds_count{route="/a"} 3
+`
+	if got := render(t, h); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	c := NewCounterVec("escaped_total", "第一行\n反斜杠\\", "value")
+	c.Inc("引号\"换行\n反斜杠\\")
+
+	want := `# HELP escaped_total 第一行\n反斜杠\\
+# TYPE escaped_total counter
+escaped_total{value="引号\"换行\n反斜杠\\"} 1
+`
+	if got := render(t, c); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{42, "42"},
+		{0.25, "0.25"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v); got != tt.want {
+			t.Errorf("formatValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestGatherSortsAndMergesFamilies(t *testing.T) {
+	b := NewGaugeFunc("b_gauge", "b", func() float64 { return 2 })
+	a1 := CollectorFunc(func() []*Family {
+		return []*Family{{Name: "a_gauge", Help: "a", Type: TypeGauge, Samples: []Sample{{Labels: []Label{{Name: "x", Value: "1"}}, Value: 1}}}}
+	})
+	a2 := CollectorFunc(func() []*Family {
+		return []*Family{{Name: "a_gauge", Help: "a", Type: TypeGauge, Samples: []Sample{{Labels: []Label{{Name: "x", Value: "2"}}, Value: 2}}}}
+	})
+
+	// 同名的指标只输出一次 HELP 和 TYPE
+	want := `# HELP a_gauge a
+# TYPE a_gauge gauge
+a_gauge{x="1"} 1
+a_gauge{x="2"} 2
+# HELP b_gauge b
+# TYPE b_gauge gauge
+b_gauge 2
+`
+	if got := render(t, b, a1, a2); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for wrong label count")
+		}
+	}()
+	NewCounterVec("x_total", "x", "a", "b").Inc("only one")
+}
+
+func TestCounterCannotDecrease(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for negative add")
+		}
+	}()
+	NewCounterVec("x_total", "x").Add(-1)
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(NewGaugeFunc("up", "存活", func() float64 { return 1 }))
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), "\nup 1\n") {
+		t.Errorf("body = %q, want the up sample", rec.Body.String())
+	}
+}
+
+func TestGoCollectorNames(t *testing.T) {
+	out := render(t, NewGoCollector())
+	for _, name := range []string{"go_goroutines ", "go_memstats_alloc_bytes ", "go_gc_cycles_total ", `go_info{version="`} {
+		if !strings.Contains(out, "\n"+name) {
+			t.Errorf("missing %s in output", name)
+		}
+	}
+}
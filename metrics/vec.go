@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// labelKey 把标签值拼成 map 的键
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// makeLabels 把标签名和标签值配成 Label
+func makeLabels(names, values []string) []Label {
+	labels := make([]Label, len(names))
+	for i, name := range names {
+		labels[i] = Label{Name: name, Value: values[i]}
+	}
+	return labels
+}
+
+// checkLabels 标签值的个数必须与标签名一致，不一致属于编程错误
+func checkLabels(name string, names, values []string) {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("指标 %s 需要 %d 个标签值，实际为 %d 个", name, len(names), len(values)))
+	}
+}
+
+// CounterVec 按标签区分的计数器
+type CounterVec struct {
+	name, help string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec 创建计数器，labelNames 为标签名（可以为空）
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labelNames: labelNames, values: make(map[string]*counterValue)}
+}
+
+// Inc 对应标签的计数加一
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 对应标签的计数加 v（v 不能为负数）
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	checkLabels(c.name, c.labelNames, labelValues)
+	if v < 0 {
+		panic(fmt.Sprintf("计数器 %s 不能减少", c.name))
+	}
+
+	key := labelKey(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labels: append([]string(nil), labelValues...)}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+// Collect 实现 Collector 接口
+func (c *CounterVec) Collect() []*Family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f := &Family{Name: c.name, Help: c.help, Type: TypeCounter}
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		f.Samples = append(f.Samples, Sample{Labels: makeLabels(c.labelNames, cv.labels), Value: cv.value})
+	}
+	return []*Family{f}
+}
+
+// DefaultBuckets 请求耗时（秒）的默认分桶
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HistogramVec 按标签区分的直方图
+type HistogramVec struct {
+	name, help string
+	labelNames []string
+	buckets    []float64 // 各分桶的上界，升序
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // 每个分桶内（不累计）的观测数
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec 创建直方图，buckets 为分桶上界（为空时使用 DefaultBuckets）
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{name: name, help: help, labelNames: labelNames, buckets: buckets, values: make(map[string]*histogramValue)}
+}
+
+// Observe 记录一次观测
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	checkLabels(h.name, h.labelNames, labelValues)
+
+	key := labelKey(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labels: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.count++
+	hv.sum += v
+}
+
+// Collect 实现 Collector 接口，分桶计数按 Prometheus 的约定累计输出
+func (h *HistogramVec) Collect() []*Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f := &Family{Name: h.name, Help: h.help, Type: TypeHistogram}
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		labels := makeLabels(h.labelNames, hv.labels)
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			f.Samples = append(f.Samples, Sample{Name: h.name + "_bucket", Labels: withLabel(labels, "le", formatValue(upper)), Value: float64(cumulative)})
+		}
+		f.Samples = append(f.Samples,
+			Sample{Name: h.name + "_bucket", Labels: withLabel(labels, "le", formatValue(math.Inf(1))), Value: float64(hv.count)},
+			Sample{Name: h.name + "_sum", Labels: labels, Value: hv.sum},
+			Sample{Name: h.name + "_count", Labels: labels, Value: float64(hv.count)},
+		)
+	}
+	return []*Family{f}
+}
+
+// withLabel 返回追加了一个标签的新切片
+func withLabel(labels []Label, name, value string) []Label {
+	out := make([]Label, len(labels), len(labels)+1)
+	copy(out, labels)
+	return append(out, Label{Name: name, Value: value})
+}
+
+// GaugeFunc 在抓取时调用函数取值的仪表盘
+type GaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+// NewGaugeFunc 创建在抓取时调用 fn 取值的仪表盘
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, fn: fn}
+}
+
+// Collect 实现 Collector 接口
+func (g *GaugeFunc) Collect() []*Family {
+	return []*Family{{Name: g.name, Help: g.help, Type: TypeGauge, Samples: []Sample{{Value: g.fn()}}}}
+}
+
+// sortedKeys 按键排序，使输出顺序稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
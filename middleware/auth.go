@@ -2,23 +2,28 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 	"sync"
 
 	"user-management-system/app"
 	"user-management-system/errors"
+	"user-management-system/models"
+	"user-management-system/rbac"
 	"user-management-system/repository/mysql"
 	"user-management-system/session"
 )
 
 // Middleware 中间件集合
 type Middleware struct {
-	Auth *AuthMiddleware
+	Auth   *AuthMiddleware
+	Tenant *TenantMiddleware
 }
 
 // NewMiddleware 创建中间件集合
 func NewMiddleware(application *app.App) *Middleware {
 	return &Middleware{
-		Auth: NewAuthMiddleware(application),
+		Auth:   NewAuthMiddleware(application),
+		Tenant: NewTenantMiddleware(application),
 	}
 }
 
@@ -64,6 +69,12 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// 每次请求都检查账户状态，被停用或锁定的用户立即失去访问权限
+		if _, err := sessionHelper.GetCurrentUser(r); err != nil {
+			m.rejectSession(w, r, err)
+			return
+		}
+
 		// 继续处理请求
 		next.ServeHTTP(w, r)
 	})
@@ -86,26 +97,99 @@ func (m *AuthMiddleware) RequirePostAuth(next http.Handler) http.Handler {
 	})
 }
 
-// RequireAdmin 要求管理员权限
-func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
+// passwordChangeAllowedPaths 被要求修改密码的会话仍可访问的路径
+var passwordChangeAllowedPaths = map[string]bool{
+	"/password/change": true,
+	"/logout":          true,
+	"/health":          true,
+	"/healthz":         true,
+	"/readyz":          true,
+	"/metrics":         true,
+}
+
+// EnforcePasswordChange 会话被标记为必须修改密码时（如密码过期），
+// 在修改完成之前拦截除修改密码页面以外的所有请求
+func (m *AuthMiddleware) EnforcePasswordChange(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 获取当前用户
-		sessionHelper := m.getSessionHelper()
-		user, err := sessionHelper.GetCurrentUser(r)
-		if err != nil {
-			// 如果获取用户信息失败，重定向到登录页面
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		if passwordChangeAllowedPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		// 检查用户是否为管理员
-		if !user.IsAdmin() {
-			// 如果不是管理员，返回权限错误
-			errors.HandleError(w, r, errors.NewForbiddenError("需要管理员权限"))
-			return
+		// 只读取内存中的会话，不查询数据库
+		sess, err := m.app.GetSessionManager().GetSession(r)
+		if err == nil {
+			if _, required := session.PasswordChangeReason(sess); required {
+				if strings.HasPrefix(r.URL.Path, "/api") {
+					errors.HandleError(w, r, errors.NewForbiddenError("请先修改密码"))
+					return
+				}
+				http.Redirect(w, r, "/password/change", http.StatusSeeOther)
+				return
+			}
 		}
 
-		// 用户是管理员，继续执行后续处理程序
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequirePermission 要求当前用户的角色授予了指定权限
+// 针对具体资源的检查（如只能修改自己）由服务层通过 rbac.Can 完成
+func (m *AuthMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 获取当前用户
+			sessionHelper := m.getSessionHelper()
+			user, err := sessionHelper.GetCurrentUser(r)
+			if err != nil {
+				// 如果获取用户信息失败，重定向到登录页面
+				m.rejectSession(w, r, err)
+				return
+			}
+
+			if !rbac.Can(user, permission, nil) {
+				errors.HandleError(w, r, errors.NewForbiddenError("没有权限："+models.PermissionLabel(permission)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireDelegablePermission 要求用户拥有指定权限，或通过委派授权对至少一部分用户拥有该操作
+// 能否对具体的用户执行操作由服务层按授权范围检查
+func (m *AuthMiddleware) RequireDelegablePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := m.getSessionHelper().GetCurrentUser(r)
+			if err != nil {
+				m.rejectSession(w, r, err)
+				return
+			}
+
+			if !rbac.CanSome(user, permission) {
+				errors.HandleError(w, r, errors.NewForbiddenError("没有权限："+models.PermissionLabel(permission)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rejectSession 处理会话用户无效的情况：账户不存在或已停用时清除会话并重定向到登录页，
+// 数据库等内部错误则直接返回错误响应，不注销用户
+func (m *AuthMiddleware) rejectSession(w http.ResponseWriter, r *http.Request, err error) {
+	if appErr, ok := errors.IsAppError(err); ok && appErr.Type == errors.InternalError {
+		errors.HandleError(w, r, err)
+		return
+	}
+
+	m.getSessionHelper().Logout(w, r)
+	if strings.HasPrefix(r.URL.Path, "/api") {
+		errors.HandleError(w, r, errors.NewUnauthorizedError("请先登录"))
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
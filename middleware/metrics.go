@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"user-management-system/logger"
+	"user-management-system/metrics"
+)
+
+// unmatchedRoute 没有匹配到路由的请求（如组织不存在时在路由之前就返回了）使用的 route 标签
+const unmatchedRoute = "unmatched"
+
+// Metrics 按方法、路由和状态码记录请求数和处理耗时
+// 路由取自请求日志信息，需要放在 RequestID 之后
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		route := unmatchedRoute
+		if req := logger.RequestFromContext(r.Context()); req != nil && req.Route() != "" {
+			route = req.Route()
+		}
+		method := metricsMethod(r.Method)
+		metrics.HTTPRequests.Inc(method, route, strconv.Itoa(rec.Status()))
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), method, route)
+	})
+}
+
+// metricsMethod 返回 method 标签的值，非标准的方法归为 OTHER，避免任意请求产生大量标签值
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"user-management-system/logger"
+)
+
+// RequestIDHeader 传递请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 接受的外部请求 ID 的最大长度，与 audit_events.request_id 列一致
+const maxRequestIDLength = 64
+
+// RequestID 为每个请求确定请求 ID：请求头中带有合法的 X-Request-ID 时沿用（便于与上游网关的日志关联），
+// 否则生成新的 ID；请求 ID 写入响应头，并随请求范围的日志记录器放入请求上下文
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx, _ := logger.NewRequestContext(r.Context(), id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID 检查外部传入的请求 ID：长度有限，且只包含字母、数字和 - _ . :
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成随机的请求 ID（32位十六进制）
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// 随机数不可用时退化为时间戳，只用于关联日志
+		return hex.EncodeToString([]byte(time.Now().Format("20060102150405.000000000")))
+	}
+	return hex.EncodeToString(b)
+}
+
+// AccessLog 请求处理完成后记录一条访问日志：方法、路径、状态码、响应字节数和耗时
+// 日志通过请求范围的日志记录器输出，带有请求 ID、用户和路由；静态文件以 debug 级别记录，5xx 响应以 error 级别记录
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		// 组织中间件会去掉路径中的组织前缀，先记下原始路径
+		path := r.URL.Path
+
+		next.ServeHTTP(rec, r)
+
+		status := rec.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case strings.HasPrefix(path, "/static/"):
+			level = slog.LevelDebug
+		}
+
+		logger.FromContext(r.Context()).Log(r.Context(), level, "access",
+			"method", r.Method,
+			"path", path,
+			"status", status,
+			"bytes", rec.bytes,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"ip", clientIP(r),
+			"user_agent", r.UserAgent(),
+		)
+	})
+}
+
+// clientIP 返回客户端地址（不含端口）
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// statusRecorder 记录响应状态码和字节数
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader 记录状态码
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write 记录写入的字节数，未调用 WriteHeader 时状态码为 200
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Status 返回响应状态码，处理器没有写入任何内容时为 200
+func (w *statusRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush 支持流式响应（如审计日志导出）
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 支持需要接管连接的处理器
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap 供 http.ResponseController 访问底层的 ResponseWriter
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"user-management-system/app"
+	"user-management-system/config"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/rbac"
+	"user-management-system/repository/interfaces"
+	"user-management-system/repository/mysql"
+	"user-management-system/session"
+	"user-management-system/tenant"
+)
+
+// tenantPathPrefix 路径前缀形式的组织地址，如 /o/acme/users
+const tenantPathPrefix = "/o/"
+
+// TenantMiddleware 解析请求所在的组织并写入请求上下文
+type TenantMiddleware struct {
+	app           *app.App
+	orgRepo       interfaces.OrganizationRepository
+	sessionHelper *session.Helper
+	once          sync.Once
+	mu            sync.RWMutex
+}
+
+// NewTenantMiddleware 创建组织解析中间件
+func NewTenantMiddleware(application *app.App) *TenantMiddleware {
+	return &TenantMiddleware{
+		app: application,
+	}
+}
+
+// getDependencies 延迟初始化组织仓库和会话辅助器
+func (m *TenantMiddleware) getDependencies() (interfaces.OrganizationRepository, *session.Helper) {
+	m.once.Do(func() {
+		m.orgRepo = mysql.NewOrganizationRepository(m.app.GetDB())
+		m.sessionHelper = session.NewHelper(m.app.GetSessionManager(), mysql.NewUserRepository(m.app.GetDB()))
+	})
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.orgRepo, m.sessionHelper
+}
+
+// operationalPaths 探测和监控使用的路径，不解析组织
+var operationalPaths = map[string]bool{
+	"/health":  true,
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Resolve 按以下顺序确定请求所在的组织：
+//  1. 子域名（配置了 TenantBaseDomain 时）
+//  2. 路径前缀 /o/{标识}/，解析后去掉前缀再交给后续处理器
+//  3. 会话中记录的组织
+//  4. 用户所属的组织（属于默认组织时优先使用默认组织），未登录或不属于任何组织时使用默认组织
+//
+// 通过子域名或路径前缀指定的组织只在用户从本站页面或地址栏打开页面时记录到会话中（见 isTopLevelNavigation），
+// 其他网站嵌入的图片、脚本或跳转链接不会改变之后请求所在的组织；指定的组织不存在时返回 404，
+// 已登录用户不是该组织成员且没有管理组织的权限时返回 403
+//
+// 请求上下文中会放入用户缓存（见 session.WithUserCache），这里按组织加载的用户由后续的认证中间件和处理器直接复用
+func (m *TenantMiddleware) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/static/") || operationalPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		orgRepo, sessionHelper := m.getDependencies()
+		r = r.WithContext(session.WithUserCache(r.Context()))
+
+		slug := subdomainSlug(r.Host, config.GetConfig().TenantBaseDomain)
+		if pathSlug, rest, ok := splitTenantPath(r.URL.Path); ok {
+			slug = pathSlug
+			r.URL.Path = rest
+			r.URL.RawPath = ""
+		}
+
+		// 已登录用户（会话无效或用户不存在时按未登录处理，由认证中间件处理）
+		sess, _ := m.app.GetSessionManager().GetSession(r)
+		if sess != nil {
+			logger.SetRequestUser(r.Context(), sess.UserID())
+		}
+
+		var org *models.Organization
+		if slug != "" {
+			found, err := orgRepo.GetBySlug(slug)
+			if err != nil {
+				errors.HandleError(w, r, errors.NewInternalError(err))
+				return
+			}
+			if found == nil {
+				http.NotFound(w, r)
+				return
+			}
+			if sess != nil {
+				user, err := sessionHelper.LoadUser(r.Context(), sess.UserID(), found.ID)
+				if err != nil {
+					errors.HandleError(w, r, errors.NewInternalError(err))
+					return
+				}
+				if user != nil && !canEnter(user, found) {
+					errors.HandleError(w, r, errors.NewForbiddenError("您不是组织 "+found.Name+" 的成员"))
+					return
+				}
+				if isTopLevelNavigation(r) {
+					session.SetOrganization(sess, found.ID)
+				}
+			}
+			org = found
+		} else {
+			var err error
+			org, err = m.fallback(r.Context(), orgRepo, sessionHelper, sess)
+			if err != nil {
+				errors.HandleError(w, r, errors.NewInternalError(err))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.WithOrganization(r.Context(), org)))
+	})
+}
+
+// fallback 请求没有指定组织时，依次使用会话中记录的组织、用户所属的组织和默认组织
+// 会话中记录的组织已被删除或用户已被移出时不报错，直接使用后面的候选
+func (m *TenantMiddleware) fallback(ctx context.Context, orgRepo interfaces.OrganizationRepository, sessionHelper *session.Helper, sess *session.Session) (*models.Organization, error) {
+	if sess == nil {
+		return orgRepo.GetBySlug(models.DefaultOrganization)
+	}
+
+	if id, ok := session.OrganizationID(sess); ok {
+		org, err := orgRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if org != nil {
+			user, err := sessionHelper.LoadUser(ctx, sess.UserID(), org.ID)
+			if err != nil {
+				return nil, err
+			}
+			if user == nil || canEnter(user, org) {
+				return org, nil
+			}
+		}
+	}
+
+	// 只查询用户所属的组织，用户本身在确定组织后按该组织加载一次
+	orgs, err := orgRepo.GetForUser(sess.UserID())
+	if err != nil {
+		return nil, err
+	}
+	slug := models.DefaultOrganization
+	if len(orgs) > 0 && !containsOrg(orgs, models.DefaultOrganization) {
+		slug = orgs[0].Slug
+	}
+	return orgRepo.GetBySlug(slug)
+}
+
+// containsOrg 检查 orgs 中是否有标识为 slug 的组织
+func containsOrg(orgs []*models.Organization, slug string) bool {
+	for _, org := range orgs {
+		if org.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// canEnter 用户是该组织成员，或在该组织中拥有管理组织的权限（平台管理员）
+// user 须按目标组织加载：角色只在分配它的组织中生效，其他组织中的角色不起作用
+func canEnter(user *models.User, org *models.Organization) bool {
+	return user.InOrg(org.Slug) || rbac.Can(user, models.PermOrgsManage, nil)
+}
+
+// isTopLevelNavigation 判断请求是否为同源或用户直接发起的页面导航（GET）
+// 浏览器提供 Fetch Metadata 时要求 Sec-Fetch-Mode 为 navigate、Sec-Fetch-Site 为 same-origin 或 none（地址栏、书签）；
+// 不提供时退而要求 Referer 与请求同源，没有 Referer 时无法确认来源，按跨站处理
+func isTopLevelNavigation(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		if mode := r.Header.Get("Sec-Fetch-Mode"); mode != "" && mode != "navigate" {
+			return false
+		}
+		if dest := r.Header.Get("Sec-Fetch-Dest"); dest != "" && dest != "document" {
+			return false
+		}
+		return site == "same-origin" || site == "none"
+	}
+
+	referer, err := url.Parse(r.Referer())
+	if err != nil || referer.Host == "" {
+		return false
+	}
+	return strings.EqualFold(referer.Host, r.Host)
+}
+
+// subdomainSlug 从 Host 中取出 baseDomain 之前的一级子域名，不匹配时返回空串
+func subdomainSlug(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(host)
+	suffix := "." + strings.ToLower(baseDomain)
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	slug := strings.TrimSuffix(host, suffix)
+	if strings.Contains(slug, ".") || slug == "www" {
+		return ""
+	}
+	return slug
+}
+
+// splitTenantPath 拆分 /o/{标识}/… 形式的路径，返回组织标识和去掉前缀后的路径
+func splitTenantPath(path string) (slug, rest string, ok bool) {
+	if !strings.HasPrefix(path, tenantPathPrefix) {
+		return "", "", false
+	}
+	slug, rest, _ = strings.Cut(strings.TrimPrefix(path, tenantPathPrefix), "/")
+	if slug == "" {
+		return "", "", false
+	}
+	return slug, "/" + rest, true
+}
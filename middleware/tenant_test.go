@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"user-management-system/models"
+)
+
+func TestCanEnter(t *testing.T) {
+	acme := &models.Organization{ID: 2, Slug: "acme"}
+	other := &models.Organization{ID: 3, Slug: "other"}
+
+	tests := []struct {
+		name  string
+		user  *models.User // 按目标组织加载的用户
+		org   *models.Organization
+		allow bool
+	}{
+		// alice 是 default 和 acme 的成员，在 default 中是组织管理员（拥有 orgs:manage 以外的全部管理权限）
+		{"成员", &models.User{ID: 1, Status: models.StatusActive, Orgs: []string{models.DefaultOrganization, "acme"}}, acme, true},
+		{"其他组织的管理员不能进入", &models.User{ID: 1, Status: models.StatusActive, Orgs: []string{models.DefaultOrganization, "acme"}}, other, false},
+		// root 只属于 default，持有平台级的管理员角色，在任何组织中都拥有 orgs:manage
+		{"平台管理员", &models.User{ID: 2, Status: models.StatusActive, Orgs: []string{models.DefaultOrganization},
+			Permissions: []string{models.PermOrgsManage}}, other, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := canEnter(tt.user, tt.org); allowed != tt.allow {
+				t.Errorf("canEnter = %v，期望 %v", allowed, tt.allow)
+			}
+		})
+	}
+}
+
+func TestIsTopLevelNavigation(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		remember bool
+	}{
+		{"站内链接", http.MethodGet, map[string]string{"Sec-Fetch-Site": "same-origin", "Sec-Fetch-Mode": "navigate", "Sec-Fetch-Dest": "document"}, true},
+		{"地址栏或书签", http.MethodGet, map[string]string{"Sec-Fetch-Site": "none", "Sec-Fetch-Mode": "navigate", "Sec-Fetch-Dest": "document"}, true},
+		{"其他网站的链接", http.MethodGet, map[string]string{"Sec-Fetch-Site": "cross-site", "Sec-Fetch-Mode": "navigate", "Sec-Fetch-Dest": "document"}, false},
+		{"其他网站嵌入的图片", http.MethodGet, map[string]string{"Sec-Fetch-Site": "cross-site", "Sec-Fetch-Mode": "no-cors", "Sec-Fetch-Dest": "image"}, false},
+		{"站内 iframe", http.MethodGet, map[string]string{"Sec-Fetch-Site": "same-origin", "Sec-Fetch-Mode": "navigate", "Sec-Fetch-Dest": "iframe"}, false},
+		{"站内脚本请求", http.MethodGet, map[string]string{"Sec-Fetch-Site": "same-origin", "Sec-Fetch-Mode": "cors", "Sec-Fetch-Dest": "empty"}, false},
+		{"表单提交", http.MethodPost, map[string]string{"Sec-Fetch-Site": "same-origin", "Sec-Fetch-Mode": "navigate", "Sec-Fetch-Dest": "document"}, false},
+		{"旧浏览器同源 Referer", http.MethodGet, map[string]string{"Referer": "https://userhub.example.com/users"}, true},
+		{"旧浏览器跨站 Referer", http.MethodGet, map[string]string{"Referer": "https://evil.example.net/"}, false},
+		{"旧浏览器没有 Referer", http.MethodGet, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "https://userhub.example.com/o/acme/users", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := isTopLevelNavigation(r); got != tt.remember {
+				t.Errorf("isTopLevelNavigation = %v，期望 %v", got, tt.remember)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"user-management-system/logger"
+	"user-management-system/tracing"
+)
+
+// Trace 为每个请求记录一个 server span：沿用请求头 traceparent 中的上游追踪，
+// span 名称为 "方法 路由"，记录状态码，5xx 响应标记为错误；追踪 ID 同时写入请求日志
+// 路由取自请求日志信息，需要放在 RequestID 之后；未启用追踪时直接交给后续处理器
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.Start(ctx, r.Method, tracing.KindServer,
+			tracing.Attr{Key: "http.request.method", Value: r.Method},
+			tracing.Attr{Key: "url.path", Value: r.URL.Path},
+			tracing.Attr{Key: "client.address", Value: clientIP(r)},
+			tracing.Attr{Key: "user_agent.original", Value: r.UserAgent()},
+			tracing.Attr{Key: "http.request_id", Value: logger.RequestID(r.Context())},
+		)
+		logger.SetRequestTrace(ctx, span.SpanContext().TraceID.String())
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.Status()
+		if req := logger.RequestFromContext(ctx); req != nil {
+			if route := req.Route(); route != "" {
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(tracing.Attr{Key: "http.route", Value: route})
+			}
+			if userID := req.UserID(); userID != 0 {
+				span.SetAttributes(tracing.Attr{Key: "enduser.id", Value: userID})
+			}
+		}
+		span.SetAttributes(tracing.Attr{Key: "http.response.status_code", Value: status})
+		if status >= 500 {
+			span.SetError(httpError(status))
+		}
+		span.End()
+	})
+}
+
+// httpError 以状态码作为 span 的错误信息
+type httpError int
+
+func (e httpError) Error() string {
+	return http.StatusText(int(e))
+}
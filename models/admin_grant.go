@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// DelegablePermissions 可以通过委派授予的用户管理操作，委派不能用于分配角色
+var DelegablePermissions = []string{
+	PermUsersUpdate,
+	PermUsersStatus,
+	PermUsersResetPassword,
+	PermUsersDelete,
+	PermUsersImpersonate,
+}
+
+// IsDelegablePermission 检查权限是否可以委派
+func IsDelegablePermission(name string) bool {
+	for _, p := range DelegablePermissions {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminGrant 委派管理授权：持有 Role 的用户可以对范围内的用户执行 Permissions 中的操作
+// 范围为持有 ScopeRole 的用户，或 ScopeGroupID 及其下级组的成员（二者取其一），且只限授权所在的组织
+type AdminGrant struct {
+	ID           int       `json:"id"`
+	Role         string    `json:"role"`                     // 获得授权的角色
+	Permissions  []string  `json:"permissions"`              // 委派的操作
+	ScopeRole    string    `json:"scope_role,omitempty"`     // 范围：持有该角色的用户
+	ScopeGroupID int       `json:"scope_group_id,omitempty"` // 范围：该组及其下级组的成员
+	ScopeGroup   string    `json:"scope_group,omitempty"`    // 范围组的组名
+	CreatedBy    string    `json:"created_by"`               // 创建授权的管理员（已删除时为空）
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Allows 检查授权是否包含指定操作
+func (g *AdminGrant) Allows(permission string) bool {
+	for _, p := range g.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 审计事件的操作类型，格式为 对象.操作
+const (
+	AuditLogin                = "auth.login"
+	AuditRegister             = "auth.register"
+	AuditUserCreate           = "user.create"
+	AuditUserUpdate           = "user.update"
+	AuditUserDelete           = "user.delete"
+	AuditUserStatus           = "user.status"
+	AuditUserResetPassword    = "user.reset_password"
+	AuditUserRestore          = "user.restore"
+	AuditUserPurge            = "user.purge"
+	AuditUserApprove          = "user.approve"
+	AuditUserReject           = "user.reject"
+	AuditUserImpersonate      = "user.impersonate"
+	AuditUserImpersonateStop  = "user.impersonate_stop"
+	AuditPasswordChange       = "password.change"
+	AuditPasswordSetup        = "password.setup"
+	AuditEmailChangeRequest   = "email.change_request"
+	AuditEmailChangeConfirm   = "email.change_confirm"
+	AuditInvitationCreate     = "invitation.create"
+	AuditInvitationResend     = "invitation.resend"
+	AuditInvitationRevoke     = "invitation.revoke"
+	AuditRoleCreate           = "role.create"
+	AuditRoleUpdate           = "role.update"
+	AuditRoleDelete           = "role.delete"
+	AuditGroupCreate          = "group.create"
+	AuditGroupUpdate          = "group.update"
+	AuditGroupDelete          = "group.delete"
+	AuditGroupAddMembers      = "group.add_members"
+	AuditGroupRemoveMember    = "group.remove_member"
+	AuditOrgCreate            = "org.create"
+	AuditOrgUpdate            = "org.update"
+	AuditOrgDelete            = "org.delete"
+	AuditOrgAddMember         = "org.add_member"
+	AuditOrgRemoveMember      = "org.remove_member"
+	AuditGrantCreate          = "grant.create"
+	AuditGrantDelete          = "grant.delete"
+	AuditChangeRequestApprove = "change_request.approve"
+	AuditChangeRequestReject  = "change_request.reject"
+)
+
+// AuditAction 审计操作类型的定义
+type AuditAction struct {
+	Name  string
+	Label string
+}
+
+// AuditActions 全部审计操作类型，按展示顺序排列
+var AuditActions = []AuditAction{
+	{AuditLogin, "登录"},
+	{AuditRegister, "注册"},
+	{AuditUserCreate, "创建用户"},
+	{AuditUserUpdate, "更新用户"},
+	{AuditUserDelete, "删除用户"},
+	{AuditUserStatus, "修改账户状态"},
+	{AuditUserResetPassword, "重置密码"},
+	{AuditUserRestore, "恢复用户"},
+	{AuditUserPurge, "永久删除用户"},
+	{AuditUserApprove, "通过注册申请"},
+	{AuditUserReject, "拒绝注册申请"},
+	{AuditUserImpersonate, "开始模拟登录"},
+	{AuditUserImpersonateStop, "结束模拟登录"},
+	{AuditPasswordChange, "修改密码"},
+	{AuditPasswordSetup, "通过链接设置密码"},
+	{AuditEmailChangeRequest, "申请修改邮箱"},
+	{AuditEmailChangeConfirm, "确认修改邮箱"},
+	{AuditInvitationCreate, "发出邀请"},
+	{AuditInvitationResend, "重新发送邀请"},
+	{AuditInvitationRevoke, "撤销邀请"},
+	{AuditRoleCreate, "创建角色"},
+	{AuditRoleUpdate, "修改角色"},
+	{AuditRoleDelete, "删除角色"},
+	{AuditGroupCreate, "创建用户组"},
+	{AuditGroupUpdate, "修改用户组"},
+	{AuditGroupDelete, "删除用户组"},
+	{AuditGroupAddMembers, "添加组成员"},
+	{AuditGroupRemoveMember, "移除组成员"},
+	{AuditOrgCreate, "创建组织"},
+	{AuditOrgUpdate, "修改组织"},
+	{AuditOrgDelete, "删除组织"},
+	{AuditOrgAddMember, "添加组织成员"},
+	{AuditOrgRemoveMember, "移除组织成员"},
+	{AuditGrantCreate, "创建委派授权"},
+	{AuditGrantDelete, "删除委派授权"},
+	{AuditChangeRequestApprove, "批准变更申请"},
+	{AuditChangeRequestReject, "拒绝变更申请"},
+}
+
+// AuditActionLabel 返回审计操作类型的中文名称
+func AuditActionLabel(name string) string {
+	for _, a := range AuditActions {
+		if a.Name == name {
+			return a.Label
+		}
+	}
+	return name
+}
+
+// 审计事件的操作对象类型
+const (
+	AuditTargetUser          = "user"
+	AuditTargetInvitation    = "invitation"
+	AuditTargetRole          = "role"
+	AuditTargetGroup         = "group"
+	AuditTargetOrganization  = "organization"
+	AuditTargetGrant         = "grant"
+	AuditTargetChangeRequest = "change_request"
+)
+
+// 审计事件的结果
+const (
+	AuditSuccess = "success" // 操作成功
+	AuditFailure = "failure" // 操作失败，Error 中记录原因
+	AuditPending = "pending" // 操作需要审批，已提交变更申请但尚未生效
+)
+
+// auditOutcomeLabels 审计事件结果的中文名称
+var auditOutcomeLabels = map[string]string{
+	AuditSuccess: "成功",
+	AuditFailure: "失败",
+	AuditPending: "待审批",
+}
+
+// AuditEvent 表示一条审计事件, 映射数据库中的audit_events表
+// 由服务层在每次修改数据和登录时写入，写入后不再修改
+// 全部事件按 Seq 组成哈希链：每条事件的 Hash 覆盖自身内容和上一条事件的 Hash，修改或删除任何一条都会使链条断开
+type AuditEvent struct {
+	ID               int64           `json:"id"`
+	Seq              int64           `json:"seq"`                       // 在哈希链中的序号，从1开始连续递增
+	OrgID            int             `json:"organization_id"`           // 事件发生时请求所在的组织
+	OccurredAt       time.Time       `json:"occurred_at"`               // 发生时间
+	ActorID          int             `json:"actor_id,omitempty"`        // 操作者 ID（未登录或系统任务时为 0）
+	ActorName        string          `json:"actor"`                     // 操作者用户名（登录失败时为尝试的用户名，系统任务时为 system）
+	ImpersonatorID   int             `json:"impersonator_id,omitempty"` // 模拟登录时实际操作的管理员 ID
+	ImpersonatorName string          `json:"impersonator,omitempty"`    // 模拟登录时实际操作的管理员用户名
+	Action           string          `json:"action"`                    // 见 Audit* 操作常量
+	TargetType       string          `json:"target_type,omitempty"`     // 见 AuditTarget* 常量
+	TargetID         int             `json:"target_id,omitempty"`       // 操作对象 ID
+	TargetName       string          `json:"target_name,omitempty"`     // 操作对象名称（用户名、角色标识等）
+	Before           json.RawMessage `json:"before,omitempty"`          // 操作前的值（JSON）
+	After            json.RawMessage `json:"after,omitempty"`           // 操作后的值或提交的内容（JSON）
+	IP               string          `json:"ip,omitempty"`              // 客户端 IP
+	UserAgent        string          `json:"user_agent,omitempty"`      // 客户端 User-Agent
+	RequestID        string          `json:"request_id,omitempty"`      // 请求 ID
+	Outcome          string          `json:"outcome"`                   // 见 Audit* 结果常量
+	Error            string          `json:"error,omitempty"`           // 失败原因
+	PrevHash         string          `json:"prev_hash"`                 // 上一条事件的哈希（第一条事件为全0）
+	Hash             string          `json:"hash"`                      // 本条事件的哈希（SHA-256，十六进制）
+}
+
+// ActionLabel 返回操作类型的中文名称
+func (e *AuditEvent) ActionLabel() string {
+	return AuditActionLabel(e.Action)
+}
+
+// OutcomeLabel 返回结果的中文名称
+func (e *AuditEvent) OutcomeLabel() string {
+	return auditOutcomeLabels[e.Outcome]
+}
+
+// AuditFilter 查询审计事件的条件，零值字段不作限制
+type AuditFilter struct {
+	Actor      string    `json:"actor"`       // 操作者或模拟登录的管理员用户名
+	Action     string    `json:"action"`      // 操作类型
+	TargetType string    `json:"target_type"` // 操作对象类型
+	TargetID   int       `json:"target_id"`   // 操作对象 ID（与 TargetType 一起使用）
+	Outcome    string    `json:"outcome"`     // 结果
+	From       time.Time `json:"from"`        // 起始时间（含）
+	To         time.Time `json:"to"`          // 截止时间（不含）
+}
+
+// AuditChainHead 哈希链的末端，映射数据库中的audit_chain表
+// 每次写入事件时在同一事务中更新，用于串行化写入以及发现末尾事件被删除
+type AuditChainHead struct {
+	Seq     int64  // 最后一条事件的序号，没有事件时为 0
+	EventID int64  // 最后一条事件的 ID
+	Hash    string // 最后一条事件的哈希
+}
+
+// AuditCheckpoint 表示哈希链的签名检查点, 映射数据库中的audit_checkpoints表
+// 用本地密钥对某一序号处的事件哈希签名，即使数据库被整体改写，检查点之前的事件也无法在不被发现的情况下修改
+type AuditCheckpoint struct {
+	ID        int64     `json:"id"`
+	Seq       int64     `json:"seq"`        // 签名时哈希链末端事件的序号
+	EventID   int64     `json:"event_id"`   // 签名时哈希链末端事件的 ID
+	EventHash string    `json:"event_hash"` // 签名时哈希链末端事件的哈希
+	KeyID     string    `json:"key_id"`     // 签名公钥的指纹
+	Signature string    `json:"signature"`  // Ed25519 签名（base64）
+	CreatedAt time.Time `json:"created_at"` // 签名时间
+}
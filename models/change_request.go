@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// 需要第二位管理员审批的敏感操作（见 config.ChangeApprovalActions）
+const (
+	ChangeActionPromoteAdmin = "promote_admin" // 修改角色使用户获得分配角色的权限
+	ChangeActionDeleteAdmin  = "delete_admin"  // 删除拥有分配角色权限的用户
+)
+
+// changeActionLabels 敏感操作的中文名称
+var changeActionLabels = map[string]string{
+	ChangeActionPromoteAdmin: "提升为管理员",
+	ChangeActionDeleteAdmin:  "删除管理员",
+}
+
+// 变更申请状态（由时间字段推导，不单独存储）
+const (
+	ChangeRequestPending  = "pending"
+	ChangeRequestApproved = "approved"
+	ChangeRequestRejected = "rejected"
+	ChangeRequestExpired  = "expired"
+)
+
+// changeRequestStatusLabels 变更申请状态的中文名称
+var changeRequestStatusLabels = map[string]string{
+	ChangeRequestPending:  "待审批",
+	ChangeRequestApproved: "已批准",
+	ChangeRequestRejected: "已拒绝",
+	ChangeRequestExpired:  "已过期",
+}
+
+// ChangeRequest 表示一项等待第二位管理员审批的敏感变更, 映射数据库中的change_requests表
+// 申请人不能批准自己的申请；批准时变更才会生效，超过有效期未处理的申请自动失效
+type ChangeRequest struct {
+	ID            int        `json:"id"`
+	Action        string     `json:"action"`          // 见 ChangeAction* 常量
+	TargetID      int        `json:"target_id"`       // 被操作的用户 ID
+	TargetName    string     `json:"target_username"` // 被操作的用户名（查询时关联得到）
+	Email         string     `json:"email,omitempty"` // promote_admin：修改后的邮箱
+	Roles         []string   `json:"roles,omitempty"` // promote_admin：修改后的角色
+	OrgID         int        `json:"organization_id"` // 申请所属的组织
+	RequestedBy   int        `json:"requested_by"`    // 申请人 ID（已删除时为 0）
+	RequesterName string     `json:"requester"`       // 申请人用户名（查询时关联得到）
+	DecidedBy     int        `json:"decided_by"`      // 批准或拒绝的管理员 ID（未处理或已删除时为 0）
+	DeciderName   string     `json:"decider"`         // 批准或拒绝的管理员用户名（查询时关联得到）
+	CreatedAt     time.Time  `json:"created_at"`      // 申请时间
+	ExpiresAt     time.Time  `json:"expires_at"`      // 过期时间
+	ApprovedAt    *time.Time `json:"approved_at"`     // 批准（变更生效）的时间
+	RejectedAt    *time.Time `json:"rejected_at"`     // 拒绝或撤回的时间
+}
+
+// Status 返回申请当前的状态
+func (c *ChangeRequest) Status() string {
+	switch {
+	case c.ApprovedAt != nil:
+		return ChangeRequestApproved
+	case c.RejectedAt != nil:
+		return ChangeRequestRejected
+	case time.Now().After(c.ExpiresAt):
+		return ChangeRequestExpired
+	default:
+		return ChangeRequestPending
+	}
+}
+
+// StatusLabel 返回申请状态的中文名称
+func (c *ChangeRequest) StatusLabel() string {
+	return changeRequestStatusLabels[c.Status()]
+}
+
+// ActionLabel 返回申请操作的中文名称
+func (c *ChangeRequest) ActionLabel() string {
+	return changeActionLabels[c.Action]
+}
+
+// IsPending 检查申请是否仍在等待审批
+func (c *ChangeRequest) IsPending() bool {
+	return c.Status() == ChangeRequestPending
+}
+
+// IsChangeAction 检查是否为可以要求审批的操作
+func IsChangeAction(action string) bool {
+	_, ok := changeActionLabels[action]
+	return ok
+}
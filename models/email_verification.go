@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// EmailVerification 表示一次待确认的邮箱变更, 映射数据库中的email_verifications表
+type EmailVerification struct {
+	ID        int       // 记录 ID
+	UserID    int       // 发起变更的用户 ID
+	Email     string    // 待验证的新邮箱
+	TokenHash string    // 验证令牌的 SHA-256 哈希（令牌明文只出现在邮件中）
+	CreatedAt time.Time // 创建时间
+	ExpiresAt time.Time // 过期时间
+}
+
+// IsExpired 检查验证链接是否已过期
+func (v *EmailVerification) IsExpired() bool {
+	return time.Now().After(v.ExpiresAt)
+}
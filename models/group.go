@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Group 表示用户组模型，映射数据库中的user_groups表
+// 组的成员继承组的角色；组可以嵌套，下级组的成员同时继承所有上级组的角色
+type Group struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`                // 组名，如 研发部
+	Description string    `json:"description"`         // 组说明
+	ParentID    *int      `json:"parent_id,omitempty"` // 上级组（顶级组为空）
+	Roles       []string  `json:"roles"`               // 授予组成员的角色
+	MemberCount int       `json:"member_count"`        // 直接成员数
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// HasRole 检查组是否直接被授予指定角色
+func (g *Group) HasRole(role string) bool {
+	for _, r := range g.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsChildOf 检查组的上级组是否为 parentID
+func (g *Group) IsChildOf(parentID int) bool {
+	return g.ParentID != nil && *g.ParentID == parentID
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// 注册方式（见 config.RegistrationMode）
+const (
+	RegistrationOpen     = "open"     // 开放注册
+	RegistrationInvite   = "invite"   // 只能通过管理员发出的邀请注册
+	RegistrationClosed   = "closed"   // 关闭注册
+	RegistrationApproval = "approval" // 开放注册，但需要管理员审批后才能登录
+)
+
+// 邀请状态（由时间字段推导，不单独存储）
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// invitationStatusLabels 邀请状态的中文名称
+var invitationStatusLabels = map[string]string{
+	InvitationPending:  "待接受",
+	InvitationAccepted: "已接受",
+	InvitationRevoked:  "已撤销",
+	InvitationExpired:  "已过期",
+}
+
+// Invitation 表示一份注册邀请, 映射数据库中的invitations表
+type Invitation struct {
+	ID             int        // 邀请 ID
+	Email          string     // 被邀请人邮箱，注册时使用该邮箱
+	Role           string     // 注册后获得的角色
+	OrgID          int        // 被邀请人注册后加入的组织
+	TokenHash      string     // 邀请令牌的 SHA-256 哈希（令牌明文只出现在邮件中）
+	InvitedBy      int        // 发出邀请的管理员 ID
+	InviterName    string     // 发出邀请的管理员用户名（查询时关联得到）
+	CreatedAt      time.Time  // 创建时间
+	SentAt         time.Time  // 最近一次发送邮件的时间
+	ExpiresAt      time.Time  // 过期时间
+	AcceptedAt     *time.Time // 接受邀请（完成注册）的时间
+	AcceptedUserID *int       // 通过该邀请注册的用户 ID
+	RevokedAt      *time.Time // 撤销时间
+}
+
+// Status 返回邀请当前的状态
+func (i *Invitation) Status() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case time.Now().After(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+// StatusLabel 返回邀请状态的中文名称
+func (i *Invitation) StatusLabel() string {
+	return invitationStatusLabels[i.Status()]
+}
+
+// IsUsable 检查邀请是否仍可用于注册
+func (i *Invitation) IsUsable() bool {
+	return i.Status() == InvitationPending
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DefaultOrganization 默认组织的标识，升级前已有的用户、用户组和邀请都归入该组织
+const DefaultOrganization = "default"
+
+// Organization 表示组织（租户）模型，映射数据库中的organizations表
+// 用户可以属于多个组织；组织管理员只能看到和管理本组织的用户
+type Organization struct {
+	ID          int       `json:"id"`
+	Slug        string    `json:"slug"`         // 组织标识，用于子域名和路径前缀，如 acme
+	Name        string    `json:"name"`         // 组织名称
+	MemberCount int       `json:"member_count"` // 成员数（不含回收站中的用户）
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IsDefault 检查是否为默认组织
+func (o *Organization) IsDefault() bool {
+	return o.Slug == DefaultOrganization
+}
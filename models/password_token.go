@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// 设置密码链接的用途
+const (
+	PasswordTokenInvite = "invite" // 管理员创建用户后发送的邀请链接
+	PasswordTokenReset  = "reset"  // 管理员强制重置密码后发送的重置链接
+)
+
+// PasswordToken 表示一个通过邮件发送的设置密码链接, 映射数据库中的password_tokens表
+type PasswordToken struct {
+	ID        int       // 记录 ID
+	UserID    int       // 需要设置密码的用户 ID
+	Purpose   string    // 用途（invite/reset）
+	TokenHash string    // 令牌的 SHA-256 哈希（令牌明文只出现在邮件中）
+	CreatedAt time.Time // 创建时间
+	ExpiresAt time.Time // 过期时间
+}
+
+// IsExpired 检查链接是否已过期
+func (t *PasswordToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -0,0 +1,65 @@
+package models
+
+// 权限字符串，格式为 资源:操作
+const (
+	PermUsersView          = "users:view"           // 查看用户列表
+	PermUsersCreate        = "users:create"         // 创建用户
+	PermUsersUpdate        = "users:update"         // 修改用户信息
+	PermUsersDelete        = "users:delete"         // 删除用户
+	PermUsersRestore       = "users:restore"        // 查看回收站、恢复用户
+	PermUsersStatus        = "users:status"         // 启用、停用、锁定账户
+	PermUsersResetPassword = "users:reset_password" // 强制重置用户密码
+	PermUsersImpersonate   = "users:impersonate"    // 以其他用户的身份登录（模拟登录）
+	PermUsersApprove       = "users:approve"        // 审批注册申请
+	PermUsersManage        = "users:manage"         // 为用户分配角色，拥有该权限的用户视为管理员
+	PermInvitationsManage  = "invitations:manage"   // 发出、重新发送、撤销注册邀请
+	PermRolesManage        = "roles:manage"         // 创建、修改、删除角色
+	PermGroupsManage       = "groups:manage"        // 创建、修改、删除用户组，管理组成员
+	PermOrgsManage         = "orgs:manage"          // 管理组织及其成员，并可进入任何组织（超级管理员）
+	PermAuditView          = "audit:view"           // 查看、导出所在组织的审计日志
+)
+
+// Permission 权限定义
+type Permission struct {
+	Name  string
+	Label string
+}
+
+// Permissions 系统支持的全部权限，按展示顺序排列
+var Permissions = []Permission{
+	{PermUsersView, "查看用户列表"},
+	{PermUsersCreate, "创建用户"},
+	{PermUsersUpdate, "修改用户信息"},
+	{PermUsersDelete, "删除用户"},
+	{PermUsersRestore, "管理回收站"},
+	{PermUsersStatus, "修改账户状态"},
+	{PermUsersResetPassword, "重置用户密码"},
+	{PermUsersImpersonate, "模拟登录用户"},
+	{PermUsersApprove, "审批注册申请"},
+	{PermUsersManage, "分配角色"},
+	{PermInvitationsManage, "管理注册邀请"},
+	{PermRolesManage, "管理角色"},
+	{PermGroupsManage, "管理用户组"},
+	{PermOrgsManage, "管理组织"},
+	{PermAuditView, "查看审计日志"},
+}
+
+// IsValidPermission 检查是否为系统支持的权限
+func IsValidPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionLabel 返回权限的中文名称
+func PermissionLabel(name string) string {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return p.Label
+		}
+	}
+	return name
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// 内置角色
+const (
+	RoleAdmin    = "admin"     // 管理员，始终拥有全部权限
+	RoleOrgAdmin = "org_admin" // 组织管理员，只能管理所在组织的用户
+	RoleUser     = "user"      // 普通用户，新注册用户的默认角色
+)
+
+// roleLabels 内置角色的中文名称
+var roleLabels = map[string]string{
+	RoleAdmin:    "管理员",
+	RoleOrgAdmin: "组织管理员",
+	RoleUser:     "普通用户",
+}
+
+// Role 表示角色模型，映射数据库中的roles表
+type Role struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`        // 角色标识，如 admin
+	Description string    `json:"description"` // 角色说明
+	IsSystem    bool      `json:"is_system"`   // 内置角色不能删除或改名
+	Permissions []string  `json:"permissions"` // 角色拥有的权限
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// HasPermission 检查角色是否拥有指定权限
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Label 返回角色的展示名称
+func (r *Role) Label() string {
+	return RoleLabel(r.Name)
+}
+
+// RoleLabel 返回角色的展示名称，内置角色使用中文名称，自定义角色使用角色标识
+func RoleLabel(name string) string {
+	if label, ok := roleLabels[name]; ok {
+		return label
+	}
+	return name
+}
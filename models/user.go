@@ -1,39 +1,111 @@
 package models
 
-import (
-	"golang.org/x/crypto/bcrypt"
-	"time"
-)
+import "time"
 
 // User 表示用户模型, 映射数据库中的users表
 type User struct {
-	ID        int       `json:"id"`         // 用户 ID
-	Username  string    `json:"username"`   // 用户名
-	Password  string    `json:"-"`          // 密码（JSON序列化时忽略）
-	Email     string    `json:"email"`      // 邮箱
-	Role      string    `json:"role"`       // 角色（user/admin）
-	CreatedAt time.Time `json:"created_at"` // 创建时间
+	ID          int        `json:"id"`                      // 用户 ID
+	Username    string     `json:"username"`                // 用户名
+	Password    string     `json:"-"`                       // 密码哈希，包含算法和参数（JSON序列化时忽略）
+	Email       string     `json:"email"`                   // 邮箱
+	Roles       []string   `json:"roles"`                   // 直接持有的角色
+	Groups      []string   `json:"groups"`                  // 直接加入的用户组
+	Orgs        []string   `json:"organizations"`           // 所属组织的标识
+	Permissions []string   `json:"permissions"`             // 由直接持有和通过用户组继承的角色汇总得到的有效权限
+	Delegated   []string   `json:"delegated_permissions"`   // 通过委派授权对部分用户拥有的操作（见 AdminGrant）
+	Status      string     `json:"status"`                  // 账户状态（见 user_status.go）
+	CreatedAt   time.Time  `json:"created_at"`              // 创建时间
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"` // 最近登录时间（从未登录时为空）
+
+	PasswordChangedAt  *time.Time `json:"password_changed_at,omitempty"` // 最近修改密码时间（为空时以创建时间为准）
+	MustChangePassword bool       `json:"must_change_password"`          // 下次登录时必须修改密码（管理员设置了临时密码）
+
+	StatusReason    string     `json:"status_reason,omitempty"`     // 最近一次状态变更的原因
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"` // 最近一次状态变更时间
+
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // 移入回收站的时间（未删除时为空）
+
+	// CurrentOrg 当前请求所在的组织，由会话助手根据请求填充，不对应数据库列
+	CurrentOrg *Organization `json:"-"`
+
+	// Impersonator 正在以该用户身份登录的管理员（模拟登录），由会话助手填充，不对应数据库列
+	Impersonator *User `json:"-"`
+
+	// CSRFToken 当前会话的 CSRF 令牌，由会话助手在模拟登录时填充，供布局中结束模拟登录的表单使用
+	CSRFToken string `json:"-"`
 }
 
-// CheckPassword
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+// InOrg 检查用户是否属于标识为 slug 的组织
+func (u *User) InOrg(slug string) bool {
+	for _, o := range u.Orgs {
+		if o == slug {
+			return true
+		}
+	}
+	return false
 }
 
-// IsAdmin 检查用户是否为管理员
+// IsAdmin 检查用户是否为管理员，即拥有分配角色的权限
 func (u *User) IsAdmin() bool {
-	return u.Role == "admin"
+	return u.HasPermission(PermUsersManage)
 }
 
-// SetPassword 设置用户密码（自动进行哈希）
-func (u *User) SetPassword(password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
+// IsImpersonated 检查当前会话是否为管理员的模拟登录
+func (u *User) IsImpersonated() bool {
+	return u.Impersonator != nil
+}
+
+// HasRole 检查用户是否持有指定角色
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
 	}
-	u.Password = string(hashedPassword)
-	return nil
+	return false
+}
+
+// HasPermission 检查用户的角色是否授予了指定权限，不考虑资源归属（见 rbac.Can）
+func (u *User) HasPermission(permission string) bool {
+	for _, p := range u.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDelegated 检查用户是否通过委派授权对至少一部分用户拥有指定操作
+func (u *User) HasDelegated(permission string) bool {
+	for _, p := range u.Delegated {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive 检查账户是否处于可登录的正常状态
+func (u *User) IsActive() bool {
+	return u.Status == StatusActive
+}
+
+// IsDeleted 检查用户是否已被删除（位于回收站）
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+// StatusLabel 返回账户状态的中文名称
+func (u *User) StatusLabel() string {
+	return StatusLabel(u.Status)
+}
+
+// PasswordSetAt 返回当前密码的设置时间
+func (u *User) PasswordSetAt() time.Time {
+	if u.PasswordChangedAt != nil {
+		return *u.PasswordChangedAt
+	}
+	return u.CreatedAt
 }
 
 // Validate 验证用户数据
@@ -41,3 +113,8 @@ func (u *User) Validate() error {
 	// 这里可以添加更多的验证逻辑
 	return nil
 }
+
+// OwnerID 用户资源归属于用户本人（见 rbac.Owned）
+func (u *User) OwnerID() int {
+	return u.ID
+}
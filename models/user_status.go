@@ -0,0 +1,52 @@
+package models
+
+// 账户状态
+const (
+	StatusPending  = "pending"  // 等待管理员审批
+	StatusActive   = "active"   // 正常
+	StatusDisabled = "disabled" // 被管理员停用
+	StatusLocked   = "locked"   // 被锁定（如安全原因）
+	StatusDeleted  = "deleted"  // 已删除
+)
+
+// statusTransitions 允许的状态转换
+var statusTransitions = map[string][]string{
+	StatusPending:  {StatusActive, StatusDisabled, StatusDeleted},
+	StatusActive:   {StatusDisabled, StatusLocked, StatusDeleted},
+	StatusDisabled: {StatusActive, StatusDeleted},
+	StatusLocked:   {StatusActive, StatusDisabled, StatusDeleted},
+	StatusDeleted:  {StatusActive},
+}
+
+// statusLabels 状态的中文名称
+var statusLabels = map[string]string{
+	StatusPending:  "待审批",
+	StatusActive:   "正常",
+	StatusDisabled: "已停用",
+	StatusLocked:   "已锁定",
+	StatusDeleted:  "已删除",
+}
+
+// IsValidStatus 检查是否为已知的账户状态
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// CanTransition 检查账户状态能否从 from 转换到 to
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusLabel 返回状态的中文名称
+func StatusLabel(status string) string {
+	if label, ok := statusLabels[status]; ok {
+		return label
+	}
+	return status
+}
@@ -0,0 +1,170 @@
+package password
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomMagic 布隆过滤器文件头
+var bloomMagic = [4]byte{'U', 'M', 'B', 'F'}
+
+// bloomVersion 文件格式版本
+const bloomVersion = 1
+
+// BloomFilter 以 SHA-1 摘要为元素的布隆过滤器
+//
+// 文件格式（小端序）：
+//
+//	magic "UMBF" | version uint8 | k uint8 | 保留 2 字节 | m uint64（位数） | n uint64（元素数） | 位数组 []uint64
+type BloomFilter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    uint8  // 哈希函数个数
+	n    uint64 // 已加入的元素数
+}
+
+// NewBloomFilter 按预计元素数和期望误判率创建布隆过滤器
+func NewBloomFilter(expected uint64, falsePositiveRate float64) *BloomFilter {
+	if expected == 0 {
+		expected = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	m := uint64(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(expected) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > 255 {
+		k = 255
+	}
+	return &BloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    uint8(k),
+	}
+}
+
+// locations 使用双重哈希计算元素对应的位
+// SHA-1 摘要本身已均匀分布，直接取前16字节作为两个基础哈希
+func (f *BloomFilter) locations(digest [20]byte, fn func(uint64) bool) bool {
+	h1 := binary.LittleEndian.Uint64(digest[0:8])
+	h2 := binary.LittleEndian.Uint64(digest[8:16]) | 1
+	for i := uint64(0); i < uint64(f.k); i++ {
+		if !fn((h1 + i*h2) % f.m) {
+			return false
+		}
+	}
+	return true
+}
+
+// Add 加入一个 SHA-1 摘要
+func (f *BloomFilter) Add(digest [20]byte) {
+	f.locations(digest, func(bit uint64) bool {
+		f.bits[bit/64] |= 1 << (bit % 64)
+		return true
+	})
+	f.n++
+}
+
+// Test 判断摘要是否可能存在（false 表示一定不存在）
+func (f *BloomFilter) Test(digest [20]byte) bool {
+	return f.locations(digest, func(bit uint64) bool {
+		return f.bits[bit/64]&(1<<(bit%64)) != 0
+	})
+}
+
+// Count 已加入的元素数
+func (f *BloomFilter) Count() uint64 {
+	return f.n
+}
+
+// SizeBytes 位数组占用的字节数
+func (f *BloomFilter) SizeBytes() int {
+	return len(f.bits) * 8
+}
+
+// HashFunctions 哈希函数个数
+func (f *BloomFilter) HashFunctions() int {
+	return int(f.k)
+}
+
+// WriteTo 写出布隆过滤器
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 24)
+	copy(header[0:4], bloomMagic[:])
+	header[4] = bloomVersion
+	header[5] = f.k
+	binary.LittleEndian.PutUint64(header[8:16], f.m)
+	binary.LittleEndian.PutUint64(header[16:24], f.n)
+	if _, err := bw.Write(header); err != nil {
+		return 0, err
+	}
+
+	word := make([]byte, 8)
+	for _, b := range f.bits {
+		binary.LittleEndian.PutUint64(word, b)
+		if _, err := bw.Write(word); err != nil {
+			return 0, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return int64(len(header) + len(f.bits)*8), nil
+}
+
+// ReadBloomFilter 读取布隆过滤器
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("读取文件头失败: %w", err)
+	}
+	if [4]byte(header[0:4]) != bloomMagic {
+		return nil, errors.New("不是布隆过滤器文件")
+	}
+	if header[4] != bloomVersion {
+		return nil, fmt.Errorf("不支持的布隆过滤器版本: %d", header[4])
+	}
+
+	f := &BloomFilter{
+		k: header[5],
+		m: binary.LittleEndian.Uint64(header[8:16]),
+		n: binary.LittleEndian.Uint64(header[16:24]),
+	}
+	if f.k == 0 || f.m == 0 {
+		return nil, errors.New("布隆过滤器参数无效")
+	}
+
+	f.bits = make([]uint64, (f.m+63)/64)
+	word := make([]byte, 8)
+	for i := range f.bits {
+		if _, err := io.ReadFull(br, word); err != nil {
+			return nil, fmt.Errorf("读取位数组失败: %w", err)
+		}
+		f.bits[i] = binary.LittleEndian.Uint64(word)
+	}
+	return f, nil
+}
+
+// LoadBloomFilter 从文件加载布隆过滤器
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBloomFilter(file)
+}
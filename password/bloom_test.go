@@ -0,0 +1,83 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	const n = 5000
+	filter := NewBloomFilter(n, 0.01)
+	for i := 0; i < n; i++ {
+		filter.Add(sha1.Sum([]byte(fmt.Sprintf("leaked-%d", i))))
+	}
+
+	var buf bytes.Buffer
+	written, err := filter.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if written != int64(buf.Len()) {
+		t.Errorf("WriteTo 返回 %d 字节，实际写出 %d 字节", written, buf.Len())
+	}
+
+	loaded, err := ReadBloomFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadBloomFilter: %v", err)
+	}
+	if loaded.Count() != n || loaded.HashFunctions() != filter.HashFunctions() || loaded.SizeBytes() != filter.SizeBytes() {
+		t.Fatalf("读回的参数不一致: count=%d k=%d size=%d", loaded.Count(), loaded.HashFunctions(), loaded.SizeBytes())
+	}
+
+	// 布隆过滤器不允许漏判
+	for i := 0; i < n; i++ {
+		if !loaded.Test(sha1.Sum([]byte(fmt.Sprintf("leaked-%d", i)))) {
+			t.Fatalf("leaked-%d 加入过滤器后读回时未命中", i)
+		}
+	}
+
+	// 误判率应接近配置值，留出足够余量避免偶然失败
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if loaded.Test(sha1.Sum([]byte(fmt.Sprintf("safe-%d", i)))) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 0.03 {
+		t.Errorf("误判率 %.4f 远高于配置的 0.01", rate)
+	}
+}
+
+func TestReadBloomFilterRejectsInvalidInput(t *testing.T) {
+	var valid bytes.Buffer
+	if _, err := NewBloomFilter(10, 0.01).WriteTo(&valid); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	good := valid.Bytes()
+
+	badVersion := append([]byte(nil), good...)
+	badVersion[4] = bloomVersion + 1
+	zeroK := append([]byte(nil), good...)
+	zeroK[5] = 0
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"空文件", nil},
+		{"文件头不完整", good[:10]},
+		{"错误的魔数", append([]byte("NOPE"), good[4:]...)},
+		{"不支持的版本", badVersion},
+		{"哈希函数个数为0", zeroK},
+		{"位数组被截断", good[:len(good)-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadBloomFilter(bytes.NewReader(tt.data)); err == nil {
+				t.Error("期望读取失败")
+			}
+		})
+	}
+}
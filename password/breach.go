@@ -0,0 +1,232 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"user-management-system/logger"
+)
+
+// BreachChecker 判断密码是否出现在已知的泄露密码库中
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// SHA1Hex 计算密码的 SHA-1 哈希（40位大写十六进制），与 HIBP 语料的格式一致
+func SHA1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// ParseCorpusLine 解析语料中的一行 "HASH:COUNT"，返回哈希（大写）和出现次数
+// 没有 ":COUNT" 部分时次数视为1
+func ParseCorpusLine(line string) (hash string, count int64, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", 0, false
+	}
+
+	count = 1
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		if _, err := fmt.Sscanf(line[i+1:], "%d", &count); err != nil {
+			return "", 0, false
+		}
+		line = line[:i]
+	}
+	return strings.ToUpper(line), count, true
+}
+
+// searchWindow 二分查找缩小到该字节范围后改为顺序扫描
+const searchWindow = 4096
+
+// RangeChecker 基于本地 HIBP 语料的泄露检查，支持两种布局：
+//   - 单个按哈希排序的文件，每行 "SHA1:COUNT"（HIBP 的 ordered-by-hash 下载格式），使用二分查找
+//   - 一个目录，按哈希前5位拆分为多个文件（文件名为前缀，可带 .txt 后缀），
+//     每行 "SUFFIX:COUNT"，与 HIBP range API 的返回格式一致
+type RangeChecker struct {
+	path  string
+	isDir bool
+	file  *os.File
+	size  int64
+}
+
+// NewRangeChecker 打开本地语料
+func NewRangeChecker(path string) (*RangeChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &RangeChecker{path: path, isDir: true}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RangeChecker{path: path, file: file, size: info.Size()}, nil
+}
+
+// IsBreached 检查密码是否在语料中
+func (c *RangeChecker) IsBreached(password string) (bool, error) {
+	hash := SHA1Hex(password)
+	if c.isDir {
+		return c.lookupRange(hash)
+	}
+	return c.lookupSorted(hash)
+}
+
+// Close 关闭语料文件
+func (c *RangeChecker) Close() error {
+	if c.file != nil {
+		return c.file.Close()
+	}
+	return nil
+}
+
+// lookupRange 在前缀目录中查找
+func (c *RangeChecker) lookupRange(hash string) (bool, error) {
+	prefix, suffix := hash[:5], hash[5:]
+
+	var file *os.File
+	var err error
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		file, err = os.Open(filepath.Join(c.path, name))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 没有该前缀的文件说明没有任何哈希以此开头
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineHash, _, ok := ParseCorpusLine(scanner.Text())
+		if ok && lineHash == suffix {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// lookupSorted 在排序文件中二分查找
+func (c *RangeChecker) lookupSorted(hash string) (bool, error) {
+	// lo 始终指向某一行的开头，且目标行（如果存在）不会在 lo 之前
+	lo, hi := int64(0), c.size
+	for hi-lo > searchWindow {
+		mid := lo + (hi-lo)/2
+		start, lineHash, err := c.nextLine(mid)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case start < 0 || start >= hi:
+			hi = mid
+		case lineHash < hash:
+			lo = start
+		default:
+			hi = start
+		}
+	}
+
+	// 从 lo 开始顺序扫描，直到遇到不小于目标的哈希
+	reader := bufio.NewReader(io.NewSectionReader(c.file, lo, c.size-lo))
+	for {
+		line, err := reader.ReadString('\n')
+		if lineHash, _, ok := ParseCorpusLine(line); ok {
+			if lineHash == hash {
+				return true, nil
+			}
+			if lineHash > hash {
+				return false, nil
+			}
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// nextLine 返回 offset 之后第一个完整行的起始位置和哈希，没有时 start 为 -1
+func (c *RangeChecker) nextLine(offset int64) (start int64, hash string, err error) {
+	buf := make([]byte, 256)
+	n, err := c.file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return -1, "", err
+	}
+	buf = buf[:n]
+
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 || i+1 >= len(buf) {
+		return -1, "", nil
+	}
+	rest := buf[i+1:]
+	if j := bytes.IndexByte(rest, '\n'); j >= 0 {
+		rest = rest[:j]
+	}
+
+	lineHash, _, ok := ParseCorpusLine(string(rest))
+	if !ok {
+		return -1, "", nil
+	}
+	return offset + int64(i) + 1, lineHash, nil
+}
+
+// BloomChecker 基于布隆过滤器的泄露检查，占用空间小，但存在可配置的误判率
+type BloomChecker struct {
+	filter *BloomFilter
+}
+
+// NewBloomChecker 从文件加载布隆过滤器
+func NewBloomChecker(path string) (*BloomChecker, error) {
+	filter, err := LoadBloomFilter(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BloomChecker{filter: filter}, nil
+}
+
+// IsBreached 检查密码是否（可能）在语料中
+func (c *BloomChecker) IsBreached(password string) (bool, error) {
+	return c.filter.Test(sha1.Sum([]byte(password))), nil
+}
+
+// BreachRule 拒绝出现在泄露密码库中的密码
+// 检查出错时只记录日志并放行，避免语料文件损坏导致无法注册
+type BreachRule struct {
+	Checker BreachChecker
+}
+
+// Check 校验密码是否已泄露
+func (r *BreachRule) Check(password string, _ Context) string {
+	breached, err := r.Checker.IsBreached(password)
+	if err != nil {
+		logger.Warn("泄露密码检查失败", "error", err)
+		return ""
+	}
+	if breached {
+		return "该密码出现在已知的数据泄露中，请换一个"
+	}
+	return ""
+}
+
+// Describe 规则说明
+func (r *BreachRule) Describe() string {
+	return "不能使用已在数据泄露中出现过的密码"
+}
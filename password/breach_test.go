@@ -0,0 +1,168 @@
+package password
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeSortedCorpus 把 passwords 的 SHA-1 按哈希排序写成 "HASH:COUNT" 格式的语料文件，返回排序后的哈希
+// 语料远大于 searchWindow，查找时会先走二分查找
+func writeSortedCorpus(t *testing.T, passwords []string) (string, []string) {
+	t.Helper()
+	hashes := make([]string, 0, len(passwords))
+	for _, p := range passwords {
+		hashes = append(hashes, SHA1Hex(p))
+	}
+	sort.Strings(hashes)
+
+	var b strings.Builder
+	for i, h := range hashes {
+		fmt.Fprintf(&b, "%s:%d\r\n", h, i+1)
+	}
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("写入语料失败: %v", err)
+	}
+	return path, hashes
+}
+
+func TestRangeCheckerSortedFile(t *testing.T) {
+	var corpus []string
+	for i := 0; i < 2000; i++ {
+		corpus = append(corpus, fmt.Sprintf("leaked-%d", i))
+	}
+	path, hashes := writeSortedCorpus(t, corpus)
+
+	checker, err := NewRangeChecker(path)
+	if err != nil {
+		t.Fatalf("NewRangeChecker: %v", err)
+	}
+	defer checker.Close()
+	if checker.size <= 4*searchWindow {
+		t.Fatalf("语料只有 %d 字节，不足以覆盖二分查找", checker.size)
+	}
+
+	// byHash 哈希 -> 明文，用于按哈希位置挑选测试用例
+	byHash := make(map[string]string, len(corpus))
+	for _, p := range corpus {
+		byHash[SHA1Hex(p)] = p
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		breached bool
+	}{
+		{"第一行", byHash[hashes[0]], true},
+		{"最后一行", byHash[hashes[len(hashes)-1]], true},
+		{"中间", byHash[hashes[len(hashes)/2]], true},
+		{"第二行", byHash[hashes[1]], true},
+		{"倒数第二行", byHash[hashes[len(hashes)-2]], true},
+		{"不在语料中", "correct horse battery staple", false},
+		{"空密码", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breached, err := checker.IsBreached(tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached: %v", err)
+			}
+			if breached != tt.breached {
+				t.Errorf("IsBreached(%q) = %v，期望 %v", tt.password, breached, tt.breached)
+			}
+		})
+	}
+
+	// 语料中的每个哈希都必须能找到
+	for _, p := range corpus {
+		if breached, err := checker.IsBreached(p); err != nil || !breached {
+			t.Fatalf("IsBreached(%q) = %v, %v，期望找到", p, breached, err)
+		}
+	}
+}
+
+func TestRangeCheckerSmallSortedFile(t *testing.T) {
+	// 小于 searchWindow 的语料直接顺序扫描
+	path, _ := writeSortedCorpus(t, []string{"password", "123456"})
+	checker, err := NewRangeChecker(path)
+	if err != nil {
+		t.Fatalf("NewRangeChecker: %v", err)
+	}
+	defer checker.Close()
+
+	for password, want := range map[string]bool{"password": true, "123456": true, "letmein": false} {
+		if got, err := checker.IsBreached(password); err != nil || got != want {
+			t.Errorf("IsBreached(%q) = %v, %v，期望 %v", password, got, err, want)
+		}
+	}
+}
+
+func TestRangeCheckerPrefixDirectory(t *testing.T) {
+	dir := t.TempDir()
+	hash := SHA1Hex("password")
+	content := "0000000000000000000000000000000000A:3\n" + hash[5:] + ":9545824\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(content), 0o644); err != nil {
+		t.Fatalf("写入语料失败: %v", err)
+	}
+
+	checker, err := NewRangeChecker(dir)
+	if err != nil {
+		t.Fatalf("NewRangeChecker: %v", err)
+	}
+	for password, want := range map[string]bool{"password": true, "letmein": false} {
+		if got, err := checker.IsBreached(password); err != nil || got != want {
+			t.Errorf("IsBreached(%q) = %v, %v，期望 %v", password, got, err, want)
+		}
+	}
+}
+
+func TestParseCorpusLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		hash  string
+		count int64
+		ok    bool
+	}{
+		{"abcdef:12\r\n", "ABCDEF", 12, true},
+		{"ABCDEF", "ABCDEF", 1, true},
+		{"", "", 0, false},
+		{"ABCDEF:x", "", 0, false},
+	}
+	for _, tt := range tests {
+		hash, count, ok := ParseCorpusLine(tt.line)
+		if hash != tt.hash || count != tt.count || ok != tt.ok {
+			t.Errorf("ParseCorpusLine(%q) = %q, %d, %v，期望 %q, %d, %v", tt.line, hash, count, ok, tt.hash, tt.count, tt.ok)
+		}
+	}
+}
+
+func TestBloomChecker(t *testing.T) {
+	filter := NewBloomFilter(10, 0.001)
+	filter.Add(sha1.Sum([]byte("password")))
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("创建文件失败: %v", err)
+	}
+	if _, err := filter.WriteTo(file); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	file.Close()
+
+	checker, err := NewBloomChecker(path)
+	if err != nil {
+		t.Fatalf("NewBloomChecker: %v", err)
+	}
+	rule := &BreachRule{Checker: checker}
+	if msg := rule.Check("password", Context{}); msg == "" {
+		t.Error("泄露的密码应被拒绝")
+	}
+	if msg := rule.Check("correct horse battery staple", Context{}); msg != "" {
+		t.Errorf("未泄露的密码被拒绝: %q", msg)
+	}
+}
@@ -0,0 +1,109 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var builtinCommonPasswords string
+
+// minDictionaryStemLength 去掉首尾数字符号后的词干短于该长度时不再匹配，避免误伤
+const minDictionaryStemLength = 4
+
+// Dictionary 常见密码字典
+type Dictionary struct {
+	words map[string]struct{}
+}
+
+// BuiltinDictionary 返回内置的常见密码字典
+func BuiltinDictionary() *Dictionary {
+	dict, _ := readDictionary(strings.NewReader(builtinCommonPasswords))
+	return dict
+}
+
+// LoadDictionary 从本地文件加载常见密码字典（每行一个密码，# 开头为注释）
+// path 为空时返回内置字典
+func LoadDictionary(path string) (*Dictionary, error) {
+	if path == "" {
+		return BuiltinDictionary(), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readDictionary(file)
+}
+
+// readDictionary 逐行读取字典
+func readDictionary(r io.Reader) (*Dictionary, error) {
+	dict := &Dictionary{words: make(map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dict.words[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return dict, nil
+}
+
+// Len 字典中的词条数
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// Matches 判断密码是否为常见密码或其简单变体
+// 变体包括：大小写变化、首尾追加数字或符号（如 Password123!）、常见的字符替换（如 p@ssw0rd）
+func (d *Dictionary) Matches(password string) bool {
+	lower := strings.ToLower(password)
+	if d.contains(lower) {
+		return true
+	}
+
+	for _, candidate := range []string{lower, unleet(lower)} {
+		stem := strings.TrimFunc(candidate, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+		if len(stem) >= minDictionaryStemLength && d.contains(stem) {
+			return true
+		}
+		if d.contains(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains 精确查找
+func (d *Dictionary) contains(word string) bool {
+	_, ok := d.words[word]
+	return ok
+}
+
+// leetReplacer 常见的字符替换
+var leetReplacer = strings.NewReplacer(
+	"@", "a", "4", "a",
+	"3", "e",
+	"1", "i", "!", "i",
+	"0", "o",
+	"$", "s", "5", "s",
+	"7", "t",
+)
+
+// unleet 还原常见的字符替换
+func unleet(s string) string {
+	return leetReplacer.Replace(s)
+}
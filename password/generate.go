@@ -0,0 +1,58 @@
+package password
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// 生成密码使用的字符集，去掉了 0/O、1/l/I 等容易看错的字符
+const (
+	generateLower  = "abcdefghijkmnpqrstuvwxyz"
+	generateUpper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	generateDigit  = "23456789"
+	generateSymbol = "!@#$%^&*-_=+?"
+)
+
+// Generate 生成长度为 length 的随机密码，大写、小写、数字和符号各至少一个
+// 用于管理员为用户设置的一次性临时密码
+func Generate(length int) (string, error) {
+	classes := []string{generateLower, generateUpper, generateDigit, generateSymbol}
+	if length < len(classes) {
+		return "", fmt.Errorf("密码长度不能小于 %d", len(classes))
+	}
+
+	all := generateLower + generateUpper + generateDigit + generateSymbol
+	b := make([]byte, length)
+	for i := range b {
+		// 前几位依次取自每一类字符，保证每类都出现，其余位从全部字符中选取
+		charset := all
+		if i < len(classes) {
+			charset = classes[i]
+		}
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		b[i] = c
+	}
+
+	// 打乱顺序，避免固定位置上的字符类别可被预测
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		b[i], b[j.Int64()] = b[j.Int64()], b[i]
+	}
+	return string(b), nil
+}
+
+// randomChar 从字符集中均匀随机地选取一个字符
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, err
+	}
+	return charset[n.Int64()], nil
+}
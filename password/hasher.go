@@ -0,0 +1,257 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"user-management-system/config"
+)
+
+// 支持的哈希算法名称
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// ErrUnknownHashFormat 无法识别的哈希格式
+var ErrUnknownHashFormat = errors.New("无法识别的密码哈希格式")
+
+// Hasher 密码哈希接口
+type Hasher interface {
+	// Hash 计算密码哈希，返回包含算法和参数的编码字符串
+	Hash(password string) (string, error)
+	// Verify 校验密码与编码后的哈希是否匹配
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash 判断已有哈希是否使用了过时的算法或参数
+	NeedsRehash(encoded string) bool
+}
+
+// BcryptHasher bcrypt 哈希
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash 计算 bcrypt 哈希
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify 校验 bcrypt 哈希
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NeedsRehash 成本因子与配置不同时需要重新哈希
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost()
+}
+
+// cost 返回有效的成本因子
+func (h *BcryptHasher) cost() int {
+	if h.Cost < bcrypt.MinCost || h.Cost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return h.Cost
+}
+
+// Argon2Params argon2id 参数
+type Argon2Params struct {
+	Memory      uint32 // 内存（KiB）
+	Iterations  uint32 // 迭代次数
+	Parallelism uint8  // 并行度
+	SaltLength  uint32 // 盐长度（字节）
+	KeyLength   uint32 // 输出长度（字节）
+}
+
+// Argon2idHasher argon2id 哈希，编码格式与 PHC 字符串一致：
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<盐>$<哈希>
+type Argon2idHasher struct {
+	Params Argon2Params
+}
+
+// Hash 计算 argon2id 哈希
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	p := h.Params
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify 使用编码中记录的参数重新计算并比较
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// NeedsRehash 参数与配置不同时需要重新哈希
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	want := h.Params
+	return p.Memory != want.Memory ||
+		p.Iterations != want.Iterations ||
+		p.Parallelism != want.Parallelism ||
+		uint32(len(salt)) != want.SaltLength ||
+		uint32(len(key)) != want.KeyLength
+}
+
+// decodeArgon2id 解析 argon2id 编码字符串
+func decodeArgon2id(encoded string) (p Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("不支持的 argon2 版本: %d", version)
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, ErrUnknownHashFormat
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
+
+// MultiHasher 按编码前缀识别算法进行校验，新哈希统一使用首选算法
+// 使用其他算法（或旧参数）的哈希会被 NeedsRehash 标记，以便登录成功后升级
+type MultiHasher struct {
+	preferred string
+	bcrypt    *BcryptHasher
+	argon2id  *Argon2idHasher
+}
+
+// NewMultiHasher 创建多算法哈希器，preferred 为新哈希使用的算法
+func NewMultiHasher(preferred string, bcryptHasher *BcryptHasher, argon2Hasher *Argon2idHasher) *MultiHasher {
+	if preferred != AlgorithmBcrypt {
+		preferred = AlgorithmArgon2id
+	}
+	return &MultiHasher{
+		preferred: preferred,
+		bcrypt:    bcryptHasher,
+		argon2id:  argon2Hasher,
+	}
+}
+
+// Hash 使用首选算法计算哈希
+func (h *MultiHasher) Hash(password string) (string, error) {
+	return h.hasherFor(h.preferred).Hash(password)
+}
+
+// Verify 根据编码识别算法后校验
+func (h *MultiHasher) Verify(encoded, password string) (bool, error) {
+	hasher := h.hasherFor(algorithmOf(encoded))
+	if hasher == nil {
+		return false, ErrUnknownHashFormat
+	}
+	return hasher.Verify(encoded, password)
+}
+
+// NeedsRehash 算法不是首选算法，或参数已过时
+func (h *MultiHasher) NeedsRehash(encoded string) bool {
+	algorithm := algorithmOf(encoded)
+	if algorithm != h.preferred {
+		return true
+	}
+	return h.hasherFor(algorithm).NeedsRehash(encoded)
+}
+
+// hasherFor 返回算法对应的哈希器
+func (h *MultiHasher) hasherFor(algorithm string) Hasher {
+	switch algorithm {
+	case AlgorithmBcrypt:
+		return h.bcrypt
+	case AlgorithmArgon2id:
+		return h.argon2id
+	default:
+		return nil
+	}
+}
+
+// algorithmOf 根据编码前缀识别算法
+func algorithmOf(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return ""
+	}
+}
+
+var (
+	defaultHasher     Hasher
+	defaultHasherOnce sync.Once
+)
+
+// DefaultHasher 返回根据配置构建的全局哈希器（只构建一次）
+func DefaultHasher() Hasher {
+	defaultHasherOnce.Do(func() {
+		defaultHasher = NewHasherFromConfig(config.GetConfig())
+	})
+	return defaultHasher
+}
+
+// NewHasherFromConfig 根据配置构建哈希器
+func NewHasherFromConfig(cfg *config.Config) Hasher {
+	return NewMultiHasher(
+		cfg.PasswordHashAlgorithm,
+		&BcryptHasher{Cost: cfg.BcryptCost},
+		&Argon2idHasher{Params: Argon2Params{
+			Memory:      cfg.Argon2Memory,
+			Iterations:  cfg.Argon2Iterations,
+			Parallelism: cfg.Argon2Parallelism,
+			SaltLength:  16,
+			KeyLength:   32,
+		}},
+	)
+}
@@ -0,0 +1,216 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params 测试使用的小参数，避免拖慢测试
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// newTestHasher 创建首选 preferred 算法、使用低成本参数的多算法哈希器
+func newTestHasher(preferred string) *MultiHasher {
+	return NewMultiHasher(preferred, &BcryptHasher{Cost: bcrypt.MinCost}, &Argon2idHasher{Params: testArgon2Params})
+}
+
+func TestHashVerifyRoundTrip(t *testing.T) {
+	hashers := map[string]Hasher{
+		AlgorithmBcrypt:   &BcryptHasher{Cost: bcrypt.MinCost},
+		AlgorithmArgon2id: &Argon2idHasher{Params: testArgon2Params},
+	}
+	for name, hasher := range hashers {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := hasher.Hash("正确的马 battery staple")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if algorithmOf(encoded) != name {
+				t.Fatalf("编码 %q 识别为 %q，期望 %q", encoded, algorithmOf(encoded), name)
+			}
+
+			if ok, err := hasher.Verify(encoded, "正确的马 battery staple"); err != nil || !ok {
+				t.Errorf("正确的密码校验失败: %v, %v", ok, err)
+			}
+			if ok, err := hasher.Verify(encoded, "正确的马 battery stapler"); err != nil || ok {
+				t.Errorf("错误的密码校验结果为 %v, %v", ok, err)
+			}
+			if hasher.NeedsRehash(encoded) {
+				t.Error("刚计算的哈希不应需要重新哈希")
+			}
+
+			// 每次哈希使用不同的盐
+			again, _ := hasher.Hash("正确的马 battery staple")
+			if again == encoded {
+				t.Error("两次哈希结果相同，盐没有随机生成")
+			}
+		})
+	}
+}
+
+func TestDecodeArgon2idRejectsMalformedEncodings(t *testing.T) {
+	encoded, err := (&Argon2idHasher{Params: testArgon2Params}).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	parts := strings.Split(encoded, "$")
+	replace := func(i int, value string) string {
+		changed := append([]string(nil), parts...)
+		changed[i] = value
+		return strings.Join(changed, "$")
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"空串", ""},
+		{"段数不足", strings.Join(parts[:5], "$")},
+		{"算法名错误", replace(1, "argon2i")},
+		{"版本格式错误", replace(2, "version=19")},
+		{"不支持的版本", replace(2, "v=16")},
+		{"参数格式错误", replace(3, "m=64;t=1;p=1")},
+		{"盐不是 base64", replace(4, "!!!")},
+		{"哈希不是 base64", replace(5, "!!!")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := decodeArgon2id(tt.encoded); err == nil {
+				t.Errorf("decodeArgon2id(%q) 期望失败", tt.encoded)
+			}
+			if ok, _ := (&Argon2idHasher{Params: testArgon2Params}).Verify(tt.encoded, "secret"); ok {
+				t.Errorf("格式错误的哈希 %q 不应校验通过", tt.encoded)
+			}
+		})
+	}
+
+	if _, _, _, err := decodeArgon2id(replace(1, "argon2i")); !errors.Is(err, ErrUnknownHashFormat) {
+		t.Errorf("算法名错误时应返回 ErrUnknownHashFormat，实际为 %v", err)
+	}
+}
+
+func TestArgon2idVerifyRejectsTamperedHash(t *testing.T) {
+	hasher := &Argon2idHasher{Params: testArgon2Params}
+	encoded, err := hasher.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	parts := strings.Split(encoded, "$")
+
+	tampered := map[string]string{
+		// 修改参数后重新计算的结果与存储的哈希不一致
+		"迭代次数": strings.Replace(encoded, "t=1", "t=2", 1),
+		"盐":    strings.Join(append(parts[:4:4], strings.Repeat("A", len(parts[4])), parts[5]), "$"),
+		"哈希":   strings.Join(append(parts[:5:5], strings.Repeat("A", len(parts[5]))), "$"),
+	}
+	for name, encoded := range tampered {
+		t.Run(name, func(t *testing.T) {
+			ok, err := hasher.Verify(encoded, "secret")
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if ok {
+				t.Error("被篡改的哈希不应校验通过")
+			}
+		})
+	}
+}
+
+func TestNeedsRehashAfterParameterChange(t *testing.T) {
+	argon2Hash, err := (&Argon2idHasher{Params: testArgon2Params}).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	bcryptHash, err := (&BcryptHasher{Cost: bcrypt.MinCost}).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	changed := func(modify func(p *Argon2Params)) *Argon2idHasher {
+		p := testArgon2Params
+		modify(&p)
+		return &Argon2idHasher{Params: p}
+	}
+
+	tests := []struct {
+		name    string
+		hasher  Hasher
+		encoded string
+		rehash  bool
+	}{
+		{"argon2id 参数未变", &Argon2idHasher{Params: testArgon2Params}, argon2Hash, false},
+		{"argon2id 内存", changed(func(p *Argon2Params) { p.Memory = 128 }), argon2Hash, true},
+		{"argon2id 迭代次数", changed(func(p *Argon2Params) { p.Iterations = 2 }), argon2Hash, true},
+		{"argon2id 并行度", changed(func(p *Argon2Params) { p.Parallelism = 2 }), argon2Hash, true},
+		{"argon2id 盐长度", changed(func(p *Argon2Params) { p.SaltLength = 32 }), argon2Hash, true},
+		{"argon2id 输出长度", changed(func(p *Argon2Params) { p.KeyLength = 64 }), argon2Hash, true},
+		{"argon2id 无法解析", &Argon2idHasher{Params: testArgon2Params}, "garbage", true},
+		{"bcrypt 成本未变", &BcryptHasher{Cost: bcrypt.MinCost}, bcryptHash, false},
+		{"bcrypt 成本提高", &BcryptHasher{Cost: bcrypt.MinCost + 1}, bcryptHash, true},
+		{"bcrypt 无法解析", &BcryptHasher{Cost: bcrypt.MinCost}, "garbage", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.rehash {
+				t.Errorf("NeedsRehash = %v，期望 %v", got, tt.rehash)
+			}
+		})
+	}
+}
+
+func TestMultiHasherVerifiesLegacyBcrypt(t *testing.T) {
+	hasher := newTestHasher(AlgorithmArgon2id)
+
+	// 升级前的用户使用 bcrypt 哈希，$2a$ 与 $2b$、$2y$ 前缀都应识别为 bcrypt
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		encoded := prefix + string(legacy[4:])
+		if algorithmOf(encoded) != AlgorithmBcrypt {
+			t.Errorf("%s 前缀未识别为 bcrypt", prefix)
+		}
+	}
+
+	ok, err := hasher.Verify(string(legacy), "secret")
+	if err != nil || !ok {
+		t.Fatalf("旧的 bcrypt 哈希校验失败: %v, %v", ok, err)
+	}
+	if !hasher.NeedsRehash(string(legacy)) {
+		t.Error("首选 argon2id 时 bcrypt 哈希应在登录后升级")
+	}
+
+	upgraded, err := hasher.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if algorithmOf(upgraded) != AlgorithmArgon2id || hasher.NeedsRehash(upgraded) {
+		t.Errorf("新哈希 %q 应使用首选的 argon2id 且无需升级", upgraded)
+	}
+
+	if _, err := hasher.Verify("$1$md5crypt$hash", "secret"); !errors.Is(err, ErrUnknownHashFormat) {
+		t.Errorf("无法识别的哈希应返回 ErrUnknownHashFormat，实际为 %v", err)
+	}
+}
+
+func TestMultiHasherPreferringBcrypt(t *testing.T) {
+	hasher := newTestHasher(AlgorithmBcrypt)
+	argon2Hash, err := (&Argon2idHasher{Params: testArgon2Params}).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if ok, err := hasher.Verify(argon2Hash, "secret"); err != nil || !ok {
+		t.Errorf("argon2id 哈希校验失败: %v, %v", ok, err)
+	}
+	if !hasher.NeedsRehash(argon2Hash) {
+		t.Error("首选 bcrypt 时 argon2id 哈希应被标记为需要重新哈希")
+	}
+
+	// 未知的首选算法按 argon2id 处理
+	if encoded, _ := newTestHasher("scrypt").Hash("secret"); algorithmOf(encoded) != AlgorithmArgon2id {
+		t.Errorf("未知首选算法时应使用 argon2id，实际为 %q", encoded)
+	}
+}
@@ -0,0 +1,158 @@
+package password
+
+import (
+	"strings"
+	"sync"
+
+	"user-management-system/config"
+	"user-management-system/errors"
+	"user-management-system/logger"
+)
+
+// MaxBytes bcrypt 只使用密码的前72个字节，超出部分会被静默忽略，因此作为长度上限
+const MaxBytes = 72
+
+// Context 校验密码时可参考的用户信息
+type Context struct {
+	Username string
+	Email    string
+}
+
+// Rule 单条密码规则
+type Rule interface {
+	// Check 校验密码，不满足规则时返回面向用户的提示信息，满足时返回空字符串
+	Check(password string, ctx Context) string
+	// Describe 规则说明，用于在页面上提示用户
+	Describe() string
+}
+
+// Policy 由多条规则组成的密码策略
+type Policy struct {
+	rules []Rule
+}
+
+// NewPolicy 使用给定的规则创建密码策略
+func NewPolicy(rules ...Rule) *Policy {
+	return &Policy{rules: rules}
+}
+
+// Use 追加规则，用于插入自定义规则
+func (p *Policy) Use(rules ...Rule) *Policy {
+	p.rules = append(p.rules, rules...)
+	return p
+}
+
+// Validate 按顺序校验所有规则
+// 返回的验证错误 Field 为 password，Message 为第一条未通过的规则，Data 中是全部未通过规则的提示
+func (p *Policy) Validate(password string, ctx Context) error {
+	if password == "" {
+		return errors.NewValidationError("password", "密码不能为空")
+	}
+
+	var violations []string
+	for _, rule := range p.rules {
+		if msg := rule.Check(password, ctx); msg != "" {
+			violations = append(violations, msg)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	appErr := errors.NewValidationError("password", violations[0])
+	appErr.Data = violations
+	return appErr
+}
+
+// Requirements 返回所有规则的说明（跳过没有说明的规则）
+func (p *Policy) Requirements() []string {
+	var reqs []string
+	for _, rule := range p.rules {
+		if desc := rule.Describe(); desc != "" {
+			reqs = append(reqs, desc)
+		}
+	}
+	return reqs
+}
+
+var (
+	defaultPolicy *Policy
+	defaultOnce   sync.Once
+)
+
+// DefaultPolicy 返回根据配置构建的全局密码策略（只构建一次）
+func DefaultPolicy() *Policy {
+	defaultOnce.Do(func() {
+		defaultPolicy = NewPolicyFromConfig(config.GetConfig())
+	})
+	return defaultPolicy
+}
+
+// NewPolicyFromConfig 根据配置构建密码策略
+func NewPolicyFromConfig(cfg *config.Config) *Policy {
+	maxLength := cfg.PasswordMaxLength
+	if maxLength <= 0 || maxLength > MaxBytes {
+		maxLength = MaxBytes
+	}
+
+	policy := NewPolicy(
+		&LengthRule{Min: cfg.PasswordMinLength, Max: maxLength},
+		&CharClassRule{
+			RequireUpper:  cfg.PasswordRequireUpper,
+			RequireLower:  cfg.PasswordRequireLower,
+			RequireDigit:  cfg.PasswordRequireDigit,
+			RequireSymbol: cfg.PasswordRequireSymbol,
+			MinClasses:    cfg.PasswordMinCharClasses,
+
+			PassphraseLength: cfg.PasswordPassphraseLength,
+		},
+	)
+
+	if cfg.PasswordRejectPersonalInfo {
+		policy.Use(&PersonalInfoRule{})
+	}
+
+	dict, err := LoadDictionary(cfg.PasswordDictionaryPath)
+	if err != nil {
+		// 字典文件不可用时退回内置字典，不应让注册整体不可用
+		logger.Warn("加载密码字典失败，使用内置字典", "path", cfg.PasswordDictionaryPath, "error", err)
+		dict = BuiltinDictionary()
+	}
+	policy.Use(&DictionaryRule{Dictionary: dict})
+
+	if cfg.PasswordMinStrength > 0 {
+		policy.Use(&StrengthRule{MinScore: cfg.PasswordMinStrength, Dictionary: dict})
+	}
+
+	if checker := newBreachCheckerFromConfig(cfg); checker != nil {
+		policy.Use(&BreachRule{Checker: checker})
+	}
+	return policy
+}
+
+// newBreachCheckerFromConfig 根据配置创建泄露密码检查器，未配置或加载失败时返回 nil
+func newBreachCheckerFromConfig(cfg *config.Config) BreachChecker {
+	if cfg.PasswordBreachBloomPath != "" {
+		checker, err := NewBloomChecker(cfg.PasswordBreachBloomPath)
+		if err == nil {
+			logger.Info("已加载泄露密码布隆过滤器", "path", cfg.PasswordBreachBloomPath, "count", checker.filter.Count())
+			return checker
+		}
+		logger.Error("加载泄露密码布隆过滤器失败", "path", cfg.PasswordBreachBloomPath, "error", err)
+	}
+
+	if cfg.PasswordBreachCorpusPath != "" {
+		checker, err := NewRangeChecker(cfg.PasswordBreachCorpusPath)
+		if err == nil {
+			logger.Info("已加载泄露密码语料", "path", cfg.PasswordBreachCorpusPath)
+			return checker
+		}
+		logger.Error("打开泄露密码语料失败", "path", cfg.PasswordBreachCorpusPath, "error", err)
+	}
+	return nil
+}
+
+// containsFold 忽略大小写判断 s 是否包含 sub
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
@@ -0,0 +1,199 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LengthRule 长度规则：Min 按字符数计算，Max 按字节数计算（与 bcrypt 的限制一致）
+type LengthRule struct {
+	Min int
+	Max int
+}
+
+// Check 校验密码长度
+func (r *LengthRule) Check(password string, _ Context) string {
+	if r.Min > 0 && utf8.RuneCountInString(password) < r.Min {
+		return fmt.Sprintf("密码长度不能少于%d个字符", r.Min)
+	}
+	if r.Max > 0 && len(password) > r.Max {
+		return fmt.Sprintf("密码不能超过%d个字节（约%d个英文字符）", r.Max, r.Max)
+	}
+	return ""
+}
+
+// Describe 规则说明
+func (r *LengthRule) Describe() string {
+	if r.Min <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("至少%d个字符，支持长句式密码", r.Min)
+}
+
+// CharClassRule 字符类别规则
+type CharClassRule struct {
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	MinClasses    int // 至少包含几类字符（大写、小写、数字、符号）
+
+	// PassphraseLength 达到该字符数的长句式密码不受 MinClasses 限制（0表示不豁免）
+	PassphraseLength int
+}
+
+// charClasses 统计密码中出现的字符类别
+func charClasses(password string) (upper, lower, digit, symbol bool) {
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case unicode.IsLetter(c):
+			// 中文等没有大小写之分的文字按小写字母计
+			lower = true
+		default:
+			symbol = true
+		}
+	}
+	return
+}
+
+// Check 校验字符类别
+func (r *CharClassRule) Check(password string, _ Context) string {
+	upper, lower, digit, symbol := charClasses(password)
+
+	switch {
+	case r.RequireUpper && !upper:
+		return "密码必须包含大写字母"
+	case r.RequireLower && !lower:
+		return "密码必须包含小写字母"
+	case r.RequireDigit && !digit:
+		return "密码必须包含数字"
+	case r.RequireSymbol && !symbol:
+		return "密码必须包含特殊符号"
+	}
+
+	if r.PassphraseLength > 0 && utf8.RuneCountInString(password) >= r.PassphraseLength {
+		return ""
+	}
+	if r.MinClasses > 1 && countTrue(upper, lower, digit, symbol) < r.MinClasses {
+		return fmt.Sprintf("密码至少需要包含大写字母、小写字母、数字、符号中的%d类", r.MinClasses)
+	}
+	return ""
+}
+
+// Describe 规则说明
+func (r *CharClassRule) Describe() string {
+	var parts []string
+	if r.RequireUpper {
+		parts = append(parts, "大写字母")
+	}
+	if r.RequireLower {
+		parts = append(parts, "小写字母")
+	}
+	if r.RequireDigit {
+		parts = append(parts, "数字")
+	}
+	if r.RequireSymbol {
+		parts = append(parts, "特殊符号")
+	}
+
+	desc := ""
+	if len(parts) > 0 {
+		desc = "必须包含" + strings.Join(parts, "、")
+	}
+	if r.MinClasses > 1 {
+		if desc != "" {
+			desc += "，"
+		}
+		desc += fmt.Sprintf("至少包含%d类字符", r.MinClasses)
+		if r.PassphraseLength > 0 {
+			desc += fmt.Sprintf("（%d个字符以上的长密码不限）", r.PassphraseLength)
+		}
+	}
+	return desc
+}
+
+// countTrue 统计为 true 的个数
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// minPersonalInfoLength 用户名或邮箱前缀短于该长度时不做包含检查，避免误伤
+const minPersonalInfoLength = 3
+
+// PersonalInfoRule 拒绝包含用户名或邮箱的密码
+type PersonalInfoRule struct{}
+
+// Check 校验密码是否包含个人信息
+func (r *PersonalInfoRule) Check(password string, ctx Context) string {
+	if len(ctx.Username) >= minPersonalInfoLength && containsFold(password, ctx.Username) {
+		return "密码不能包含用户名"
+	}
+
+	if ctx.Email != "" {
+		local := ctx.Email
+		if i := strings.Index(local, "@"); i >= 0 {
+			local = local[:i]
+		}
+		if containsFold(password, ctx.Email) ||
+			(len(local) >= minPersonalInfoLength && containsFold(password, local)) {
+			return "密码不能包含邮箱地址"
+		}
+	}
+	return ""
+}
+
+// Describe 规则说明
+func (r *PersonalInfoRule) Describe() string {
+	return "不能包含用户名或邮箱"
+}
+
+// DictionaryRule 拒绝常见密码
+type DictionaryRule struct {
+	Dictionary *Dictionary
+}
+
+// Check 校验密码是否为常见密码
+func (r *DictionaryRule) Check(password string, _ Context) string {
+	if r.Dictionary != nil && r.Dictionary.Matches(password) {
+		return "密码过于常见，容易被猜到，请换一个"
+	}
+	return ""
+}
+
+// Describe 规则说明
+func (r *DictionaryRule) Describe() string {
+	return "不能使用常见密码"
+}
+
+// StrengthRule 要求密码强度评分不低于 MinScore（0-4）
+type StrengthRule struct {
+	MinScore   int
+	Dictionary *Dictionary
+}
+
+// Check 校验密码强度
+func (r *StrengthRule) Check(password string, _ Context) string {
+	if Estimate(password, r.Dictionary).Score < r.MinScore {
+		return "密码强度不足，请使用更长或更复杂的密码"
+	}
+	return ""
+}
+
+// Describe 规则说明
+func (r *StrengthRule) Describe() string {
+	return fmt.Sprintf("强度至少达到“%s”", scoreLabels[clampScore(r.MinScore)])
+}
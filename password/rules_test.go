@@ -0,0 +1,147 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLengthRule(t *testing.T) {
+	rule := &LengthRule{Min: 8, Max: MaxBytes}
+
+	tests := []struct {
+		name     string
+		password string
+		ok       bool
+	}{
+		{"达到最小长度", "abcdefgh", true},
+		{"少于最小长度", "abcdefg", false},
+		// 8个汉字共24个字节，最小长度按字符数计算
+		{"中文按字符计数", "正确马匹电池订书", true},
+		{"中文不足最小长度", "正确马匹电池订", false},
+		{"刚好达到字节上限", strings.Repeat("a", MaxBytes), true},
+		{"超过字节上限", strings.Repeat("a", MaxBytes+1), false},
+		// 25个汉字为75个字节，字符数不多但超过了 bcrypt 的限制
+		{"中文超过字节上限", strings.Repeat("密", 25), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := rule.Check(tt.password, Context{})
+			if (msg == "") != tt.ok {
+				t.Errorf("Check(%q) = %q，期望通过 = %v", tt.password, msg, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCharClassRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     CharClassRule
+		password string
+		ok       bool
+	}{
+		{"满足类别数", CharClassRule{MinClasses: 3}, "Abcdef12", true},
+		{"类别数不足", CharClassRule{MinClasses: 3}, "abcdef12", false},
+		{"长句式密码豁免类别数", CharClassRule{MinClasses: 3, PassphraseLength: 20}, "correct horse battery", true},
+		{"未达到长句式长度", CharClassRule{MinClasses: 3, PassphraseLength: 20}, "correct horse", false},
+		{"未配置豁免", CharClassRule{MinClasses: 3}, "correct horse battery staple", false},
+		// 豁免只放宽类别数，明确要求的类别仍然必须满足
+		{"长句式密码仍需满足必选类别", CharClassRule{RequireDigit: true, PassphraseLength: 20}, "correct horse battery", false},
+		{"缺少大写字母", CharClassRule{RequireUpper: true}, "abc123!", false},
+		{"缺少符号", CharClassRule{RequireSymbol: true}, "Abc123", false},
+		// 中文没有大小写之分，按小写字母计
+		{"中文计为小写字母", CharClassRule{RequireLower: true, MinClasses: 2}, "密码123", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.rule.Check(tt.password, Context{})
+			if (msg == "") != tt.ok {
+				t.Errorf("Check(%q) = %q，期望通过 = %v", tt.password, msg, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPersonalInfoRule(t *testing.T) {
+	rule := &PersonalInfoRule{}
+	ctx := Context{Username: "alice", Email: "wonder.land@example.com"}
+
+	tests := []struct {
+		name     string
+		ctx      Context
+		password string
+		ok       bool
+	}{
+		{"不含个人信息", ctx, "Tr0ub4dor&3", true},
+		{"包含用户名", ctx, "my-alice-2024", false},
+		{"用户名不区分大小写", ctx, "ALICE2024!", false},
+		{"包含邮箱前缀", ctx, "Wonder.Land99", false},
+		{"包含完整邮箱", ctx, "x-wonder.land@example.com", false},
+		// 过短的用户名和邮箱前缀不做检查，避免误伤
+		{"用户名过短", Context{Username: "al", Email: "al@example.com"}, "pal-friendly", true},
+		{"没有个人信息", Context{}, "anything", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := rule.Check(tt.password, tt.ctx)
+			if (msg == "") != tt.ok {
+				t.Errorf("Check(%q) = %q，期望通过 = %v", tt.password, msg, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDictionaryRule(t *testing.T) {
+	dict, err := readDictionary(strings.NewReader("# 注释\npassword\ndragon\n\nletmein\n"))
+	if err != nil {
+		t.Fatalf("readDictionary: %v", err)
+	}
+	if dict.Len() != 3 {
+		t.Fatalf("词条数 = %d，期望 3", dict.Len())
+	}
+	rule := &DictionaryRule{Dictionary: dict}
+
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"password", false},
+		{"PassWord", false},
+		{"Password123!", false}, // 首尾追加数字和符号
+		{"p@ssw0rd", false},     // 常见的字符替换
+		{"2024dragon", false},
+		{"d!", true}, // 词干过短，不匹配
+		{"correct horse battery staple", true},
+		{"passwordless-login", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			msg := rule.Check(tt.password, Context{})
+			if (msg == "") != tt.ok {
+				t.Errorf("Check(%q) = %q，期望通过 = %v", tt.password, msg, tt.ok)
+			}
+		})
+	}
+
+	if msg := (&DictionaryRule{}).Check("password", Context{}); msg != "" {
+		t.Errorf("没有字典时不应拒绝，实际为 %q", msg)
+	}
+}
+
+func TestPolicyValidateReportsEveryViolation(t *testing.T) {
+	policy := NewPolicy(&LengthRule{Min: 12}, &CharClassRule{RequireDigit: true}, &PersonalInfoRule{})
+
+	err := policy.Validate("alice", Context{Username: "alice"})
+	if err == nil {
+		t.Fatal("期望校验失败")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "12") {
+		t.Errorf("第一条提示应为长度规则，实际为 %q", msg)
+	}
+	if err := policy.Validate("", Context{}); err == nil {
+		t.Error("空密码应被拒绝")
+	}
+	if err := policy.Validate("long enough 42", Context{Username: "alice"}); err != nil {
+		t.Errorf("期望通过，实际为 %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package password
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// Strength 密码强度估算结果
+type Strength struct {
+	Score   int     // 0-4，越大越强
+	Entropy float64 // 估算的熵（比特）
+	Label   string  // 面向用户的描述
+}
+
+// scoreLabels 强度评分对应的描述
+var scoreLabels = [...]string{"非常弱", "弱", "一般", "强", "非常强"}
+
+// 熵阈值：分别对应评分 1、2、3、4 的下限
+var entropyThresholds = [...]float64{28, 36, 60, 80}
+
+// Estimate 估算密码强度
+// 以字符集大小和长度计算理论熵，再对重复字符、连续序列和常见密码进行惩罚
+func Estimate(password string, dict *Dictionary) Strength {
+	if password == "" {
+		return Strength{Score: 0, Label: scoreLabels[0]}
+	}
+
+	upper, lower, digit, symbol := charClasses(password)
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+
+	// 只计算“有效”字符：重复字符和连续序列（abc、123、cba）贡献减半
+	runes := []rune(password)
+	effective := 0.0
+	for i, c := range runes {
+		if i > 0 && (c == runes[i-1] || c-runes[i-1] == 1 || runes[i-1]-c == 1) {
+			effective += 0.5
+			continue
+		}
+		effective++
+	}
+
+	entropy := effective * math.Log2(float64(pool))
+
+	// 常见密码（或其变体）几乎没有熵
+	if dict != nil && dict.Matches(password) {
+		entropy = math.Min(entropy, 10)
+	}
+
+	score := 0
+	for i, threshold := range entropyThresholds {
+		if entropy >= threshold {
+			score = i + 1
+		}
+	}
+
+	// 过短的密码无论字符多复杂都不算强
+	if utf8.RuneCountInString(password) < 8 && score > 1 {
+		score = 1
+	}
+
+	return Strength{Score: score, Entropy: entropy, Label: scoreLabels[score]}
+}
+
+// clampScore 将评分限制在 0-4 之间
+func clampScore(score int) int {
+	if score < 0 {
+		return 0
+	}
+	if score >= len(scoreLabels) {
+		return len(scoreLabels) - 1
+	}
+	return score
+}
@@ -0,0 +1,42 @@
+// Package rbac 提供基于角色的权限检查
+//
+// 用户通过角色获得权限（见 models.Permissions），权限字符串的格式为 资源:操作。
+// 部分权限对用户本人拥有的资源总是允许，例如任何用户都可以修改自己的信息。
+package rbac
+
+import "user-management-system/models"
+
+// Owned 由可以归属于某个用户的资源实现
+type Owned interface {
+	OwnerID() int
+}
+
+// selfPermissions 对本人拥有的资源无需角色授权的权限
+var selfPermissions = map[string]bool{
+	models.PermUsersUpdate: true,
+}
+
+// Can 检查 user 能否对 resource 执行 permission 对应的操作
+// resource 为 nil 表示不针对具体资源的操作（如查看列表）
+func Can(user *models.User, permission string, resource interface{}) bool {
+	if user == nil || !user.IsActive() {
+		return false
+	}
+	if user.HasPermission(permission) {
+		return true
+	}
+
+	if owned, ok := resource.(Owned); ok && selfPermissions[permission] {
+		return owned.OwnerID() == user.ID
+	}
+	return false
+}
+
+// CanSome 检查 user 能否对至少一部分用户执行 permission 对应的操作：拥有该权限，或通过委派授权获得了该操作
+// 委派的操作具体能否用于某个用户由服务层按授权范围判断
+func CanSome(user *models.User, permission string) bool {
+	if Can(user, permission, nil) {
+		return true
+	}
+	return user != nil && user.IsActive() && user.HasDelegated(permission)
+}
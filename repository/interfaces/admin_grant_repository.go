@@ -0,0 +1,33 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// AdminGrantRepository 定义委派管理授权的数据访问接口
+// 授权属于组织，只对该组织的成员生效
+type AdminGrantRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) AdminGrantRepository
+
+	// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+	ForOrganization(orgID int) AdminGrantRepository
+
+	// GetAll 获取所有授权，按创建时间排序
+	GetAll() ([]*models.AdminGrant, error)
+
+	// GetByID 根据ID获取授权，未找到时返回 nil
+	GetByID(id int) (*models.AdminGrant, error)
+
+	// Create 创建授权，grant.Role 和 grant.ScopeRole 为角色标识；授权属于仓库限定的组织（未限定时属于默认组织）
+	Create(grant *models.AdminGrant, createdBy int) error
+
+	// Delete 删除授权
+	Delete(id int) error
+
+	// DelegatedActions 返回 actorID 通过授权可以对各用户执行的操作（用户ID -> 操作列表）
+	// targetID 不为 0 时只查询该用户
+	DelegatedActions(actorID, targetID int) (map[int][]string, error)
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// AuditRepository 定义审计事件的数据访问接口
+// 事件属于发生时请求所在的组织，只追加不修改；全部组织的事件共同组成一条哈希链
+type AuditRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) AuditRepository
+
+	// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+	ForOrganization(orgID int) AuditRepository
+
+	// Create 把审计事件追加到哈希链末端，设置 event 的 ID、Seq、PrevHash 和 Hash；event.OrgID 为 0 时归入默认组织
+	Create(event *models.AuditEvent) error
+
+	// Find 按条件查询审计事件，最近发生的在前，返回 offset 开始的至多 limit 条以及符合条件的总数
+	Find(filter *models.AuditFilter, limit, offset int) ([]*models.AuditEvent, int, error)
+
+	// Each 按发生时间顺序遍历符合条件的全部审计事件（用于导出），fn 返回错误时停止遍历
+	Each(filter *models.AuditFilter, fn func(*models.AuditEvent) error) error
+
+	// ChainHead 返回哈希链的末端
+	ChainHead() (*models.AuditChainHead, error)
+
+	// EachInChain 按序号顺序遍历哈希链中的全部事件（不受组织限定），fn 返回错误时停止遍历
+	EachInChain(fn func(*models.AuditEvent) error) error
+
+	// CreateCheckpoint 写入签名检查点；该序号已有检查点时不写入并返回 false
+	CreateCheckpoint(cp *models.AuditCheckpoint) (bool, error)
+
+	// GetLatestCheckpoint 返回序号最大的检查点，没有检查点时返回 nil
+	GetLatestCheckpoint() (*models.AuditCheckpoint, error)
+
+	// GetCheckpoints 按序号顺序返回全部检查点
+	GetCheckpoints() ([]*models.AuditCheckpoint, error)
+}
@@ -0,0 +1,37 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// ChangeRequestRepository 定义需要双人审批的变更申请的数据访问接口
+// 申请属于组织，只能由该组织的管理员处理
+type ChangeRequestRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) ChangeRequestRepository
+
+	// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+	ForOrganization(orgID int) ChangeRequestRepository
+
+	// Create 创建申请，申请属于仓库限定的组织（未限定时属于默认组织）
+	Create(request *models.ChangeRequest) error
+
+	// GetByID 根据ID获取申请，未找到时返回 nil
+	GetByID(id int) (*models.ChangeRequest, error)
+
+	// GetAll 获取所有申请，最近创建的在前
+	GetAll() ([]*models.ChangeRequest, error)
+
+	// GetPendingByTarget 获取针对该用户且仍在等待审批的申请，没有时返回 nil
+	GetPendingByTarget(targetID int) (*models.ChangeRequest, error)
+
+	// Approve 批准申请并应用其中的变更，二者在同一事务中完成
+	// 锁定申请行和所有正常状态的管理员后调用 check 按最新状态重新校验，check 返回错误时不批准并原样返回该错误；
+	// 申请已被处理、已过期或目标用户已不存在时返回 sql.ErrNoRows，变更不会被应用
+	Approve(id, deciderID int, check func(req *models.ChangeRequest) error) error
+
+	// Reject 拒绝或撤回仍在等待审批的申请
+	Reject(id, deciderID int) error
+}
@@ -0,0 +1,25 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// EmailVerificationRepository 定义邮箱验证记录的数据访问接口
+type EmailVerificationRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) EmailVerificationRepository
+
+	// Create 创建验证记录
+	Create(verification *models.EmailVerification) error
+
+	// GetByTokenHash 根据令牌哈希获取验证记录
+	GetByTokenHash(tokenHash string) (*models.EmailVerification, error)
+
+	// GetPendingByUserID 获取用户最近一条未过期的验证记录
+	GetPendingByUserID(userID int) (*models.EmailVerification, error)
+
+	// DeleteByUserID 删除用户的全部验证记录
+	DeleteByUserID(userID int) error
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// GroupRepository 定义用户组的数据访问接口
+// 用户组属于组织，组名只需在组织内唯一
+type GroupRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) GroupRepository
+
+	// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+	ForOrganization(orgID int) GroupRepository
+
+	// GetAll 获取所有用户组及其角色和直接成员数，按组名排序
+	GetAll() ([]*models.Group, error)
+
+	// GetByID 根据ID获取用户组，未找到时返回 nil
+	GetByID(id int) (*models.Group, error)
+
+	// GetByName 根据组名获取用户组，未找到时返回 nil
+	GetByName(name string) (*models.Group, error)
+
+	// Create 创建用户组并授予 group.Roles 中的角色，组属于仓库限定的组织（未限定时属于默认组织）
+	Create(group *models.Group) error
+
+	// Update 修改用户组的名称、说明和上级组，并将角色替换为 group.Roles
+	Update(group *models.Group) error
+
+	// Delete 删除用户组，其下级组成为顶级组，成员关系随之移除
+	Delete(id int) error
+
+	// AddMembers 将用户加入组，已是成员的用户以及不属于该组所在组织的用户会被忽略
+	AddMembers(groupID int, userIDs []int) error
+
+	// RemoveMember 将用户移出组
+	RemoveMember(groupID, userID int) error
+}
@@ -0,0 +1,38 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"user-management-system/models"
+)
+
+// InvitationRepository 定义注册邀请的数据访问接口
+type InvitationRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) InvitationRepository
+
+	// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+	ForOrganization(orgID int) InvitationRepository
+
+	// Create 创建邀请，被邀请人注册后加入仓库限定的组织（未限定时加入默认组织）
+	Create(invitation *models.Invitation) error
+
+	// GetByID 根据ID获取邀请
+	GetByID(id int) (*models.Invitation, error)
+
+	// GetByTokenHash 根据令牌哈希获取邀请，不受组织限定
+	GetByTokenHash(tokenHash string) (*models.Invitation, error)
+
+	// GetPendingByEmail 获取发给该邮箱且仍然有效的邀请
+	GetPendingByEmail(email string) (*models.Invitation, error)
+
+	// GetAll 获取所有邀请，最近创建的在前
+	GetAll() ([]*models.Invitation, error)
+
+	// UpdateToken 重新发送邀请时更换令牌并延长有效期
+	UpdateToken(id int, tokenHash string, expiresAt time.Time) error
+
+	// Revoke 撤销邀请
+	Revoke(id int) error
+}
@@ -0,0 +1,40 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// OrganizationRepository 定义组织（租户）的数据访问接口
+type OrganizationRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) OrganizationRepository
+
+	// GetAll 获取所有组织及其成员数，按标识排序
+	GetAll() ([]*models.Organization, error)
+
+	// GetByID 根据ID获取组织，未找到时返回 nil
+	GetByID(id int) (*models.Organization, error)
+
+	// GetBySlug 根据标识获取组织，未找到时返回 nil
+	GetBySlug(slug string) (*models.Organization, error)
+
+	// GetForUser 获取用户所属的组织，按标识排序
+	GetForUser(userID int) ([]*models.Organization, error)
+
+	// Create 创建组织
+	Create(org *models.Organization) error
+
+	// Update 修改组织的标识和名称
+	Update(org *models.Organization) error
+
+	// Delete 删除组织及其用户组、邀请和在该组织中分配的角色，成员关系通过外键级联移除
+	Delete(id int) error
+
+	// AddMember 将用户加入组织并在该组织中分配普通用户角色，已是成员时忽略
+	AddMember(orgID, userID int) error
+
+	// RemoveMember 将用户移出组织，同时移出该组织的所有用户组并撤销在该组织中分配的角色
+	RemoveMember(orgID, userID int) error
+}
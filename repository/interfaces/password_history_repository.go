@@ -0,0 +1,18 @@
+package interfaces
+
+import "context"
+
+// PasswordHistoryRepository 定义密码历史的数据访问接口
+type PasswordHistoryRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) PasswordHistoryRepository
+
+	// Add 记录一个用过的密码哈希
+	Add(userID int, passwordHash string) error
+
+	// GetRecent 获取用户最近使用过的 limit 个密码哈希（从新到旧）
+	GetRecent(userID int, limit int) ([]string, error)
+
+	// Prune 只保留用户最近的 keep 条记录
+	Prune(userID int, keep int) error
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// PasswordTokenRepository 定义设置密码链接的数据访问接口
+type PasswordTokenRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) PasswordTokenRepository
+
+	// Create 创建设置密码链接记录
+	Create(token *models.PasswordToken) error
+
+	// GetByTokenHash 根据令牌哈希获取记录
+	GetByTokenHash(tokenHash string) (*models.PasswordToken, error)
+
+	// DeleteByUserID 删除用户的全部设置密码链接
+	DeleteByUserID(userID int) error
+}
@@ -0,0 +1,32 @@
+package interfaces
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// RoleRepository 定义角色的数据访问接口
+type RoleRepository interface {
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) RoleRepository
+
+	// GetAll 获取所有角色及其权限，内置角色在前
+	GetAll() ([]*models.Role, error)
+
+	// GetByName 根据角色标识获取角色及其权限，未找到时返回 nil
+	GetByName(name string) (*models.Role, error)
+
+	// GetByID 根据ID获取角色及其权限，未找到时返回 nil
+	GetByID(id int) (*models.Role, error)
+
+	// Create 创建角色并写入其权限
+	Create(role *models.Role) error
+
+	// Update 修改角色的标识和说明，并将权限替换为 role.Permissions
+	// 改名时同步更新引用旧标识的注册邀请
+	Update(role *models.Role, oldName string) error
+
+	// Delete 删除角色，用户持有的该角色随之移除
+	Delete(id int) error
+}
@@ -1,12 +1,32 @@
 package interfaces
 
-import "user-management-system/models"
+import (
+	"context"
+	"time"
+
+	"user-management-system/models"
+)
 
 // UserRepository 定义用户数据访问接口
+// 除回收站相关的方法外，所有方法都只作用于未删除的用户
 type UserRepository interface {
-	//Creat 创建用户
+	// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+	WithContext(ctx context.Context) UserRepository
+
+	// ForOrganization 返回限定在指定组织成员内的仓库，用户的角色和权限按该组织计算，orgID 为 0 时不限定
+	ForOrganization(orgID int) UserRepository
+
+	// WithRolesIn 返回按指定组织计算用户角色、用户组和权限的仓库，查询不限定该组织的成员
+	// orgID 为 0 时按所有组织计算
+	WithRolesIn(orgID int) UserRepository
+
+	//Creat 创建用户，加入仓库限定的组织（未限定时加入默认组织），并在该组织中分配 user.Roles 中的角色
 	Create(user *models.User) error
 
+	// CreateWithInvitation 与 Create 相同，并将邀请标记为已被该用户接受，二者在同一事务中完成
+	// 邀请已被接受、已撤销或已过期时返回 sql.ErrNoRows，用户不会被创建
+	CreateWithInvitation(user *models.User, invitationID int) error
+
 	//GetByID 根据ID获取用户
 	GetByID(id int) (*models.User, error)
 
@@ -22,12 +42,57 @@ type UserRepository interface {
 	// Update 更新用户信息
 	Update(user *models.User) error
 
-	// UpdateEmailAndRole 更新用户邮箱和角色
-	UpdateEmailAndRole(id int, email, role string) error
+	// UpdateEmailAndRoles 更新用户邮箱，并将用户在限定组织中的角色（包括平台级角色）替换为 roles
+	UpdateEmailAndRoles(id int, email string, roles []string) error
+
+	// UpdateEmail 更新用户邮箱
+	UpdateEmail(id int, email string) error
+
+	// UpdatePassword 更新用户密码，同时记录密码修改时间并清除强制修改标记
+	UpdatePassword(id int, hashedPassword string) error
+
+	// ResetPassword 由管理员重置用户密码，mustChange 表示用户下次登录时必须修改密码
+	ResetPassword(id int, hashedPassword string, mustChange bool) error
+
+	// UpdatePasswordHash 只替换密码哈希（用于算法升级），不改变密码修改时间
+	UpdatePasswordHash(id int, hashedPassword string) error
+
+	// UpdateLastLogin 记录用户最近登录时间
+	UpdateLastLogin(id int, at time.Time) error
+
+	// UpdateStatus 更新账户状态及原因
+	UpdateStatus(id int, status, reason string) error
+
+	// GetByStatus 获取指定账户状态的用户，最早创建的在前
+	GetByStatus(status string) ([]*models.User, error)
+
+	// Approve 通过待审批的注册，将账户置为正常状态
+	Approve(id int) error
 
-	// Delete 删除用户
+	// Reject 拒绝待审批的注册：记录原因并将用户移入回收站
+	Reject(id int, reason string) error
+
+	// Delete 软删除用户（移入回收站）
 	Delete(id int) error
 
+	// GetDeleted 获取回收站中的所有用户
+	GetDeleted() ([]*models.User, error)
+
+	// GetDeletedByID 根据ID获取回收站中的用户
+	GetDeletedByID(id int) (*models.User, error)
+
+	// Restore 从回收站恢复用户
+	Restore(id int) error
+
+	// PurgeDeletedBefore 永久删除在 before 之前移入回收站的用户
+	PurgeDeletedBefore(before time.Time) (int64, error)
+
+	// ExistsDeleted 检查回收站中是否有使用该用户名的用户
+	ExistsDeleted(username string) (bool, error)
+
+	// ExistsDeletedByEmail 检查回收站中是否有使用该邮箱的用户
+	ExistsDeletedByEmail(email string) (bool, error)
+
 	// Exists 检查用户是否存在
 	Exists(username string) (bool, error)
 
@@ -39,4 +104,19 @@ type UserRepository interface {
 
 	// CountByRole 根据角色统计用户数
 	CountByRole(role string) (int64, error)
+
+	// CountGroupByRole 按角色统计直接持有该角色的用户数（不含已删除的用户），没有用户的角色计为0
+	CountGroupByRole() (map[string]int64, error)
+
+	// CountByPermissionAndStatus 统计通过角色（包括从用户组继承的角色）拥有指定权限且处于指定账户状态的用户数
+	CountByPermissionAndStatus(permission, status string) (int64, error)
+
+	// CountByPermissionAndStatusExcludingRole 统计不依靠 roleID 这个角色、通过其他角色拥有指定权限且处于指定账户状态的用户数
+	CountByPermissionAndStatusExcludingRole(permission, status string, roleID int) (int64, error)
+
+	// GetByRoleID 获取直接持有或通过用户组继承指定角色的用户
+	GetByRoleID(roleID int) ([]*models.User, error)
+
+	// GetByGroupID 获取指定组的成员，nested 为 true 时同时包括所有下级组的成员
+	GetByGroupID(groupID int, nested bool) ([]*models.User, error)
 }
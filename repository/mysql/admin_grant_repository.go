@@ -0,0 +1,215 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// adminGrantSelect 查询授权及其操作（以逗号拼接），顺序与 scanAdminGrant 保持一致
+const adminGrantSelect = `
+	SELECT ag.id, r.name, COALESCE(sr.name, ''), COALESCE(ag.scope_group_id, 0), COALESCE(g.name, ''),
+		COALESCE(u.username, ''), ag.created_at,
+		COALESCE((SELECT GROUP_CONCAT(ap.permission ORDER BY ap.permission SEPARATOR ',')
+			FROM admin_grant_permissions ap WHERE ap.grant_id = ag.id), '')
+	FROM admin_grants ag
+	JOIN roles r ON r.id = ag.role_id
+	LEFT JOIN roles sr ON sr.id = ag.scope_role_id
+	LEFT JOIN user_groups g ON g.id = ag.scope_group_id
+	LEFT JOIN users u ON u.id = ag.created_by`
+
+// adminGrantRepository MySQL实现的委派管理授权仓库
+type adminGrantRepository struct {
+	db    *tracedDB
+	orgID int // 限定的组织，0 表示不限定
+}
+
+// NewAdminGrantRepository 创建MySQL委派管理授权仓库实例
+func NewAdminGrantRepository(db *sql.DB) interfaces.AdminGrantRepository {
+	return &adminGrantRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+func (r *adminGrantRepository) ForOrganization(orgID int) interfaces.AdminGrantRepository {
+	return &adminGrantRepository{db: r.db, orgID: orgID}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *adminGrantRepository) WithContext(ctx context.Context) interfaces.AdminGrantRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// inOrg 返回限定组织的查询条件（以 AND 开头），column 为组织ID列；未限定组织时返回空串
+func (r *adminGrantRepository) inOrg(column string) string {
+	if r.orgID == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s = %d", column, r.orgID)
+}
+
+// scanAdminGrant 将一行查询结果扫描为授权模型
+func scanAdminGrant(row rowScanner) (*models.AdminGrant, error) {
+	grant := &models.AdminGrant{}
+	var permissions string
+
+	err := row.Scan(
+		&grant.ID,
+		&grant.Role,
+		&grant.ScopeRole,
+		&grant.ScopeGroupID,
+		&grant.ScopeGroup,
+		&grant.CreatedBy,
+		&grant.CreatedAt,
+		&permissions,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	grant.Permissions = splitList(permissions)
+	return grant, nil
+}
+
+// GetAll 获取所有授权，按创建时间排序
+func (r *adminGrantRepository) GetAll() ([]*models.AdminGrant, error) {
+	rows, err := r.db.Query(adminGrantSelect + ` WHERE 1 = 1` + r.inOrg("ag.organization_id") + ` ORDER BY ag.created_at, ag.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []*models.AdminGrant
+	for rows.Next() {
+		grant, err := scanAdminGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// GetByID 根据ID获取授权，未找到时返回 nil
+func (r *adminGrantRepository) GetByID(id int) (*models.AdminGrant, error) {
+	grant, err := scanAdminGrant(r.db.QueryRow(adminGrantSelect+` WHERE ag.id = ?`+r.inOrg("ag.organization_id"), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return grant, nil
+}
+
+// Create 创建授权及其操作，授权属于仓库限定的组织（未限定时属于默认组织）
+func (r *adminGrantRepository) Create(grant *models.AdminGrant, createdBy int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	orgID := r.orgID
+	if orgID == 0 {
+		if err := tx.QueryRow(`SELECT id FROM organizations WHERE slug = ?`, models.DefaultOrganization).Scan(&orgID); err != nil {
+			return err
+		}
+	}
+
+	var scopeRole, scopeGroup interface{}
+	if grant.ScopeRole != "" {
+		scopeRole = grant.ScopeRole
+	}
+	if grant.ScopeGroupID != 0 {
+		scopeGroup = grant.ScopeGroupID
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO admin_grants (organization_id, role_id, scope_role_id, scope_group_id, created_by, created_at)
+		VALUES (?, (SELECT id FROM roles WHERE name = ?), (SELECT id FROM roles WHERE name = ?), ?, ?, ?)`,
+		orgID, grant.Role, scopeRole, scopeGroup, createdBy, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if len(grant.Permissions) > 0 {
+		query := `INSERT INTO admin_grant_permissions (grant_id, permission) VALUES (?, ?)` +
+			strings.Repeat(", (?, ?)", len(grant.Permissions)-1)
+		args := make([]interface{}, 0, 2*len(grant.Permissions))
+		for _, permission := range grant.Permissions {
+			args = append(args, id, permission)
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	grant.ID = int(id)
+	grant.CreatedAt = now
+	return nil
+}
+
+// Delete 删除授权，授权的操作通过外键级联移除
+func (r *adminGrantRepository) Delete(id int) error {
+	return execAffectingOne(r.db, `DELETE FROM admin_grants WHERE id = ?`+r.inOrg("organization_id"), id)
+}
+
+// DelegatedActions 返回 actorID 通过授权可以对各用户执行的操作（用户ID -> 操作列表）
+// 操作者和目标用户持有的角色都按授权所在的组织计算，包括通过该组织的用户组继承的角色；范围组包括其下级组
+func (r *adminGrantRepository) DelegatedActions(actorID, targetID int) (map[int][]string, error) {
+	query := `
+		SELECT DISTINCT om.user_id, ap.permission
+		FROM admin_grants ag
+		JOIN admin_grant_permissions ap ON ap.grant_id = ag.id
+		JOIN organization_members om ON om.organization_id = ag.organization_id
+		WHERE ` + holdsRoleIn("?", "ag.role_id", "ag.organization_id") + ` AND (
+			` + holdsRoleIn("om.user_id", "ag.scope_role_id", "ag.organization_id") + `
+			OR EXISTS (SELECT 1 FROM group_members gm JOIN group_closure gc ON gc.descendant_id = gm.group_id
+				WHERE gm.user_id = om.user_id AND gc.ancestor_id = ag.scope_group_id)
+		)` + r.inOrg("ag.organization_id")
+	args := []interface{}{actorID, actorID}
+	if targetID != 0 {
+		query += ` AND om.user_id = ?`
+		args = append(args, targetID)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := make(map[int][]string)
+	for rows.Next() {
+		var userID int
+		var permission string
+		if err := rows.Scan(&userID, &permission); err != nil {
+			return nil, err
+		}
+		actions[userID] = append(actions[userID], permission)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
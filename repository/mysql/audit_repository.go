@@ -0,0 +1,347 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"user-management-system/audit"
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// auditColumns 查询审计事件时统一使用的列，顺序与 scanAuditEvent 保持一致
+const auditColumns = `id, seq, organization_id, occurred_at, COALESCE(actor_id, 0), actor_name, COALESCE(impersonator_id, 0), impersonator_name,
+	action, target_type, COALESCE(target_id, 0), target_name, before_value, after_value, ip, user_agent, request_id, outcome, error,
+	prev_hash, hash`
+
+// checkpointColumns 查询检查点时统一使用的列，顺序与 scanAuditCheckpoint 保持一致
+const checkpointColumns = `id, seq, event_id, event_hash, key_id, signature, created_at`
+
+// auditRepository MySQL实现的审计事件仓库
+type auditRepository struct {
+	db    *tracedDB
+	orgID int // 限定的组织，0 表示不限定
+}
+
+// NewAuditRepository 创建MySQL审计事件仓库实例
+func NewAuditRepository(db *sql.DB) interfaces.AuditRepository {
+	return &auditRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+func (r *auditRepository) ForOrganization(orgID int) interfaces.AuditRepository {
+	return &auditRepository{db: r.db, orgID: orgID}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *auditRepository) WithContext(ctx context.Context) interfaces.AuditRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// scanAuditEvent 将一行查询结果扫描为审计事件模型
+func scanAuditEvent(row rowScanner) (*models.AuditEvent, error) {
+	event := &models.AuditEvent{}
+	var before, after []byte
+
+	err := row.Scan(
+		&event.ID,
+		&event.Seq,
+		&event.OrgID,
+		&event.OccurredAt,
+		&event.ActorID,
+		&event.ActorName,
+		&event.ImpersonatorID,
+		&event.ImpersonatorName,
+		&event.Action,
+		&event.TargetType,
+		&event.TargetID,
+		&event.TargetName,
+		&before,
+		&after,
+		&event.IP,
+		&event.UserAgent,
+		&event.RequestID,
+		&event.Outcome,
+		&event.Error,
+		&event.PrevHash,
+		&event.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(before) > 0 {
+		event.Before = before
+	}
+	if len(after) > 0 {
+		event.After = after
+	}
+	return event, nil
+}
+
+// Create 把审计事件追加到哈希链末端，event.OrgID 为 0 时归入默认组织
+// 在同一事务中锁定并更新 audit_chain，保证并发写入时序号连续、每条事件都链接到前一条
+func (r *auditRepository) Create(event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (seq, organization_id, occurred_at, actor_id, actor_name, impersonator_id, impersonator_name,
+			action, target_type, target_id, target_name, before_value, after_value, ip, user_agent, request_id, outcome, error,
+			prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if event.OrgID == 0 {
+		if err := r.db.QueryRow(`SELECT id FROM organizations WHERE slug = ?`, models.DefaultOrganization).Scan(&event.OrgID); err != nil {
+			return err
+		}
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	// 与数据库保存的精度一致，读出后才能复算出相同的哈希
+	event.OccurredAt = audit.Timestamp(event.OccurredAt)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var lastSeq int64
+	if err := tx.QueryRow(`SELECT last_seq, last_hash FROM audit_chain WHERE id = 1 FOR UPDATE`).Scan(&lastSeq, &event.PrevHash); err != nil {
+		return err
+	}
+	event.Seq = lastSeq + 1
+	event.Hash = audit.EventHash(event)
+
+	result, err := tx.Exec(query,
+		event.Seq, event.OrgID, event.OccurredAt, nullableID(event.ActorID), event.ActorName,
+		nullableID(event.ImpersonatorID), event.ImpersonatorName,
+		event.Action, event.TargetType, nullableID(event.TargetID), event.TargetName,
+		nullableJSON(event.Before), nullableJSON(event.After),
+		event.IP, event.UserAgent, event.RequestID, event.Outcome, event.Error,
+		event.PrevHash, event.Hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE audit_chain SET last_seq = ?, last_event_id = ?, last_hash = ? WHERE id = 1`,
+		event.Seq, id, event.Hash); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	event.ID = id
+	return nil
+}
+
+// Find 按条件查询审计事件，最近发生的在前，返回 offset 开始的至多 limit 条以及符合条件的总数
+func (r *auditRepository) Find(filter *models.AuditFilter, limit, offset int) ([]*models.AuditEvent, int, error) {
+	where, args := r.where(filter)
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_events`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_events` + where + ` ORDER BY occurred_at DESC, id DESC LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// Each 按发生时间顺序遍历符合条件的全部审计事件（用于导出），fn 返回错误时停止遍历
+func (r *auditRepository) Each(filter *models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	where, args := r.where(filter)
+
+	rows, err := r.db.Query(`SELECT `+auditColumns+` FROM audit_events`+where+` ORDER BY occurred_at, id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ChainHead 返回哈希链的末端
+func (r *auditRepository) ChainHead() (*models.AuditChainHead, error) {
+	head := &models.AuditChainHead{}
+	err := r.db.QueryRow(`SELECT last_seq, last_event_id, last_hash FROM audit_chain WHERE id = 1`).
+		Scan(&head.Seq, &head.EventID, &head.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// EachInChain 按序号顺序遍历哈希链中的全部事件（不受组织限定），fn 返回错误时停止遍历
+func (r *auditRepository) EachInChain(fn func(*models.AuditEvent) error) error {
+	rows, err := r.db.Query(`SELECT ` + auditColumns + ` FROM audit_events ORDER BY seq`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanAuditCheckpoint 将一行查询结果扫描为检查点模型
+func scanAuditCheckpoint(row rowScanner) (*models.AuditCheckpoint, error) {
+	cp := &models.AuditCheckpoint{}
+	err := row.Scan(&cp.ID, &cp.Seq, &cp.EventID, &cp.EventHash, &cp.KeyID, &cp.Signature, &cp.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// CreateCheckpoint 写入签名检查点；该序号已有检查点时不写入并返回 false
+func (r *auditRepository) CreateCheckpoint(cp *models.AuditCheckpoint) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT IGNORE INTO audit_checkpoints (seq, event_id, event_hash, key_id, signature, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, cp.Seq, cp.EventID, cp.EventHash, cp.KeyID, cp.Signature, cp.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	cp.ID, err = result.LastInsertId()
+	return true, err
+}
+
+// GetLatestCheckpoint 返回序号最大的检查点，没有检查点时返回 nil
+func (r *auditRepository) GetLatestCheckpoint() (*models.AuditCheckpoint, error) {
+	cp, err := scanAuditCheckpoint(r.db.QueryRow(`SELECT ` + checkpointColumns + ` FROM audit_checkpoints ORDER BY seq DESC LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return cp, err
+}
+
+// GetCheckpoints 按序号顺序返回全部检查点
+func (r *auditRepository) GetCheckpoints() ([]*models.AuditCheckpoint, error) {
+	rows, err := r.db.Query(`SELECT ` + checkpointColumns + ` FROM audit_checkpoints ORDER BY seq`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []*models.AuditCheckpoint
+	for rows.Next() {
+		cp, err := scanAuditCheckpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+// where 根据查询条件和限定的组织生成 WHERE 子句及参数
+func (r *auditRepository) where(filter *models.AuditFilter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if r.orgID != 0 {
+		conds = append(conds, "organization_id = ?")
+		args = append(args, r.orgID)
+	}
+	if filter.Actor != "" {
+		conds = append(conds, "(actor_name = ? OR impersonator_name = ?)")
+		args = append(args, filter.Actor, filter.Actor)
+	}
+	if filter.Action != "" {
+		conds = append(conds, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.TargetType != "" {
+		conds = append(conds, "target_type = ?")
+		args = append(args, filter.TargetType)
+		if filter.TargetID != 0 {
+			conds = append(conds, "target_id = ?")
+			args = append(args, filter.TargetID)
+		}
+	}
+	if filter.Outcome != "" {
+		conds = append(conds, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		conds = append(conds, "occurred_at >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "occurred_at < ?")
+		args = append(args, filter.To)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// nullableID 将 0 转换为 NULL
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// nullableJSON 将空的 JSON 值转换为 NULL，非空时按原文保存
+func nullableJSON(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
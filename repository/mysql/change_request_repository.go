@@ -0,0 +1,243 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// changeRequestSelect 查询申请时统一使用的语句，顺序与 scanChangeRequest 保持一致
+const changeRequestSelect = `
+	SELECT cr.id, cr.action, cr.target_id, COALESCE(t.username, ''), cr.email, cr.roles, cr.organization_id,
+		COALESCE(cr.requested_by, 0), COALESCE(ru.username, ''), COALESCE(cr.decided_by, 0), COALESCE(du.username, ''),
+		cr.created_at, cr.expires_at, cr.approved_at, cr.rejected_at
+	FROM change_requests cr
+	LEFT JOIN users t ON t.id = cr.target_id
+	LEFT JOIN users ru ON ru.id = cr.requested_by
+	LEFT JOIN users du ON du.id = cr.decided_by`
+
+// changeRequestPending 仍在等待审批的申请的查询条件
+const changeRequestPending = `cr.approved_at IS NULL AND cr.rejected_at IS NULL AND cr.expires_at > ?`
+
+// changeRequestRepository MySQL实现的变更申请仓库
+type changeRequestRepository struct {
+	db    *tracedDB
+	orgID int // 限定的组织，0 表示不限定
+}
+
+// NewChangeRequestRepository 创建MySQL变更申请仓库实例
+func NewChangeRequestRepository(db *sql.DB) interfaces.ChangeRequestRepository {
+	return &changeRequestRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+func (r *changeRequestRepository) ForOrganization(orgID int) interfaces.ChangeRequestRepository {
+	return &changeRequestRepository{db: r.db, orgID: orgID}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *changeRequestRepository) WithContext(ctx context.Context) interfaces.ChangeRequestRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// inOrg 返回限定组织的查询条件（以 AND 开头），column 为组织ID列；未限定组织时返回空串
+func (r *changeRequestRepository) inOrg(column string) string {
+	if r.orgID == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s = %d", column, r.orgID)
+}
+
+// scanChangeRequest 将一行查询结果扫描为变更申请模型
+func scanChangeRequest(row rowScanner) (*models.ChangeRequest, error) {
+	req := &models.ChangeRequest{}
+	var roles string
+	var approvedAt, rejectedAt sql.NullTime
+
+	err := row.Scan(
+		&req.ID,
+		&req.Action,
+		&req.TargetID,
+		&req.TargetName,
+		&req.Email,
+		&roles,
+		&req.OrgID,
+		&req.RequestedBy,
+		&req.RequesterName,
+		&req.DecidedBy,
+		&req.DeciderName,
+		&req.CreatedAt,
+		&req.ExpiresAt,
+		&approvedAt,
+		&rejectedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Roles = splitList(roles)
+	if approvedAt.Valid {
+		req.ApprovedAt = &approvedAt.Time
+	}
+	if rejectedAt.Valid {
+		req.RejectedAt = &rejectedAt.Time
+	}
+	return req, nil
+}
+
+// Create 创建申请，申请属于仓库限定的组织（未限定时属于默认组织）
+func (r *changeRequestRepository) Create(req *models.ChangeRequest) error {
+	query := `
+		INSERT INTO change_requests (organization_id, action, target_id, email, roles, requested_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	orgID := r.orgID
+	if orgID == 0 {
+		if err := r.db.QueryRow(`SELECT id FROM organizations WHERE slug = ?`, models.DefaultOrganization).Scan(&orgID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	result, err := r.db.Exec(query, orgID, req.Action, req.TargetID, req.Email, strings.Join(req.Roles, ","),
+		req.RequestedBy, now, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	req.ID = int(id)
+	req.OrgID = orgID
+	req.CreatedAt = now
+	return nil
+}
+
+// GetByID 根据ID获取申请，未找到时返回 nil
+func (r *changeRequestRepository) GetByID(id int) (*models.ChangeRequest, error) {
+	return r.getOne(changeRequestSelect+` WHERE cr.id = ?`+r.inOrg("cr.organization_id"), id)
+}
+
+// GetAll 获取所有申请，最近创建的在前
+func (r *changeRequestRepository) GetAll() ([]*models.ChangeRequest, error) {
+	rows, err := r.db.Query(changeRequestSelect + ` WHERE 1 = 1` + r.inOrg("cr.organization_id") + ` ORDER BY cr.created_at DESC, cr.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*models.ChangeRequest
+	for rows.Next() {
+		req, err := scanChangeRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetPendingByTarget 获取针对该用户且仍在等待审批的申请，没有时返回 nil
+func (r *changeRequestRepository) GetPendingByTarget(targetID int) (*models.ChangeRequest, error) {
+	query := changeRequestSelect + `
+		WHERE cr.target_id = ? AND ` + changeRequestPending + r.inOrg("cr.organization_id") + `
+		ORDER BY cr.created_at DESC
+		LIMIT 1`
+	return r.getOne(query, targetID, time.Now())
+}
+
+// lockActiveAdmins 锁定所有正常状态、拥有 users:manage 权限的用户行
+// 删除、停用用户和修改用户角色都会先修改或锁定用户行，因此在事务提交前管理员的数量不会被其他事务减少
+const lockActiveAdmins = `
+	SELECT u.id FROM users u
+	WHERE u.status = ? AND u.deleted_at IS NULL AND u.id IN (
+		SELECT er.user_id FROM (` + allUserRoles + `) er
+		JOIN role_permissions rp ON rp.role_id = er.role_id
+		WHERE rp.permission = ?)
+	FOR UPDATE`
+
+// Approve 批准申请并应用其中的变更，二者在同一事务中完成
+// 锁定申请行和所有正常状态的管理员后调用 check 按最新状态重新校验，check 返回错误时不批准并原样返回该错误；
+// 申请已被处理、已过期或目标用户已不存在时返回 sql.ErrNoRows，变更不会被应用
+func (r *changeRequestRepository) Approve(id, deciderID int, check func(req *models.ChangeRequest) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 锁定申请行，避免两位管理员同时批准
+	req, err := scanChangeRequest(tx.QueryRow(changeRequestSelect+`
+		WHERE cr.id = ? AND `+changeRequestPending+r.inOrg("cr.organization_id")+` FOR UPDATE`, id, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	// 锁定管理员后再校验，校验时统计的管理员数量（如“不能删除最后一个管理员”）在提交前一直有效
+	rows, err := tx.Query(lockActiveAdmins, models.StatusActive, models.PermUsersManage)
+	if err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := check(req); err != nil {
+		return err
+	}
+
+	query := `UPDATE change_requests SET approved_at = ?, decided_by = ? WHERE id = ?`
+	if err := execAffectingOne(tx, query, time.Now(), deciderID, req.ID); err != nil {
+		return err
+	}
+
+	// 申请与目标用户属于同一组织，角色在该组织中替换
+	switch req.Action {
+	case models.ChangeActionPromoteAdmin:
+		err = updateEmailAndRoles(tx, req.TargetID, req.OrgID, req.Email, req.Roles)
+	case models.ChangeActionDeleteAdmin:
+		err = softDeleteUser(tx, req.TargetID, orgMemberCond("id", req.OrgID))
+	default:
+		err = fmt.Errorf("未知的变更操作: %s", req.Action)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Reject 拒绝或撤回仍在等待审批的申请
+func (r *changeRequestRepository) Reject(id, deciderID int) error {
+	query := `
+		UPDATE change_requests SET rejected_at = ?, decided_by = ?
+		WHERE id = ? AND approved_at IS NULL AND rejected_at IS NULL AND expires_at > ?` + r.inOrg("organization_id")
+	now := time.Now()
+	return execAffectingOne(r.db, query, now, deciderID, id, now)
+}
+
+// getOne 执行单行查询，未找到时返回 nil, nil
+func (r *changeRequestRepository) getOne(query string, args ...interface{}) (*models.ChangeRequest, error) {
+	req, err := scanChangeRequest(r.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return req, nil
+}
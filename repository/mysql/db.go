@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"user-management-system/tracing"
+)
+
+// tracedDB 仓库使用的数据库连接：语句通过 ctx 执行，ctx 中有活动的 span 时为每个语句记录一个子 span
+// 没有父 span 的语句（如中间件、后台任务中的查询）不单独开始追踪，避免产生大量只有一条 SQL 的追踪
+type tracedDB struct {
+	*sql.DB
+	ctx context.Context
+}
+
+// newTracedDB 包装数据库连接，初始时没有父 span
+func newTracedDB(db *sql.DB) *tracedDB {
+	return &tracedDB{DB: db, ctx: context.Background()}
+}
+
+// withContext 返回在 ctx 中执行语句的连接
+// ctx 只用于传递追踪信息：请求被取消（如客户端断开）时不中断已经开始的语句和事务，避免操作只完成一半
+func (db *tracedDB) withContext(ctx context.Context) *tracedDB {
+	return &tracedDB{DB: db.DB, ctx: context.WithoutCancel(ctx)}
+}
+
+// Query 执行查询；span 只覆盖语句的执行，不包括读取结果
+func (db *tracedDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	span := startQuerySpan(db.ctx, query)
+	rows, err := db.DB.QueryContext(db.ctx, query, args...)
+	span.EndWithError(err)
+	return rows, err
+}
+
+// QueryRow 执行只返回一行的查询
+func (db *tracedDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	span := startQuerySpan(db.ctx, query)
+	row := db.DB.QueryRowContext(db.ctx, query, args...)
+	span.EndWithError(row.Err())
+	return row
+}
+
+// Exec 执行不返回结果的语句
+func (db *tracedDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	span := startQuerySpan(db.ctx, query)
+	result, err := db.DB.ExecContext(db.ctx, query, args...)
+	span.EndWithError(err)
+	return result, err
+}
+
+// Begin 开始事务，事务中的语句同样在 ctx 中执行并记录 span
+func (db *tracedDB) Begin() (*tracedTx, error) {
+	tx, err := db.DB.BeginTx(db.ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, ctx: db.ctx}, nil
+}
+
+// tracedTx 记录 span 的事务
+type tracedTx struct {
+	*sql.Tx
+	ctx context.Context
+}
+
+// Query 在事务中执行查询
+func (tx *tracedTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	span := startQuerySpan(tx.ctx, query)
+	rows, err := tx.Tx.QueryContext(tx.ctx, query, args...)
+	span.EndWithError(err)
+	return rows, err
+}
+
+// QueryRow 在事务中执行只返回一行的查询
+func (tx *tracedTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	span := startQuerySpan(tx.ctx, query)
+	row := tx.Tx.QueryRowContext(tx.ctx, query, args...)
+	span.EndWithError(row.Err())
+	return row
+}
+
+// Exec 在事务中执行不返回结果的语句
+func (tx *tracedTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	span := startQuerySpan(tx.ctx, query)
+	result, err := tx.Tx.ExecContext(tx.ctx, query, args...)
+	span.EndWithError(err)
+	return result, err
+}
+
+// startQuerySpan 在 ctx 有父 span 时开始一个 SQL span，否则返回 nil
+func startQuerySpan(ctx context.Context, query string) *tracing.Span {
+	if !tracing.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	_, span := tracing.Start(ctx, "SQL "+tracing.SQLOperation(query), tracing.KindClient,
+		tracing.Attr{Key: "db.system", Value: "mysql"},
+	)
+	if span.IsRecording() {
+		span.SetAttributes(tracing.Attr{Key: "db.statement", Value: tracing.SanitizeSQL(query)})
+	}
+	return span
+}
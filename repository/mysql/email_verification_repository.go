@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// emailVerificationRepository MySQL实现的邮箱验证仓库
+type emailVerificationRepository struct {
+	db *tracedDB
+}
+
+// NewEmailVerificationRepository 创建MySQL邮箱验证仓库实例
+func NewEmailVerificationRepository(db *sql.DB) interfaces.EmailVerificationRepository {
+	return &emailVerificationRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *emailVerificationRepository) WithContext(ctx context.Context) interfaces.EmailVerificationRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// Create 创建验证记录
+func (r *emailVerificationRepository) Create(v *models.EmailVerification) error {
+	query := `
+		INSERT INTO email_verifications (user_id, email, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, v.UserID, v.Email, v.TokenHash, now, v.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	v.ID = int(id)
+	v.CreatedAt = now
+	return nil
+}
+
+// GetByTokenHash 根据令牌哈希获取验证记录
+func (r *emailVerificationRepository) GetByTokenHash(tokenHash string) (*models.EmailVerification, error) {
+	query := `
+		SELECT id, user_id, email, token_hash, created_at, expires_at
+		FROM email_verifications
+		WHERE token_hash = ?
+	`
+	return r.getOne(query, tokenHash)
+}
+
+// GetPendingByUserID 获取用户最近一条未过期的验证记录
+func (r *emailVerificationRepository) GetPendingByUserID(userID int) (*models.EmailVerification, error) {
+	query := `
+		SELECT id, user_id, email, token_hash, created_at, expires_at
+		FROM email_verifications
+		WHERE user_id = ? AND expires_at > ?
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	return r.getOne(query, userID, time.Now())
+}
+
+// DeleteByUserID 删除用户的全部验证记录
+func (r *emailVerificationRepository) DeleteByUserID(userID int) error {
+	_, err := r.db.Exec(`DELETE FROM email_verifications WHERE user_id = ?`, userID)
+	return err
+}
+
+// getOne 执行单行查询，未找到时返回 nil, nil
+func (r *emailVerificationRepository) getOne(query string, args ...interface{}) (*models.EmailVerification, error) {
+	v := &models.EmailVerification{}
+	err := r.db.QueryRow(query, args...).Scan(
+		&v.ID,
+		&v.UserID,
+		&v.Email,
+		&v.TokenHash,
+		&v.CreatedAt,
+		&v.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,328 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// groupSelect 查询用户组及其角色（以逗号拼接）和直接成员数，顺序与 scanGroup 保持一致
+const groupSelect = `
+	SELECT g.id, g.name, g.description, g.parent_id, g.created_at,
+		COALESCE((SELECT GROUP_CONCAT(r.name ORDER BY r.name SEPARATOR ',')
+			FROM group_roles gr JOIN roles r ON r.id = gr.role_id
+			WHERE gr.group_id = g.id), ''),
+		(SELECT COUNT(*) FROM group_members gm JOIN users u ON u.id = gm.user_id
+			WHERE gm.group_id = g.id AND u.deleted_at IS NULL)
+	FROM user_groups g`
+
+// groupRepository MySQL实现的用户组仓库
+type groupRepository struct {
+	db    *tracedDB
+	orgID int // 限定的组织，0 表示不限定
+}
+
+// NewGroupRepository 创建MySQL用户组仓库实例
+func NewGroupRepository(db *sql.DB) interfaces.GroupRepository {
+	return &groupRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+func (r *groupRepository) ForOrganization(orgID int) interfaces.GroupRepository {
+	return &groupRepository{db: r.db, orgID: orgID}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *groupRepository) WithContext(ctx context.Context) interfaces.GroupRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// inOrg 返回限定组织的查询条件（以 AND 开头），column 为组织ID列；未限定组织时返回空串
+func (r *groupRepository) inOrg(column string) string {
+	if r.orgID == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s = %d", column, r.orgID)
+}
+
+// scanGroup 将一行查询结果扫描为用户组模型
+func scanGroup(row rowScanner) (*models.Group, error) {
+	group := &models.Group{}
+	var parentID sql.NullInt64
+	var roles string
+
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&group.Description,
+		&parentID,
+		&group.CreatedAt,
+		&roles,
+		&group.MemberCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		group.ParentID = &id
+	}
+	group.Roles = splitList(roles)
+	return group, nil
+}
+
+// GetAll 获取所有用户组及其角色和直接成员数，按组名排序
+func (r *groupRepository) GetAll() ([]*models.Group, error) {
+	rows, err := r.db.Query(groupSelect + ` WHERE 1 = 1` + r.inOrg("g.organization_id") + ` ORDER BY g.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*models.Group
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetByID 根据ID获取用户组，未找到时返回 nil
+func (r *groupRepository) GetByID(id int) (*models.Group, error) {
+	return r.getOne(groupSelect+` WHERE g.id = ?`+r.inOrg("g.organization_id"), id)
+}
+
+// GetByName 根据组名获取用户组，未找到时返回 nil（组名只在组织内唯一）
+func (r *groupRepository) GetByName(name string) (*models.Group, error) {
+	return r.getOne(groupSelect+` WHERE g.name = ?`+r.inOrg("g.organization_id"), name)
+}
+
+// getOne 执行单行查询，未找到时返回 nil
+func (r *groupRepository) getOne(query string, args ...interface{}) (*models.Group, error) {
+	group, err := scanGroup(r.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return group, nil
+}
+
+// Create 创建用户组并授予 group.Roles 中的角色，组属于仓库限定的组织（未限定时属于默认组织）
+func (r *groupRepository) Create(group *models.Group) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	orgID := r.orgID
+	if orgID == 0 {
+		if err := tx.QueryRow(`SELECT id FROM organizations WHERE slug = ?`, models.DefaultOrganization).Scan(&orgID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(`INSERT INTO user_groups (organization_id, name, description, parent_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+		orgID, group.Name, group.Description, group.ParentID, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := insertGroupRoles(tx, int(id), group.Roles); err != nil {
+		return err
+	}
+	if err := rebuildGroupClosure(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	group.ID = int(id)
+	group.CreatedAt = now
+	return nil
+}
+
+// Update 修改用户组的名称、说明和上级组，并将角色替换为 group.Roles
+func (r *groupRepository) Update(group *models.Group) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 先锁定组确认其存在（内容未变化时 UPDATE 的影响行数为0，不能用来判断）
+	var lockedID int
+	if err := tx.QueryRow(`SELECT id FROM user_groups WHERE id = ?`+r.inOrg("organization_id")+` FOR UPDATE`, group.ID).Scan(&lockedID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE user_groups SET name = ?, description = ?, parent_id = ? WHERE id = ?`,
+		group.Name, group.Description, group.ParentID, group.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM group_roles WHERE group_id = ?`, group.ID); err != nil {
+		return err
+	}
+	if err := insertGroupRoles(tx, group.ID, group.Roles); err != nil {
+		return err
+	}
+	if err := rebuildGroupClosure(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete 删除用户组，下级组的 parent_id 通过外键置空，成员关系和角色通过外键级联移除
+func (r *groupRepository) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM user_groups WHERE id = ?`+r.inOrg("organization_id"), id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := rebuildGroupClosure(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddMembers 将用户加入组，已是成员的用户以及不属于该组所在组织的用户会被忽略
+func (r *groupRepository) AddMembers(groupID int, userIDs []int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, userID := range userIDs {
+		query := `
+			INSERT IGNORE INTO group_members (group_id, user_id)
+			SELECT g.id, om.user_id FROM user_groups g
+			JOIN organization_members om ON om.organization_id = g.organization_id
+			WHERE g.id = ? AND om.user_id = ?` + r.inOrg("g.organization_id")
+		if _, err := tx.Exec(query, groupID, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveMember 将用户移出组
+func (r *groupRepository) RemoveMember(groupID, userID int) error {
+	query := `
+		DELETE gm FROM group_members gm
+		JOIN user_groups g ON g.id = gm.group_id
+		WHERE gm.group_id = ? AND gm.user_id = ?` + r.inOrg("g.organization_id")
+	return execAffectingOne(r.db, query, groupID, userID)
+}
+
+// insertGroupRoles 按角色标识为组授予角色，不存在的角色返回错误
+func insertGroupRoles(tx *tracedTx, groupID int, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO group_roles (group_id, role_id) SELECT ?, id FROM roles WHERE name IN (?` +
+		strings.Repeat(", ?", len(roles)-1) + `)`
+	args := make([]interface{}, 0, len(roles)+1)
+	args = append(args, groupID)
+	for _, role := range roles {
+		args = append(args, role)
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(rowsAffected) != len(roles) {
+		return fmt.Errorf("部分角色不存在: %v", roles)
+	}
+	return nil
+}
+
+// rebuildGroupClosure 根据 parent_id 重建 group_closure
+// 组的数量通常很少，整体重建比增量维护简单可靠；user_groups 在事务中加锁，避免并发修改层级
+func rebuildGroupClosure(tx *tracedTx) error {
+	rows, err := tx.Query(`SELECT id, parent_id FROM user_groups FOR UPDATE`)
+	if err != nil {
+		return err
+	}
+	parents := make(map[int]int)
+	var ids []int
+	for rows.Next() {
+		var id int
+		var parentID sql.NullInt64
+		if err := rows.Scan(&id, &parentID); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+		if parentID.Valid {
+			parents[id] = int(parentID.Int64)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM group_closure`); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		// 沿上级组向上遍历，遇到环时停止（服务层会拒绝产生环的修改）
+		visited := map[int]bool{}
+		for ancestor, ok := id, true; ok && !visited[ancestor]; ancestor, ok = parents[ancestor] {
+			visited[ancestor] = true
+			if _, err := tx.Exec(`INSERT INTO group_closure (ancestor_id, descendant_id) VALUES (?, ?)`,
+				ancestor, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// invitationColumns 查询邀请时统一使用的列，顺序与 scanInvitation 保持一致
+const invitationColumns = `i.id, i.email, i.role, COALESCE(i.organization_id, 0), i.token_hash, i.invited_by, COALESCE(u.username, ''),
+	i.created_at, i.sent_at, i.expires_at, i.accepted_at, i.accepted_user_id, i.revoked_at`
+
+// invitationFrom 关联发出邀请的管理员（管理员可能已被删除）
+const invitationFrom = ` FROM invitations i LEFT JOIN users u ON u.id = i.invited_by`
+
+// invitationRepository MySQL实现的注册邀请仓库
+type invitationRepository struct {
+	db    *tracedDB
+	orgID int // 限定的组织，0 表示不限定
+}
+
+// NewInvitationRepository 创建MySQL注册邀请仓库实例
+func NewInvitationRepository(db *sql.DB) interfaces.InvitationRepository {
+	return &invitationRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+func (r *invitationRepository) ForOrganization(orgID int) interfaces.InvitationRepository {
+	return &invitationRepository{db: r.db, orgID: orgID}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *invitationRepository) WithContext(ctx context.Context) interfaces.InvitationRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// inOrg 返回限定组织的查询条件（以 AND 开头），column 为组织ID列；未限定组织时返回空串
+func (r *invitationRepository) inOrg(column string) string {
+	if r.orgID == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s = %d", column, r.orgID)
+}
+
+// scanInvitation 将一行查询结果扫描为邀请模型
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	inv := &models.Invitation{}
+	var acceptedAt, revokedAt sql.NullTime
+	var acceptedUserID sql.NullInt64
+
+	err := row.Scan(
+		&inv.ID,
+		&inv.Email,
+		&inv.Role,
+		&inv.OrgID,
+		&inv.TokenHash,
+		&inv.InvitedBy,
+		&inv.InviterName,
+		&inv.CreatedAt,
+		&inv.SentAt,
+		&inv.ExpiresAt,
+		&acceptedAt,
+		&acceptedUserID,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if acceptedAt.Valid {
+		inv.AcceptedAt = &acceptedAt.Time
+	}
+	if acceptedUserID.Valid {
+		id := int(acceptedUserID.Int64)
+		inv.AcceptedUserID = &id
+	}
+	if revokedAt.Valid {
+		inv.RevokedAt = &revokedAt.Time
+	}
+	return inv, nil
+}
+
+// Create 创建邀请，被邀请人注册后加入仓库限定的组织（未限定时加入默认组织）
+func (r *invitationRepository) Create(inv *models.Invitation) error {
+	query := `
+		INSERT INTO invitations (email, role, organization_id, token_hash, invited_by, created_at, sent_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	orgID := r.orgID
+	if orgID == 0 {
+		if err := r.db.QueryRow(`SELECT id FROM organizations WHERE slug = ?`, models.DefaultOrganization).Scan(&orgID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	result, err := r.db.Exec(query, inv.Email, inv.Role, orgID, inv.TokenHash, inv.InvitedBy, now, now, inv.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	inv.ID = int(id)
+	inv.OrgID = orgID
+	inv.CreatedAt = now
+	inv.SentAt = now
+	return nil
+}
+
+// GetByID 根据ID获取邀请
+func (r *invitationRepository) GetByID(id int) (*models.Invitation, error) {
+	return r.getOne(`SELECT `+invitationColumns+invitationFrom+` WHERE i.id = ?`+r.inOrg("i.organization_id"), id)
+}
+
+// GetByTokenHash 根据令牌哈希获取邀请，不受组织限定（被邀请人注册时还不属于任何组织）
+func (r *invitationRepository) GetByTokenHash(tokenHash string) (*models.Invitation, error) {
+	return r.getOne(`SELECT `+invitationColumns+invitationFrom+` WHERE i.token_hash = ?`, tokenHash)
+}
+
+// GetPendingByEmail 获取发给该邮箱且仍然有效的邀请
+func (r *invitationRepository) GetPendingByEmail(email string) (*models.Invitation, error) {
+	query := `SELECT ` + invitationColumns + invitationFrom + `
+		WHERE i.email = ? AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > ?` + r.inOrg("i.organization_id") + `
+		ORDER BY i.created_at DESC
+		LIMIT 1`
+	return r.getOne(query, email, time.Now())
+}
+
+// GetAll 获取所有邀请，最近创建的在前
+func (r *invitationRepository) GetAll() ([]*models.Invitation, error) {
+	rows, err := r.db.Query(`SELECT ` + invitationColumns + invitationFrom + ` WHERE 1 = 1` + r.inOrg("i.organization_id") + ` ORDER BY i.created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*models.Invitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// UpdateToken 重新发送邀请时更换令牌并延长有效期
+func (r *invitationRepository) UpdateToken(id int, tokenHash string, expiresAt time.Time) error {
+	query := `UPDATE invitations SET token_hash = ?, sent_at = ?, expires_at = ? WHERE id = ?` + r.inOrg("organization_id")
+	return execAffectingOne(r.db, query, tokenHash, time.Now(), expiresAt, id)
+}
+
+// acceptInvitation 标记邀请已被 userID 接受，与创建用户在同一事务中执行（见 userRepository.CreateWithInvitation）
+// 已接受、已撤销或已过期的邀请不会被修改，此时返回 sql.ErrNoRows
+func acceptInvitation(db execer, id, userID int) error {
+	query := `
+		UPDATE invitations SET accepted_at = ?, accepted_user_id = ?
+		WHERE id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?
+	`
+	now := time.Now()
+	return execAffectingOne(db, query, now, userID, id, now)
+}
+
+// Revoke 撤销邀请，已接受或已撤销的邀请不会被修改
+func (r *invitationRepository) Revoke(id int) error {
+	query := `UPDATE invitations SET revoked_at = ? WHERE id = ? AND accepted_at IS NULL AND revoked_at IS NULL` + r.inOrg("organization_id")
+	return execAffectingOne(r.db, query, time.Now(), id)
+}
+
+// getOne 执行单行查询，未找到时返回 nil, nil
+func (r *invitationRepository) getOne(query string, args ...interface{}) (*models.Invitation, error) {
+	inv, err := scanInvitation(r.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return inv, nil
+}
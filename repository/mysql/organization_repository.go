@@ -0,0 +1,224 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// organizationSelect 查询组织及其成员数（不含回收站中的用户），顺序与 scanOrganization 保持一致
+const organizationSelect = `
+	SELECT o.id, o.slug, o.name, o.created_at,
+		(SELECT COUNT(*) FROM organization_members om JOIN users u ON u.id = om.user_id
+			WHERE om.organization_id = o.id AND u.deleted_at IS NULL)
+	FROM organizations o`
+
+// organizationRepository MySQL实现的组织仓库
+type organizationRepository struct {
+	db *tracedDB
+}
+
+// NewOrganizationRepository 创建MySQL组织仓库实例
+func NewOrganizationRepository(db *sql.DB) interfaces.OrganizationRepository {
+	return &organizationRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *organizationRepository) WithContext(ctx context.Context) interfaces.OrganizationRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// scanOrganization 将一行查询结果扫描为组织模型
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	org := &models.Organization{}
+	err := row.Scan(&org.ID, &org.Slug, &org.Name, &org.CreatedAt, &org.MemberCount)
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// GetAll 获取所有组织及其成员数，按标识排序
+func (r *organizationRepository) GetAll() ([]*models.Organization, error) {
+	return r.getMany(organizationSelect + ` ORDER BY o.slug`)
+}
+
+// GetByID 根据ID获取组织，未找到时返回 nil
+func (r *organizationRepository) GetByID(id int) (*models.Organization, error) {
+	return r.getOne(organizationSelect+` WHERE o.id = ?`, id)
+}
+
+// GetBySlug 根据标识获取组织，未找到时返回 nil
+func (r *organizationRepository) GetBySlug(slug string) (*models.Organization, error) {
+	return r.getOne(organizationSelect+` WHERE o.slug = ?`, slug)
+}
+
+// GetForUser 获取用户所属的组织，按标识排序
+func (r *organizationRepository) GetForUser(userID int) ([]*models.Organization, error) {
+	query := organizationSelect + `
+		WHERE o.id IN (SELECT organization_id FROM organization_members WHERE user_id = ?)
+		ORDER BY o.slug`
+	return r.getMany(query, userID)
+}
+
+// getOne 执行单行查询，未找到时返回 nil
+func (r *organizationRepository) getOne(query string, args ...interface{}) (*models.Organization, error) {
+	org, err := scanOrganization(r.db.QueryRow(query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// getMany 执行多行查询
+func (r *organizationRepository) getMany(query string, args ...interface{}) ([]*models.Organization, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []*models.Organization
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// Create 创建组织
+func (r *organizationRepository) Create(org *models.Organization) error {
+	now := time.Now()
+	result, err := r.db.Exec(`INSERT INTO organizations (slug, name, created_at) VALUES (?, ?, ?)`,
+		org.Slug, org.Name, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	org.ID = int(id)
+	org.CreatedAt = now
+	return nil
+}
+
+// Update 修改组织的标识和名称
+func (r *organizationRepository) Update(org *models.Organization) error {
+	// 内容未变化时影响行数为0，因此不检查影响行数，由服务层先确认组织存在
+	_, err := r.db.Exec(`UPDATE organizations SET slug = ?, name = ? WHERE id = ?`, org.Slug, org.Name, org.ID)
+	return err
+}
+
+// Delete 删除组织及其用户组、邀请和在该组织中分配的角色，成员关系通过外键级联移除
+func (r *organizationRepository) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 用户组、邀请和角色分配的 organization_id 没有外键（用户组和邀请的旧数据迁移前为NULL，平台级角色为0），需要手动删除
+	if _, err := tx.Exec(`DELETE FROM user_groups WHERE organization_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM invitations WHERE organization_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE organization_id = ?`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM organizations WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := rebuildGroupClosure(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddMember 将用户加入组织并在该组织中分配普通用户角色，已是成员时忽略
+// 角色按组织分配，用户在其他组织中的角色不会带入新组织
+func (r *organizationRepository) AddMember(orgID, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT IGNORE INTO organization_members (organization_id, user_id) VALUES (?, ?)`, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if added, err := result.RowsAffected(); err != nil || added == 0 {
+		return err
+	}
+	_, err = tx.Exec(`INSERT IGNORE INTO user_roles (user_id, organization_id, role_id)
+		SELECT ?, ?, id FROM roles WHERE name = ?`, userID, orgID, models.RoleUser)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveMember 将用户移出组织，同时移出该组织的所有用户组并撤销在该组织中分配的角色
+func (r *organizationRepository) RemoveMember(orgID, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE organization_id = ? AND user_id = ?`, orgID, userID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE gm FROM group_members gm
+		JOIN user_groups g ON g.id = gm.group_id
+		WHERE g.organization_id = ? AND gm.user_id = ?
+	`, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM organization_members WHERE organization_id = ? AND user_id = ?`, orgID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
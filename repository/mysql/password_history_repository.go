@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"user-management-system/repository/interfaces"
+)
+
+// passwordHistoryRepository MySQL实现的密码历史仓库
+type passwordHistoryRepository struct {
+	db *tracedDB
+}
+
+// NewPasswordHistoryRepository 创建MySQL密码历史仓库实例
+func NewPasswordHistoryRepository(db *sql.DB) interfaces.PasswordHistoryRepository {
+	return &passwordHistoryRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *passwordHistoryRepository) WithContext(ctx context.Context) interfaces.PasswordHistoryRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// Add 记录一个用过的密码哈希
+func (r *passwordHistoryRepository) Add(userID int, passwordHash string) error {
+	query := `INSERT INTO password_history (user_id, password_hash, created_at) VALUES (?, ?, ?)`
+	_, err := r.db.Exec(query, userID, passwordHash, time.Now())
+	return err
+}
+
+// GetRecent 获取用户最近使用过的 limit 个密码哈希（从新到旧）
+func (r *passwordHistoryRepository) GetRecent(userID int, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash FROM password_history
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// Prune 只保留用户最近的 keep 条记录
+func (r *passwordHistoryRepository) Prune(userID int, keep int) error {
+	// MySQL 不支持在 DELETE 的子查询中直接使用 LIMIT，需要再包一层派生表
+	query := `
+		DELETE FROM password_history
+		WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM (
+				SELECT id FROM password_history
+				WHERE user_id = ?
+				ORDER BY created_at DESC, id DESC
+				LIMIT ?
+			) AS recent
+		)
+	`
+	_, err := r.db.Exec(query, userID, userID, keep)
+	return err
+}
@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// passwordTokenRepository MySQL实现的设置密码链接仓库
+type passwordTokenRepository struct {
+	db *tracedDB
+}
+
+// NewPasswordTokenRepository 创建MySQL设置密码链接仓库实例
+func NewPasswordTokenRepository(db *sql.DB) interfaces.PasswordTokenRepository {
+	return &passwordTokenRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *passwordTokenRepository) WithContext(ctx context.Context) interfaces.PasswordTokenRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// Create 创建设置密码链接记录
+func (r *passwordTokenRepository) Create(t *models.PasswordToken) error {
+	query := `
+		INSERT INTO password_tokens (user_id, purpose, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, t.UserID, t.Purpose, t.TokenHash, now, t.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	t.ID = int(id)
+	t.CreatedAt = now
+	return nil
+}
+
+// GetByTokenHash 根据令牌哈希获取记录，未找到时返回 nil, nil
+func (r *passwordTokenRepository) GetByTokenHash(tokenHash string) (*models.PasswordToken, error) {
+	query := `
+		SELECT id, user_id, purpose, token_hash, created_at, expires_at
+		FROM password_tokens
+		WHERE token_hash = ?
+	`
+
+	t := &models.PasswordToken{}
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteByUserID 删除用户的全部设置密码链接
+func (r *passwordTokenRepository) DeleteByUserID(userID int) error {
+	_, err := r.db.Exec(`DELETE FROM password_tokens WHERE user_id = ?`, userID)
+	return err
+}
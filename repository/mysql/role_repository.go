@@ -0,0 +1,198 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// roleSelect 查询角色及其权限（以逗号拼接），顺序与 scanRole 保持一致
+const roleSelect = `
+	SELECT r.id, r.name, r.description, r.is_system, r.created_at,
+		COALESCE(GROUP_CONCAT(rp.permission ORDER BY rp.permission SEPARATOR ','), '')
+	FROM roles r
+	LEFT JOIN role_permissions rp ON rp.role_id = r.id`
+
+// roleRepository MySQL实现的角色仓库
+type roleRepository struct {
+	db *tracedDB
+}
+
+// NewRoleRepository 创建MySQL角色仓库实例
+func NewRoleRepository(db *sql.DB) interfaces.RoleRepository {
+	return &roleRepository{
+		db: newTracedDB(db),
+	}
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *roleRepository) WithContext(ctx context.Context) interfaces.RoleRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// scanRole 将一行查询结果扫描为角色模型
+func scanRole(row rowScanner) (*models.Role, error) {
+	role := &models.Role{}
+	var permissions string
+
+	err := row.Scan(
+		&role.ID,
+		&role.Name,
+		&role.Description,
+		&role.IsSystem,
+		&role.CreatedAt,
+		&permissions,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Permissions = splitList(permissions)
+	return role, nil
+}
+
+// splitList 拆分以逗号拼接的列表，空字符串返回 nil
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// GetAll 获取所有角色及其权限，内置角色在前
+func (r *roleRepository) GetAll() ([]*models.Role, error) {
+	query := roleSelect + ` GROUP BY r.id ORDER BY r.is_system DESC, r.name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*models.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetByName 根据角色标识获取角色及其权限，未找到时返回 nil
+func (r *roleRepository) GetByName(name string) (*models.Role, error) {
+	query := roleSelect + ` WHERE r.name = ? GROUP BY r.id`
+
+	role, err := scanRole(r.db.QueryRow(query, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// GetByID 根据ID获取角色及其权限，未找到时返回 nil
+func (r *roleRepository) GetByID(id int) (*models.Role, error) {
+	query := roleSelect + ` WHERE r.id = ? GROUP BY r.id`
+
+	role, err := scanRole(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// Create 创建角色并写入其权限
+func (r *roleRepository) Create(role *models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`INSERT INTO roles (name, description, is_system, created_at) VALUES (?, ?, 0, ?)`,
+		role.Name, role.Description, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := insertRolePermissions(tx, int(id), role.Permissions); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	role.ID = int(id)
+	role.CreatedAt = now
+	return nil
+}
+
+// Update 修改角色的标识和说明，并将权限替换为 role.Permissions
+// 改名时同步更新引用旧标识的注册邀请
+func (r *roleRepository) Update(role *models.Role, oldName string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 先锁定角色行确认角色存在（内容未变化时 UPDATE 的影响行数为0，不能用来判断）
+	var lockedID int
+	if err := tx.QueryRow(`SELECT id FROM roles WHERE id = ? FOR UPDATE`, role.ID).Scan(&lockedID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE roles SET name = ?, description = ? WHERE id = ?`,
+		role.Name, role.Description, role.ID); err != nil {
+		return err
+	}
+	if role.Name != oldName {
+		if _, err := tx.Exec(`UPDATE invitations SET role = ? WHERE role = ?`, role.Name, oldName); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = ?`, role.ID); err != nil {
+		return err
+	}
+	if err := insertRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete 删除角色，用户持有的该角色通过外键级联移除
+func (r *roleRepository) Delete(id int) error {
+	return execAffectingOne(r.db, `DELETE FROM roles WHERE id = ? AND is_system = 0`, id)
+}
+
+// insertRolePermissions 为角色写入权限
+func insertRolePermissions(tx *tracedTx, roleID int, permissions []string) error {
+	for _, permission := range permissions {
+		if _, err := tx.Exec(`INSERT INTO role_permissions (role_id, permission) VALUES (?, ?)`,
+			roleID, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,39 +1,319 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 	"user-management-system/models"
 	"user-management-system/repository/interfaces"
 )
 
+// allUserRoles 用户在所有组织中直接持有的角色，以及通过所在组和这些组的上级组继承的角色
+// 作为派生表使用，结果列为 user_id, role_id
+const allUserRoles = `
+	SELECT ur.user_id, ur.role_id FROM user_roles ur
+	UNION
+	SELECT gm.user_id, gr.role_id FROM group_members gm
+	JOIN group_closure gc ON gc.descendant_id = gm.group_id
+	JOIN group_roles gr ON gr.group_id = gc.ancestor_id`
+
+// effectiveUserRoles 返回用户在组织 orgID 中的有效角色：平台级角色、在该组织直接持有的角色，
+// 以及通过该组织的用户组和这些组的上级组继承的角色；orgID 为 0 时返回所有组织中的角色（见 allUserRoles）
+// 作为派生表使用，结果列为 user_id, role_id
+func effectiveUserRoles(orgID int) string {
+	if orgID == 0 {
+		return allUserRoles
+	}
+	return fmt.Sprintf(`
+	SELECT ur.user_id, ur.role_id FROM user_roles ur WHERE ur.organization_id IN (0, %d)
+	UNION
+	SELECT gm.user_id, gr.role_id FROM group_members gm
+	JOIN user_groups g ON g.id = gm.group_id
+	JOIN group_closure gc ON gc.descendant_id = gm.group_id
+	JOIN group_roles gr ON gr.group_id = gc.ancestor_id
+	WHERE g.organization_id = %d`, orgID, orgID)
+}
+
+// holdsRoleIn 返回检查用户 user 在组织 org 中持有角色 role 的条件：作为平台级角色或在该组织中直接持有，
+// 或通过该组织的用户组及其上级组继承；参数为列名或占位符，占位符会在条件中出现两次
+func holdsRoleIn(user, role, org string) string {
+	return fmt.Sprintf(`(EXISTS (SELECT 1 FROM user_roles hr
+			WHERE hr.user_id = %[1]s AND hr.role_id = %[2]s AND hr.organization_id IN (0, %[3]s))
+		OR EXISTS (SELECT 1 FROM group_members hm
+			JOIN user_groups hg ON hg.id = hm.group_id
+			JOIN group_closure hc ON hc.descendant_id = hm.group_id
+			JOIN group_roles hgr ON hgr.group_id = hc.ancestor_id
+			WHERE hm.user_id = %[1]s AND hgr.role_id = %[2]s AND hg.organization_id = %[3]s))`, user, role, org)
+}
+
+// userColumns 查询用户时统一使用的列，顺序与 scanUser 保持一致
+// 角色、用户组、所属组织、有效权限和委派的操作由 loadRelations 对查到的全部用户用一条查询批量加载
+const userColumns = `id, username, password, email, created_at, last_login_at, password_changed_at,
+	status, status_reason, status_changed_at, deleted_at, must_change_password`
+
+// 用户关联数据的种类，即 userRelationsQuery 结果中的 relation 列
+const (
+	relationRole       = "role"
+	relationGroup      = "group"
+	relationOrg        = "org"
+	relationPermission = "permission"
+	relationDelegated  = "delegated"
+)
+
+// userRelationsQuery 批量查询用户的关联数据，结果列为 user_id, relation, name，按用户、种类和名称排序
+// 占位符依次为：用户ID列表、直接持有的角色的组织条件、用户组的组织条件、委派授权的组织条件、用户的有效角色（userRolesOf）
+const userRelationsQuery = `
+	SELECT ur.user_id AS user_id, 'role' AS relation, r.name AS name
+	FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+	WHERE ur.user_id IN (%[1]s)%[2]s
+	UNION
+	SELECT gm.user_id, 'group', g.name
+	FROM group_members gm JOIN user_groups g ON g.id = gm.group_id
+	WHERE gm.user_id IN (%[1]s)%[3]s
+	UNION
+	SELECT om.user_id, 'org', o.slug
+	FROM organization_members om JOIN organizations o ON o.id = om.organization_id
+	WHERE om.user_id IN (%[1]s)
+	UNION
+	SELECT er.user_id, 'permission', rp.permission
+	FROM (%[5]s) er JOIN role_permissions rp ON rp.role_id = er.role_id
+	UNION
+	SELECT er.user_id, 'delegated', ap.permission
+	FROM (%[5]s) er JOIN admin_grants ag ON ag.role_id = er.role_id%[4]s
+	JOIN admin_grant_permissions ap ON ap.grant_id = ag.id
+	ORDER BY user_id, relation, name`
+
+// userRolesOf 返回指定用户直接持有和通过用户组及其上级组继承的角色，作为派生表使用，结果列为 user_id, role_id
+// 占位符与 userRelationsQuery 的前三个相同
+const userRolesOf = `
+	SELECT ur.user_id, ur.role_id FROM user_roles ur
+	WHERE ur.user_id IN (%[1]s)%[2]s
+	UNION
+	SELECT gm.user_id, gr.role_id FROM group_members gm
+	JOIN user_groups g ON g.id = gm.group_id
+	JOIN group_closure gc ON gc.descendant_id = gm.group_id
+	JOIN group_roles gr ON gr.group_id = gc.ancestor_id
+	WHERE gm.user_id IN (%[1]s)%[3]s`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer 兼容 *sql.DB 与 *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// scanUser 将一行查询结果扫描为用户模型
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	var lastLoginAt, passwordChangedAt, statusChangedAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Password,
+		&user.Email,
+		&user.CreatedAt,
+		&lastLoginAt,
+		&passwordChangedAt,
+		&user.Status,
+		&user.StatusReason,
+		&statusChangedAt,
+		&deletedAt,
+		&user.MustChangePassword,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+	if passwordChangedAt.Valid {
+		user.PasswordChangedAt = &passwordChangedAt.Time
+	}
+	if statusChangedAt.Valid {
+		user.StatusChangedAt = &statusChangedAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+	return user, nil
+}
+
+// notDeleted 除回收站相关的方法外，所有查询和更新都只作用于未删除的用户
+const notDeleted = `deleted_at IS NULL`
+
 // userRepository MySQL实现的用户仓库
 type userRepository struct {
-	db *sql.DB
+	db         *tracedDB
+	orgID      int // 限定的组织，0 表示不限定
+	rolesOrgID int // 按哪个组织计算用户的角色、用户组和权限，0 表示所有组织
 }
 
 // NewUserRepository 创建MySQL用户仓库实例
 func NewUserRepository(db *sql.DB) interfaces.UserRepository {
 	return &userRepository{
-		db: db,
+		db: newTracedDB(db),
 	}
 }
 
-// Create 创建新用户
+// ForOrganization 返回限定在指定组织内的仓库，orgID 为 0 时不限定
+// 限定后按ID查询、列表、统计和管理员对用户的修改都只作用于该组织的成员；
+// 按用户名和邮箱的查询与存在性检查仍是全局的，因为用户名和邮箱在所有组织中唯一
+func (r *userRepository) ForOrganization(orgID int) interfaces.UserRepository {
+	return &userRepository{db: r.db, orgID: orgID, rolesOrgID: orgID}
+}
+
+// WithRolesIn 返回按组织 orgID 计算用户角色、用户组和权限的仓库，查询不限定该组织的成员
+// 用于加载当前组织中的操作者：拥有 orgs:manage 的平台管理员不是该组织成员时也能进入
+func (r *userRepository) WithRolesIn(orgID int) interfaces.UserRepository {
+	scoped := *r
+	scoped.rolesOrgID = orgID
+	return &scoped
+}
+
+// WithContext 返回在 ctx 中执行语句的仓库，ctx 中有活动的追踪 span 时为每个语句记录 SQL span
+func (r *userRepository) WithContext(ctx context.Context) interfaces.UserRepository {
+	scoped := *r
+	scoped.db = r.db.withContext(ctx)
+	return &scoped
+}
+
+// loadRelations 用一条查询为 users 加载角色、用户组、所属组织、有效权限和委派的操作
+// rolesOrgID 不为 0 时只加载平台级角色和在该组织中分配的角色、该组织的用户组和委派授权，有效权限也按此计算
+func (r *userRepository) loadRelations(users []*models.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.User, len(users))
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+		ids = append(ids, strconv.Itoa(user.ID))
+	}
+
+	idList := strings.Join(ids, ", ")
+	roleCond, groupCond, grantCond := "", "", ""
+	if r.rolesOrgID != 0 {
+		roleCond = fmt.Sprintf(" AND ur.organization_id IN (0, %d)", r.rolesOrgID)
+		groupCond = fmt.Sprintf(" AND g.organization_id = %d", r.rolesOrgID)
+		grantCond = fmt.Sprintf(" AND ag.organization_id = %d", r.rolesOrgID)
+	}
+	roles := fmt.Sprintf(userRolesOf, idList, roleCond, groupCond)
+	rows, err := r.db.Query(fmt.Sprintf(userRelationsQuery, idList, roleCond, groupCond, grantCond, roles))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		var relation, name string
+		if err := rows.Scan(&userID, &relation, &name); err != nil {
+			return err
+		}
+		user := byID[userID]
+		switch relation {
+		case relationRole:
+			user.Roles = append(user.Roles, name)
+		case relationGroup:
+			user.Groups = append(user.Groups, name)
+		case relationOrg:
+			user.Orgs = append(user.Orgs, name)
+		case relationPermission:
+			user.Permissions = append(user.Permissions, name)
+		case relationDelegated:
+			user.Delegated = append(user.Delegated, name)
+		}
+	}
+	return rows.Err()
+}
+
+// inOrg 返回限定组织成员的查询条件（以 AND 开头），column 为用户ID列；未限定组织时返回空串
+func (r *userRepository) inOrg(column string) string {
+	return orgMemberCond(column, r.orgID)
+}
+
+// rolesInOrg 返回只保留在限定组织中生效的角色分配（平台级角色和该组织的角色）的 user_roles 条件（以 WHERE 开头）
+// 未限定组织时返回空串
+func (r *userRepository) rolesInOrg() string {
+	if r.orgID == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" WHERE organization_id IN (0, %d)", r.orgID)
+}
+
+// orgMemberCond 返回限定为组织 orgID 成员的查询条件（以 AND 开头），column 为用户ID列；orgID 为 0 时返回空串
+func orgMemberCond(column string, orgID int) string {
+	if orgID == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s IN (SELECT user_id FROM organization_members WHERE organization_id = %d)", column, orgID)
+}
+
+// Create 创建新用户，并在同一事务中加入组织、分配 user.Roles 中的角色
+// 仓库限定了组织时加入该组织并在该组织中分配角色，否则使用默认组织
 func (r *userRepository) Create(user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.insertUser(tx, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateWithInvitation 创建用户并将邀请标记为已被该用户接受，二者在同一事务中完成
+// 邀请已被接受、已撤销或已过期时返回 sql.ErrNoRows，用户不会被创建
+func (r *userRepository) CreateWithInvitation(user *models.User, invitationID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.insertUser(tx, user); err != nil {
+		return err
+	}
+	if err := acceptInvitation(tx, invitationID, user.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertUser 在事务中插入用户，加入仓库限定的组织（未限定时加入默认组织），并在该组织中分配 user.Roles 中的角色
+// 成功后填充 user 的 ID、创建时间和密码修改时间
+func (r *userRepository) insertUser(tx *tracedTx, user *models.User) error {
 	//防止 SQL 注入攻击
 	query := `
-		INSERT INTO users (username, password, email, role, created_at) 
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO users (username, password, email, status, created_at, password_changed_at, must_change_password) 
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.Exec(query,
+	// 未指定状态的新用户默认为正常状态
+	if user.Status == "" {
+		user.Status = models.StatusActive
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(query,
 		user.Username,
 		user.Password,
 		user.Email,
-		user.Role,
-		time.Now(),
+		user.Status,
+		now,
+		now,
+		user.MustChangePassword,
 	)
 
 	if err != nil {
@@ -46,142 +326,112 @@ func (r *userRepository) Create(user *models.User) error {
 		return err
 	}
 
-	user.ID = int(id)           //将自增ID赋值给用户ID
-	user.CreatedAt = time.Now() //将当前时间赋值给用户创建时间
+	orgID, err := roleOrganization(tx, r.orgID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO organization_members (organization_id, user_id) VALUES (?, ?)`, orgID, id); err != nil {
+		return err
+	}
+	if err := insertUserRoles(tx, int(id), orgID, user.Roles); err != nil {
+		return err
+	}
+
+	user.ID = int(id)    //将自增ID赋值给用户ID
+	user.CreatedAt = now //将当前时间赋值给用户创建时间
+	user.PasswordChangedAt = &now
 
 	return nil
 }
 
-// GetByID 根据ID获取用户
-func (r *userRepository) GetByID(id int) (*models.User, error) {
-	user := &models.User{}
+// roleOrganization 返回分配角色时使用的组织：orgID 为 0（仓库未限定组织）时使用默认组织
+func roleOrganization(tx *tracedTx, orgID int) (int, error) {
+	if orgID != 0 {
+		return orgID, nil
+	}
+	err := tx.QueryRow(`SELECT id FROM organizations WHERE slug = ?`, models.DefaultOrganization).Scan(&orgID)
+	return orgID, err
+}
 
-	query := `
-		SELECT id, username, password, email, role, created_at 
-		FROM users 
-		WHERE id = ?
-	`
+// insertUserRoles 按角色标识在组织 orgID 中为用户添加角色，不存在的角色返回错误
+// 拥有 orgs:manage 权限的角色是平台级角色，分配在组织 0 上，在所有组织中生效
+func insertUserRoles(tx *tracedTx, userID, orgID int, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
 
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.Email,
-		&user.Role,
-		&user.CreatedAt,
-	)
+	query := `INSERT INTO user_roles (user_id, organization_id, role_id)
+		SELECT ?, IF(EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.role_id = roles.id AND rp.permission = ?), 0, ?), id
+		FROM roles WHERE name IN (?` + strings.Repeat(", ?", len(roles)-1) + `)`
+	args := make([]interface{}, 0, len(roles)+3)
+	args = append(args, userID, models.PermOrgsManage, orgID)
+	for _, role := range roles {
+		args = append(args, role)
+	}
 
+	result, err := tx.Exec(query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
+		return err
 	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(rowsAffected) != len(roles) {
+		return fmt.Errorf("部分角色不存在: %v", roles)
+	}
+	return nil
+}
 
-	return user, nil
+// GetByID 根据ID获取用户
+func (r *userRepository) GetByID(id int) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND ` + notDeleted + r.inOrg("id")
+	return r.getOne(query, id)
 }
 
 // GetByUsername 根据用户名获取用户
 func (r *userRepository) GetByUsername(username string) (*models.User, error) {
-	user := &models.User{}
-
-	query := `
-		SELECT id, username, password, email, role, created_at 
-		FROM users 
-		WHERE username = ?
-	`
-
-	err := r.db.QueryRow(query, username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.Email,
-		&user.Role,
-		&user.CreatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return user, nil
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = ? AND ` + notDeleted
+	return r.getOne(query, username)
 }
 
 // GetByEmail 根据邮箱获取用户
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
-	user := &models.User{}
-	query := `
-		SELECT id, username, password, email, role, created_at 
-		FROM users 
-		WHERE email = ?
-	`
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.Email,
-		&user.Role,
-		&user.CreatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ? AND ` + notDeleted
+	return r.getOne(query, email)
+}
+
+// getOne 执行单行查询并加载用户的关联数据，未找到时返回 nil, nil
+func (r *userRepository) getOne(query string, args ...interface{}) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow(query, args...))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if err := r.loadRelations([]*models.User{user}); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
 // GetAll 获取所有用户
 func (r *userRepository) GetAll() ([]*models.User, error) {
-	query := `
-		SELECT id, username, email, role, created_at 
-		FROM users 
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var users []*models.User
-
-	for rows.Next() {
-		user := &models.User{}
-		err := rows.Scan(
-			&user.ID,
-			&user.Username,
-			&user.Email,
-			&user.Role,
-			&user.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	return users, nil
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + notDeleted + r.inOrg("id") + ` ORDER BY created_at DESC`
+	return r.getMany(query)
 }
 
 // Update 更新用户信息
 func (r *userRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users
-		SET username = ?, email = ?, role = ?
-		WHERE id = ?
-	`
+		SET username = ?, email = ?
+		WHERE id = ? AND deleted_at IS NULL` + r.inOrg("id")
 
 	result, err := r.db.Exec(query,
 		user.Username,
 		user.Email,
-		user.Role,
 		user.ID,
 	)
 	if err != nil {
@@ -199,14 +449,162 @@ func (r *userRepository) Update(user *models.User) error {
 
 }
 
-// UpdateEmailAndRole 更新用户邮箱和角色
-func (r *userRepository) UpdateEmailAndRole(id int, email, role string) error {
+// UpdateEmailAndRoles 在同一事务中更新用户邮箱并将角色替换为 roles
+func (r *userRepository) UpdateEmailAndRoles(id int, email string, roles []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateEmailAndRoles(tx, id, r.orgID, email, roles); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateEmailAndRoles 在事务中更新组织 orgID 中的用户的邮箱，并替换其在该组织中的角色和平台级角色
+// orgID 为 0 时不限定用户所在的组织，角色按默认组织替换；变更申请获批时与申请状态的修改在同一事务中执行
+func updateEmailAndRoles(tx *tracedTx, id, orgID int, email string, roles []string) error {
+	// 先锁定用户行确认用户存在（邮箱未变化时 UPDATE 的影响行数为0，不能用来判断）
+	var lockedID int
+	err := tx.QueryRow(`SELECT id FROM users WHERE id = ? AND `+notDeleted+orgMemberCond("id", orgID)+` FOR UPDATE`, id).Scan(&lockedID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET email = ? WHERE id = ?`, email, id); err != nil {
+		return err
+	}
+	orgID, err = roleOrganization(tx, orgID)
+	if err != nil {
+		return err
+	}
+	// 组织中显示的角色包括平台级角色，因此一并替换
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = ? AND organization_id IN (0, ?)`, id, orgID); err != nil {
+		return err
+	}
+	return insertUserRoles(tx, id, orgID, roles)
+}
+
+// UpdateEmail 更新用户邮箱
+func (r *userRepository) UpdateEmail(id int, email string) error {
+	query := `UPDATE users SET email = ? WHERE id = ? AND ` + notDeleted + r.inOrg("id")
+	return execAffectingOne(r.db, query, email, id)
+}
+
+// UpdatePassword 更新用户密码，同时记录密码修改时间并清除强制修改标记
+func (r *userRepository) UpdatePassword(id int, hashedPassword string) error {
+	query := `UPDATE users SET password = ?, password_changed_at = ?, must_change_password = 0 WHERE id = ? AND ` + notDeleted
+	return execAffectingOne(r.db, query, hashedPassword, time.Now(), id)
+}
+
+// ResetPassword 由管理员重置用户密码，mustChange 表示用户下次登录时必须修改密码
+func (r *userRepository) ResetPassword(id int, hashedPassword string, mustChange bool) error {
+	query := `UPDATE users SET password = ?, password_changed_at = ?, must_change_password = ? WHERE id = ? AND ` + notDeleted + r.inOrg("id")
+	return execAffectingOne(r.db, query, hashedPassword, time.Now(), mustChange, id)
+}
+
+// UpdatePasswordHash 只替换密码哈希（用于算法升级），不改变密码修改时间
+func (r *userRepository) UpdatePasswordHash(id int, hashedPassword string) error {
+	query := `UPDATE users SET password = ? WHERE id = ? AND ` + notDeleted
+	return execAffectingOne(r.db, query, hashedPassword, id)
+}
+
+// UpdateLastLogin 记录用户最近登录时间
+func (r *userRepository) UpdateLastLogin(id int, at time.Time) error {
+	// 同一秒内重复登录时值不变、影响行数为0，因此不检查影响行数
+	query := `UPDATE users SET last_login_at = ? WHERE id = ? AND ` + notDeleted
+	_, err := r.db.Exec(query, at, id)
+	return err
+}
+
+// UpdateStatus 更新账户状态及原因
+func (r *userRepository) UpdateStatus(id int, status, reason string) error {
+	query := `UPDATE users SET status = ?, status_reason = ?, status_changed_at = ? WHERE id = ? AND ` + notDeleted + r.inOrg("id")
+	return execAffectingOne(r.db, query, status, reason, time.Now(), id)
+}
+
+// GetByStatus 获取指定账户状态的用户，最早创建的在前
+func (r *userRepository) GetByStatus(status string) ([]*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE status = ? AND ` + notDeleted + r.inOrg("id") + ` ORDER BY created_at ASC`
+	return r.getMany(query, status)
+}
+
+// GetByRoleID 获取直接持有或通过用户组继承指定角色的用户
+func (r *userRepository) GetByRoleID(roleID int) ([]*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users
+		WHERE id IN (SELECT er.user_id FROM (` + effectiveUserRoles(r.orgID) + `) er WHERE er.role_id = ?) AND ` + notDeleted + r.inOrg("id") + `
+		ORDER BY username`
+	return r.getMany(query, roleID)
+}
+
+// GetByGroupID 获取指定组的成员，nested 为 true 时同时包括所有下级组的成员
+func (r *userRepository) GetByGroupID(groupID int, nested bool) ([]*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users
+		WHERE id IN (SELECT user_id FROM group_members WHERE group_id = ?) AND ` + notDeleted + r.inOrg("id") + `
+		ORDER BY username`
+	if nested {
+		query = `SELECT ` + userColumns + ` FROM users
+			WHERE id IN (
+				SELECT gm.user_id FROM group_members gm
+				JOIN group_closure gc ON gc.descendant_id = gm.group_id
+				WHERE gc.ancestor_id = ?
+			) AND ` + notDeleted + r.inOrg("id") + `
+			ORDER BY username`
+	}
+	return r.getMany(query, groupID)
+}
+
+// getMany 执行多行查询，再用一条查询批量加载所有用户的关联数据
+func (r *userRepository) getMany(query string, args ...interface{}) ([]*models.User, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	// 关联数据的查询需要在结果集关闭之后执行，避免同时占用两个连接
+	rows.Close()
+	if err := r.loadRelations(users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Approve 通过待审批的注册，将账户置为正常状态
+// 只作用于仍处于待审批状态的用户，避免覆盖并发的审批结果
+func (r *userRepository) Approve(id int) error {
+	query := `UPDATE users SET status = ?, status_reason = '', status_changed_at = ? WHERE id = ? AND status = ? AND ` + notDeleted + r.inOrg("id")
+	return execAffectingOne(r.db, query, models.StatusActive, time.Now(), id, models.StatusPending)
+}
+
+// Reject 拒绝待审批的注册：记录原因并将用户移入回收站
+// 只作用于仍处于待审批状态的用户，避免覆盖并发的审批结果
+func (r *userRepository) Reject(id int, reason string) error {
 	query := `
 		UPDATE users
-		SET email = ?, role = ?
-		WHERE id = ?
-	`
-	result, err := r.db.Exec(query, email, role, id)
+		SET deleted_at = ?, status = ?, status_reason = ?, status_changed_at = ?
+		WHERE id = ? AND status = ? AND deleted_at IS NULL` + r.inOrg("id")
+
+	now := time.Now()
+	return execAffectingOne(r.db, query, now, models.StatusDeleted, reason, now, id, models.StatusPending)
+}
+
+// execAffectingOne 执行更新语句，没有匹配到任何行时返回 sql.ErrNoRows
+func execAffectingOne(db execer, query string, args ...interface{}) error {
+	result, err := db.Exec(query, args...)
 	if err != nil {
 		return err
 	}
@@ -221,31 +619,89 @@ func (r *userRepository) UpdateEmailAndRole(id int, email, role string) error {
 	return nil
 }
 
-// Delete 删除用户
+// Delete 软删除用户：记录删除时间并将状态置为已删除，数据保留在回收站中
 func (r *userRepository) Delete(id int) error {
-	query := `DELETE FROM users WHERE id = ?`
+	err := softDeleteUser(r.db, id, r.inOrg("id"))
+	if err == sql.ErrNoRows {
+		return errors.New("用户不存在")
+	}
+	return err
+}
+
+// softDeleteUser 将用户移入回收站，orgCond 为限定组织的查询条件（见 inOrg）；用户不存在或已删除时返回 sql.ErrNoRows
+func softDeleteUser(db execer, id int, orgCond string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = ?, status = ?, status_changed_at = ?
+		WHERE id = ? AND deleted_at IS NULL` + orgCond
+
+	now := time.Now()
+	return execAffectingOne(db, query, now, models.StatusDeleted, now, id)
+}
+
+// GetDeleted 获取回收站中的所有用户，最近删除的在前
+func (r *userRepository) GetDeleted() ([]*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE deleted_at IS NOT NULL` + r.inOrg("id") + ` ORDER BY deleted_at DESC`
+	return r.getMany(query)
+}
+
+// GetDeletedByID 根据ID获取回收站中的用户
+func (r *userRepository) GetDeletedByID(id int) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND deleted_at IS NOT NULL` + r.inOrg("id")
+	return r.getOne(query, id)
+}
+
+// Restore 从回收站恢复用户，恢复后为正常状态
+func (r *userRepository) Restore(id int) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, status = ?, status_reason = '', status_changed_at = ?
+		WHERE id = ? AND deleted_at IS NOT NULL` + r.inOrg("id")
+	return execAffectingOne(r.db, query, models.StatusActive, time.Now(), id)
+}
+
+// PurgeDeletedBefore 永久删除在 before 之前移入回收站的用户，返回删除的数量
+// 关联的邮箱验证、密码历史等记录通过外键级联删除
+func (r *userRepository) PurgeDeletedBefore(before time.Time) (int64, error) {
+	query := `DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ?`
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.Exec(query, before)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return result.RowsAffected()
+}
 
-	rowsAffected, err := result.RowsAffected()
+// ExistsDeleted 检查回收站中是否有使用该用户名的用户
+func (r *userRepository) ExistsDeleted(username string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM users WHERE username = ? AND deleted_at IS NOT NULL`
+
+	err := r.db.QueryRow(query, username).Scan(&count)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("用户不存在")
+	return count > 0, nil
+}
+
+// ExistsDeletedByEmail 检查回收站中是否有使用该邮箱的用户
+func (r *userRepository) ExistsDeletedByEmail(email string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM users WHERE email = ? AND deleted_at IS NOT NULL`
+
+	err := r.db.QueryRow(query, email).Scan(&count)
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return count > 0, nil
 }
 
 // Exists 检查用户是否存在
 func (r *userRepository) Exists(username string) (bool, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM users WHERE username = ?`
+	query := `SELECT COUNT(*) FROM users WHERE username = ? AND ` + notDeleted
 
 	err := r.db.QueryRow(query, username).Scan(&count)
 	if err != nil {
@@ -258,7 +714,7 @@ func (r *userRepository) Exists(username string) (bool, error) {
 // ExistsByEmail 检查邮箱是否已被使用
 func (r *userRepository) ExistsByEmail(email string) (bool, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM users WHERE email = ?`
+	query := `SELECT COUNT(*) FROM users WHERE email = ? AND ` + notDeleted
 
 	err := r.db.QueryRow(query, email).Scan(&count)
 	if err != nil {
@@ -271,7 +727,7 @@ func (r *userRepository) ExistsByEmail(email string) (bool, error) {
 // Count 获取用户总数
 func (r *userRepository) Count() (int64, error) {
 	var count int64
-	query := `SELECT COUNT(*) FROM users`
+	query := `SELECT COUNT(*) FROM users WHERE ` + notDeleted + r.inOrg("id")
 
 	err := r.db.QueryRow(query).Scan(&count)
 	if err != nil {
@@ -281,10 +737,52 @@ func (r *userRepository) Count() (int64, error) {
 	return count, nil
 }
 
+// CountByPermissionAndStatus 统计通过角色（包括从用户组继承的角色）拥有指定权限且处于指定账户状态的用户数
+// 该统计用于防止系统中没有管理员，因此不受组织限定
+func (r *userRepository) CountByPermissionAndStatus(permission, status string) (int64, error) {
+	var count int64
+	query := `
+		SELECT COUNT(DISTINCT u.id) FROM users u
+		JOIN (` + allUserRoles + `) er ON er.user_id = u.id
+		JOIN role_permissions rp ON rp.role_id = er.role_id
+		WHERE rp.permission = ? AND u.status = ? AND u.deleted_at IS NULL
+	`
+
+	err := r.db.QueryRow(query, permission, status).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountByPermissionAndStatusExcludingRole 统计不依靠 roleID 这个角色、通过其他角色拥有指定权限且处于指定账户状态的用户数
+// 用于判断修改或删除角色后是否还有人拥有该权限
+func (r *userRepository) CountByPermissionAndStatusExcludingRole(permission, status string, roleID int) (int64, error) {
+	var count int64
+	query := `
+		SELECT COUNT(DISTINCT u.id) FROM users u
+		JOIN (` + allUserRoles + `) er ON er.user_id = u.id
+		JOIN role_permissions rp ON rp.role_id = er.role_id
+		WHERE rp.permission = ? AND u.status = ? AND er.role_id <> ? AND u.deleted_at IS NULL
+	`
+
+	err := r.db.QueryRow(query, permission, status, roleID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 // CountByRole 根据角色统计用户数
 func (r *userRepository) CountByRole(role string) (int64, error) {
 	var count int64
-	query := `SELECT COUNT(*) FROM users WHERE role = ?`
+	query := `
+		SELECT COUNT(*) FROM users u
+		JOIN (SELECT DISTINCT user_id, role_id FROM user_roles` + r.rolesInOrg() + `) ur ON ur.user_id = u.id
+		JOIN roles r ON r.id = ur.role_id
+		WHERE r.name = ? AND u.deleted_at IS NULL` + r.inOrg("u.id")
 
 	err := r.db.QueryRow(query, role).Scan(&count)
 	if err != nil {
@@ -293,3 +791,29 @@ func (r *userRepository) CountByRole(role string) (int64, error) {
 
 	return count, nil
 }
+
+// CountGroupByRole 按角色统计直接持有该角色的用户数（不含已删除的用户），没有用户的角色计为0
+func (r *userRepository) CountGroupByRole() (map[string]int64, error) {
+	query := `
+		SELECT r.name, COUNT(u.id) FROM roles r
+		LEFT JOIN (SELECT DISTINCT user_id, role_id FROM user_roles` + r.rolesInOrg() + `) ur ON ur.role_id = r.id
+		LEFT JOIN users u ON u.id = ur.user_id AND u.deleted_at IS NULL` + r.inOrg("u.id") + `
+		GROUP BY r.id, r.name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var count int64
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+		counts[name] = count
+	}
+	return counts, rows.Err()
+}
@@ -1,12 +1,14 @@
 package router
 
 import (
-	"fmt"
 	"net/http"
 
 	"user-management-system/app"
 	"user-management-system/controllers"
+	"user-management-system/errors"
+	"user-management-system/logger"
 	"user-management-system/middleware"
+	"user-management-system/models"
 	"user-management-system/session"
 )
 
@@ -33,6 +35,11 @@ func (r *Router) Setup() http.Handler {
 	// 创建CSRF中间件
 	csrfMiddleware := session.NewCSRFMiddleware(r.app.SessionManager)
 
+	// 按权限保护路由，权限与角色的对应关系见 roles、role_permissions 表
+	requirePermission := r.middleware.Auth.RequirePermission
+	// 可以委派的用户管理操作：拥有权限或通过委派授权获得了该操作的用户都可以访问，具体能否操作某个用户由服务层判断
+	requireDelegable := r.middleware.Auth.RequireDelegablePermission
+
 	// 静态文件
 	fs := http.FileServer(http.Dir("static"))
 	r.mux.Handle("/static/", http.StripPrefix("/static/", fs))
@@ -45,32 +52,306 @@ func (r *Router) Setup() http.Handler {
 	r.mux.HandleFunc("/register", r.handleRegister)
 	r.mux.HandleFunc("/logout", r.controllers.Auth.HandleLogout)
 
-	// 用户管理（需要认证）
-	r.mux.Handle("/users", r.middleware.Auth.RequireAuth(
+	// 用户管理（需要查看用户列表的权限）
+	r.mux.Handle("/users", requirePermission(models.PermUsersView)(
 		http.HandlerFunc(r.controllers.User.RenderUsersPage),
 	))
 
-	// 用户删除（需要管理员权限 + CSRF保护）
-	r.mux.Handle("/users/delete", r.middleware.Auth.RequireAdmin(
+	// 用户删除（需要相应权限或委派授权 + CSRF保护）
+	r.mux.Handle("/users/delete", requireDelegable(models.PermUsersDelete)(
 		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleDeleteUser)),
 	))
 
-	// 用户更新（需要管理员权限 + CSRF保护）
-	r.mux.Handle("/users/update", r.middleware.Auth.RequireAdmin(
+	// 用户更新（需要相应权限或委派授权 + CSRF保护）
+	r.mux.Handle("/users/update", requireDelegable(models.PermUsersUpdate)(
 		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleUpdateUser)),
 	))
 
+	// 账户状态（需要相应权限或委派授权 + CSRF保护）
+	r.mux.Handle("/users/status", requireDelegable(models.PermUsersStatus)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleUpdateStatus)),
+	))
+
+	// 管理员创建用户（需要相应权限 + CSRF保护）
+	r.mux.Handle("/users/create", requirePermission(models.PermUsersCreate)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleCreateUser)),
+	))
+
+	// 管理员强制重置密码（需要相应权限或委派授权 + CSRF保护）
+	r.mux.Handle("/users/reset-password", requireDelegable(models.PermUsersResetPassword)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleResetPassword)),
+	))
+
+	// 模拟登录（需要相应权限或委派授权 + CSRF保护）
+	r.mux.Handle("/users/impersonate", requireDelegable(models.PermUsersImpersonate)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleStartImpersonation)),
+	))
+
+	// 结束模拟登录（需要认证 + CSRF保护）；放在每个页面的提示条中，只能让会话恢复为管理员本人
+	r.mux.Handle("/impersonation/stop", r.middleware.Auth.RequireAuth(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleStopImpersonation)),
+	))
+
+	// 注册邀请（需要相应权限）
+	r.mux.Handle("/invitations", requirePermission(models.PermInvitationsManage)(
+		http.HandlerFunc(r.controllers.Invitation.RenderInvitationsPage),
+	))
+
+	// 发出、重新发送、撤销邀请（需要相应权限 + CSRF保护）
+	r.mux.Handle("/invitations/create", requirePermission(models.PermInvitationsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Invitation.HandleCreateInvitation)),
+	))
+	r.mux.Handle("/invitations/resend", requirePermission(models.PermInvitationsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Invitation.HandleResendInvitation)),
+	))
+	r.mux.Handle("/invitations/revoke", requirePermission(models.PermInvitationsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Invitation.HandleRevokeInvitation)),
+	))
+
+	// 角色管理（需要相应权限）
+	r.mux.Handle("/roles", requirePermission(models.PermRolesManage)(
+		http.HandlerFunc(r.controllers.Role.RenderRolesPage),
+	))
+	r.mux.Handle("/roles/edit", requirePermission(models.PermRolesManage)(
+		http.HandlerFunc(r.controllers.Role.RenderEditRolePage),
+	))
+
+	// 创建、预览变更、修改、删除角色（需要相应权限 + CSRF保护）
+	r.mux.Handle("/roles/create", requirePermission(models.PermRolesManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Role.HandleCreateRole)),
+	))
+	r.mux.Handle("/roles/preview", requirePermission(models.PermRolesManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Role.HandlePreviewRole)),
+	))
+	r.mux.Handle("/roles/update", requirePermission(models.PermRolesManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Role.HandleUpdateRole)),
+	))
+	r.mux.Handle("/roles/delete", requirePermission(models.PermRolesManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Role.HandleDeleteRole)),
+	))
+
+	// 用户组管理（需要相应权限）
+	r.mux.Handle("/groups", requirePermission(models.PermGroupsManage)(
+		http.HandlerFunc(r.controllers.Group.RenderGroupsPage),
+	))
+	r.mux.Handle("/groups/edit", requirePermission(models.PermGroupsManage)(
+		http.HandlerFunc(r.controllers.Group.RenderEditGroupPage),
+	))
+
+	// 创建、修改、删除用户组，添加和移除成员（需要相应权限 + CSRF保护）
+	r.mux.Handle("/groups/create", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleCreateGroup)),
+	))
+	r.mux.Handle("/groups/update", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleUpdateGroup)),
+	))
+	r.mux.Handle("/groups/delete", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleDeleteGroup)),
+	))
+	r.mux.Handle("/groups/members/add", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleAddMembers)),
+	))
+	r.mux.Handle("/groups/members/remove", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleRemoveMember)),
+	))
+
+	// 委派管理（需要分配角色的权限）
+	r.mux.Handle("/grants", requirePermission(models.PermUsersManage)(
+		http.HandlerFunc(r.controllers.AdminGrant.RenderGrantsPage),
+	))
+	r.mux.Handle("/grants/create", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.AdminGrant.HandleCreateGrant)),
+	))
+	r.mux.Handle("/grants/delete", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.AdminGrant.HandleDeleteGrant)),
+	))
+
+	// 双人审批（需要分配角色的权限）
+	r.mux.Handle("/change-requests", requirePermission(models.PermUsersManage)(
+		http.HandlerFunc(r.controllers.ChangeRequest.RenderChangeRequestsPage),
+	))
+	r.mux.Handle("/change-requests/approve", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.ChangeRequest.HandleApproveChangeRequest)),
+	))
+	r.mux.Handle("/change-requests/reject", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.ChangeRequest.HandleRejectChangeRequest)),
+	))
+
+	// 审计日志（需要查看审计日志的权限）
+	r.mux.Handle("/audit", requirePermission(models.PermAuditView)(
+		http.HandlerFunc(r.controllers.Audit.RenderAuditPage),
+	))
+	r.mux.Handle("/audit/export", requirePermission(models.PermAuditView)(
+		http.HandlerFunc(r.controllers.Audit.HandleExportAudit),
+	))
+
+	// 组织管理（需要相应权限）
+	r.mux.Handle("/organizations", requirePermission(models.PermOrgsManage)(
+		http.HandlerFunc(r.controllers.Organization.RenderOrganizationsPage),
+	))
+	r.mux.Handle("/organizations/edit", requirePermission(models.PermOrgsManage)(
+		http.HandlerFunc(r.controllers.Organization.RenderEditOrganizationPage),
+	))
+
+	// 创建、修改、删除组织，添加和移除成员（需要相应权限 + CSRF保护）
+	r.mux.Handle("/organizations/create", requirePermission(models.PermOrgsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Organization.HandleCreateOrganization)),
+	))
+	r.mux.Handle("/organizations/update", requirePermission(models.PermOrgsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Organization.HandleUpdateOrganization)),
+	))
+	r.mux.Handle("/organizations/delete", requirePermission(models.PermOrgsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Organization.HandleDeleteOrganization)),
+	))
+	r.mux.Handle("/organizations/members/add", requirePermission(models.PermOrgsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Organization.HandleAddMember)),
+	))
+	r.mux.Handle("/organizations/members/remove", requirePermission(models.PermOrgsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Organization.HandleRemoveMember)),
+	))
+
+	// 有效权限（需要认证，查看其他用户需要 users:view 权限，由服务层检查）
+	r.mux.Handle("/users/permissions", r.middleware.Auth.RequireAuth(
+		http.HandlerFunc(r.controllers.User.RenderPermissionsPage),
+	))
+
+	// 回收站（需要相应权限）
+	r.mux.Handle("/users/trash", requirePermission(models.PermUsersRestore)(
+		http.HandlerFunc(r.controllers.User.RenderTrashPage),
+	))
+
+	// 从回收站恢复用户（需要相应权限 + CSRF保护）
+	r.mux.Handle("/users/restore", requirePermission(models.PermUsersRestore)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleRestoreUser)),
+	))
+
+	// 注册审批（需要相应权限）
+	r.mux.Handle("/users/approvals", requirePermission(models.PermUsersApprove)(
+		http.HandlerFunc(r.controllers.User.RenderApprovalsPage),
+	))
+
+	// 批量通过或拒绝注册申请（需要相应权限 + CSRF保护）
+	r.mux.Handle("/users/approvals/process", requirePermission(models.PermUsersApprove)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleApprovals)),
+	))
+
+	// 个人资料（需要认证）
+	r.mux.Handle("/profile", r.middleware.Auth.RequireAuth(
+		http.HandlerFunc(r.controllers.Profile.RenderProfilePage),
+	))
+
+	// 修改邮箱（需要认证 + CSRF保护）
+	r.mux.Handle("/profile/email", r.middleware.Auth.RequireAuth(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Profile.HandleChangeEmail)),
+	))
+
+	// 修改密码（需要认证 + CSRF保护）
+	r.mux.Handle("/profile/password", r.middleware.Auth.RequireAuth(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Profile.HandleChangePassword)),
+	))
+
+	// 强制修改密码（密码过期等情况，需要认证 + CSRF保护）
+	r.mux.Handle("/password/change", r.middleware.Auth.RequireAuth(
+		csrfMiddleware(http.HandlerFunc(r.handlePasswordChange)),
+	))
+
+	// 邮箱验证链接（通过令牌验证，无需登录）
+	r.mux.HandleFunc("/profile/verify-email", r.controllers.Profile.HandleVerifyEmail)
+
+	// 邀请或重置密码链接（通过令牌验证，无需登录）
+	r.mux.HandleFunc("/password/setup", r.handlePasswordSetup)
+
 	// API路由
-	//r.mux.HandleFunc("/api/users", r.controllers.User.HandleAPIUsers)
 	//r.mux.HandleFunc("/api/users/stats", r.controllers.User.HandleAPIUserStats)
 
-	// 健康检查
-	r.mux.HandleFunc("/health", func(w http.ResponseWriter, req *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintln(w, "OK")
-	})
+	// 创建用户（需要相应权限 + CSRF保护，令牌通过 X-CSRF-Token 请求头传递）
+	r.mux.Handle("/api/users", requirePermission(models.PermUsersCreate)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleAPICreateUser)),
+	))
+
+	// 强制重置密码（需要相应权限或委派授权 + CSRF保护）
+	r.mux.Handle("/api/users/reset-password", requireDelegable(models.PermUsersResetPassword)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleAPIResetPassword)),
+	))
+
+	// 修改用户、删除用户、修改账户状态（需要相应权限或委派授权 + CSRF保护）
+	r.mux.Handle("/api/users/update", requireDelegable(models.PermUsersUpdate)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleAPIUpdateUser)),
+	))
+	r.mux.Handle("/api/users/delete", requireDelegable(models.PermUsersDelete)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleAPIDeleteUser)),
+	))
+	r.mux.Handle("/api/users/status", requireDelegable(models.PermUsersStatus)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.User.HandleAPIUpdateStatus)),
+	))
+
+	// 用户组（需要相应权限 + CSRF保护）
+	r.mux.Handle("/api/groups", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleAPIGroups)),
+	))
+	r.mux.Handle("/api/groups/update", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleAPIUpdateGroup)),
+	))
+	r.mux.Handle("/api/groups/delete", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleAPIDeleteGroup)),
+	))
+	r.mux.Handle("/api/groups/members", requirePermission(models.PermGroupsManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.Group.HandleAPIGroupMembers)),
+	))
+
+	// 委派授权（需要分配角色的权限 + CSRF保护）
+	r.mux.Handle("/api/grants", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.AdminGrant.HandleAPIGrants)),
+	))
+	r.mux.Handle("/api/grants/delete", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.AdminGrant.HandleAPIDeleteGrant)),
+	))
+
+	// 双人审批（需要分配角色的权限 + CSRF保护）
+	r.mux.Handle("/api/change-requests", requirePermission(models.PermUsersManage)(
+		http.HandlerFunc(r.controllers.ChangeRequest.HandleAPIChangeRequests),
+	))
+	r.mux.Handle("/api/change-requests/approve", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.ChangeRequest.HandleAPIApproveChangeRequest)),
+	))
+	r.mux.Handle("/api/change-requests/reject", requirePermission(models.PermUsersManage)(
+		csrfMiddleware(http.HandlerFunc(r.controllers.ChangeRequest.HandleAPIRejectChangeRequest)),
+	))
+
+	// 审计日志（需要查看审计日志的权限）
+	r.mux.Handle("/api/audit", requirePermission(models.PermAuditView)(
+		http.HandlerFunc(r.controllers.Audit.HandleAPIAuditEvents),
+	))
+
+	// 有效权限（需要认证，查看其他用户需要 users:view 权限）
+	r.mux.Handle("/api/users/permissions", r.middleware.Auth.RequireAuth(
+		http.HandlerFunc(r.controllers.User.HandleAPIUserPermissions),
+	))
+
+	// Prometheus 指标
+	r.mux.HandleFunc("/metrics", r.controllers.Metrics.ServeMetrics)
 
-	return r.mux
+	// 存活与就绪检查（/health 保留为就绪检查的别名）
+	r.mux.HandleFunc("/healthz", r.controllers.Health.ServeLiveness)
+	r.mux.HandleFunc("/readyz", r.controllers.Health.ServeReadiness)
+	r.mux.HandleFunc("/health", r.controllers.Health.ServeReadiness)
+
+	// 请求 ID 最先确定，之后的访问日志、错误响应和审计事件都带有请求 ID；追踪在访问日志之外开始，访问日志带有追踪 ID
+	// 访问日志和请求指标记录包括 panic 在内的最终响应；组织解析在路由匹配之前执行，路径前缀 /o/{标识}/ 去掉后再匹配路由
+	// 被要求修改密码的会话在修改完成前不能访问其他页面
+	return middleware.RequestID(middleware.Trace(middleware.AccessLog(middleware.Metrics(errors.RecoverMiddleware(
+		r.middleware.Tenant.Resolve(r.middleware.Auth.EnforcePasswordChange(r.withRoute(r.mux))),
+	)))))
+}
+
+// withRoute 把请求匹配的路由模式记录到请求日志中
+func (r *Router) withRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, pattern := r.mux.Handler(req); pattern != "" {
+			logger.SetRequestRoute(req.Context(), pattern)
+		}
+		next.ServeHTTP(w, req)
+	})
 }
 
 func (r *Router) handleHome(w http.ResponseWriter, req *http.Request) {
@@ -96,3 +377,19 @@ func (r *Router) handleRegister(w http.ResponseWriter, req *http.Request) {
 		r.controllers.Auth.RenderRegisterPage(w, req)
 	}
 }
+
+func (r *Router) handlePasswordChange(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		r.controllers.Profile.HandleRequiredPasswordChange(w, req)
+	} else {
+		r.controllers.Profile.RenderRequiredPasswordChangePage(w, req)
+	}
+}
+
+func (r *Router) handlePasswordSetup(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		r.controllers.Auth.HandlePasswordSetup(w, req)
+	} else {
+		r.controllers.Auth.RenderPasswordSetupPage(w, req)
+	}
+}
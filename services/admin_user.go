@@ -0,0 +1,307 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/errors"
+	"user-management-system/mail"
+	"user-management-system/models"
+	"user-management-system/password"
+)
+
+// 管理员创建用户、重置密码时设置密码的方式
+const (
+	PasswordModeManual    = "manual"    // 管理员直接设置密码（仅创建用户时可用）
+	PasswordModeTemporary = "temporary" // 生成一次性临时密码，用户首次登录后必须修改
+	PasswordModeLink      = "link"      // 通过邮件发送设置密码链接，由用户自己设置
+)
+
+// CreateUserInput 管理员创建用户的参数
+type CreateUserInput struct {
+	Username     string   `json:"username"`
+	Email        string   `json:"email"`
+	Roles        []string `json:"roles"`         // 为空时为普通用户角色
+	Status       string   `json:"status"`        // 为空时为正常状态
+	PasswordMode string   `json:"password_mode"` // 见 PasswordMode* 常量，为空时为 manual
+	Password     string   `json:"password"`      // 仅 manual 模式使用
+}
+
+// PasswordSetup 管理员创建用户或重置密码后需要告知管理员的结果
+type PasswordSetup struct {
+	TemporaryPassword string `json:"temporary_password,omitempty"` // 生成的临时密码，只返回这一次
+	LinkSentTo        string `json:"link_sent_to,omitempty"`       // 设置密码链接发送到的邮箱
+}
+
+// CreateUser 管理员直接创建用户，可以指定角色和账户状态
+// 提升管理员需要审批时，不能直接创建拥有分配角色权限的用户
+func (s *userServiceImpl) CreateUser(actor *models.User, input *CreateUserInput) (*models.User, *PasswordSetup, error) {
+	if err := requirePermission(actor, models.PermUsersCreate); err != nil {
+		return nil, nil, err
+	}
+
+	username := strings.TrimSpace(input.Username)
+	email := strings.TrimSpace(input.Email)
+	if err := validateUsername(username); err != nil {
+		return nil, nil, err
+	}
+	if email == "" {
+		return nil, nil, errors.NewValidationError("email", "邮箱不能为空")
+	}
+	if !strings.Contains(email, "@") {
+		return nil, nil, errors.NewValidationError("email", "邮箱格式不正确")
+	}
+	roleNames := input.Roles
+	if len(roleNames) == 0 {
+		roleNames = []string{models.RoleUser}
+	}
+	roles, permissions, err := s.resolveRoles(actor, roleNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.refuseUnapprovedPromotion(permissions[models.PermUsersManage], "创建该用户"); err != nil {
+		return nil, nil, err
+	}
+	status := input.Status
+	if status == "" {
+		status = models.StatusActive
+	}
+	if status != models.StatusActive && status != models.StatusPending && status != models.StatusDisabled {
+		return nil, nil, errors.NewValidationError("status", "新用户只能是正常、待审批或已停用状态")
+	}
+	mode := input.PasswordMode
+	if mode == "" {
+		mode = PasswordModeManual
+	}
+
+	exists, err := s.usernameTaken(username)
+	if err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("检查用户名失败: %w", err))
+	}
+	if exists {
+		return nil, nil, errors.NewConflictError("用户名已存在")
+	}
+	emailExists, err := s.emailTaken(email, 0)
+	if err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
+	}
+	if emailExists {
+		return nil, nil, errors.NewConflictError("邮箱已被使用")
+	}
+
+	user := &models.User{
+		Username: username,
+		Email:    email,
+		Roles:    roles,
+		Status:   status,
+	}
+	setup := &PasswordSetup{}
+
+	var plainPassword string
+	switch mode {
+	case PasswordModeManual:
+		if err := s.passwordPolicy.Validate(input.Password, password.Context{Username: username, Email: email}); err != nil {
+			return nil, nil, err
+		}
+		plainPassword = input.Password
+	case PasswordModeTemporary:
+		if plainPassword, err = s.generateTemporaryPassword(user); err != nil {
+			return nil, nil, err
+		}
+		user.MustChangePassword = true
+		setup.TemporaryPassword = plainPassword
+	case PasswordModeLink:
+		// 用户通过邮件链接设置密码之前，使用一个无人知道的随机密码
+		if plainPassword, _, err = newToken(); err != nil {
+			return nil, nil, errors.NewInternalError(fmt.Errorf("生成随机密码失败: %w", err))
+		}
+	default:
+		return nil, nil, errors.NewValidationError("password_mode", "无效的密码设置方式")
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(plainPassword)
+	if err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("设置密码失败: %w", err))
+	}
+	user.Password = hashedPassword
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("保存用户失败: %w", err))
+	}
+
+	switch mode {
+	case PasswordModeManual:
+		s.recordPasswordHistory(user.ID, user.Password)
+	case PasswordModeLink:
+		if err := s.sendPasswordLink(user, models.PasswordTokenInvite); err != nil {
+			return nil, nil, err
+		}
+		setup.LinkSentTo = user.Email
+	}
+	return user, setup, nil
+}
+
+// ForcePasswordReset 强制重置用户密码，当前密码立即失效，需要 users:reset_password 权限或对该用户的委派授权
+// 调用方负责让该用户已有的会话失效
+func (s *userServiceImpl) ForcePasswordReset(actor *models.User, id int, mode string) (*PasswordSetup, error) {
+	if err := requireNotImpersonated(actor); err != nil {
+		return nil, err
+	}
+	if id == actor.ID {
+		return nil, errors.NewForbiddenError("请在个人资料页修改自己的密码")
+	}
+	if mode != PasswordModeTemporary && mode != PasswordModeLink {
+		return nil, errors.NewValidationError("mode", "无效的密码重置方式")
+	}
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+	}
+	if user == nil {
+		return nil, errors.NewNotFoundError("用户")
+	}
+	if err := s.authorizeOver(actor, user, models.PermUsersResetPassword); err != nil {
+		return nil, err
+	}
+
+	setup := &PasswordSetup{}
+	var plainPassword string
+	if mode == PasswordModeTemporary {
+		if plainPassword, err = s.generateTemporaryPassword(user); err != nil {
+			return nil, err
+		}
+		setup.TemporaryPassword = plainPassword
+	} else if plainPassword, _, err = newToken(); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("生成随机密码失败: %w", err))
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(plainPassword)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("设置密码失败: %w", err))
+	}
+	if err := s.userRepo.ResetPassword(user.ID, hashedPassword, mode == PasswordModeTemporary); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("重置密码失败: %w", err))
+	}
+
+	if mode == PasswordModeLink {
+		if err := s.sendPasswordLink(user, models.PasswordTokenReset); err != nil {
+			return nil, err
+		}
+		setup.LinkSentTo = user.Email
+	} else if err := s.tokenRepo.DeleteByUserID(user.ID); err != nil {
+		// 之前发出的链接不再有效
+		return nil, errors.NewInternalError(fmt.Errorf("清理旧链接失败: %w", err))
+	}
+	return setup, nil
+}
+
+// VerifyPasswordSetupToken 校验邮件中的设置密码链接，返回对应的用户和链接记录
+func (s *userServiceImpl) VerifyPasswordSetupToken(token string) (*models.User, *models.PasswordToken, error) {
+	if token == "" {
+		return nil, nil, errors.NewValidationError("token", "链接无效")
+	}
+
+	record, err := s.tokenRepo.GetByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("查询链接失败: %w", err))
+	}
+	if record == nil {
+		return nil, nil, errors.NewValidationError("token", "链接无效或已被使用")
+	}
+	if record.IsExpired() {
+		return nil, nil, errors.NewValidationError("token", "链接已过期，请联系管理员重新发送")
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("获取用户失败: %w", err))
+	}
+	if user == nil {
+		return nil, nil, errors.NewNotFoundError("用户")
+	}
+	return user, record, nil
+}
+
+// CompletePasswordSetup 通过邮件中的链接设置新密码，链接使用后失效
+func (s *userServiceImpl) CompletePasswordSetup(token, newPassword string) (*models.User, error) {
+	user, _, err := s.VerifyPasswordSetupToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return nil, err
+	}
+	if err := s.tokenRepo.DeleteByUserID(user.ID); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("清理链接失败: %w", err))
+	}
+	return user, nil
+}
+
+// sendPasswordLink 生成设置密码链接并通过邮件发送给用户，旧链接随之失效
+func (s *userServiceImpl) sendPasswordLink(user *models.User, purpose string) error {
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("生成链接令牌失败: %w", err))
+	}
+
+	if err := s.tokenRepo.DeleteByUserID(user.ID); err != nil {
+		return errors.NewInternalError(fmt.Errorf("清理旧链接失败: %w", err))
+	}
+	record := &models.PasswordToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.cfg.PasswordSetupLinkTTL),
+	}
+	if err := s.tokenRepo.Create(record); err != nil {
+		return errors.NewInternalError(fmt.Errorf("保存链接失败: %w", err))
+	}
+
+	link := s.cfg.BaseURL + "/password/setup?token=" + token
+	expires := record.ExpiresAt.Format("2006-01-02 15:04")
+	msg := &mail.Message{To: user.Email}
+	if purpose == models.PasswordTokenInvite {
+		msg.Subject = "您的账户已创建"
+		msg.Body = fmt.Sprintf("%s，您好：\n\n管理员已为您创建了账户（用户名：%s），请在 %s 前打开以下链接设置密码：\n\n%s\n",
+			user.Username, user.Username, expires, link)
+	} else {
+		msg.Subject = "请重置您的密码"
+		msg.Body = fmt.Sprintf("%s，您好：\n\n管理员已重置了您的密码，原密码已失效。请在 %s 前打开以下链接设置新密码：\n\n%s\n",
+			user.Username, expires, link)
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return errors.NewInternalError(fmt.Errorf("发送邮件失败: %w", err))
+	}
+	return nil
+}
+
+// generateTemporaryPassword 生成符合当前密码策略的一次性临时密码
+func (s *userServiceImpl) generateTemporaryPassword(user *models.User) (string, error) {
+	ctx := password.Context{Username: user.Username, Email: user.Email}
+
+	// 随机生成的密码几乎总能通过策略，多试几次以防万一
+	for i := 0; i < 5; i++ {
+		plain, err := password.Generate(s.cfg.TemporaryPasswordLength)
+		if err != nil {
+			return "", errors.NewInternalError(fmt.Errorf("生成临时密码失败: %w", err))
+		}
+		if s.passwordPolicy.Validate(plain, ctx) == nil {
+			return plain, nil
+		}
+	}
+	return "", errors.NewInternalError(fmt.Errorf("无法生成符合密码策略的临时密码"))
+}
+
+// validateUsername 校验用户名格式
+func validateUsername(username string) error {
+	if username == "" {
+		return errors.NewValidationError("username", "用户名不能为空")
+	}
+	if len(username) < 3 || len(username) > 20 {
+		return errors.NewValidationError("username", "用户名长度必须在3到20个字符之间")
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"user-management-system/errors"
+	"user-management-system/mail"
+	"user-management-system/models"
+)
+
+// ApprovalFailure 批量审批中未能处理的一个用户
+type ApprovalFailure struct {
+	UserID  int
+	Message string
+}
+
+// ApprovalResult 批量审批的结果
+type ApprovalResult struct {
+	Processed []*models.User    // 已处理的用户
+	Failed    []ApprovalFailure // 未能处理的用户及原因
+}
+
+// GetPendingUsers 获取等待审批的用户，最早注册的在前
+func (s *userServiceImpl) GetPendingUsers() ([]*models.User, error) {
+	users, err := s.userRepo.GetByStatus(models.StatusPending)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取待审批用户失败: %w", err))
+	}
+	return users, nil
+}
+
+// ApproveUsers 批量通过注册申请，通过后用户可以登录并会收到通知邮件
+// 单个用户处理失败不影响其他用户，失败原因记录在结果中
+func (s *userServiceImpl) ApproveUsers(actor *models.User, ids []int) (*ApprovalResult, error) {
+	if err := requirePermission(actor, models.PermUsersApprove); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, errors.NewValidationError("user_ids", "请选择要审批的用户")
+	}
+
+	return s.processApprovals(ids, func(user *models.User) error {
+		if err := s.userRepo.Approve(user.ID); err != nil {
+			return s.approvalRepoError(err)
+		}
+		s.notifyApproval(user, true, "")
+		return nil
+	}), nil
+}
+
+// RejectUsers 批量拒绝注册申请，被拒绝的用户移入回收站并会收到通知邮件
+// reason 为可选的拒绝原因，会记录在账户上并写入通知邮件
+func (s *userServiceImpl) RejectUsers(actor *models.User, ids []int, reason string) (*ApprovalResult, error) {
+	if err := requirePermission(actor, models.PermUsersApprove); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, errors.NewValidationError("user_ids", "请选择要审批的用户")
+	}
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > 255 {
+		return nil, errors.NewValidationError("reason", "原因不能超过255个字符")
+	}
+
+	return s.processApprovals(ids, func(user *models.User) error {
+		if err := s.userRepo.Reject(user.ID, reason); err != nil {
+			return s.approvalRepoError(err)
+		}
+		s.notifyApproval(user, false, reason)
+		return nil
+	}), nil
+}
+
+// processApprovals 对每个待审批用户执行 apply，收集处理结果
+func (s *userServiceImpl) processApprovals(ids []int, apply func(user *models.User) error) *ApprovalResult {
+	result := &ApprovalResult{}
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		user, err := s.userRepo.GetByID(id)
+		if err != nil {
+			result.Failed = append(result.Failed, ApprovalFailure{UserID: id, Message: "查询用户失败"})
+			s.log().Error("查询待审批用户失败", "user_id", id, "error", err)
+			continue
+		}
+		if user == nil {
+			result.Failed = append(result.Failed, ApprovalFailure{UserID: id, Message: "用户不存在"})
+			continue
+		}
+		if user.Status != models.StatusPending {
+			result.Failed = append(result.Failed, ApprovalFailure{UserID: id, Message: user.Username + " 不在待审批状态"})
+			continue
+		}
+
+		if err := apply(user); err != nil {
+			message := err.Error()
+			if appErr, ok := errors.IsAppError(err); ok {
+				message = appErr.Message
+			}
+			result.Failed = append(result.Failed, ApprovalFailure{UserID: id, Message: user.Username + " " + message})
+			continue
+		}
+		result.Processed = append(result.Processed, user)
+	}
+	return result
+}
+
+// approvalRepoError 转换审批时的仓库错误，没有匹配到行说明用户已被其他管理员处理
+func (s *userServiceImpl) approvalRepoError(err error) error {
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return errors.NewConflictError("已被其他管理员处理")
+	}
+	s.log().Error("更新审批状态失败", "error", err)
+	return errors.NewInternalError(fmt.Errorf("更新审批状态失败: %w", err))
+}
+
+// notifyApproval 将审批结果通过邮件通知用户，发送失败只记录日志，不影响审批结果
+func (s *userServiceImpl) notifyApproval(user *models.User, approved bool, reason string) {
+	msg := &mail.Message{To: user.Email}
+	if approved {
+		msg.Subject = "您的注册申请已通过"
+		msg.Body = fmt.Sprintf("%s，您好：\n\n您的注册申请已通过审批，现在可以登录了：\n\n%s\n",
+			user.Username, s.cfg.BaseURL+"/login")
+	} else {
+		msg.Subject = "您的注册申请未通过"
+		msg.Body = fmt.Sprintf("%s，您好：\n\n很遗憾，您的注册申请未通过审批。\n", user.Username)
+		if reason != "" {
+			msg.Body += fmt.Sprintf("\n原因：%s\n", reason)
+		}
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		s.log().Warn("发送审批结果通知失败", "user", user.Username, "error", err)
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// 审计日志每页显示的事件数
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+)
+
+// AuditPage 一页审计事件
+type AuditPage struct {
+	Events   []*models.AuditEvent `json:"events"`
+	Total    int                  `json:"total"`     // 符合条件的事件总数
+	Page     int                  `json:"page"`      // 当前页码，从1开始
+	PageSize int                  `json:"page_size"` // 每页事件数
+}
+
+// Pages 返回总页数
+func (p *AuditPage) Pages() int {
+	if p.Total == 0 {
+		return 1
+	}
+	return (p.Total + p.PageSize - 1) / p.PageSize
+}
+
+// HasPrev 检查是否有上一页
+func (p *AuditPage) HasPrev() bool {
+	return p.Page > 1
+}
+
+// HasNext 检查是否有下一页
+func (p *AuditPage) HasNext() bool {
+	return p.Page < p.Pages()
+}
+
+// AuditService 审计日志服务接口：查询和导出审计事件，为哈希链生成签名检查点
+// 审计事件由各服务的审计装饰器写入
+type AuditService interface {
+	// ForOrganization 返回只查询指定组织的审计事件的服务，orgID 为 0 时不限定
+	ForOrganization(orgID int) AuditService
+
+	// WithContext 返回以 ctx 中的追踪 span 为父 span 的服务
+	WithContext(ctx context.Context) AuditService
+
+	GetAuditEvents(filter *models.AuditFilter, page, pageSize int) (*AuditPage, error)
+	ExportAuditEvents(filter *models.AuditFilter, fn func(*models.AuditEvent) error) error
+	CheckpointAuditChain() (*models.AuditCheckpoint, error)
+}
+
+// auditServiceImpl 是 AuditService 接口的具体实现
+type auditServiceImpl struct {
+	auditRepo   interfaces.AuditRepository
+	auditSigner *audit.Signer
+}
+
+// NewAuditService 创建审计日志服务，启用追踪时每个方法记录一个 span
+func NewAuditService(deps *ServiceDependencies) AuditService {
+	return newTracingAuditService(&auditServiceImpl{auditRepo: deps.AuditRepository, auditSigner: deps.AuditSigner})
+}
+
+// ForOrganization 返回只查询指定组织的审计事件的服务副本
+func (s *auditServiceImpl) ForOrganization(orgID int) AuditService {
+	return &auditServiceImpl{auditRepo: s.auditRepo.ForOrganization(orgID), auditSigner: s.auditSigner}
+}
+
+// WithContext 返回在 ctx 中执行语句的服务副本
+func (s *auditServiceImpl) WithContext(ctx context.Context) AuditService {
+	return &auditServiceImpl{auditRepo: s.auditRepo.WithContext(ctx), auditSigner: s.auditSigner}
+}
+
+// GetAuditEvents 按条件分页查询审计事件，最近发生的在前
+func (s *auditServiceImpl) GetAuditEvents(filter *models.AuditFilter, page, pageSize int) (*AuditPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultAuditPageSize
+	}
+	if pageSize > MaxAuditPageSize {
+		pageSize = MaxAuditPageSize
+	}
+
+	events, total, err := s.auditRepo.Find(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询审计日志失败: %w", err))
+	}
+	if events == nil {
+		events = []*models.AuditEvent{}
+	}
+	return &AuditPage{Events: events, Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+// ExportAuditEvents 按发生时间顺序把符合条件的全部审计事件逐条交给 fn（用于导出）
+func (s *auditServiceImpl) ExportAuditEvents(filter *models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	if err := s.auditRepo.Each(filter, fn); err != nil {
+		return errors.NewInternalError(fmt.Errorf("导出审计日志失败: %w", err))
+	}
+	return nil
+}
+
+// CheckpointAuditChain 用审计签名密钥为哈希链当前的末端签名并保存为检查点
+// 没有事件或末端已有检查点时返回 nil；未配置签名密钥时返回错误
+func (s *auditServiceImpl) CheckpointAuditChain() (*models.AuditCheckpoint, error) {
+	return checkpointAuditChain(s.auditRepo, s.auditSigner)
+}
+
+// checkpointAuditChain 用 signer 为 auditRepo 中哈希链的末端生成检查点（审计装饰器按条数生成检查点时也使用）
+func checkpointAuditChain(auditRepo interfaces.AuditRepository, auditSigner *audit.Signer) (*models.AuditCheckpoint, error) {
+	if auditSigner == nil {
+		return nil, errors.NewInternalError(stderrors.New("未配置审计签名密钥"))
+	}
+
+	head, err := auditRepo.ChainHead()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("读取审计哈希链失败: %w", err))
+	}
+	if head.Seq == 0 {
+		return nil, nil
+	}
+	latest, err := auditRepo.GetLatestCheckpoint()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("读取审计检查点失败: %w", err))
+	}
+	if latest != nil && latest.Seq >= head.Seq {
+		return nil, nil
+	}
+
+	cp := &models.AuditCheckpoint{
+		Seq:       head.Seq,
+		EventID:   head.EventID,
+		EventHash: head.Hash,
+		CreatedAt: audit.Timestamp(time.Now()),
+	}
+	auditSigner.Sign(cp)
+
+	created, err := auditRepo.CreateCheckpoint(cp)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("保存审计检查点失败: %w", err))
+	}
+	if !created {
+		// 并发生成了同一序号的检查点
+		return nil, nil
+	}
+	return cp, nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"time"
+
+	"user-management-system/logger"
+)
+
+// StartAuditCheckpointJob 启动后台任务，每隔 interval 为审计哈希链的末端生成一次签名检查点，
+// 保证写入量很少时最近的事件也能及时受到签名保护；末端已有检查点时跳过
+// 返回的函数用于停止任务；interval 不大于0时不启动
+func StartAuditCheckpointJob(auditService AuditService, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				runAuditCheckpoint(auditService)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// runAuditCheckpoint 生成一次检查点并记录结果
+func runAuditCheckpoint(auditService AuditService) {
+	cp, err := auditService.CheckpointAuditChain()
+	if err != nil {
+		logger.Error("生成审计检查点失败", "error", err)
+		return
+	}
+	if cp != nil {
+		logger.Info("已生成审计检查点", "seq", cp.Seq, "hash", cp.EventHash)
+	}
+}
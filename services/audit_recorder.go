@@ -0,0 +1,821 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"user-management-system/audit"
+	"user-management-system/config"
+	"user-management-system/errors"
+	"user-management-system/logger"
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// auditor 写入审计事件：包括操作者、模拟登录的管理员、操作对象、操作前后的值、请求信息和结果
+// 各服务的审计装饰器共用；审计事件写入失败只记录日志，不影响操作本身
+// 每写入 checkpointEvery 条事件为哈希链末端生成一个签名检查点
+type auditor struct {
+	auditRepo       interfaces.AuditRepository
+	auditSigner     *audit.Signer
+	meta            audit.Meta
+	checkpointEvery int
+	ctx             context.Context // 执行的请求上下文，为 nil 时使用全局日志记录器
+}
+
+// newAuditor 使用 deps 中的审计仓库和签名器，checkpointEvery 不大于0时不按条数生成检查点
+func newAuditor(deps *ServiceDependencies) auditor {
+	return auditor{
+		auditRepo:       deps.AuditRepository,
+		auditSigner:     deps.AuditSigner,
+		checkpointEvery: config.GetConfig().AuditCheckpointEvery,
+	}
+}
+
+// forRequest 返回记录 meta 中请求信息的副本
+func (a auditor) forRequest(meta audit.Meta) auditor {
+	a.meta = meta
+	return a
+}
+
+// withContext 返回在 ctx 中写入审计事件的副本，写入失败的日志使用 ctx 中请求范围的日志记录器
+func (a auditor) withContext(ctx context.Context) auditor {
+	a.auditRepo = a.auditRepo.WithContext(ctx)
+	a.ctx = ctx
+	return a
+}
+
+// auditingService 在 UserService 之外记录审计事件：每次修改数据和登录都写入一条事件
+// 查询类方法直接使用内层服务
+type auditingService struct {
+	UserService
+	auditor
+}
+
+// newAuditingService 为服务加上审计记录
+func newAuditingService(inner UserService, a auditor) UserService {
+	return &auditingService{UserService: inner, auditor: a}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录审计事件
+func (a *auditingService) ForOrganization(orgID int) UserService {
+	return &auditingService{UserService: a.UserService.ForOrganization(orgID), auditor: a.auditor}
+}
+
+// ForRequest 返回记录审计事件时使用 meta 中请求信息的服务
+func (a *auditingService) ForRequest(meta audit.Meta) UserService {
+	return &auditingService{UserService: a.UserService.ForRequest(meta), auditor: a.forRequest(meta)}
+}
+
+// WithContext 返回在 ctx 中执行的服务，审计事件的写入同样在 ctx 中执行
+func (a *auditingService) WithContext(ctx context.Context) UserService {
+	return &auditingService{UserService: a.UserService.WithContext(ctx), auditor: a.withContext(ctx)}
+}
+
+// auditTarget 审计事件的操作对象
+type auditTarget struct {
+	Type string
+	ID   int
+	Name string
+}
+
+// auditSystemActor 后台任务等没有操作者的事件使用的操作者名称
+const auditSystemActor = "system"
+
+// 审计事件中文本字段的最大保存长度，与 audit_events 表的列一致
+const (
+	maxAuditName      = 50 // actor_name 和 impersonator_name
+	maxAuditTarget    = 100
+	maxAuditRequestID = 64
+	maxAuditError     = 255
+)
+
+// record 写入一条审计事件；err 不为空时结果为失败，pending 为 true 时结果为待审批
+// actor 为空时 actorName 作为操作者名称（如登录失败时尝试的用户名）
+func (a *auditor) record(action string, actor *models.User, actorName string, target auditTarget,
+	before, after interface{}, pending bool, err error) {
+	event := &models.AuditEvent{
+		OrgID:      a.meta.OrgID,
+		OccurredAt: time.Now(),
+		ActorName:  actorName,
+		Action:     action,
+		TargetType: target.Type,
+		TargetID:   target.ID,
+		TargetName: target.Name,
+		Before:     auditValue(before),
+		After:      auditValue(after),
+		IP:         a.meta.IP,
+		UserAgent:  a.meta.UserAgent,
+		RequestID:  a.meta.RequestID,
+		Outcome:    models.AuditSuccess,
+	}
+	if actor != nil {
+		event.ActorID = actor.ID
+		event.ActorName = actor.Username
+		if actor.Impersonator != nil {
+			event.ImpersonatorID = actor.Impersonator.ID
+			event.ImpersonatorName = actor.Impersonator.Username
+		}
+	}
+	if event.ActorName == "" {
+		event.ActorName = auditSystemActor
+	}
+	// 登录失败时的用户名和请求头来自客户端，超出列长度时截断，避免事件无法写入
+	event.ActorName = truncateAuditText(event.ActorName, maxAuditName)
+	event.TargetName = truncateAuditText(event.TargetName, maxAuditTarget)
+	event.RequestID = truncateAuditText(event.RequestID, maxAuditRequestID)
+	switch {
+	case err != nil:
+		event.Outcome = models.AuditFailure
+		event.Error = auditErrorMessage(err)
+	case pending:
+		event.Outcome = models.AuditPending
+	}
+
+	log := logger.L()
+	if a.ctx != nil {
+		log = logger.FromContext(a.ctx)
+	}
+	if err := a.auditRepo.Create(event); err != nil {
+		log.Error("写入审计事件失败", "action", action, "actor", event.ActorName, "error", err)
+		return
+	}
+
+	if a.checkpointEvery > 0 && event.Seq%int64(a.checkpointEvery) == 0 {
+		if _, err := checkpointAuditChain(a.auditRepo, a.auditSigner); err != nil {
+			log.Error("生成审计检查点失败", "error", err)
+		}
+	}
+}
+
+// auditValue 将操作前后的值序列化为 JSON，值为空时返回 nil
+func auditValue(value interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// auditErrorMessage 返回记录在审计事件中的失败原因：可以展示给用户的错误使用其提示，其余使用错误详情
+func auditErrorMessage(err error) string {
+	message := err.Error()
+	if appErr, ok := errors.IsAppError(err); ok && appErr.Type != errors.InternalError {
+		message = appErr.Message
+	}
+	return truncateAuditText(message, maxAuditError)
+}
+
+// truncateAuditText 把文本截断到至多 max 字节，不截断多字节字符
+func truncateAuditText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// userTarget 返回以用户为操作对象的审计目标
+func userTarget(id int, users ...*models.User) auditTarget {
+	target := auditTarget{Type: models.AuditTargetUser, ID: id}
+	for _, u := range users {
+		if u != nil {
+			target.Name = u.Username
+			break
+		}
+	}
+	return target
+}
+
+// auditUser 审计事件中记录的用户信息，不包含密码等敏感字段
+type auditUser struct {
+	Username           string   `json:"username"`
+	Email              string   `json:"email"`
+	Roles              []string `json:"roles"`
+	Groups             []string `json:"groups"`
+	Status             string   `json:"status"`
+	StatusReason       string   `json:"status_reason,omitempty"`
+	MustChangePassword bool     `json:"must_change_password,omitempty"`
+}
+
+// userSnapshot 返回用户在审计事件中记录的信息，用户为空时返回 nil
+func userSnapshot(u *models.User) interface{} {
+	if u == nil {
+		return nil
+	}
+	return &auditUser{
+		Username:           u.Username,
+		Email:              u.Email,
+		Roles:              u.Roles,
+		Groups:             u.Groups,
+		Status:             u.Status,
+		StatusReason:       u.StatusReason,
+		MustChangePassword: u.MustChangePassword,
+	}
+}
+
+// lookupUser 查询用户当前的状态，用于记录操作前后的值；查询失败时返回 nil
+func (a *auditingService) lookupUser(id int) *models.User {
+	user, err := a.UserService.GetUserByID(id)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// invitationSnapshot 返回邀请在审计事件中记录的信息（不包含令牌）
+func invitationSnapshot(inv *models.Invitation) interface{} {
+	if inv == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"email":      inv.Email,
+		"role":       inv.Role,
+		"expires_at": inv.ExpiresAt,
+	}
+}
+
+// approvalSnapshot 返回批量审批结果在审计事件中记录的信息
+func approvalSnapshot(ids []int, reason string, result *ApprovalResult) interface{} {
+	value := map[string]interface{}{"user_ids": ids}
+	if reason != "" {
+		value["reason"] = reason
+	}
+	if result != nil {
+		processed := make([]string, 0, len(result.Processed))
+		for _, u := range result.Processed {
+			processed = append(processed, u.Username)
+		}
+		value["processed"] = processed
+		if len(result.Failed) > 0 {
+			value["failed"] = result.Failed
+		}
+	}
+	return value
+}
+
+// RegisterUser 注册用户并记录审计事件，注册成功时操作者为新用户
+func (a *auditingService) RegisterUser(username, password, email, invitationToken string) (*models.User, error) {
+	user, err := a.UserService.RegisterUser(username, password, email, invitationToken)
+	var id int
+	if user != nil {
+		id = user.ID
+	}
+	a.record(models.AuditRegister, user, username, auditTarget{Type: models.AuditTargetUser, ID: id, Name: username},
+		nil, userSnapshot(user), false, err)
+	return user, err
+}
+
+// AuthenticateUser 验证登录并记录审计事件，登录失败时记录尝试的用户名
+func (a *auditingService) AuthenticateUser(username, password string) (*models.User, error) {
+	user, err := a.UserService.AuthenticateUser(username, password)
+	var id int
+	if user != nil {
+		id = user.ID
+	}
+	a.record(models.AuditLogin, user, username, auditTarget{Type: models.AuditTargetUser, ID: id, Name: username},
+		nil, nil, false, err)
+	return user, err
+}
+
+// UpdateUser 修改用户并记录审计事件，需要审批时记录提交的变更申请
+func (a *auditingService) UpdateUser(actor *models.User, id int, email string, roles []string) (*models.ChangeRequest, error) {
+	before := a.lookupUser(id)
+	pending, err := a.UserService.UpdateUser(actor, id, email, roles)
+
+	var after interface{}
+	if pending != nil {
+		after = pending
+	} else if err == nil {
+		after = userSnapshot(a.lookupUser(id))
+	}
+	a.record(models.AuditUserUpdate, actor, "", userTarget(id, before), userSnapshot(before), after, pending != nil, err)
+	return pending, err
+}
+
+// DeleteUser 删除用户并记录审计事件，需要审批时记录提交的变更申请
+func (a *auditingService) DeleteUser(actor *models.User, id int) (*models.ChangeRequest, error) {
+	before := a.lookupUser(id)
+	pending, err := a.UserService.DeleteUser(actor, id)
+
+	var after interface{}
+	if pending != nil {
+		after = pending
+	}
+	a.record(models.AuditUserDelete, actor, "", userTarget(id, before), userSnapshot(before), after, pending != nil, err)
+	return pending, err
+}
+
+// ChangeStatus 修改账户状态并记录审计事件
+func (a *auditingService) ChangeStatus(actor *models.User, id int, status, reason string) error {
+	before := a.lookupUser(id)
+	err := a.UserService.ChangeStatus(actor, id, status, reason)
+
+	var after interface{}
+	if err == nil {
+		after = userSnapshot(a.lookupUser(id))
+	}
+	a.record(models.AuditUserStatus, actor, "", userTarget(id, before), userSnapshot(before), after, false, err)
+	return err
+}
+
+// CreateUser 创建用户并记录审计事件（不记录密码）
+func (a *auditingService) CreateUser(actor *models.User, input *CreateUserInput) (*models.User, *PasswordSetup, error) {
+	user, setup, err := a.UserService.CreateUser(actor, input)
+
+	target := auditTarget{Type: models.AuditTargetUser, Name: input.Username}
+	if user != nil {
+		target.ID = user.ID
+	}
+	a.record(models.AuditUserCreate, actor, "", target, nil, userSnapshot(user), false, err)
+	return user, setup, err
+}
+
+// ForcePasswordReset 重置用户密码并记录审计事件（不记录临时密码）
+func (a *auditingService) ForcePasswordReset(actor *models.User, id int, mode string) (*PasswordSetup, error) {
+	setup, err := a.UserService.ForcePasswordReset(actor, id, mode)
+	a.record(models.AuditUserResetPassword, actor, "", userTarget(id, a.lookupUser(id)),
+		nil, map[string]string{"mode": mode}, false, err)
+	return setup, err
+}
+
+// CompletePasswordSetup 通过邮件链接设置密码并记录审计事件，操作者为用户本人
+func (a *auditingService) CompletePasswordSetup(token, newPassword string) (*models.User, error) {
+	user, err := a.UserService.CompletePasswordSetup(token, newPassword)
+	var id int
+	if user != nil {
+		id = user.ID
+	}
+	a.record(models.AuditPasswordSetup, user, "", userTarget(id, user), nil, nil, false, err)
+	return user, err
+}
+
+// CreateInvitation 发出邀请并记录审计事件
+func (a *auditingService) CreateInvitation(actor *models.User, email, role string) (*models.Invitation, error) {
+	inv, err := a.UserService.CreateInvitation(actor, email, role)
+
+	target := auditTarget{Type: models.AuditTargetInvitation, Name: email}
+	after := invitationSnapshot(inv)
+	if inv != nil {
+		target.ID = inv.ID
+	} else {
+		after = map[string]string{"email": email, "role": role}
+	}
+	a.record(models.AuditInvitationCreate, actor, "", target, nil, after, false, err)
+	return inv, err
+}
+
+// ResendInvitation 重新发送邀请并记录审计事件
+func (a *auditingService) ResendInvitation(actor *models.User, id int) (*models.Invitation, error) {
+	inv, err := a.UserService.ResendInvitation(actor, id)
+
+	target := auditTarget{Type: models.AuditTargetInvitation, ID: id}
+	if inv != nil {
+		target.Name = inv.Email
+	}
+	a.record(models.AuditInvitationResend, actor, "", target, nil, invitationSnapshot(inv), false, err)
+	return inv, err
+}
+
+// RevokeInvitation 撤销邀请并记录审计事件
+func (a *auditingService) RevokeInvitation(actor *models.User, id int) error {
+	err := a.UserService.RevokeInvitation(actor, id)
+	a.record(models.AuditInvitationRevoke, actor, "", auditTarget{Type: models.AuditTargetInvitation, ID: id},
+		nil, nil, false, err)
+	return err
+}
+
+// ApproveUsers 批量通过注册申请并记录审计事件
+func (a *auditingService) ApproveUsers(actor *models.User, ids []int) (*ApprovalResult, error) {
+	result, err := a.UserService.ApproveUsers(actor, ids)
+	a.record(models.AuditUserApprove, actor, "", auditTarget{Type: models.AuditTargetUser},
+		nil, approvalSnapshot(ids, "", result), false, err)
+	return result, err
+}
+
+// RejectUsers 批量拒绝注册申请并记录审计事件
+func (a *auditingService) RejectUsers(actor *models.User, ids []int, reason string) (*ApprovalResult, error) {
+	result, err := a.UserService.RejectUsers(actor, ids, reason)
+	a.record(models.AuditUserReject, actor, "", auditTarget{Type: models.AuditTargetUser},
+		nil, approvalSnapshot(ids, reason, result), false, err)
+	return result, err
+}
+
+// RestoreUser 从回收站恢复用户并记录审计事件
+func (a *auditingService) RestoreUser(actor *models.User, id int) error {
+	err := a.UserService.RestoreUser(actor, id)
+
+	after := a.lookupUser(id)
+	a.record(models.AuditUserRestore, actor, "", userTarget(id, after), nil, userSnapshot(after), false, err)
+	return err
+}
+
+// PurgeDeletedUsers 永久删除超过保留期的回收站用户，有用户被删除或清除失败时记录审计事件
+func (a *auditingService) PurgeDeletedUsers(now time.Time) (int64, error) {
+	count, err := a.UserService.PurgeDeletedUsers(now)
+	if count > 0 || err != nil {
+		a.record(models.AuditUserPurge, nil, auditSystemActor, auditTarget{Type: models.AuditTargetUser},
+			nil, map[string]int64{"count": count}, false, err)
+	}
+	return count, err
+}
+
+// ChangePassword 修改密码并记录审计事件（不记录密码）
+func (a *auditingService) ChangePassword(actor *models.User, targetID int, currentPassword, newPassword string) error {
+	err := a.UserService.ChangePassword(actor, targetID, currentPassword, newPassword)
+	a.record(models.AuditPasswordChange, actor, "", userTarget(targetID, a.lookupUser(targetID)), nil, nil, false, err)
+	return err
+}
+
+// RequestEmailChange 申请修改邮箱并记录审计事件
+func (a *auditingService) RequestEmailChange(actor *models.User, targetID int, newEmail string) error {
+	before := a.lookupUser(targetID)
+	err := a.UserService.RequestEmailChange(actor, targetID, newEmail)
+
+	var oldEmail interface{}
+	if before != nil {
+		oldEmail = map[string]string{"email": before.Email}
+	}
+	a.record(models.AuditEmailChangeRequest, actor, "", userTarget(targetID, before),
+		oldEmail, map[string]string{"email": newEmail}, false, err)
+	return err
+}
+
+// ConfirmEmailChange 通过验证链接确认修改邮箱并记录审计事件，操作者为用户本人
+func (a *auditingService) ConfirmEmailChange(token string) (*models.User, error) {
+	user, err := a.UserService.ConfirmEmailChange(token)
+
+	var id int
+	var after interface{}
+	if user != nil {
+		id = user.ID
+		after = map[string]string{"email": user.Email}
+	}
+	a.record(models.AuditEmailChangeConfirm, user, "", userTarget(id, user), nil, after, false, err)
+	return user, err
+}
+
+// StartImpersonation 开始模拟登录并记录审计事件，会话切换失败时记为失败
+func (a *auditingService) StartImpersonation(actor *models.User, targetID int, switchSession func(target *models.User) error) (*models.User, error) {
+	target, err := a.UserService.StartImpersonation(actor, targetID, switchSession)
+	if target == nil {
+		target = a.lookupUser(targetID)
+	}
+	a.record(models.AuditUserImpersonate, actor, "", userTarget(targetID, target), nil, nil, false, err)
+	return target, err
+}
+
+// StopImpersonation 结束模拟登录并记录审计事件：操作者为模拟者本人，操作对象为被模拟的用户
+func (a *auditingService) StopImpersonation(actor *models.User, restoreSession func() error) error {
+	err := a.UserService.StopImpersonation(actor, restoreSession)
+	if actor != nil && actor.Impersonator != nil {
+		a.record(models.AuditUserImpersonateStop, actor.Impersonator, "", userTarget(actor.ID, actor), nil, nil, false, err)
+	}
+	return err
+}
+
+// auditingRoleService 在 RoleService 之外记录角色变更的审计事件，查询类方法直接使用内层服务
+type auditingRoleService struct {
+	RoleService
+	auditor
+}
+
+// newAuditingRoleService 为服务加上审计记录
+func newAuditingRoleService(inner RoleService, a auditor) RoleService {
+	return &auditingRoleService{RoleService: inner, auditor: a}
+}
+
+// ForRequest 返回记录审计事件时使用 meta 中请求信息的服务
+func (a *auditingRoleService) ForRequest(meta audit.Meta) RoleService {
+	return &auditingRoleService{RoleService: a.RoleService.ForRequest(meta), auditor: a.forRequest(meta)}
+}
+
+// WithContext 返回在 ctx 中执行的服务，审计事件的写入同样在 ctx 中执行
+func (a *auditingRoleService) WithContext(ctx context.Context) RoleService {
+	return &auditingRoleService{RoleService: a.RoleService.WithContext(ctx), auditor: a.withContext(ctx)}
+}
+
+// CreateRole 创建角色并记录审计事件
+func (a *auditingRoleService) CreateRole(actor *models.User, input *RoleInput) (*models.Role, error) {
+	role, err := a.RoleService.CreateRole(actor, input)
+
+	target := auditTarget{Type: models.AuditTargetRole, Name: input.Name}
+	var after interface{} = input
+	if role != nil {
+		target.ID = role.ID
+		after = role
+	}
+	a.record(models.AuditRoleCreate, actor, "", target, nil, after, false, err)
+	return role, err
+}
+
+// UpdateRole 修改角色并记录审计事件
+func (a *auditingRoleService) UpdateRole(actor *models.User, id int, input *RoleInput) (*models.Role, error) {
+	before, _ := a.RoleService.GetRole(id)
+	role, err := a.RoleService.UpdateRole(actor, id, input)
+
+	target := auditTarget{Type: models.AuditTargetRole, ID: id, Name: input.Name}
+	var after interface{} = input
+	if role != nil {
+		after = role
+	}
+	a.record(models.AuditRoleUpdate, actor, "", target, before, after, false, err)
+	return role, err
+}
+
+// DeleteRole 删除角色并记录审计事件
+func (a *auditingRoleService) DeleteRole(actor *models.User, id int) error {
+	before, _ := a.RoleService.GetRole(id)
+	err := a.RoleService.DeleteRole(actor, id)
+
+	target := auditTarget{Type: models.AuditTargetRole, ID: id}
+	if before != nil {
+		target.Name = before.Name
+	}
+	a.record(models.AuditRoleDelete, actor, "", target, before, nil, false, err)
+	return err
+}
+
+// auditingGroupService 在 GroupService 之外记录用户组及其成员变更的审计事件，查询类方法直接使用内层服务
+type auditingGroupService struct {
+	GroupService
+	auditor
+}
+
+// newAuditingGroupService 为服务加上审计记录
+func newAuditingGroupService(inner GroupService, a auditor) GroupService {
+	return &auditingGroupService{GroupService: inner, auditor: a}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录审计事件
+func (a *auditingGroupService) ForOrganization(orgID int) GroupService {
+	return &auditingGroupService{GroupService: a.GroupService.ForOrganization(orgID), auditor: a.auditor}
+}
+
+// ForRequest 返回记录审计事件时使用 meta 中请求信息的服务
+func (a *auditingGroupService) ForRequest(meta audit.Meta) GroupService {
+	return &auditingGroupService{GroupService: a.GroupService.ForRequest(meta), auditor: a.forRequest(meta)}
+}
+
+// WithContext 返回在 ctx 中执行的服务，审计事件的写入同样在 ctx 中执行
+func (a *auditingGroupService) WithContext(ctx context.Context) GroupService {
+	return &auditingGroupService{GroupService: a.GroupService.WithContext(ctx), auditor: a.withContext(ctx)}
+}
+
+// CreateGroup 创建用户组并记录审计事件
+func (a *auditingGroupService) CreateGroup(actor *models.User, input *GroupInput) (*models.Group, error) {
+	group, err := a.GroupService.CreateGroup(actor, input)
+
+	target := auditTarget{Type: models.AuditTargetGroup, Name: input.Name}
+	var after interface{} = input
+	if group != nil {
+		target.ID = group.ID
+		after = group
+	}
+	a.record(models.AuditGroupCreate, actor, "", target, nil, after, false, err)
+	return group, err
+}
+
+// UpdateGroup 修改用户组并记录审计事件
+func (a *auditingGroupService) UpdateGroup(actor *models.User, id int, input *GroupInput) (*models.Group, error) {
+	before, _ := a.GroupService.GetGroup(id)
+	group, err := a.GroupService.UpdateGroup(actor, id, input)
+
+	target := auditTarget{Type: models.AuditTargetGroup, ID: id, Name: input.Name}
+	var after interface{} = input
+	if group != nil {
+		after = group
+	}
+	a.record(models.AuditGroupUpdate, actor, "", target, before, after, false, err)
+	return group, err
+}
+
+// DeleteGroup 删除用户组并记录审计事件
+func (a *auditingGroupService) DeleteGroup(actor *models.User, id int) error {
+	before, _ := a.GroupService.GetGroup(id)
+	err := a.GroupService.DeleteGroup(actor, id)
+
+	target := auditTarget{Type: models.AuditTargetGroup, ID: id}
+	if before != nil {
+		target.Name = before.Name
+	}
+	a.record(models.AuditGroupDelete, actor, "", target, before, nil, false, err)
+	return err
+}
+
+// AddGroupMembers 添加组成员并记录审计事件
+func (a *auditingGroupService) AddGroupMembers(actor *models.User, id int, userIDs []int) error {
+	err := a.GroupService.AddGroupMembers(actor, id, userIDs)
+	a.record(models.AuditGroupAddMembers, actor, "", a.groupTarget(id),
+		nil, map[string][]int{"user_ids": userIDs}, false, err)
+	return err
+}
+
+// RemoveGroupMember 移除组成员并记录审计事件
+func (a *auditingGroupService) RemoveGroupMember(actor *models.User, id, userID int) error {
+	err := a.GroupService.RemoveGroupMember(actor, id, userID)
+	a.record(models.AuditGroupRemoveMember, actor, "", a.groupTarget(id),
+		map[string]int{"user_id": userID}, nil, false, err)
+	return err
+}
+
+// groupTarget 返回以用户组为操作对象的审计目标
+func (a *auditingGroupService) groupTarget(id int) auditTarget {
+	target := auditTarget{Type: models.AuditTargetGroup, ID: id}
+	if group, _ := a.GroupService.GetGroup(id); group != nil {
+		target.Name = group.Name
+	}
+	return target
+}
+
+// auditingOrganizationService 在 OrganizationService 之外记录组织及其成员变更的审计事件，查询类方法直接使用内层服务
+type auditingOrganizationService struct {
+	OrganizationService
+	auditor
+}
+
+// newAuditingOrganizationService 为服务加上审计记录
+func newAuditingOrganizationService(inner OrganizationService, a auditor) OrganizationService {
+	return &auditingOrganizationService{OrganizationService: inner, auditor: a}
+}
+
+// ForRequest 返回记录审计事件时使用 meta 中请求信息的服务
+func (a *auditingOrganizationService) ForRequest(meta audit.Meta) OrganizationService {
+	return &auditingOrganizationService{OrganizationService: a.OrganizationService.ForRequest(meta), auditor: a.forRequest(meta)}
+}
+
+// WithContext 返回在 ctx 中执行的服务，审计事件的写入同样在 ctx 中执行
+func (a *auditingOrganizationService) WithContext(ctx context.Context) OrganizationService {
+	return &auditingOrganizationService{OrganizationService: a.OrganizationService.WithContext(ctx), auditor: a.withContext(ctx)}
+}
+
+// CreateOrganization 创建组织并记录审计事件
+func (a *auditingOrganizationService) CreateOrganization(actor *models.User, input *OrganizationInput) (*models.Organization, error) {
+	org, err := a.OrganizationService.CreateOrganization(actor, input)
+
+	target := auditTarget{Type: models.AuditTargetOrganization, Name: input.Slug}
+	var after interface{} = input
+	if org != nil {
+		target.ID = org.ID
+		after = org
+	}
+	a.record(models.AuditOrgCreate, actor, "", target, nil, after, false, err)
+	return org, err
+}
+
+// UpdateOrganization 修改组织并记录审计事件
+func (a *auditingOrganizationService) UpdateOrganization(actor *models.User, id int, input *OrganizationInput) (*models.Organization, error) {
+	before, _ := a.OrganizationService.GetOrganization(id)
+	org, err := a.OrganizationService.UpdateOrganization(actor, id, input)
+
+	target := auditTarget{Type: models.AuditTargetOrganization, ID: id, Name: input.Slug}
+	var after interface{} = input
+	if org != nil {
+		after = org
+	}
+	a.record(models.AuditOrgUpdate, actor, "", target, before, after, false, err)
+	return org, err
+}
+
+// DeleteOrganization 删除组织并记录审计事件
+func (a *auditingOrganizationService) DeleteOrganization(actor *models.User, id int) error {
+	before, _ := a.OrganizationService.GetOrganization(id)
+	err := a.OrganizationService.DeleteOrganization(actor, id)
+
+	target := auditTarget{Type: models.AuditTargetOrganization, ID: id}
+	if before != nil {
+		target.Name = before.Slug
+	}
+	a.record(models.AuditOrgDelete, actor, "", target, before, nil, false, err)
+	return err
+}
+
+// AddOrganizationMember 添加组织成员并记录审计事件
+func (a *auditingOrganizationService) AddOrganizationMember(actor *models.User, id int, username string) error {
+	err := a.OrganizationService.AddOrganizationMember(actor, id, username)
+	a.record(models.AuditOrgAddMember, actor, "", a.organizationTarget(id),
+		nil, map[string]string{"username": username}, false, err)
+	return err
+}
+
+// RemoveOrganizationMember 移除组织成员并记录审计事件
+func (a *auditingOrganizationService) RemoveOrganizationMember(actor *models.User, id, userID int) error {
+	err := a.OrganizationService.RemoveOrganizationMember(actor, id, userID)
+	a.record(models.AuditOrgRemoveMember, actor, "", a.organizationTarget(id),
+		map[string]int{"user_id": userID}, nil, false, err)
+	return err
+}
+
+// organizationTarget 返回以组织为操作对象的审计目标
+func (a *auditingOrganizationService) organizationTarget(id int) auditTarget {
+	target := auditTarget{Type: models.AuditTargetOrganization, ID: id}
+	if org, _ := a.OrganizationService.GetOrganization(id); org != nil {
+		target.Name = org.Slug
+	}
+	return target
+}
+
+// auditingAdminGrantService 在 AdminGrantService 之外记录委派授权变更的审计事件，查询类方法直接使用内层服务
+type auditingAdminGrantService struct {
+	AdminGrantService
+	auditor
+}
+
+// newAuditingAdminGrantService 为服务加上审计记录
+func newAuditingAdminGrantService(inner AdminGrantService, a auditor) AdminGrantService {
+	return &auditingAdminGrantService{AdminGrantService: inner, auditor: a}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录审计事件
+func (a *auditingAdminGrantService) ForOrganization(orgID int) AdminGrantService {
+	return &auditingAdminGrantService{AdminGrantService: a.AdminGrantService.ForOrganization(orgID), auditor: a.auditor}
+}
+
+// ForRequest 返回记录审计事件时使用 meta 中请求信息的服务
+func (a *auditingAdminGrantService) ForRequest(meta audit.Meta) AdminGrantService {
+	return &auditingAdminGrantService{AdminGrantService: a.AdminGrantService.ForRequest(meta), auditor: a.forRequest(meta)}
+}
+
+// WithContext 返回在 ctx 中执行的服务，审计事件的写入同样在 ctx 中执行
+func (a *auditingAdminGrantService) WithContext(ctx context.Context) AdminGrantService {
+	return &auditingAdminGrantService{AdminGrantService: a.AdminGrantService.WithContext(ctx), auditor: a.withContext(ctx)}
+}
+
+// CreateAdminGrant 创建委派授权并记录审计事件
+func (a *auditingAdminGrantService) CreateAdminGrant(actor *models.User, input *AdminGrantInput) (*models.AdminGrant, error) {
+	grant, err := a.AdminGrantService.CreateAdminGrant(actor, input)
+
+	target := auditTarget{Type: models.AuditTargetGrant, Name: input.Role}
+	var after interface{} = input
+	if grant != nil {
+		target.ID = grant.ID
+		after = grant
+	}
+	a.record(models.AuditGrantCreate, actor, "", target, nil, after, false, err)
+	return grant, err
+}
+
+// DeleteAdminGrant 删除委派授权并记录审计事件
+func (a *auditingAdminGrantService) DeleteAdminGrant(actor *models.User, id int) error {
+	err := a.AdminGrantService.DeleteAdminGrant(actor, id)
+	a.record(models.AuditGrantDelete, actor, "", auditTarget{Type: models.AuditTargetGrant, ID: id},
+		nil, nil, false, err)
+	return err
+}
+
+// auditingChangeRequestService 在 ChangeRequestService 之外记录变更申请的审批的审计事件，查询类方法直接使用内层服务
+type auditingChangeRequestService struct {
+	ChangeRequestService
+	auditor
+}
+
+// newAuditingChangeRequestService 为服务加上审计记录
+func newAuditingChangeRequestService(inner ChangeRequestService, a auditor) ChangeRequestService {
+	return &auditingChangeRequestService{ChangeRequestService: inner, auditor: a}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录审计事件
+func (a *auditingChangeRequestService) ForOrganization(orgID int) ChangeRequestService {
+	return &auditingChangeRequestService{ChangeRequestService: a.ChangeRequestService.ForOrganization(orgID), auditor: a.auditor}
+}
+
+// ForRequest 返回记录审计事件时使用 meta 中请求信息的服务
+func (a *auditingChangeRequestService) ForRequest(meta audit.Meta) ChangeRequestService {
+	return &auditingChangeRequestService{ChangeRequestService: a.ChangeRequestService.ForRequest(meta), auditor: a.forRequest(meta)}
+}
+
+// WithContext 返回在 ctx 中执行的服务，审计事件的写入同样在 ctx 中执行
+func (a *auditingChangeRequestService) WithContext(ctx context.Context) ChangeRequestService {
+	return &auditingChangeRequestService{ChangeRequestService: a.ChangeRequestService.WithContext(ctx), auditor: a.withContext(ctx)}
+}
+
+// ApproveChangeRequest 批准变更申请并记录审计事件
+func (a *auditingChangeRequestService) ApproveChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error) {
+	req, err := a.ChangeRequestService.ApproveChangeRequest(actor, id)
+	a.record(models.AuditChangeRequestApprove, actor, "", changeRequestTarget(id, req), nil, req, false, err)
+	return req, err
+}
+
+// RejectChangeRequest 拒绝变更申请并记录审计事件
+func (a *auditingChangeRequestService) RejectChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error) {
+	req, err := a.ChangeRequestService.RejectChangeRequest(actor, id)
+	a.record(models.AuditChangeRequestReject, actor, "", changeRequestTarget(id, req), nil, req, false, err)
+	return req, err
+}
+
+// changeRequestTarget 返回以变更申请为操作对象的审计目标，名称为申请针对的用户名
+func changeRequestTarget(id int, req *models.ChangeRequest) auditTarget {
+	target := auditTarget{Type: models.AuditTargetChangeRequest, ID: id}
+	if req != nil {
+		target.Name = req.TargetName
+	}
+	return target
+}
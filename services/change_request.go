@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/models"
+)
+
+// ChangeRequestService 双人审批服务接口：需要审批的操作（见 config.ChangeApprovalActions）保存为变更申请，
+// 由另一位管理员批准后生效
+type ChangeRequestService interface {
+	// ForOrganization 返回限定在指定组织内的服务，只能查询和处理该组织的变更申请，orgID 为 0 时不限定
+	ForOrganization(orgID int) ChangeRequestService
+
+	// ForRequest 返回在审计事件中记录 meta 中请求信息的服务
+	ForRequest(meta audit.Meta) ChangeRequestService
+
+	// WithContext 返回以 ctx 中的追踪 span 为父 span 的服务
+	WithContext(ctx context.Context) ChangeRequestService
+
+	GetChangeRequests() ([]*models.ChangeRequest, error)
+	ApproveChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error)
+	RejectChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error)
+}
+
+// changeRequestServiceImpl 是 ChangeRequestService 接口的具体实现
+type changeRequestServiceImpl struct {
+	*serviceCore
+}
+
+// NewChangeRequestService 创建双人审批服务，处理申请时记录审计事件，启用追踪时每个方法记录一个 span
+func NewChangeRequestService(deps *ServiceDependencies) ChangeRequestService {
+	return newTracingChangeRequestService(newAuditingChangeRequestService(&changeRequestServiceImpl{newServiceCore(deps)}, newAuditor(deps)))
+}
+
+// ForOrganization 返回限定在指定组织内的服务副本，orgID 为 0 时不限定
+func (s *changeRequestServiceImpl) ForOrganization(orgID int) ChangeRequestService {
+	return &changeRequestServiceImpl{s.forOrganization(orgID)}
+}
+
+// ForRequest 实现本身不使用请求信息，审计事件由 auditingChangeRequestService 记录
+func (s *changeRequestServiceImpl) ForRequest(meta audit.Meta) ChangeRequestService {
+	return s
+}
+
+// WithContext 返回所有仓库都在 ctx 中执行语句的服务副本
+func (s *changeRequestServiceImpl) WithContext(ctx context.Context) ChangeRequestService {
+	return &changeRequestServiceImpl{s.withContext(ctx)}
+}
+
+// GetChangeRequests 获取所有变更申请，最近提交的在前
+func (s *changeRequestServiceImpl) GetChangeRequests() ([]*models.ChangeRequest, error) {
+	requests, err := s.changeRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取变更申请失败: %w", err))
+	}
+	return requests, nil
+}
+
+// ApproveChangeRequest 由另一位管理员批准变更申请，变更随即生效
+// 批准人需要拥有分配角色的权限，并且按当前状态有权直接执行该变更；申请人不能批准自己的申请
+func (s *changeRequestServiceImpl) ApproveChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error) {
+	if err := requirePermission(actor, models.PermUsersManage); err != nil {
+		return nil, err
+	}
+	if err := requireNotImpersonated(actor); err != nil {
+		return nil, err
+	}
+
+	req, err := s.getChangeRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if !req.IsPending() {
+		return nil, errors.NewConflictError("申请" + req.StatusLabel() + "，不能批准")
+	}
+	if req.RequestedBy == actor.ID {
+		return nil, errors.NewForbiddenError("不能批准自己提交的申请，请由另一位管理员审批")
+	}
+
+	// 申请提交后用户的状态可能已经变化，在批准的事务中锁定申请和管理员后，按批准人的权限和当前状态重新校验
+	err = s.changeRepo.Approve(req.ID, actor.ID, func(locked *models.ChangeRequest) error {
+		return s.checkChangeRequest(actor, locked)
+	})
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			return nil, appErr
+		}
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewConflictError("申请已被处理、已过期或用户已不存在")
+		}
+		return nil, errors.NewInternalError(fmt.Errorf("批准申请失败: %w", err))
+	}
+
+	now := time.Now()
+	req.ApprovedAt = &now
+	req.DecidedBy = actor.ID
+	req.DeciderName = actor.Username
+	return req, nil
+}
+
+// checkChangeRequest 校验 actor 现在能否直接执行申请中的变更
+func (s *changeRequestServiceImpl) checkChangeRequest(actor *models.User, req *models.ChangeRequest) error {
+	switch req.Action {
+	case models.ChangeActionPromoteAdmin:
+		_, _, _, err := s.checkUserUpdate(actor, req.TargetID, req.Email, req.Roles)
+		return err
+	case models.ChangeActionDeleteAdmin:
+		_, err := s.checkUserDelete(actor, req.TargetID)
+		return err
+	default:
+		return errors.NewValidationError("action", "未知的变更操作")
+	}
+}
+
+// RejectChangeRequest 拒绝变更申请，申请人也可以用它撤回自己的申请
+func (s *changeRequestServiceImpl) RejectChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error) {
+	if err := requirePermission(actor, models.PermUsersManage); err != nil {
+		return nil, err
+	}
+
+	req, err := s.getChangeRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if !req.IsPending() {
+		return nil, errors.NewConflictError("申请" + req.StatusLabel() + "，不能拒绝")
+	}
+
+	if err := s.changeRepo.Reject(req.ID, actor.ID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewConflictError("申请已被处理或已过期")
+		}
+		return nil, errors.NewInternalError(fmt.Errorf("拒绝申请失败: %w", err))
+	}
+
+	now := time.Now()
+	req.RejectedAt = &now
+	req.DecidedBy = actor.ID
+	req.DeciderName = actor.Username
+	return req, nil
+}
+
+// requiresApproval 检查操作是否配置为需要双人审批
+func (s *serviceCore) requiresApproval(action string) bool {
+	for _, a := range s.cfg.ChangeApprovalActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// refuseUnapprovedPromotion 提升管理员需要审批时，拒绝不经变更申请就使用户获得 users:manage 权限的操作
+// 变更申请只针对已存在用户的角色修改，其他途径（创建用户、邀请、加入用户组、修改角色或组）一律拒绝
+func (s *serviceCore) refuseUnapprovedPromotion(promotes bool, what string) error {
+	if promotes && s.requiresApproval(models.ChangeActionPromoteAdmin) {
+		return errors.NewForbiddenError(what + "会使用户获得分配角色的权限，提升管理员需要另一位管理员审批，请先分配普通角色，再修改用户角色提交变更申请")
+	}
+	return nil
+}
+
+// createChangeRequest 保存等待审批的变更申请，同一用户同时只能有一份待审批的申请
+func (s *serviceCore) createChangeRequest(actor, target *models.User, action, email string, roles []string) (*models.ChangeRequest, error) {
+	pending, err := s.changeRepo.GetPendingByTarget(target.ID)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询变更申请失败: %w", err))
+	}
+	if pending != nil {
+		return nil, errors.NewConflictError("该用户已有待审批的变更申请（" + pending.ActionLabel() + "），请等待处理后再提交")
+	}
+
+	req := &models.ChangeRequest{
+		Action:        action,
+		TargetID:      target.ID,
+		TargetName:    target.Username,
+		Email:         email,
+		Roles:         roles,
+		RequestedBy:   actor.ID,
+		RequesterName: actor.Username,
+		ExpiresAt:     time.Now().Add(s.cfg.ChangeApprovalTTL),
+	}
+	if err := s.changeRepo.Create(req); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("保存变更申请失败: %w", err))
+	}
+	return req, nil
+}
+
+// getChangeRequest 根据ID获取变更申请
+func (s *changeRequestServiceImpl) getChangeRequest(id int) (*models.ChangeRequest, error) {
+	if id <= 0 {
+		return nil, errors.NewValidationError("id", "无效的申请ID")
+	}
+	req, err := s.changeRepo.GetByID(id)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询变更申请失败: %w", err))
+	}
+	if req == nil {
+		return nil, errors.NewNotFoundError("变更申请")
+	}
+	return req, nil
+}
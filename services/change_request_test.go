@@ -0,0 +1,279 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"user-management-system/config"
+	"user-management-system/errors"
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+)
+
+// fakeRoleRepo 内存中的角色仓库，只实现测试用到的方法
+type fakeRoleRepo struct {
+	interfaces.RoleRepository
+	roles   []*models.Role
+	updated *models.Role
+}
+
+func (r *fakeRoleRepo) GetAll() ([]*models.Role, error) {
+	return r.roles, nil
+}
+
+func (r *fakeRoleRepo) GetByID(id int) (*models.Role, error) {
+	for _, role := range r.roles {
+		if role.ID == id {
+			copied := *role
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepo) GetByName(name string) (*models.Role, error) {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepo) Update(role *models.Role, oldName string) error {
+	r.updated = role
+	return nil
+}
+
+// fakeGroupRepo 内存中的用户组仓库
+type fakeGroupRepo struct {
+	interfaces.GroupRepository
+	groups []*models.Group
+}
+
+func (r *fakeGroupRepo) GetAll() ([]*models.Group, error) {
+	return r.groups, nil
+}
+
+// fakeUserRepo 内存中的用户仓库，roleHolders 为 GetByRoleID 返回的用户
+type fakeUserRepo struct {
+	interfaces.UserRepository
+	users       []*models.User
+	roleHolders []*models.User
+}
+
+func (r *fakeUserRepo) GetByID(id int) (*models.User, error) {
+	for _, user := range r.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) GetAll() ([]*models.User, error) {
+	return r.users, nil
+}
+
+func (r *fakeUserRepo) GetByRoleID(roleID int) ([]*models.User, error) {
+	return r.roleHolders, nil
+}
+
+func (r *fakeUserRepo) CountByPermissionAndStatus(permission, status string) (int64, error) {
+	var count int64
+	for _, user := range r.users {
+		if user.HasPermission(permission) && user.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// fakeChangeRepo 内存中的变更申请仓库，concurrent 模拟在批准事务加锁之前其他事务已提交的修改
+type fakeChangeRepo struct {
+	interfaces.ChangeRequestRepository
+	requests   []*models.ChangeRequest
+	concurrent func()
+	approved   bool
+}
+
+func (r *fakeChangeRepo) GetByID(id int) (*models.ChangeRequest, error) {
+	for _, req := range r.requests {
+		if req.ID == id {
+			return req, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeChangeRepo) Approve(id, deciderID int, check func(req *models.ChangeRequest) error) error {
+	if r.concurrent != nil {
+		r.concurrent()
+	}
+	req, _ := r.GetByID(id)
+	if err := check(req); err != nil {
+		return err
+	}
+	r.approved = true
+	return nil
+}
+
+// newFourEyesCore 创建使用内存仓库的服务共享部分，approve 为 true 时提升管理员需要审批
+// 仓库中有拥有全部权限的管理员 alice（ID 1）和普通用户 bob（ID 2，加入了只授予普通用户角色的 ops 组）
+func newFourEyesCore(approve bool) (*serviceCore, *models.User) {
+	var all []string
+	for _, p := range models.Permissions {
+		all = append(all, p.Name)
+	}
+	admin := &models.User{ID: 1, Username: "alice", Status: models.StatusActive, Roles: []string{models.RoleAdmin}, Permissions: all}
+	bob := &models.User{ID: 2, Username: "bob", Status: models.StatusActive, Roles: []string{models.RoleUser}, Groups: []string{"ops"}}
+
+	cfg := &config.Config{}
+	if approve {
+		cfg.ChangeApprovalActions = []string{models.ChangeActionPromoteAdmin}
+	}
+	core := &serviceCore{
+		userRepo: &fakeUserRepo{users: []*models.User{admin, bob}, roleHolders: []*models.User{bob}},
+		roleRepo: &fakeRoleRepo{roles: []*models.Role{
+			{ID: 1, Name: models.RoleAdmin, IsSystem: true, Permissions: all},
+			{ID: 2, Name: models.RoleUser, IsSystem: true},
+			{ID: 3, Name: "viewer", Permissions: []string{models.PermUsersView}},
+		}},
+		groupRepo: &fakeGroupRepo{groups: []*models.Group{
+			{ID: 10, Name: "ops", Roles: []string{models.RoleUser}, MemberCount: 1},
+			{ID: 11, Name: "admins", Roles: []string{models.RoleAdmin}},
+		}},
+		cfg: cfg,
+	}
+	return core, admin
+}
+
+// expectApprovalRefused 检查操作因需要审批而被拒绝
+func expectApprovalRefused(t *testing.T, err error) {
+	t.Helper()
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type != errors.ForbiddenError || !strings.Contains(appErr.Message, "审批") {
+		t.Fatalf("期望因需要审批被拒绝，实际错误为 %v", err)
+	}
+}
+
+func TestCreateUserRefusesAdminWhenApprovalRequired(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	s := &userServiceImpl{core}
+
+	_, _, err := s.CreateUser(admin, &CreateUserInput{Username: "carol", Email: "carol@example.com", Roles: []string{models.RoleAdmin}})
+	expectApprovalRefused(t, err)
+}
+
+func TestCreateInvitationRefusesAdminWhenApprovalRequired(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	s := &userServiceImpl{core}
+
+	_, err := s.CreateInvitation(admin, "carol@example.com", models.RoleAdmin)
+	expectApprovalRefused(t, err)
+}
+
+func TestAddGroupMembersRefusesAdminGroupWhenApprovalRequired(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	s := &groupServiceImpl{core}
+
+	// groupRepo.AddMembers 未实现，走到保存这一步会 panic
+	err := s.AddGroupMembers(admin, 11, []int{2})
+	expectApprovalRefused(t, err)
+}
+
+func TestUpdateGroupRefusesAdminRoleWhenApprovalRequired(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	s := &groupServiceImpl{core}
+
+	_, err := s.UpdateGroup(admin, 10, &GroupInput{Name: "ops", Roles: []string{models.RoleAdmin}})
+	expectApprovalRefused(t, err)
+}
+
+func TestUpdateRoleRefusesUsersManageWhenApprovalRequired(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	s := &roleServiceImpl{core}
+
+	input := &RoleInput{Name: "viewer", Permissions: []string{models.PermUsersView, models.PermUsersManage}}
+	_, err := s.UpdateRole(admin, 3, input)
+	expectApprovalRefused(t, err)
+	if updated := core.roleRepo.(*fakeRoleRepo).updated; updated != nil {
+		t.Fatalf("被拒绝的修改不应保存，实际保存了 %+v", updated)
+	}
+}
+
+func TestUpdateRoleAllowsUsersManageWithoutApproval(t *testing.T) {
+	core, admin := newFourEyesCore(false)
+	s := &roleServiceImpl{core}
+
+	input := &RoleInput{Name: "viewer", Permissions: []string{models.PermUsersView, models.PermUsersManage}}
+	if _, err := s.UpdateRole(admin, 3, input); err != nil {
+		t.Fatalf("不需要审批时应直接修改角色，实际错误为 %v", err)
+	}
+	if core.roleRepo.(*fakeRoleRepo).updated == nil {
+		t.Fatal("角色修改没有保存")
+	}
+}
+
+func TestUpdateRoleAllowsUsersManageForExistingAdmins(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	core.userRepo.(*fakeUserRepo).roleHolders = []*models.User{admin}
+	s := &roleServiceImpl{core}
+
+	// 持有该角色的用户已经是管理员，不会有人因此被提升
+	input := &RoleInput{Name: "viewer", Permissions: []string{models.PermUsersView, models.PermUsersManage}}
+	if _, err := s.UpdateRole(admin, 3, input); err != nil {
+		t.Fatalf("没有用户被提升时不需要审批，实际错误为 %v", err)
+	}
+}
+
+// newDeleteAdminRequest 在 core 中加入管理员 dave（ID 4）和另一位管理员提交的删除 dave 的申请
+func newDeleteAdminRequest(core *serviceCore, admin *models.User) *fakeChangeRepo {
+	dave := &models.User{ID: 4, Username: "dave", Status: models.StatusActive, Roles: admin.Roles, Permissions: admin.Permissions}
+	users := core.userRepo.(*fakeUserRepo)
+	users.users = append(users.users, dave)
+
+	changes := &fakeChangeRepo{requests: []*models.ChangeRequest{{
+		ID: 1, Action: models.ChangeActionDeleteAdmin, TargetID: dave.ID, TargetName: dave.Username,
+		RequestedBy: 5, ExpiresAt: time.Now().Add(time.Hour),
+	}}}
+	core.changeRepo = changes
+	return changes
+}
+
+func TestApproveChangeRequestDeletesAdmin(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	changes := newDeleteAdminRequest(core, admin)
+	s := &changeRequestServiceImpl{core}
+
+	if _, err := s.ApproveChangeRequest(admin, 1); err != nil {
+		t.Fatalf("ApproveChangeRequest: %v", err)
+	}
+	if !changes.approved {
+		t.Error("申请没有被批准")
+	}
+}
+
+// TestApproveChangeRequestRechecksUnderLock 批准前读取的状态可能已经过时，
+// 校验在批准的事务中进行，加锁前其他事务的修改（这里 alice 的管理权限已在数据库中被移除）会被考虑在内
+func TestApproveChangeRequestRechecksUnderLock(t *testing.T) {
+	core, admin := newFourEyesCore(true)
+	changes := newDeleteAdminRequest(core, admin)
+	users := core.userRepo.(*fakeUserRepo)
+	changes.concurrent = func() {
+		demoted := *admin
+		demoted.Permissions = nil
+		users.users[0] = &demoted
+	}
+	s := &changeRequestServiceImpl{core}
+
+	_, err := s.ApproveChangeRequest(admin, 1)
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type != errors.ForbiddenError || !strings.Contains(appErr.Message, "最后一个管理员") {
+		t.Fatalf("期望因删除最后一个管理员被拒绝，实际错误为 %v", err)
+	}
+	if changes.approved {
+		t.Error("校验失败的申请不应被批准")
+	}
+}
@@ -0,0 +1,692 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/models"
+)
+
+// GroupService 用户组服务接口：用户组及其成员的查询和管理
+type GroupService interface {
+	// ForOrganization 返回限定在指定组织内的服务，只能查询和管理该组织的用户组，orgID 为 0 时不限定
+	ForOrganization(orgID int) GroupService
+
+	// ForRequest 返回在审计事件中记录 meta 中请求信息的服务
+	ForRequest(meta audit.Meta) GroupService
+
+	// WithContext 返回以 ctx 中的追踪 span 为父 span 的服务
+	WithContext(ctx context.Context) GroupService
+
+	GetGroups() ([]*models.Group, error)
+	GetGroup(id int) (*models.Group, error)
+	GetGroupMembers(id int) ([]*models.User, error)
+	GetUsersInGroup(id int) ([]*models.User, error)
+	CreateGroup(actor *models.User, input *GroupInput) (*models.Group, error)
+	UpdateGroup(actor *models.User, id int, input *GroupInput) (*models.Group, error)
+	DeleteGroup(actor *models.User, id int) error
+	AddGroupMembers(actor *models.User, id int, userIDs []int) error
+	RemoveGroupMember(actor *models.User, id, userID int) error
+}
+
+// groupServiceImpl 是 GroupService 接口的具体实现
+type groupServiceImpl struct {
+	*serviceCore
+}
+
+// NewGroupService 创建用户组服务，修改用户组时记录审计事件，启用追踪时每个方法记录一个 span
+func NewGroupService(deps *ServiceDependencies) GroupService {
+	return newTracingGroupService(newAuditingGroupService(&groupServiceImpl{newServiceCore(deps)}, newAuditor(deps)))
+}
+
+// ForOrganization 返回限定在指定组织内的服务副本，orgID 为 0 时不限定
+func (s *groupServiceImpl) ForOrganization(orgID int) GroupService {
+	return &groupServiceImpl{s.forOrganization(orgID)}
+}
+
+// ForRequest 实现本身不使用请求信息，审计事件由 auditingGroupService 记录
+func (s *groupServiceImpl) ForRequest(meta audit.Meta) GroupService {
+	return s
+}
+
+// WithContext 返回所有仓库都在 ctx 中执行语句的服务副本
+func (s *groupServiceImpl) WithContext(ctx context.Context) GroupService {
+	return &groupServiceImpl{s.withContext(ctx)}
+}
+
+// GroupInput 创建或修改用户组时提交的内容
+type GroupInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ParentID    int      `json:"parent_id"` // 上级组ID，0 表示顶级组
+	Roles       []string `json:"roles"`
+}
+
+// PermissionSource 有效权限的一个来源
+type PermissionSource struct {
+	Role  string `json:"role"`            // 授予该权限的角色
+	Group string `json:"group,omitempty"` // 角色所在的用户组，直接持有角色时为空
+	Via   string `json:"via,omitempty"`   // 用户直接加入的组，角色继承自该组的上级组时不为空
+}
+
+// PermissionGrant 一项有效权限及其全部来源
+type PermissionGrant struct {
+	Permission string             `json:"permission"`
+	Label      string             `json:"label"`
+	Sources    []PermissionSource `json:"sources"`
+}
+
+// EffectivePermissions 用户的有效权限明细
+type EffectivePermissions struct {
+	User   *models.User      `json:"user"`
+	Groups []string          `json:"groups"` // 直接加入的组及其全部上级组
+	Grants []PermissionGrant `json:"grants"` // 按权限目录的顺序排列
+}
+
+// GetGroups 获取所有用户组
+func (s *groupServiceImpl) GetGroups() ([]*models.Group, error) {
+	groups, err := s.groupRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取用户组列表失败: %w", err))
+	}
+	return groups, nil
+}
+
+// GetGroup 根据ID获取用户组
+func (s *groupServiceImpl) GetGroup(id int) (*models.Group, error) {
+	return s.getGroup(id)
+}
+
+// getGroup 根据ID获取用户组（委派授权的范围也使用）
+func (s *serviceCore) getGroup(id int) (*models.Group, error) {
+	if id <= 0 {
+		return nil, errors.NewValidationError("id", "无效的用户组ID")
+	}
+	group, err := s.groupRepo.GetByID(id)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询用户组失败: %w", err))
+	}
+	if group == nil {
+		return nil, errors.NewNotFoundError("用户组")
+	}
+	return group, nil
+}
+
+// GetGroupMembers 获取用户组的直接成员
+func (s *groupServiceImpl) GetGroupMembers(id int) ([]*models.User, error) {
+	return s.groupUsers(id, false)
+}
+
+// GetUsersInGroup 获取用户组及其所有下级组的成员
+func (s *groupServiceImpl) GetUsersInGroup(id int) ([]*models.User, error) {
+	return s.groupUsers(id, true)
+}
+
+// groupUsers 获取用户组的成员，nested 为 true 时包括下级组的成员
+func (s *groupServiceImpl) groupUsers(id int, nested bool) ([]*models.User, error) {
+	if _, err := s.getGroup(id); err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.GetByGroupID(id, nested)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询组成员失败: %w", err))
+	}
+	return users, nil
+}
+
+// CreateGroup 创建用户组
+func (s *groupServiceImpl) CreateGroup(actor *models.User, input *GroupInput) (*models.Group, error) {
+	if err := requirePermission(actor, models.PermGroupsManage); err != nil {
+		return nil, err
+	}
+
+	graph, err := s.loadGroupGraph()
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.buildGroup(actor, graph, nil, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("创建用户组失败: %w", err))
+	}
+	return group, nil
+}
+
+// UpdateGroup 修改用户组的名称、说明、上级组和角色
+func (s *groupServiceImpl) UpdateGroup(actor *models.User, id int, input *GroupInput) (*models.Group, error) {
+	if err := requirePermission(actor, models.PermGroupsManage); err != nil {
+		return nil, err
+	}
+
+	graph, err := s.loadGroupGraph()
+	if err != nil {
+		return nil, err
+	}
+	existing, ok := graph.groups[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("用户组")
+	}
+	group, err := s.buildGroup(actor, graph, existing, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// 成员通过组获得的管理权限可能因为修改角色或上级组而失去
+	if graph.grants(existing, models.PermUsersManage) {
+		renamed := func(user *models.User) []string {
+			return replaceName(user.Groups, existing.Name, group.Name)
+		}
+		if err := s.ensureManagersRemain(graph.with(group, false), renamed); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.groupRepo.Update(group); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewNotFoundError("用户组")
+		}
+		return nil, errors.NewInternalError(fmt.Errorf("修改用户组失败: %w", err))
+	}
+	return group, nil
+}
+
+// DeleteGroup 删除用户组，其下级组成为顶级组，成员失去通过该组获得的角色
+func (s *groupServiceImpl) DeleteGroup(actor *models.User, id int) error {
+	if err := requirePermission(actor, models.PermGroupsManage); err != nil {
+		return err
+	}
+
+	graph, err := s.loadGroupGraph()
+	if err != nil {
+		return err
+	}
+	existing, ok := graph.groups[id]
+	if !ok {
+		return errors.NewNotFoundError("用户组")
+	}
+	if graph.privileged(existing) {
+		if err := requirePermission(actor, models.PermUsersManage); err != nil {
+			return err
+		}
+	}
+	if graph.grants(existing, models.PermUsersManage) {
+		if err := s.ensureManagersRemain(graph.with(existing, true), directGroups); err != nil {
+			return err
+		}
+	}
+
+	if err := s.groupRepo.Delete(id); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.NewNotFoundError("用户组")
+		}
+		return errors.NewInternalError(fmt.Errorf("删除用户组失败: %w", err))
+	}
+	return nil
+}
+
+// AddGroupMembers 将用户加入组，已是成员的用户会被忽略
+// 组（包括其上级组）授予普通用户以外的角色时，需要 users:manage 权限，且操作者必须拥有这些角色的全部权限；
+// 提升管理员需要审批时，不能把非管理员加入授予 users:manage 权限的组
+func (s *groupServiceImpl) AddGroupMembers(actor *models.User, id int, userIDs []int) error {
+	if err := requirePermission(actor, models.PermGroupsManage); err != nil {
+		return err
+	}
+
+	graph, err := s.loadGroupGraph()
+	if err != nil {
+		return err
+	}
+	group, ok := graph.groups[id]
+	if !ok {
+		return errors.NewNotFoundError("用户组")
+	}
+	if graph.privileged(group) {
+		if err := requirePermission(actor, models.PermUsersManage); err != nil {
+			return err
+		}
+		if err := requireGrantable(actor, graph.permissions(group)); err != nil {
+			return err
+		}
+	}
+
+	manages := graph.grants(group, models.PermUsersManage)
+	promotes := false
+	ids := make([]int, 0, len(userIDs))
+	seen := make(map[int]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if userID <= 0 || seen[userID] {
+			continue
+		}
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil {
+			return errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+		}
+		if user == nil {
+			return errors.NewNotFoundError("用户")
+		}
+		seen[userID] = true
+		ids = append(ids, userID)
+		promotes = promotes || manages && !user.IsAdmin()
+	}
+	if len(ids) == 0 {
+		return errors.NewValidationError("user_ids", "请至少选择一个用户")
+	}
+	if err := s.refuseUnapprovedPromotion(promotes, "加入该组"); err != nil {
+		return err
+	}
+
+	if err := s.groupRepo.AddMembers(id, ids); err != nil {
+		return errors.NewInternalError(fmt.Errorf("添加组成员失败: %w", err))
+	}
+	return nil
+}
+
+// RemoveGroupMember 将用户移出组
+func (s *groupServiceImpl) RemoveGroupMember(actor *models.User, id, userID int) error {
+	if err := requirePermission(actor, models.PermGroupsManage); err != nil {
+		return err
+	}
+
+	graph, err := s.loadGroupGraph()
+	if err != nil {
+		return err
+	}
+	group, ok := graph.groups[id]
+	if !ok {
+		return errors.NewNotFoundError("用户组")
+	}
+	if graph.privileged(group) {
+		if err := requirePermission(actor, models.PermUsersManage); err != nil {
+			return err
+		}
+	}
+	if graph.grants(group, models.PermUsersManage) {
+		removed := func(user *models.User) []string {
+			if user.ID == userID {
+				return replaceName(user.Groups, group.Name, "")
+			}
+			return user.Groups
+		}
+		if err := s.ensureManagersRemain(graph, removed); err != nil {
+			return err
+		}
+	}
+
+	if err := s.groupRepo.RemoveMember(id, userID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.NewNotFoundError("组成员")
+		}
+		return errors.NewInternalError(fmt.Errorf("移除组成员失败: %w", err))
+	}
+	return nil
+}
+
+// GetEffectivePermissions 获取用户的有效权限及每项权限的来源
+// 用户可以查看自己的有效权限，查看其他用户需要 users:view 权限
+func (s *userServiceImpl) GetEffectivePermissions(actor *models.User, userID int) (*EffectivePermissions, error) {
+	if actor == nil {
+		return nil, errors.NewUnauthorizedError("")
+	}
+	if actor.ID != userID {
+		if err := requirePermission(actor, models.PermUsersView); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := s.loadGroupGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EffectivePermissions{User: user}
+	seen := make(map[int]bool)
+	for _, name := range user.Groups {
+		group, ok := graph.names[name]
+		if !ok {
+			continue
+		}
+		for _, ancestor := range graph.ancestors(group) {
+			if !seen[ancestor.ID] {
+				seen[ancestor.ID] = true
+				result.Groups = append(result.Groups, ancestor.Name)
+			}
+		}
+	}
+
+	sources := graph.sources(user.Roles, user.Groups)
+	for _, p := range models.Permissions {
+		if src, ok := sources[p.Name]; ok {
+			result.Grants = append(result.Grants, PermissionGrant{Permission: p.Name, Label: p.Label, Sources: src})
+		}
+	}
+	return result, nil
+}
+
+// buildGroup 校验提交的内容并生成新的用户组，existing 为 nil 表示创建用户组
+// 改变组授予成员的角色时，如果涉及普通用户以外的角色，需要 users:manage 权限
+func (s *groupServiceImpl) buildGroup(actor *models.User, graph *groupGraph, existing *models.Group, input *GroupInput) (*models.Group, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.NewValidationError("name", "组名不能为空")
+	}
+	if utf8.RuneCountInString(name) > 64 {
+		return nil, errors.NewValidationError("name", "组名不能超过64个字符")
+	}
+	if strings.Contains(name, ",") {
+		return nil, errors.NewValidationError("name", "组名不能包含逗号")
+	}
+	description := strings.TrimSpace(input.Description)
+	if utf8.RuneCountInString(description) > 255 {
+		return nil, errors.NewValidationError("description", "说明不能超过255个字符")
+	}
+	if other, ok := graph.names[name]; ok && (existing == nil || other.ID != existing.ID) {
+		return nil, errors.NewConflictError("组名已被使用")
+	}
+
+	group := &models.Group{Name: name, Description: description}
+	if existing != nil {
+		group.ID = existing.ID
+		group.MemberCount = existing.MemberCount
+		group.CreatedAt = existing.CreatedAt
+	}
+
+	if input.ParentID != 0 {
+		parent, ok := graph.groups[input.ParentID]
+		if !ok {
+			return nil, errors.NewValidationError("parent_id", "上级组不存在")
+		}
+		if existing != nil {
+			for _, ancestor := range graph.ancestors(parent) {
+				if ancestor.ID == existing.ID {
+					return nil, errors.NewValidationError("parent_id", "不能把组移动到它自己或它的下级组之下")
+				}
+			}
+		}
+		parentID := parent.ID
+		group.ParentID = &parentID
+	}
+
+	seen := make(map[string]bool, len(input.Roles))
+	for _, role := range input.Roles {
+		role = strings.TrimSpace(role)
+		if role == "" || seen[role] {
+			continue
+		}
+		if _, ok := graph.roles[role]; !ok {
+			return nil, errors.NewValidationError("roles", "角色不存在："+role)
+		}
+		seen[role] = true
+		group.Roles = append(group.Roles, role)
+	}
+
+	changed := existing == nil || !sameRoles(existing.Roles, group.Roles) || !sameParent(existing, group)
+	next := graph.with(group, false)
+	if changed && (existing != nil && graph.privileged(existing) || next.privileged(group)) {
+		if err := requirePermission(actor, models.PermUsersManage); err != nil {
+			return nil, err
+		}
+		if err := requireGrantable(actor, next.permissions(group)); err != nil {
+			return nil, err
+		}
+	}
+	if changed && next.grants(group, models.PermUsersManage) {
+		promotes, err := s.promotesMembers(next, directGroups)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.refuseUnapprovedPromotion(promotes, "该用户组的角色或上级组"); err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
+}
+
+// ensureManagersRemain 检查在 graph 描述的角色和组下，是否仍有正常状态的用户拥有 users:manage 权限
+// memberGroups 返回变更后用户直接加入的组
+// 服务限定了组织时 graph 只包含该组织的组，组织以外的用户不受影响，按其当前权限计算
+func (s *serviceCore) ensureManagersRemain(graph *groupGraph, memberGroups func(*models.User) []string) error {
+	members, err := s.userRepo.GetByStatus(models.StatusActive)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("查询管理员失败: %w", err))
+	}
+	for _, user := range members {
+		if _, ok := graph.sources(user.Roles, memberGroups(user))[models.PermUsersManage]; ok {
+			return nil
+		}
+	}
+
+	if s.orgID != 0 {
+		inScope := make(map[int]bool, len(members))
+		for _, user := range members {
+			inScope[user.ID] = true
+		}
+		users, err := s.userRepo.ForOrganization(0).GetByStatus(models.StatusActive)
+		if err != nil {
+			return errors.NewInternalError(fmt.Errorf("查询管理员失败: %w", err))
+		}
+		for _, user := range users {
+			if !inScope[user.ID] && user.HasPermission(models.PermUsersManage) {
+				return nil
+			}
+		}
+	}
+	return errors.NewForbiddenError("该变更会使系统中没有可以分配角色的管理员")
+}
+
+// promotesMembers 检查在 graph 描述的角色和组下，是否有尚未拥有 users:manage 权限的用户会通过所在组获得该权限
+// memberGroups 返回变更后用户直接加入的组
+func (s *serviceCore) promotesMembers(graph *groupGraph, memberGroups func(*models.User) []string) (bool, error) {
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return false, errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+	}
+	for _, user := range users {
+		if user.IsAdmin() {
+			continue
+		}
+		if _, ok := graph.sources(nil, memberGroups(user))[models.PermUsersManage]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// inheritedPermissions 获取用户通过所在组继承的权限
+func (s *serviceCore) inheritedPermissions(user *models.User) (map[string][]PermissionSource, error) {
+	if len(user.Groups) == 0 {
+		return nil, nil
+	}
+	graph, err := s.loadGroupGraph()
+	if err != nil {
+		return nil, err
+	}
+	return graph.sources(nil, user.Groups), nil
+}
+
+// directGroups 返回用户当前直接加入的组
+func directGroups(user *models.User) []string {
+	return user.Groups
+}
+
+// replaceName 将列表中的 old 替换为 new，new 为空时移除 old
+func replaceName(names []string, old, new string) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == old {
+			if new == "" {
+				continue
+			}
+			name = new
+		}
+		result = append(result, name)
+	}
+	return result
+}
+
+// sameParent 比较两个组的上级组是否相同
+func sameParent(a, b *models.Group) bool {
+	if a.ParentID == nil || b.ParentID == nil {
+		return a.ParentID == nil && b.ParentID == nil
+	}
+	return *a.ParentID == *b.ParentID
+}
+
+// groupGraph 计算有效权限所需的角色和用户组快照
+type groupGraph struct {
+	roles  map[string]*models.Role
+	groups map[int]*models.Group
+	names  map[string]*models.Group
+}
+
+// loadGroupGraph 读取当前所有角色和用户组
+func (s *serviceCore) loadGroupGraph() (*groupGraph, error) {
+	roles, err := s.roleRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取角色列表失败: %w", err))
+	}
+	groups, err := s.groupRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取用户组列表失败: %w", err))
+	}
+
+	graph := &groupGraph{roles: make(map[string]*models.Role, len(roles))}
+	for _, role := range roles {
+		graph.roles[role.Name] = role
+	}
+	graph.setGroups(groups)
+	return graph, nil
+}
+
+// setGroups 替换快照中的用户组
+func (g *groupGraph) setGroups(groups []*models.Group) {
+	g.groups = make(map[int]*models.Group, len(groups))
+	g.names = make(map[string]*models.Group, len(groups))
+	for _, group := range groups {
+		g.groups[group.ID] = group
+		g.names[group.Name] = group
+	}
+}
+
+// with 返回用 group 替换同ID的组（或新增该组）后的快照，用于在保存前评估变更的影响
+// remove 为 true 时从快照中删除该组，其下级组成为顶级组
+func (g *groupGraph) with(group *models.Group, remove bool) *groupGraph {
+	groups := make([]*models.Group, 0, len(g.groups)+1)
+	for _, existing := range g.groups {
+		if existing.ID == group.ID {
+			continue
+		}
+		if remove && existing.IsChildOf(group.ID) {
+			orphan := *existing
+			orphan.ParentID = nil
+			existing = &orphan
+		}
+		groups = append(groups, existing)
+	}
+	if !remove {
+		groups = append(groups, group)
+	}
+
+	next := &groupGraph{roles: g.roles}
+	next.setGroups(groups)
+	return next
+}
+
+// ancestors 返回组本身及其全部上级组，从组本身开始逐级向上
+func (g *groupGraph) ancestors(group *models.Group) []*models.Group {
+	var result []*models.Group
+	visited := make(map[int]bool)
+	for current := group; current != nil && !visited[current.ID]; {
+		visited[current.ID] = true
+		result = append(result, current)
+		if current.ParentID == nil {
+			break
+		}
+		current = g.groups[*current.ParentID]
+	}
+	return result
+}
+
+// privileged 检查组的成员是否会通过该组获得普通用户以外的角色
+func (g *groupGraph) privileged(group *models.Group) bool {
+	for _, ancestor := range g.ancestors(group) {
+		for _, role := range ancestor.Roles {
+			if role != models.RoleUser {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permissions 返回组的成员通过该组（包括其上级组）获得的全部权限
+func (g *groupGraph) permissions(group *models.Group) map[string]bool {
+	result := make(map[string]bool)
+	for _, ancestor := range g.ancestors(group) {
+		for _, name := range ancestor.Roles {
+			if role, ok := g.roles[name]; ok {
+				for _, p := range role.Permissions {
+					result[p] = true
+				}
+			}
+		}
+	}
+	return result
+}
+
+// grants 检查组的成员是否会通过该组获得指定权限
+func (g *groupGraph) grants(group *models.Group, permission string) bool {
+	for _, ancestor := range g.ancestors(group) {
+		for _, name := range ancestor.Roles {
+			if role, ok := g.roles[name]; ok && role.HasPermission(permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sources 计算直接持有 roles 并直接加入 groups 的用户的有效权限，返回每项权限的来源
+func (g *groupGraph) sources(roles, groups []string) map[string][]PermissionSource {
+	result := make(map[string][]PermissionSource)
+	add := func(name string, source PermissionSource) {
+		role, ok := g.roles[name]
+		if !ok {
+			return
+		}
+		for _, p := range role.Permissions {
+			result[p] = append(result[p], source)
+		}
+	}
+
+	for _, role := range roles {
+		add(role, PermissionSource{Role: role})
+	}
+	for _, name := range groups {
+		group, ok := g.names[name]
+		if !ok {
+			continue
+		}
+		for _, ancestor := range g.ancestors(group) {
+			for _, role := range ancestor.Roles {
+				source := PermissionSource{Role: role, Group: ancestor.Name}
+				if ancestor.ID != group.ID {
+					source.Via = group.Name
+				}
+				add(role, source)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+
+	"user-management-system/errors"
+	"user-management-system/models"
+)
+
+// StartImpersonation 让 actor 以 targetID 对应用户的身份登录（模拟登录），返回目标用户
+// 需要 users:impersonate 权限或对该用户的委派授权，且目标用户不能拥有 actor 所没有的权限；
+// 检查通过后调用 switchSession 切换会话，切换失败时模拟登录同样失败
+func (s *userServiceImpl) StartImpersonation(actor *models.User, targetID int, switchSession func(target *models.User) error) (*models.User, error) {
+	if err := requireNotImpersonated(actor); err != nil {
+		return nil, err
+	}
+	if targetID <= 0 {
+		return nil, errors.NewValidationError("id", "无效的用户ID")
+	}
+	if targetID == actor.ID {
+		return nil, errors.NewValidationError("id", "不能模拟登录自己")
+	}
+
+	target, err := s.userRepo.GetByID(targetID)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+	}
+	if target == nil {
+		return nil, errors.NewNotFoundError("用户")
+	}
+	if !target.IsActive() {
+		return nil, errors.NewConflictError("只能模拟登录" + models.StatusLabel(models.StatusActive) + "状态的账户")
+	}
+	if err := s.authorizeOver(actor, target, models.PermUsersImpersonate); err != nil {
+		return nil, err
+	}
+	if err := switchSession(target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// StopImpersonation 结束模拟登录：actor 为正在被模拟的用户（Impersonator 为模拟者），
+// 调用 restoreSession 把会话恢复为模拟者本人
+func (s *userServiceImpl) StopImpersonation(actor *models.User, restoreSession func() error) error {
+	if actor == nil {
+		return errors.NewUnauthorizedError("")
+	}
+	if !actor.IsImpersonated() {
+		return errors.NewConflictError("当前不是模拟登录")
+	}
+	return restoreSession()
+}
+
+// requireNotImpersonated 模拟登录期间禁止执行敏感操作（修改密码或邮箱、删除账户、重置他人密码、再次模拟登录）
+func requireNotImpersonated(actor *models.User) error {
+	if actor == nil {
+		return errors.NewUnauthorizedError("")
+	}
+	if actor.IsImpersonated() {
+		return errors.NewForbiddenError("模拟登录期间不能执行此操作")
+	}
+	return nil
+}
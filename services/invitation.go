@@ -0,0 +1,190 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/errors"
+	"user-management-system/mail"
+	"user-management-system/models"
+)
+
+// CreateInvitation 管理员向邮箱发出注册邀请，注册后获得指定角色
+// 提升管理员需要审批时，不能邀请拥有分配角色权限的角色
+func (s *userServiceImpl) CreateInvitation(actor *models.User, email, role string) (*models.Invitation, error) {
+	if err := requirePermission(actor, models.PermInvitationsManage); err != nil {
+		return nil, err
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, errors.NewValidationError("email", "邮箱不能为空")
+	}
+	if !strings.Contains(email, "@") {
+		return nil, errors.NewValidationError("email", "邮箱格式不正确")
+	}
+	if role == "" {
+		role = models.RoleUser
+	}
+	// 被邀请人注册后获得该角色，邀请普通用户以外的角色需要分配角色的权限
+	_, permissions, err := s.resolveRoles(actor, []string{role})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refuseUnapprovedPromotion(permissions[models.PermUsersManage], "该邀请"); err != nil {
+		return nil, err
+	}
+
+	emailExists, err := s.emailTaken(email, 0)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
+	}
+	if emailExists {
+		return nil, errors.NewConflictError("该邮箱已注册")
+	}
+	pending, err := s.invitationRepo.GetPendingByEmail(email)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询邀请失败: %w", err))
+	}
+	if pending != nil {
+		return nil, errors.NewConflictError("已向该邮箱发出过邀请，如需再次发送请使用重新发送")
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("生成邀请令牌失败: %w", err))
+	}
+	invitation := &models.Invitation{
+		Email:       email,
+		Role:        role,
+		TokenHash:   tokenHash,
+		InvitedBy:   actor.ID,
+		InviterName: actor.Username,
+		ExpiresAt:   time.Now().Add(s.cfg.InvitationTTL),
+	}
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("保存邀请失败: %w", err))
+	}
+
+	if err := s.sendInvitation(invitation, token); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// GetInvitations 获取所有邀请
+func (s *userServiceImpl) GetInvitations() ([]*models.Invitation, error) {
+	invitations, err := s.invitationRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取邀请列表失败: %w", err))
+	}
+	return invitations, nil
+}
+
+// ResendInvitation 重新发送邀请，旧链接失效并重新计算有效期
+func (s *userServiceImpl) ResendInvitation(actor *models.User, id int) (*models.Invitation, error) {
+	if err := requirePermission(actor, models.PermInvitationsManage); err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.getInvitation(id)
+	if err != nil {
+		return nil, err
+	}
+	// 过期的邀请可以重新发送，已接受或已撤销的不行
+	if status := invitation.Status(); status != models.InvitationPending && status != models.InvitationExpired {
+		return nil, errors.NewConflictError("邀请" + invitation.StatusLabel() + "，不能重新发送")
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("生成邀请令牌失败: %w", err))
+	}
+	expiresAt := time.Now().Add(s.cfg.InvitationTTL)
+	if err := s.invitationRepo.UpdateToken(invitation.ID, tokenHash, expiresAt); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("更新邀请失败: %w", err))
+	}
+	invitation.TokenHash = tokenHash
+	invitation.ExpiresAt = expiresAt
+
+	if err := s.sendInvitation(invitation, token); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// RevokeInvitation 撤销尚未被接受的邀请
+func (s *userServiceImpl) RevokeInvitation(actor *models.User, id int) error {
+	if err := requirePermission(actor, models.PermInvitationsManage); err != nil {
+		return err
+	}
+
+	invitation, err := s.getInvitation(id)
+	if err != nil {
+		return err
+	}
+	if status := invitation.Status(); status == models.InvitationAccepted || status == models.InvitationRevoked {
+		return errors.NewConflictError("邀请" + invitation.StatusLabel() + "，不能撤销")
+	}
+
+	if err := s.invitationRepo.Revoke(invitation.ID); err != nil {
+		return errors.NewInternalError(fmt.Errorf("撤销邀请失败: %w", err))
+	}
+	return nil
+}
+
+// VerifyInvitation 校验注册邀请令牌，返回可用的邀请
+func (s *userServiceImpl) VerifyInvitation(token string) (*models.Invitation, error) {
+	if token == "" {
+		return nil, errors.NewForbiddenError("当前仅限受邀用户注册，请使用邀请邮件中的链接")
+	}
+
+	invitation, err := s.invitationRepo.GetByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询邀请失败: %w", err))
+	}
+	if invitation == nil {
+		return nil, errors.NewForbiddenError("邀请链接无效")
+	}
+
+	switch invitation.Status() {
+	case models.InvitationAccepted:
+		return nil, errors.NewForbiddenError("邀请已被使用")
+	case models.InvitationRevoked:
+		return nil, errors.NewForbiddenError("邀请已被撤销")
+	case models.InvitationExpired:
+		return nil, errors.NewForbiddenError("邀请已过期，请联系管理员重新发送")
+	}
+	return invitation, nil
+}
+
+// getInvitation 根据ID获取邀请，不存在时返回未找到错误
+func (s *userServiceImpl) getInvitation(id int) (*models.Invitation, error) {
+	if id <= 0 {
+		return nil, errors.NewValidationError("id", "无效的邀请ID")
+	}
+	invitation, err := s.invitationRepo.GetByID(id)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询邀请失败: %w", err))
+	}
+	if invitation == nil {
+		return nil, errors.NewNotFoundError("邀请")
+	}
+	return invitation, nil
+}
+
+// sendInvitation 发送邀请邮件
+func (s *userServiceImpl) sendInvitation(invitation *models.Invitation, token string) error {
+	link := s.cfg.BaseURL + "/register?invitation=" + token
+	msg := &mail.Message{
+		To:      invitation.Email,
+		Subject: "您收到了一份注册邀请",
+		Body: fmt.Sprintf("您好：\n\n%s 邀请您注册 UserHub 账户，请在 %s 前打开以下链接完成注册：\n\n%s\n\n如果您不认识邀请人，请忽略本邮件。\n",
+			invitation.InviterName, invitation.ExpiresAt.Format("2006-01-02 15:04"), link),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return errors.NewInternalError(fmt.Errorf("发送邀请邮件失败: %w", err))
+	}
+	return nil
+}
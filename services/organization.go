@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/models"
+)
+
+// OrganizationService 组织服务接口：组织及其成员的管理，需要 orgs:manage 权限，不限定在某个组织内
+type OrganizationService interface {
+	// ForRequest 返回在审计事件中记录 meta 中请求信息的服务
+	ForRequest(meta audit.Meta) OrganizationService
+
+	// WithContext 返回以 ctx 中的追踪 span 为父 span 的服务
+	WithContext(ctx context.Context) OrganizationService
+
+	GetOrganizations() ([]*models.Organization, error)
+	GetOrganization(id int) (*models.Organization, error)
+	GetOrganizationMembers(id int) ([]*models.User, error)
+	CreateOrganization(actor *models.User, input *OrganizationInput) (*models.Organization, error)
+	UpdateOrganization(actor *models.User, id int, input *OrganizationInput) (*models.Organization, error)
+	DeleteOrganization(actor *models.User, id int) error
+	AddOrganizationMember(actor *models.User, id int, username string) error
+	RemoveOrganizationMember(actor *models.User, id, userID int) error
+}
+
+// organizationServiceImpl 是 OrganizationService 接口的具体实现
+type organizationServiceImpl struct {
+	*serviceCore
+}
+
+// NewOrganizationService 创建组织服务，修改组织时记录审计事件，启用追踪时每个方法记录一个 span
+func NewOrganizationService(deps *ServiceDependencies) OrganizationService {
+	return newTracingOrganizationService(newAuditingOrganizationService(&organizationServiceImpl{newServiceCore(deps)}, newAuditor(deps)))
+}
+
+// ForRequest 实现本身不使用请求信息，审计事件由 auditingOrganizationService 记录
+func (s *organizationServiceImpl) ForRequest(meta audit.Meta) OrganizationService {
+	return s
+}
+
+// WithContext 返回所有仓库都在 ctx 中执行语句的服务副本
+func (s *organizationServiceImpl) WithContext(ctx context.Context) OrganizationService {
+	return &organizationServiceImpl{s.withContext(ctx)}
+}
+
+// organizationSlugPattern 组织标识用于子域名和路径前缀，只能使用小写字母、数字和连字符，且以字母或数字开头
+var organizationSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,31}$`)
+
+// reservedOrganizationSlugs 不能用作组织标识的名称（www 不会被解析为子域名）
+var reservedOrganizationSlugs = map[string]bool{
+	"www": true,
+}
+
+// OrganizationInput 创建或修改组织时提交的内容
+type OrganizationInput struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// GetOrganizations 获取所有组织
+func (s *organizationServiceImpl) GetOrganizations() ([]*models.Organization, error) {
+	orgs, err := s.orgRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取组织列表失败: %w", err))
+	}
+	return orgs, nil
+}
+
+// GetOrganization 根据ID获取组织
+func (s *organizationServiceImpl) GetOrganization(id int) (*models.Organization, error) {
+	if id <= 0 {
+		return nil, errors.NewValidationError("id", "无效的组织ID")
+	}
+	org, err := s.orgRepo.GetByID(id)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询组织失败: %w", err))
+	}
+	if org == nil {
+		return nil, errors.NewNotFoundError("组织")
+	}
+	return org, nil
+}
+
+// GetOrganizationMembers 获取组织的成员
+func (s *organizationServiceImpl) GetOrganizationMembers(id int) ([]*models.User, error) {
+	if _, err := s.GetOrganization(id); err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.ForOrganization(id).GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询组织成员失败: %w", err))
+	}
+	return users, nil
+}
+
+// CreateOrganization 创建组织
+func (s *organizationServiceImpl) CreateOrganization(actor *models.User, input *OrganizationInput) (*models.Organization, error) {
+	if err := requirePermission(actor, models.PermOrgsManage); err != nil {
+		return nil, err
+	}
+
+	org, err := s.buildOrganization(nil, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.Create(org); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("创建组织失败: %w", err))
+	}
+	return org, nil
+}
+
+// UpdateOrganization 修改组织的标识和名称，默认组织的标识不能修改
+func (s *organizationServiceImpl) UpdateOrganization(actor *models.User, id int, input *OrganizationInput) (*models.Organization, error) {
+	if err := requirePermission(actor, models.PermOrgsManage); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetOrganization(id)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.buildOrganization(existing, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.Update(org); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("修改组织失败: %w", err))
+	}
+	return org, nil
+}
+
+// DeleteOrganization 删除没有成员的组织，组织的用户组和邀请随之删除；默认组织不能删除
+func (s *organizationServiceImpl) DeleteOrganization(actor *models.User, id int) error {
+	if err := requirePermission(actor, models.PermOrgsManage); err != nil {
+		return err
+	}
+
+	org, err := s.GetOrganization(id)
+	if err != nil {
+		return err
+	}
+	if org.IsDefault() {
+		return errors.NewForbiddenError("默认组织不能删除")
+	}
+	if org.MemberCount > 0 {
+		return errors.NewConflictError("请先移除组织的全部成员")
+	}
+
+	if err := s.orgRepo.Delete(id); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.NewNotFoundError("组织")
+		}
+		return errors.NewInternalError(fmt.Errorf("删除组织失败: %w", err))
+	}
+	return nil
+}
+
+// AddOrganizationMember 按用户名将用户加入组织，已是成员时返回冲突错误
+func (s *organizationServiceImpl) AddOrganizationMember(actor *models.User, id int, username string) error {
+	if err := requirePermission(actor, models.PermOrgsManage); err != nil {
+		return err
+	}
+
+	org, err := s.GetOrganization(id)
+	if err != nil {
+		return err
+	}
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return errors.NewValidationError("username", "用户名不能为空")
+	}
+	user, err := s.userRepo.GetByUsername(username)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("获取用户失败: %w", err))
+	}
+	if user == nil {
+		return errors.NewNotFoundError("用户不存在")
+	}
+	if user.InOrg(org.Slug) {
+		return errors.NewConflictError("该用户已是组织成员")
+	}
+
+	if err := s.orgRepo.AddMember(org.ID, user.ID); err != nil {
+		return errors.NewInternalError(fmt.Errorf("添加组织成员失败: %w", err))
+	}
+	return nil
+}
+
+// RemoveOrganizationMember 将用户移出组织，同时移出该组织的所有用户组
+// 用户至少要属于一个组织
+func (s *organizationServiceImpl) RemoveOrganizationMember(actor *models.User, id, userID int) error {
+	if err := requirePermission(actor, models.PermOrgsManage); err != nil {
+		return err
+	}
+
+	org, err := s.GetOrganization(id)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.ForOrganization(0).GetByID(userID)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+	}
+	if user == nil || !user.InOrg(org.Slug) {
+		return errors.NewNotFoundError("组织成员")
+	}
+	if len(user.Orgs) <= 1 {
+		return errors.NewConflictError("用户至少要属于一个组织")
+	}
+
+	if err := s.orgRepo.RemoveMember(org.ID, user.ID); err != nil {
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errors.NewNotFoundError("组织成员")
+		}
+		return errors.NewInternalError(fmt.Errorf("移除组织成员失败: %w", err))
+	}
+	return nil
+}
+
+// buildOrganization 校验提交的内容并生成新的组织，existing 为 nil 表示创建组织
+func (s *organizationServiceImpl) buildOrganization(existing *models.Organization, input *OrganizationInput) (*models.Organization, error) {
+	slug := strings.ToLower(strings.TrimSpace(input.Slug))
+	name := strings.TrimSpace(input.Name)
+	if !organizationSlugPattern.MatchString(slug) || reservedOrganizationSlugs[slug] {
+		return nil, errors.NewValidationError("slug", "组织标识须为2到32个小写字母、数字或连字符，并以字母或数字开头")
+	}
+	if name == "" {
+		return nil, errors.NewValidationError("name", "组织名称不能为空")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return nil, errors.NewValidationError("name", "组织名称不能超过100个字符")
+	}
+
+	org := &models.Organization{Slug: slug, Name: name}
+	if existing != nil {
+		if existing.IsDefault() && slug != existing.Slug {
+			return nil, errors.NewForbiddenError("默认组织的标识不能修改")
+		}
+		org.ID = existing.ID
+		org.MemberCount = existing.MemberCount
+		org.CreatedAt = existing.CreatedAt
+	}
+
+	other, err := s.orgRepo.GetBySlug(slug)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("检查组织标识失败: %w", err))
+	}
+	if other != nil && (existing == nil || other.ID != existing.ID) {
+		return nil, errors.NewConflictError("组织标识已被使用")
+	}
+	return org, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"time"
+
+	"user-management-system/logger"
+)
+
+// StartPurgeJob 启动后台任务，每隔 interval 永久删除一次超过保留期的回收站用户
+// 返回的函数用于停止任务；interval 不大于0时不启动
+func StartPurgeJob(userService UserService, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// 启动时先执行一次，避免长时间运行前的积压
+			purgeDeletedUsers(userService)
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// purgeDeletedUsers 执行一次清除并记录结果
+func purgeDeletedUsers(userService UserService) {
+	count, err := userService.PurgeDeletedUsers(time.Now())
+	if err != nil {
+		logger.Error("清除回收站用户失败", "error", err)
+		return
+	}
+	if count > 0 {
+		logger.Info("已永久删除超过保留期的回收站用户", "count", count)
+	}
+}
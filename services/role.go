@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"user-management-system/audit"
+	"user-management-system/errors"
+	"user-management-system/models"
+	"user-management-system/rbac"
+)
+
+// RoleService 角色服务接口：角色的查询和管理
+// 角色不按组织划分，修改角色会影响所有组织中持有该角色的用户
+type RoleService interface {
+	// ForRequest 返回在审计事件中记录 meta 中请求信息的服务
+	ForRequest(meta audit.Meta) RoleService
+
+	// WithContext 返回以 ctx 中的追踪 span 为父 span 的服务
+	WithContext(ctx context.Context) RoleService
+
+	//权限检查
+	Can(user *models.User, permission string, resource interface{}) bool
+
+	//角色管理
+	GetRoles() ([]*models.Role, error)
+	GetRole(id int) (*models.Role, error)
+	CreateRole(actor *models.User, input *RoleInput) (*models.Role, error)
+	PreviewRoleUpdate(actor *models.User, id int, input *RoleInput) (*RoleChangePreview, error)
+	UpdateRole(actor *models.User, id int, input *RoleInput) (*models.Role, error)
+	PreviewRoleDelete(actor *models.User, id int) (*RoleChangePreview, error)
+	DeleteRole(actor *models.User, id int) error
+}
+
+// roleServiceImpl 是 RoleService 接口的具体实现
+type roleServiceImpl struct {
+	*serviceCore
+}
+
+// NewRoleService 创建角色服务，修改角色时记录审计事件，启用追踪时每个方法记录一个 span
+func NewRoleService(deps *ServiceDependencies) RoleService {
+	return newTracingRoleService(newAuditingRoleService(&roleServiceImpl{newServiceCore(deps)}, newAuditor(deps)))
+}
+
+// ForRequest 实现本身不使用请求信息，审计事件由 auditingRoleService 记录
+func (s *roleServiceImpl) ForRequest(meta audit.Meta) RoleService {
+	return s
+}
+
+// WithContext 返回所有仓库都在 ctx 中执行语句的服务副本
+func (s *roleServiceImpl) WithContext(ctx context.Context) RoleService {
+	return &roleServiceImpl{s.withContext(ctx)}
+}
+
+// Can 检查 user 能否对 resource 执行 permission 对应的操作（见 rbac.Can）
+func (s *roleServiceImpl) Can(user *models.User, permission string, resource interface{}) bool {
+	return rbac.Can(user, permission, resource)
+}
+
+// GetRoles 获取所有角色及其权限
+func (s *roleServiceImpl) GetRoles() ([]*models.Role, error) {
+	roles, err := s.roleRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取角色列表失败: %w", err))
+	}
+	return roles, nil
+}
+
+// requirePermission 检查操作者是否拥有指定权限
+func requirePermission(actor *models.User, permission string) error {
+	if actor == nil {
+		return errors.NewUnauthorizedError("")
+	}
+	if !rbac.Can(actor, permission, nil) {
+		return errors.NewForbiddenError("没有权限：" + models.PermissionLabel(permission))
+	}
+	return nil
+}
+
+// resolveRoles 校验并去重角色标识，返回角色列表及这些角色汇总的权限
+// 至少需要一个角色；分配默认的普通用户角色以外的角色需要 users:manage 权限，且操作者必须拥有这些角色的全部权限
+func (s *serviceCore) resolveRoles(actor *models.User, names []string) ([]string, map[string]bool, error) {
+	all, err := s.roleRepo.GetAll()
+	if err != nil {
+		return nil, nil, errors.NewInternalError(fmt.Errorf("获取角色列表失败: %w", err))
+	}
+	byName := make(map[string]*models.Role, len(all))
+	for _, role := range all {
+		byName[role.Name] = role
+	}
+
+	roles := make([]string, 0, len(names))
+	permissions := make(map[string]bool)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		role, ok := byName[name]
+		if !ok {
+			return nil, nil, errors.NewValidationError("roles", "角色不存在："+name)
+		}
+		seen[name] = true
+		roles = append(roles, name)
+		for _, p := range role.Permissions {
+			permissions[p] = true
+		}
+	}
+	if len(roles) == 0 {
+		return nil, nil, errors.NewValidationError("roles", "请至少选择一个角色")
+	}
+
+	if actor != nil && !(len(roles) == 1 && roles[0] == models.RoleUser) {
+		if err := requirePermission(actor, models.PermUsersManage); err != nil {
+			return nil, nil, err
+		}
+		if err := requireGrantable(actor, permissions); err != nil {
+			return nil, nil, err
+		}
+	}
+	return roles, permissions, nil
+}
+
+// requireGrantable 授予角色时操作者必须拥有这些角色的全部权限，
+// 防止组织管理员把自己或他人提升为可以管理所有组织的管理员
+func requireGrantable(actor *models.User, permissions map[string]bool) error {
+	for _, p := range models.Permissions {
+		if permissions[p.Name] && !actor.HasPermission(p.Name) {
+			return errors.NewForbiddenError("不能授予自己没有的权限：" + p.Label)
+		}
+	}
+	return nil
+}
+
+// sameRoles 比较两组角色是否相同（忽略顺序）
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, r := range a {
+		set[r] = true
+	}
+	for _, r := range b {
+		if !set[r] {
+			return false
+		}
+	}
+	return true
+}
+
+// roleNamePattern 角色标识只能使用小写字母、数字、下划线和连字符，且以字母开头
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// RoleInput 创建或修改角色时提交的内容
+type RoleInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleChangePreview 修改或删除角色前展示给管理员的影响范围
+type RoleChangePreview struct {
+	Role               *models.Role   // 变更前的角色
+	Updated            *models.Role   // 变更后的角色，删除时为 nil
+	AddedPermissions   []string       // 新增的权限
+	RemovedPermissions []string       // 移除的权限
+	AffectedUsers      []*models.User // 持有该角色、权限会随之变化的用户
+	UsersWithoutRoles  []*models.User // 删除后不再持有任何角色的用户
+}
+
+// GetRole 根据ID获取角色
+func (s *roleServiceImpl) GetRole(id int) (*models.Role, error) {
+	if id <= 0 {
+		return nil, errors.NewValidationError("id", "无效的角色ID")
+	}
+	role, err := s.roleRepo.GetByID(id)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询角色失败: %w", err))
+	}
+	if role == nil {
+		return nil, errors.NewNotFoundError("角色")
+	}
+	return role, nil
+}
+
+// CreateRole 创建自定义角色
+func (s *roleServiceImpl) CreateRole(actor *models.User, input *RoleInput) (*models.Role, error) {
+	if err := requirePermission(actor, models.PermRolesManage); err != nil {
+		return nil, err
+	}
+
+	role, err := s.buildRole(actor, nil, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("创建角色失败: %w", err))
+	}
+	return role, nil
+}
+
+// PreviewRoleUpdate 校验对角色的修改并返回其影响范围，不会保存修改
+func (s *roleServiceImpl) PreviewRoleUpdate(actor *models.User, id int, input *RoleInput) (*RoleChangePreview, error) {
+	if err := requirePermission(actor, models.PermRolesManage); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.buildRole(actor, existing, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureManagerRemains(existing, updated.Permissions); err != nil {
+		return nil, err
+	}
+
+	preview := &RoleChangePreview{Role: existing, Updated: updated}
+	preview.AddedPermissions, preview.RemovedPermissions = diffPermissions(existing.Permissions, updated.Permissions)
+	if preview.AffectedUsers, err = s.roleHolders(existing.ID); err != nil {
+		return nil, err
+	}
+	// 为角色加上 users:manage 会使持有该角色（包括通过用户组继承）的用户成为管理员
+	if !existing.HasPermission(models.PermUsersManage) && updated.HasPermission(models.PermUsersManage) {
+		promotes := false
+		for _, user := range preview.AffectedUsers {
+			promotes = promotes || !user.IsAdmin()
+		}
+		if err := s.refuseUnapprovedPromotion(promotes, "该修改"); err != nil {
+			return nil, err
+		}
+	}
+	return preview, nil
+}
+
+// UpdateRole 修改角色的标识、说明和权限
+// 提升管理员需要审批时，不能为已有非管理员用户持有的角色加上分配角色的权限
+func (s *roleServiceImpl) UpdateRole(actor *models.User, id int, input *RoleInput) (*models.Role, error) {
+	preview, err := s.PreviewRoleUpdate(actor, id, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roleRepo.Update(preview.Updated, preview.Role.Name); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("修改角色失败: %w", err))
+	}
+	return preview.Updated, nil
+}
+
+// PreviewRoleDelete 校验角色能否删除并返回其影响范围，不会删除角色
+func (s *roleServiceImpl) PreviewRoleDelete(actor *models.User, id int) (*RoleChangePreview, error) {
+	if err := requirePermission(actor, models.PermRolesManage); err != nil {
+		return nil, err
+	}
+
+	role, err := s.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem {
+		return nil, errors.NewForbiddenError("内置角色不能删除")
+	}
+	if err := s.ensureManagerRemains(role, nil); err != nil {
+		return nil, err
+	}
+
+	// 被邀请人注册时会获得邀请中指定的角色，角色删除后这些邀请将无法使用
+	invitations, err := s.invitationRepo.GetAll()
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询邀请失败: %w", err))
+	}
+	for _, invitation := range invitations {
+		if invitation.Role == role.Name && invitation.Status() == models.InvitationPending {
+			return nil, errors.NewConflictError("还有未使用的注册邀请指定了该角色，请先撤销这些邀请")
+		}
+	}
+
+	preview := &RoleChangePreview{Role: role, RemovedPermissions: role.Permissions}
+	if preview.AffectedUsers, err = s.roleHolders(role.ID); err != nil {
+		return nil, err
+	}
+	for _, user := range preview.AffectedUsers {
+		// 通过用户组继承该角色的用户在组中仍保留其他角色，只关注直接持有的角色
+		if len(user.Roles) == 1 && user.Roles[0] == role.Name {
+			preview.UsersWithoutRoles = append(preview.UsersWithoutRoles, user)
+		}
+	}
+	return preview, nil
+}
+
+// DeleteRole 删除自定义角色，持有该角色的用户随之失去该角色
+func (s *roleServiceImpl) DeleteRole(actor *models.User, id int) error {
+	if _, err := s.PreviewRoleDelete(actor, id); err != nil {
+		return err
+	}
+	if err := s.roleRepo.Delete(id); err != nil {
+		return errors.NewInternalError(fmt.Errorf("删除角色失败: %w", err))
+	}
+	return nil
+}
+
+// buildRole 校验提交的内容并生成新的角色，existing 为 nil 表示创建角色
+// 操作者只能授予自己拥有的权限；内置角色不能改名，管理员角色的权限不能修改
+func (s *roleServiceImpl) buildRole(actor *models.User, existing *models.Role, input *RoleInput) (*models.Role, error) {
+	name := strings.ToLower(strings.TrimSpace(input.Name))
+	description := strings.TrimSpace(input.Description)
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.NewValidationError("name", "角色标识须为2到32个小写字母、数字、下划线或连字符，并以字母开头")
+	}
+	if len([]rune(description)) > 255 {
+		return nil, errors.NewValidationError("description", "说明不能超过255个字符")
+	}
+
+	// 按权限列表的顺序整理，忽略重复项
+	requested := make(map[string]bool, len(input.Permissions))
+	for _, p := range input.Permissions {
+		if !models.IsValidPermission(p) {
+			return nil, errors.NewValidationError("permissions", "无效的权限："+p)
+		}
+		requested[p] = true
+	}
+	permissions := make([]string, 0, len(requested))
+	for _, p := range models.Permissions {
+		if requested[p.Name] {
+			permissions = append(permissions, p.Name)
+		}
+	}
+
+	var current []string
+	if existing != nil {
+		current = existing.Permissions
+		if existing.IsSystem && name != existing.Name {
+			return nil, errors.NewForbiddenError("内置角色不能改名")
+		}
+		if existing.Name == models.RoleAdmin {
+			if added, removed := diffPermissions(current, permissions); len(added) > 0 || len(removed) > 0 {
+				return nil, errors.NewForbiddenError("管理员角色始终拥有全部权限，不能修改")
+			}
+		}
+	}
+	added, _ := diffPermissions(current, permissions)
+	for _, p := range added {
+		if !actor.HasPermission(p) {
+			return nil, errors.NewForbiddenError("不能授予自己没有的权限：" + models.PermissionLabel(p))
+		}
+	}
+
+	other, err := s.roleRepo.GetByName(name)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("检查角色标识失败: %w", err))
+	}
+	if other != nil && (existing == nil || other.ID != existing.ID) {
+		return nil, errors.NewConflictError("角色标识已存在")
+	}
+
+	role := &models.Role{Name: name, Description: description, Permissions: permissions}
+	if existing != nil {
+		role.ID = existing.ID
+		role.IsSystem = existing.IsSystem
+		role.CreatedAt = existing.CreatedAt
+	}
+	return role, nil
+}
+
+// ensureManagerRemains 角色的权限变为 permissions（删除时为 nil）后，系统中必须仍有正常状态的用户拥有 users:manage 权限
+func (s *roleServiceImpl) ensureManagerRemains(role *models.Role, permissions []string) error {
+	if !role.HasPermission(models.PermUsersManage) {
+		return nil
+	}
+	for _, p := range permissions {
+		if p == models.PermUsersManage {
+			return nil
+		}
+	}
+
+	count, err := s.userRepo.CountByPermissionAndStatusExcludingRole(models.PermUsersManage, models.StatusActive, role.ID)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("查询管理员数量失败: %w", err))
+	}
+	if count == 0 {
+		return errors.NewForbiddenError("该变更会使系统中没有可以分配角色的管理员")
+	}
+	return nil
+}
+
+// roleHolders 获取持有指定角色的用户
+func (s *roleServiceImpl) roleHolders(roleID int) ([]*models.User, error) {
+	users, err := s.userRepo.GetByRoleID(roleID)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询角色用户失败: %w", err))
+	}
+	return users, nil
+}
+
+// diffPermissions 比较两组权限，返回 after 相对 before 新增和移除的权限
+func diffPermissions(before, after []string) (added, removed []string) {
+	inBefore := make(map[string]bool, len(before))
+	for _, p := range before {
+		inBefore[p] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, p := range after {
+		inAfter[p] = true
+		if !inBefore[p] {
+			added = append(added, p)
+		}
+	}
+	for _, p := range before {
+		if !inAfter[p] {
+			removed = append(removed, p)
+		}
+	}
+	return added, removed
+}
@@ -1,20 +1,49 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"log/slog"
+
+	"user-management-system/audit"
+	"user-management-system/config"
+	"user-management-system/logger"
+	"user-management-system/mail"
+	"user-management-system/password"
 	"user-management-system/repository/interfaces"
 	"user-management-system/repository/mysql"
 )
 
 // Service 是所有服务的集合，用于统一管理服务实例
+// 各服务共用同一组仓库，按业务划分：用户账户、角色、用户组、组织、委派授权、双人审批和审计日志
 type Service struct {
-	UserService UserService
+	UserService          UserService
+	RoleService          RoleService
+	GroupService         GroupService
+	OrganizationService  OrganizationService
+	AdminGrantService    AdminGrantService
+	ChangeRequestService ChangeRequestService
+	AuditService         AuditService
 }
 
 // ServiceDependencies 服务依赖项
 type ServiceDependencies struct {
-	DB             *sql.DB
-	UserRepository interfaces.UserRepository
+	DB                          *sql.DB
+	UserRepository              interfaces.UserRepository
+	EmailVerificationRepository interfaces.EmailVerificationRepository
+	PasswordHistoryRepository   interfaces.PasswordHistoryRepository
+	PasswordTokenRepository     interfaces.PasswordTokenRepository
+	InvitationRepository        interfaces.InvitationRepository
+	RoleRepository              interfaces.RoleRepository
+	GroupRepository             interfaces.GroupRepository
+	OrganizationRepository      interfaces.OrganizationRepository
+	AdminGrantRepository        interfaces.AdminGrantRepository
+	ChangeRequestRepository     interfaces.ChangeRequestRepository
+	AuditRepository             interfaces.AuditRepository
+	AuditSigner                 *audit.Signer // 为空时使用根据配置加载的全局签名器（未配置密钥时不生成检查点）
+	Mailer                      mail.Mailer
+	PasswordPolicy              *password.Policy
+	PasswordHasher              password.Hasher
 }
 
 // NewService  创建一个新的服务集合实例
@@ -23,8 +52,60 @@ func NewService(deps *ServiceDependencies) *Service {
 	if deps.UserRepository == nil {
 		deps.UserRepository = mysql.NewUserRepository(deps.DB)
 	}
+	if deps.EmailVerificationRepository == nil {
+		deps.EmailVerificationRepository = mysql.NewEmailVerificationRepository(deps.DB)
+	}
+	if deps.PasswordHistoryRepository == nil {
+		deps.PasswordHistoryRepository = mysql.NewPasswordHistoryRepository(deps.DB)
+	}
+	if deps.PasswordTokenRepository == nil {
+		deps.PasswordTokenRepository = mysql.NewPasswordTokenRepository(deps.DB)
+	}
+	if deps.InvitationRepository == nil {
+		deps.InvitationRepository = mysql.NewInvitationRepository(deps.DB)
+	}
+	if deps.RoleRepository == nil {
+		deps.RoleRepository = mysql.NewRoleRepository(deps.DB)
+	}
+	if deps.GroupRepository == nil {
+		deps.GroupRepository = mysql.NewGroupRepository(deps.DB)
+	}
+	if deps.OrganizationRepository == nil {
+		deps.OrganizationRepository = mysql.NewOrganizationRepository(deps.DB)
+	}
+	if deps.AdminGrantRepository == nil {
+		deps.AdminGrantRepository = mysql.NewAdminGrantRepository(deps.DB)
+	}
+	if deps.ChangeRequestRepository == nil {
+		deps.ChangeRequestRepository = mysql.NewChangeRequestRepository(deps.DB)
+	}
+	if deps.AuditRepository == nil {
+		deps.AuditRepository = mysql.NewAuditRepository(deps.DB)
+	}
+	// 如果没有提供审计签名器，使用根据配置加载的全局签名器
+	if deps.AuditSigner == nil {
+		deps.AuditSigner = audit.DefaultSigner()
+	}
+	// 如果没有提供Mailer，根据配置创建
+	if deps.Mailer == nil {
+		deps.Mailer = mail.NewMailer(config.GetConfig())
+	}
+	// 如果没有提供密码策略，使用根据配置构建的全局策略
+	if deps.PasswordPolicy == nil {
+		deps.PasswordPolicy = password.DefaultPolicy()
+	}
+	// 如果没有提供密码哈希器，使用根据配置构建的全局哈希器
+	if deps.PasswordHasher == nil {
+		deps.PasswordHasher = password.DefaultHasher()
+	}
 	return &Service{
-		UserService: NewUserService(deps.UserRepository),
+		UserService:          NewUserService(deps),
+		RoleService:          NewRoleService(deps),
+		GroupService:         NewGroupService(deps),
+		OrganizationService:  NewOrganizationService(deps),
+		AdminGrantService:    NewAdminGrantService(deps),
+		ChangeRequestService: NewChangeRequestService(deps),
+		AuditService:         NewAuditService(deps),
 	}
 }
 
@@ -35,3 +116,87 @@ func NewServiceWithDB(db *sql.DB) *Service {
 		UserRepository: mysql.NewUserRepository(db),
 	})
 }
+
+// serviceCore 各服务共享的仓库和配置，以及跨服务使用的校验（分配角色、管理员数量、双人审批等）
+type serviceCore struct {
+	userRepo         interfaces.UserRepository
+	verificationRepo interfaces.EmailVerificationRepository
+	historyRepo      interfaces.PasswordHistoryRepository
+	tokenRepo        interfaces.PasswordTokenRepository
+	invitationRepo   interfaces.InvitationRepository
+	roleRepo         interfaces.RoleRepository
+	groupRepo        interfaces.GroupRepository
+	orgRepo          interfaces.OrganizationRepository
+	grantRepo        interfaces.AdminGrantRepository
+	changeRepo       interfaces.ChangeRequestRepository
+	auditRepo        interfaces.AuditRepository
+	auditSigner      *audit.Signer
+	orgID            int // 限定的组织，0 表示不限定
+	mailer           mail.Mailer
+	passwordPolicy   *password.Policy
+	passwordHasher   password.Hasher
+	cfg              *config.Config
+	ctx              context.Context // 执行的请求上下文，为 nil 时不在请求中
+}
+
+// newServiceCore 使用 deps 中的仓库创建共享部分，deps 中的依赖需已全部就绪
+func newServiceCore(deps *ServiceDependencies) *serviceCore {
+	return &serviceCore{
+		userRepo:         deps.UserRepository,
+		verificationRepo: deps.EmailVerificationRepository,
+		historyRepo:      deps.PasswordHistoryRepository,
+		tokenRepo:        deps.PasswordTokenRepository,
+		invitationRepo:   deps.InvitationRepository,
+		roleRepo:         deps.RoleRepository,
+		groupRepo:        deps.GroupRepository,
+		orgRepo:          deps.OrganizationRepository,
+		grantRepo:        deps.AdminGrantRepository,
+		changeRepo:       deps.ChangeRequestRepository,
+		auditRepo:        deps.AuditRepository,
+		auditSigner:      deps.AuditSigner,
+		mailer:           deps.Mailer,
+		passwordPolicy:   deps.PasswordPolicy,
+		passwordHasher:   deps.PasswordHasher,
+		cfg:              config.GetConfig(),
+	}
+}
+
+// forOrganization 返回限定在指定组织内的副本，orgID 为 0 时不限定
+// 用户、用户组、邀请、委派授权、变更申请和审计事件按组织划分，角色和组织本身不划分
+func (s *serviceCore) forOrganization(orgID int) *serviceCore {
+	scoped := *s
+	scoped.orgID = orgID
+	scoped.userRepo = s.userRepo.ForOrganization(orgID)
+	scoped.groupRepo = s.groupRepo.ForOrganization(orgID)
+	scoped.invitationRepo = s.invitationRepo.ForOrganization(orgID)
+	scoped.grantRepo = s.grantRepo.ForOrganization(orgID)
+	scoped.changeRepo = s.changeRepo.ForOrganization(orgID)
+	scoped.auditRepo = s.auditRepo.ForOrganization(orgID)
+	return &scoped
+}
+
+// withContext 返回所有仓库都在 ctx 中执行语句的副本，日志使用 ctx 中请求范围的日志记录器
+func (s *serviceCore) withContext(ctx context.Context) *serviceCore {
+	scoped := *s
+	scoped.ctx = ctx
+	scoped.userRepo = s.userRepo.WithContext(ctx)
+	scoped.verificationRepo = s.verificationRepo.WithContext(ctx)
+	scoped.historyRepo = s.historyRepo.WithContext(ctx)
+	scoped.tokenRepo = s.tokenRepo.WithContext(ctx)
+	scoped.invitationRepo = s.invitationRepo.WithContext(ctx)
+	scoped.roleRepo = s.roleRepo.WithContext(ctx)
+	scoped.groupRepo = s.groupRepo.WithContext(ctx)
+	scoped.orgRepo = s.orgRepo.WithContext(ctx)
+	scoped.grantRepo = s.grantRepo.WithContext(ctx)
+	scoped.changeRepo = s.changeRepo.WithContext(ctx)
+	scoped.auditRepo = s.auditRepo.WithContext(ctx)
+	return &scoped
+}
+
+// log 返回请求范围的日志记录器，不在请求中时返回全局日志记录器
+func (s *serviceCore) log() *slog.Logger {
+	if s.ctx == nil {
+		return logger.L()
+	}
+	return logger.FromContext(s.ctx)
+}
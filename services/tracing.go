@@ -0,0 +1,714 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"user-management-system/audit"
+	"user-management-system/models"
+	"user-management-system/tracing"
+)
+
+// tracingService 为 UserService 的每个方法记录一个名为 UserService.<方法名> 的 span，
+// 父 span 取自 WithContext 传入的上下文（通常是 HTTP 请求的 span）；内层服务在该 span 下执行，
+// 审计事件的写入和仓库中的 SQL 语句都成为它的子 span
+// PurgeTime、IsPasswordExpired 只做内存中的判断，不记录 span；未启用追踪时直接调用内层服务
+type tracingService struct {
+	UserService
+	ctx context.Context
+}
+
+// newTracingService 为服务加上追踪
+func newTracingService(inner UserService) UserService {
+	return &tracingService{UserService: inner, ctx: context.Background()}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录 span
+func (t *tracingService) ForOrganization(orgID int) UserService {
+	return &tracingService{UserService: t.UserService.ForOrganization(orgID), ctx: t.ctx}
+}
+
+// ForRequest 返回在审计事件中记录 meta 中请求信息的服务，继续记录 span
+func (t *tracingService) ForRequest(meta audit.Meta) UserService {
+	return &tracingService{UserService: t.UserService.ForRequest(meta), ctx: t.ctx}
+}
+
+// WithContext 返回以 ctx 中的 span 为父 span 的服务
+func (t *tracingService) WithContext(ctx context.Context) UserService {
+	return &tracingService{UserService: t.UserService, ctx: ctx}
+}
+
+// start 开始方法的 span，返回在该 span 下执行的内层服务；span 不会被导出时直接返回内层服务
+func (t *tracingService) start(method string) (UserService, *tracing.Span) {
+	ctx, span := tracing.Start(t.ctx, "UserService."+method, tracing.KindInternal)
+	if !span.IsRecording() {
+		return t.UserService, span
+	}
+	return t.UserService.WithContext(ctx), span
+}
+
+// 用户认证相关
+func (t *tracingService) RegisterUser(username, password, email, invitationToken string) (*models.User, error) {
+	svc, span := t.start("RegisterUser")
+	user, err := svc.RegisterUser(username, password, email, invitationToken)
+	span.EndWithError(err)
+	return user, err
+}
+
+func (t *tracingService) AuthenticateUser(username, password string) (*models.User, error) {
+	svc, span := t.start("AuthenticateUser")
+	user, err := svc.AuthenticateUser(username, password)
+	span.EndWithError(err)
+	return user, err
+}
+
+// 用户管理相关
+func (t *tracingService) GetUserByID(id int) (*models.User, error) {
+	svc, span := t.start("GetUserByID")
+	user, err := svc.GetUserByID(id)
+	span.EndWithError(err)
+	return user, err
+}
+
+func (t *tracingService) GetUserByUsername(username string) (*models.User, error) {
+	svc, span := t.start("GetUserByUsername")
+	user, err := svc.GetUserByUsername(username)
+	span.EndWithError(err)
+	return user, err
+}
+
+func (t *tracingService) GetAllUsers() ([]*models.User, error) {
+	svc, span := t.start("GetAllUsers")
+	users, err := svc.GetAllUsers()
+	span.EndWithError(err)
+	return users, err
+}
+
+func (t *tracingService) UpdateUser(actor *models.User, id int, email string, roles []string) (*models.ChangeRequest, error) {
+	svc, span := t.start("UpdateUser")
+	req, err := svc.UpdateUser(actor, id, email, roles)
+	span.EndWithError(err)
+	return req, err
+}
+
+func (t *tracingService) DeleteUser(actor *models.User, id int) (*models.ChangeRequest, error) {
+	svc, span := t.start("DeleteUser")
+	req, err := svc.DeleteUser(actor, id)
+	span.EndWithError(err)
+	return req, err
+}
+
+func (t *tracingService) ChangeStatus(actor *models.User, id int, status, reason string) error {
+	svc, span := t.start("ChangeStatus")
+	err := svc.ChangeStatus(actor, id, status, reason)
+	span.EndWithError(err)
+	return err
+}
+
+// 管理员创建用户、重置密码
+func (t *tracingService) CreateUser(actor *models.User, input *CreateUserInput) (*models.User, *PasswordSetup, error) {
+	svc, span := t.start("CreateUser")
+	user, setup, err := svc.CreateUser(actor, input)
+	span.EndWithError(err)
+	return user, setup, err
+}
+
+func (t *tracingService) ForcePasswordReset(actor *models.User, id int, mode string) (*PasswordSetup, error) {
+	svc, span := t.start("ForcePasswordReset")
+	setup, err := svc.ForcePasswordReset(actor, id, mode)
+	span.EndWithError(err)
+	return setup, err
+}
+
+func (t *tracingService) VerifyPasswordSetupToken(token string) (*models.User, *models.PasswordToken, error) {
+	svc, span := t.start("VerifyPasswordSetupToken")
+	user, passwordToken, err := svc.VerifyPasswordSetupToken(token)
+	span.EndWithError(err)
+	return user, passwordToken, err
+}
+
+func (t *tracingService) CompletePasswordSetup(token, newPassword string) (*models.User, error) {
+	svc, span := t.start("CompletePasswordSetup")
+	user, err := svc.CompletePasswordSetup(token, newPassword)
+	span.EndWithError(err)
+	return user, err
+}
+
+// 注册邀请
+func (t *tracingService) CreateInvitation(actor *models.User, email, role string) (*models.Invitation, error) {
+	svc, span := t.start("CreateInvitation")
+	invitation, err := svc.CreateInvitation(actor, email, role)
+	span.EndWithError(err)
+	return invitation, err
+}
+
+func (t *tracingService) GetInvitations() ([]*models.Invitation, error) {
+	svc, span := t.start("GetInvitations")
+	invitations, err := svc.GetInvitations()
+	span.EndWithError(err)
+	return invitations, err
+}
+
+func (t *tracingService) ResendInvitation(actor *models.User, id int) (*models.Invitation, error) {
+	svc, span := t.start("ResendInvitation")
+	invitation, err := svc.ResendInvitation(actor, id)
+	span.EndWithError(err)
+	return invitation, err
+}
+
+func (t *tracingService) RevokeInvitation(actor *models.User, id int) error {
+	svc, span := t.start("RevokeInvitation")
+	err := svc.RevokeInvitation(actor, id)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingService) VerifyInvitation(token string) (*models.Invitation, error) {
+	svc, span := t.start("VerifyInvitation")
+	invitation, err := svc.VerifyInvitation(token)
+	span.EndWithError(err)
+	return invitation, err
+}
+
+// 注册审批
+func (t *tracingService) GetPendingUsers() ([]*models.User, error) {
+	svc, span := t.start("GetPendingUsers")
+	users, err := svc.GetPendingUsers()
+	span.EndWithError(err)
+	return users, err
+}
+
+func (t *tracingService) ApproveUsers(actor *models.User, ids []int) (*ApprovalResult, error) {
+	svc, span := t.start("ApproveUsers")
+	result, err := svc.ApproveUsers(actor, ids)
+	span.EndWithError(err)
+	return result, err
+}
+
+func (t *tracingService) RejectUsers(actor *models.User, ids []int, reason string) (*ApprovalResult, error) {
+	svc, span := t.start("RejectUsers")
+	result, err := svc.RejectUsers(actor, ids, reason)
+	span.EndWithError(err)
+	return result, err
+}
+
+// 回收站相关
+func (t *tracingService) GetDeletedUsers() ([]*models.User, error) {
+	svc, span := t.start("GetDeletedUsers")
+	users, err := svc.GetDeletedUsers()
+	span.EndWithError(err)
+	return users, err
+}
+
+func (t *tracingService) RestoreUser(actor *models.User, id int) error {
+	svc, span := t.start("RestoreUser")
+	err := svc.RestoreUser(actor, id)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingService) PurgeDeletedUsers(now time.Time) (int64, error) {
+	svc, span := t.start("PurgeDeletedUsers")
+	n, err := svc.PurgeDeletedUsers(now)
+	span.EndWithError(err)
+	return n, err
+}
+
+func (t *tracingService) ChangePassword(actor *models.User, targetID int, currentPassword, newPassword string) error {
+	svc, span := t.start("ChangePassword")
+	err := svc.ChangePassword(actor, targetID, currentPassword, newPassword)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingService) RequestEmailChange(actor *models.User, targetID int, newEmail string) error {
+	svc, span := t.start("RequestEmailChange")
+	err := svc.RequestEmailChange(actor, targetID, newEmail)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingService) ConfirmEmailChange(token string) (*models.User, error) {
+	svc, span := t.start("ConfirmEmailChange")
+	user, err := svc.ConfirmEmailChange(token)
+	span.EndWithError(err)
+	return user, err
+}
+
+func (t *tracingService) GetPendingEmailChange(userID int) (*models.EmailVerification, error) {
+	svc, span := t.start("GetPendingEmailChange")
+	verification, err := svc.GetPendingEmailChange(userID)
+	span.EndWithError(err)
+	return verification, err
+}
+
+func (t *tracingService) RecordLogin(id int) error {
+	svc, span := t.start("RecordLogin")
+	err := svc.RecordLogin(id)
+	span.EndWithError(err)
+	return err
+}
+
+// 模拟登录
+func (t *tracingService) StartImpersonation(actor *models.User, targetID int, switchSession func(target *models.User) error) (*models.User, error) {
+	svc, span := t.start("StartImpersonation")
+	user, err := svc.StartImpersonation(actor, targetID, switchSession)
+	span.EndWithError(err)
+	return user, err
+}
+
+func (t *tracingService) StopImpersonation(actor *models.User, restoreSession func() error) error {
+	svc, span := t.start("StopImpersonation")
+	err := svc.StopImpersonation(actor, restoreSession)
+	span.EndWithError(err)
+	return err
+}
+
+// 有效权限和可执行的操作
+func (t *tracingService) GetEffectivePermissions(actor *models.User, userID int) (*EffectivePermissions, error) {
+	svc, span := t.start("GetEffectivePermissions")
+	perms, err := svc.GetEffectivePermissions(actor, userID)
+	span.EndWithError(err)
+	return perms, err
+}
+
+func (t *tracingService) GetUserActions(actor *models.User, users []*models.User) (map[int]UserActions, error) {
+	svc, span := t.start("GetUserActions")
+	result, err := svc.GetUserActions(actor, users)
+	span.EndWithError(err)
+	return result, err
+}
+
+// 统计相关
+func (t *tracingService) GetUserStats() (map[string]interface{}, error) {
+	svc, span := t.start("GetUserStats")
+	result, err := svc.GetUserStats()
+	span.EndWithError(err)
+	return result, err
+}
+
+// tracingRoleService 为 RoleService 的每个方法记录一个名为 RoleService.<方法名> 的 span，行为与 tracingService 相同；Can 只做内存中的判断，不记录 span
+type tracingRoleService struct {
+	RoleService
+	ctx context.Context
+}
+
+// newTracingRoleService 为服务加上追踪
+func newTracingRoleService(inner RoleService) RoleService {
+	return &tracingRoleService{RoleService: inner, ctx: context.Background()}
+}
+
+// ForRequest 返回在审计事件中记录 meta 中请求信息的服务，继续记录 span
+func (t *tracingRoleService) ForRequest(meta audit.Meta) RoleService {
+	return &tracingRoleService{RoleService: t.RoleService.ForRequest(meta), ctx: t.ctx}
+}
+
+// WithContext 返回以 ctx 中的 span 为父 span 的服务
+func (t *tracingRoleService) WithContext(ctx context.Context) RoleService {
+	return &tracingRoleService{RoleService: t.RoleService, ctx: ctx}
+}
+
+// start 开始方法的 span，返回在该 span 下执行的内层服务
+func (t *tracingRoleService) start(method string) (RoleService, *tracing.Span) {
+	ctx, span := tracing.Start(t.ctx, "RoleService."+method, tracing.KindInternal)
+	if !span.IsRecording() {
+		return t.RoleService, span
+	}
+	return t.RoleService.WithContext(ctx), span
+}
+
+func (t *tracingRoleService) GetRoles() ([]*models.Role, error) {
+	svc, span := t.start("GetRoles")
+	roles, err := svc.GetRoles()
+	span.EndWithError(err)
+	return roles, err
+}
+
+func (t *tracingRoleService) GetRole(id int) (*models.Role, error) {
+	svc, span := t.start("GetRole")
+	role, err := svc.GetRole(id)
+	span.EndWithError(err)
+	return role, err
+}
+
+func (t *tracingRoleService) CreateRole(actor *models.User, input *RoleInput) (*models.Role, error) {
+	svc, span := t.start("CreateRole")
+	role, err := svc.CreateRole(actor, input)
+	span.EndWithError(err)
+	return role, err
+}
+
+func (t *tracingRoleService) PreviewRoleUpdate(actor *models.User, id int, input *RoleInput) (*RoleChangePreview, error) {
+	svc, span := t.start("PreviewRoleUpdate")
+	preview, err := svc.PreviewRoleUpdate(actor, id, input)
+	span.EndWithError(err)
+	return preview, err
+}
+
+func (t *tracingRoleService) UpdateRole(actor *models.User, id int, input *RoleInput) (*models.Role, error) {
+	svc, span := t.start("UpdateRole")
+	role, err := svc.UpdateRole(actor, id, input)
+	span.EndWithError(err)
+	return role, err
+}
+
+func (t *tracingRoleService) PreviewRoleDelete(actor *models.User, id int) (*RoleChangePreview, error) {
+	svc, span := t.start("PreviewRoleDelete")
+	preview, err := svc.PreviewRoleDelete(actor, id)
+	span.EndWithError(err)
+	return preview, err
+}
+
+func (t *tracingRoleService) DeleteRole(actor *models.User, id int) error {
+	svc, span := t.start("DeleteRole")
+	err := svc.DeleteRole(actor, id)
+	span.EndWithError(err)
+	return err
+}
+
+// tracingGroupService 为 GroupService 的每个方法记录一个名为 GroupService.<方法名> 的 span，行为与 tracingService 相同
+type tracingGroupService struct {
+	GroupService
+	ctx context.Context
+}
+
+// newTracingGroupService 为服务加上追踪
+func newTracingGroupService(inner GroupService) GroupService {
+	return &tracingGroupService{GroupService: inner, ctx: context.Background()}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录 span
+func (t *tracingGroupService) ForOrganization(orgID int) GroupService {
+	return &tracingGroupService{GroupService: t.GroupService.ForOrganization(orgID), ctx: t.ctx}
+}
+
+// ForRequest 返回在审计事件中记录 meta 中请求信息的服务，继续记录 span
+func (t *tracingGroupService) ForRequest(meta audit.Meta) GroupService {
+	return &tracingGroupService{GroupService: t.GroupService.ForRequest(meta), ctx: t.ctx}
+}
+
+// WithContext 返回以 ctx 中的 span 为父 span 的服务
+func (t *tracingGroupService) WithContext(ctx context.Context) GroupService {
+	return &tracingGroupService{GroupService: t.GroupService, ctx: ctx}
+}
+
+// start 开始方法的 span，返回在该 span 下执行的内层服务
+func (t *tracingGroupService) start(method string) (GroupService, *tracing.Span) {
+	ctx, span := tracing.Start(t.ctx, "GroupService."+method, tracing.KindInternal)
+	if !span.IsRecording() {
+		return t.GroupService, span
+	}
+	return t.GroupService.WithContext(ctx), span
+}
+
+func (t *tracingGroupService) GetGroups() ([]*models.Group, error) {
+	svc, span := t.start("GetGroups")
+	groups, err := svc.GetGroups()
+	span.EndWithError(err)
+	return groups, err
+}
+
+func (t *tracingGroupService) GetGroup(id int) (*models.Group, error) {
+	svc, span := t.start("GetGroup")
+	group, err := svc.GetGroup(id)
+	span.EndWithError(err)
+	return group, err
+}
+
+func (t *tracingGroupService) GetGroupMembers(id int) ([]*models.User, error) {
+	svc, span := t.start("GetGroupMembers")
+	users, err := svc.GetGroupMembers(id)
+	span.EndWithError(err)
+	return users, err
+}
+
+func (t *tracingGroupService) GetUsersInGroup(id int) ([]*models.User, error) {
+	svc, span := t.start("GetUsersInGroup")
+	users, err := svc.GetUsersInGroup(id)
+	span.EndWithError(err)
+	return users, err
+}
+
+func (t *tracingGroupService) CreateGroup(actor *models.User, input *GroupInput) (*models.Group, error) {
+	svc, span := t.start("CreateGroup")
+	group, err := svc.CreateGroup(actor, input)
+	span.EndWithError(err)
+	return group, err
+}
+
+func (t *tracingGroupService) UpdateGroup(actor *models.User, id int, input *GroupInput) (*models.Group, error) {
+	svc, span := t.start("UpdateGroup")
+	group, err := svc.UpdateGroup(actor, id, input)
+	span.EndWithError(err)
+	return group, err
+}
+
+func (t *tracingGroupService) DeleteGroup(actor *models.User, id int) error {
+	svc, span := t.start("DeleteGroup")
+	err := svc.DeleteGroup(actor, id)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingGroupService) AddGroupMembers(actor *models.User, id int, userIDs []int) error {
+	svc, span := t.start("AddGroupMembers")
+	err := svc.AddGroupMembers(actor, id, userIDs)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingGroupService) RemoveGroupMember(actor *models.User, id, userID int) error {
+	svc, span := t.start("RemoveGroupMember")
+	err := svc.RemoveGroupMember(actor, id, userID)
+	span.EndWithError(err)
+	return err
+}
+
+// tracingOrganizationService 为 OrganizationService 的每个方法记录一个名为 OrganizationService.<方法名> 的 span，行为与 tracingService 相同
+type tracingOrganizationService struct {
+	OrganizationService
+	ctx context.Context
+}
+
+// newTracingOrganizationService 为服务加上追踪
+func newTracingOrganizationService(inner OrganizationService) OrganizationService {
+	return &tracingOrganizationService{OrganizationService: inner, ctx: context.Background()}
+}
+
+// ForRequest 返回在审计事件中记录 meta 中请求信息的服务，继续记录 span
+func (t *tracingOrganizationService) ForRequest(meta audit.Meta) OrganizationService {
+	return &tracingOrganizationService{OrganizationService: t.OrganizationService.ForRequest(meta), ctx: t.ctx}
+}
+
+// WithContext 返回以 ctx 中的 span 为父 span 的服务
+func (t *tracingOrganizationService) WithContext(ctx context.Context) OrganizationService {
+	return &tracingOrganizationService{OrganizationService: t.OrganizationService, ctx: ctx}
+}
+
+// start 开始方法的 span，返回在该 span 下执行的内层服务
+func (t *tracingOrganizationService) start(method string) (OrganizationService, *tracing.Span) {
+	ctx, span := tracing.Start(t.ctx, "OrganizationService."+method, tracing.KindInternal)
+	if !span.IsRecording() {
+		return t.OrganizationService, span
+	}
+	return t.OrganizationService.WithContext(ctx), span
+}
+
+func (t *tracingOrganizationService) GetOrganizations() ([]*models.Organization, error) {
+	svc, span := t.start("GetOrganizations")
+	organizations, err := svc.GetOrganizations()
+	span.EndWithError(err)
+	return organizations, err
+}
+
+func (t *tracingOrganizationService) GetOrganization(id int) (*models.Organization, error) {
+	svc, span := t.start("GetOrganization")
+	organization, err := svc.GetOrganization(id)
+	span.EndWithError(err)
+	return organization, err
+}
+
+func (t *tracingOrganizationService) GetOrganizationMembers(id int) ([]*models.User, error) {
+	svc, span := t.start("GetOrganizationMembers")
+	users, err := svc.GetOrganizationMembers(id)
+	span.EndWithError(err)
+	return users, err
+}
+
+func (t *tracingOrganizationService) CreateOrganization(actor *models.User, input *OrganizationInput) (*models.Organization, error) {
+	svc, span := t.start("CreateOrganization")
+	organization, err := svc.CreateOrganization(actor, input)
+	span.EndWithError(err)
+	return organization, err
+}
+
+func (t *tracingOrganizationService) UpdateOrganization(actor *models.User, id int, input *OrganizationInput) (*models.Organization, error) {
+	svc, span := t.start("UpdateOrganization")
+	organization, err := svc.UpdateOrganization(actor, id, input)
+	span.EndWithError(err)
+	return organization, err
+}
+
+func (t *tracingOrganizationService) DeleteOrganization(actor *models.User, id int) error {
+	svc, span := t.start("DeleteOrganization")
+	err := svc.DeleteOrganization(actor, id)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingOrganizationService) AddOrganizationMember(actor *models.User, id int, username string) error {
+	svc, span := t.start("AddOrganizationMember")
+	err := svc.AddOrganizationMember(actor, id, username)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingOrganizationService) RemoveOrganizationMember(actor *models.User, id, userID int) error {
+	svc, span := t.start("RemoveOrganizationMember")
+	err := svc.RemoveOrganizationMember(actor, id, userID)
+	span.EndWithError(err)
+	return err
+}
+
+// tracingAdminGrantService 为 AdminGrantService 的每个方法记录一个名为 AdminGrantService.<方法名> 的 span，行为与 tracingService 相同
+type tracingAdminGrantService struct {
+	AdminGrantService
+	ctx context.Context
+}
+
+// newTracingAdminGrantService 为服务加上追踪
+func newTracingAdminGrantService(inner AdminGrantService) AdminGrantService {
+	return &tracingAdminGrantService{AdminGrantService: inner, ctx: context.Background()}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录 span
+func (t *tracingAdminGrantService) ForOrganization(orgID int) AdminGrantService {
+	return &tracingAdminGrantService{AdminGrantService: t.AdminGrantService.ForOrganization(orgID), ctx: t.ctx}
+}
+
+// ForRequest 返回在审计事件中记录 meta 中请求信息的服务，继续记录 span
+func (t *tracingAdminGrantService) ForRequest(meta audit.Meta) AdminGrantService {
+	return &tracingAdminGrantService{AdminGrantService: t.AdminGrantService.ForRequest(meta), ctx: t.ctx}
+}
+
+// WithContext 返回以 ctx 中的 span 为父 span 的服务
+func (t *tracingAdminGrantService) WithContext(ctx context.Context) AdminGrantService {
+	return &tracingAdminGrantService{AdminGrantService: t.AdminGrantService, ctx: ctx}
+}
+
+// start 开始方法的 span，返回在该 span 下执行的内层服务
+func (t *tracingAdminGrantService) start(method string) (AdminGrantService, *tracing.Span) {
+	ctx, span := tracing.Start(t.ctx, "AdminGrantService."+method, tracing.KindInternal)
+	if !span.IsRecording() {
+		return t.AdminGrantService, span
+	}
+	return t.AdminGrantService.WithContext(ctx), span
+}
+
+func (t *tracingAdminGrantService) GetAdminGrants() ([]*models.AdminGrant, error) {
+	svc, span := t.start("GetAdminGrants")
+	grants, err := svc.GetAdminGrants()
+	span.EndWithError(err)
+	return grants, err
+}
+
+func (t *tracingAdminGrantService) CreateAdminGrant(actor *models.User, input *AdminGrantInput) (*models.AdminGrant, error) {
+	svc, span := t.start("CreateAdminGrant")
+	grant, err := svc.CreateAdminGrant(actor, input)
+	span.EndWithError(err)
+	return grant, err
+}
+
+func (t *tracingAdminGrantService) DeleteAdminGrant(actor *models.User, id int) error {
+	svc, span := t.start("DeleteAdminGrant")
+	err := svc.DeleteAdminGrant(actor, id)
+	span.EndWithError(err)
+	return err
+}
+
+// tracingChangeRequestService 为 ChangeRequestService 的每个方法记录一个名为 ChangeRequestService.<方法名> 的 span，行为与 tracingService 相同
+type tracingChangeRequestService struct {
+	ChangeRequestService
+	ctx context.Context
+}
+
+// newTracingChangeRequestService 为服务加上追踪
+func newTracingChangeRequestService(inner ChangeRequestService) ChangeRequestService {
+	return &tracingChangeRequestService{ChangeRequestService: inner, ctx: context.Background()}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录 span
+func (t *tracingChangeRequestService) ForOrganization(orgID int) ChangeRequestService {
+	return &tracingChangeRequestService{ChangeRequestService: t.ChangeRequestService.ForOrganization(orgID), ctx: t.ctx}
+}
+
+// ForRequest 返回在审计事件中记录 meta 中请求信息的服务，继续记录 span
+func (t *tracingChangeRequestService) ForRequest(meta audit.Meta) ChangeRequestService {
+	return &tracingChangeRequestService{ChangeRequestService: t.ChangeRequestService.ForRequest(meta), ctx: t.ctx}
+}
+
+// WithContext 返回以 ctx 中的 span 为父 span 的服务
+func (t *tracingChangeRequestService) WithContext(ctx context.Context) ChangeRequestService {
+	return &tracingChangeRequestService{ChangeRequestService: t.ChangeRequestService, ctx: ctx}
+}
+
+// start 开始方法的 span，返回在该 span 下执行的内层服务
+func (t *tracingChangeRequestService) start(method string) (ChangeRequestService, *tracing.Span) {
+	ctx, span := tracing.Start(t.ctx, "ChangeRequestService."+method, tracing.KindInternal)
+	if !span.IsRecording() {
+		return t.ChangeRequestService, span
+	}
+	return t.ChangeRequestService.WithContext(ctx), span
+}
+
+func (t *tracingChangeRequestService) GetChangeRequests() ([]*models.ChangeRequest, error) {
+	svc, span := t.start("GetChangeRequests")
+	reqs, err := svc.GetChangeRequests()
+	span.EndWithError(err)
+	return reqs, err
+}
+
+func (t *tracingChangeRequestService) ApproveChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error) {
+	svc, span := t.start("ApproveChangeRequest")
+	req, err := svc.ApproveChangeRequest(actor, id)
+	span.EndWithError(err)
+	return req, err
+}
+
+func (t *tracingChangeRequestService) RejectChangeRequest(actor *models.User, id int) (*models.ChangeRequest, error) {
+	svc, span := t.start("RejectChangeRequest")
+	req, err := svc.RejectChangeRequest(actor, id)
+	span.EndWithError(err)
+	return req, err
+}
+
+// tracingAuditService 为 AuditService 的每个方法记录一个名为 AuditService.<方法名> 的 span，行为与 tracingService 相同
+type tracingAuditService struct {
+	AuditService
+	ctx context.Context
+}
+
+// newTracingAuditService 为服务加上追踪
+func newTracingAuditService(inner AuditService) AuditService {
+	return &tracingAuditService{AuditService: inner, ctx: context.Background()}
+}
+
+// ForOrganization 返回限定在指定组织内的服务，继续记录 span
+func (t *tracingAuditService) ForOrganization(orgID int) AuditService {
+	return &tracingAuditService{AuditService: t.AuditService.ForOrganization(orgID), ctx: t.ctx}
+}
+
+// WithContext 返回以 ctx 中的 span 为父 span 的服务
+func (t *tracingAuditService) WithContext(ctx context.Context) AuditService {
+	return &tracingAuditService{AuditService: t.AuditService, ctx: ctx}
+}
+
+// start 开始方法的 span，返回在该 span 下执行的内层服务
+func (t *tracingAuditService) start(method string) (AuditService, *tracing.Span) {
+	ctx, span := tracing.Start(t.ctx, "AuditService."+method, tracing.KindInternal)
+	if !span.IsRecording() {
+		return t.AuditService, span
+	}
+	return t.AuditService.WithContext(ctx), span
+}
+
+func (t *tracingAuditService) GetAuditEvents(filter *models.AuditFilter, page, pageSize int) (*AuditPage, error) {
+	svc, span := t.start("GetAuditEvents")
+	auditPage, err := svc.GetAuditEvents(filter, page, pageSize)
+	span.EndWithError(err)
+	return auditPage, err
+}
+
+func (t *tracingAuditService) ExportAuditEvents(filter *models.AuditFilter, fn func(*models.AuditEvent) error) error {
+	svc, span := t.start("ExportAuditEvents")
+	err := svc.ExportAuditEvents(filter, fn)
+	span.EndWithError(err)
+	return err
+}
+
+func (t *tracingAuditService) CheckpointAuditChain() (*models.AuditCheckpoint, error) {
+	svc, span := t.start("CheckpointAuditChain")
+	cp, err := svc.CheckpointAuditChain()
+	span.EndWithError(err)
+	return cp, err
+}
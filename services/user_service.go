@@ -1,27 +1,86 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	stderrors "errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"user-management-system/audit"
 	"user-management-system/errors"
+	"user-management-system/mail"
 	"user-management-system/models"
-	"user-management-system/repository/interfaces"
+	"user-management-system/password"
+	"user-management-system/rbac"
 )
 
 // UserService 用户服务接口
 type UserService interface {
+	// ForOrganization 返回限定在指定组织内的服务，用户、用户组和邀请的查询与管理只作用于该组织
+	// orgID 为 0 时不限定；用户本人的操作（登录、修改资料等）应使用不限定组织的服务
+	ForOrganization(orgID int) UserService
+
+	// ForRequest 返回在审计事件中记录 meta 中请求信息（客户端地址、请求ID、所在组织等）的服务
+	ForRequest(meta audit.Meta) UserService
+
+	// WithContext 返回以 ctx 中的追踪 span 为父 span 的服务，方法和其中的 SQL 语句记录为子 span
+	WithContext(ctx context.Context) UserService
+
 	// 用户认证相关
-	RegisterUser(username, password, email string) error
+	RegisterUser(username, password, email, invitationToken string) (*models.User, error)
 	AuthenticateUser(username, password string) (*models.User, error)
 
 	//用户管理相关
 	GetUserByID(id int) (*models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
 	GetAllUsers() ([]*models.User, error)
-	UpdateUser(id int, email, role string) error
-	DeleteUser(id int) error
-
-	//权限检查
-	IsAdmin(user *models.User) bool
+	// UpdateUser、DeleteUser 的操作需要审批时（见 config.ChangeApprovalActions）不会立即生效，返回创建的变更申请
+	UpdateUser(actor *models.User, id int, email string, roles []string) (*models.ChangeRequest, error)
+	DeleteUser(actor *models.User, id int) (*models.ChangeRequest, error)
+	ChangeStatus(actor *models.User, id int, status, reason string) error
+
+	//管理员创建用户、重置密码
+	CreateUser(actor *models.User, input *CreateUserInput) (*models.User, *PasswordSetup, error)
+	ForcePasswordReset(actor *models.User, id int, mode string) (*PasswordSetup, error)
+	VerifyPasswordSetupToken(token string) (*models.User, *models.PasswordToken, error)
+	CompletePasswordSetup(token, newPassword string) (*models.User, error)
+
+	//注册邀请
+	CreateInvitation(actor *models.User, email, role string) (*models.Invitation, error)
+	GetInvitations() ([]*models.Invitation, error)
+	ResendInvitation(actor *models.User, id int) (*models.Invitation, error)
+	RevokeInvitation(actor *models.User, id int) error
+	VerifyInvitation(token string) (*models.Invitation, error)
+
+	//注册审批
+	GetPendingUsers() ([]*models.User, error)
+	ApproveUsers(actor *models.User, ids []int) (*ApprovalResult, error)
+	RejectUsers(actor *models.User, ids []int, reason string) (*ApprovalResult, error)
+
+	//回收站相关
+	GetDeletedUsers() ([]*models.User, error)
+	RestoreUser(actor *models.User, id int) error
+	PurgeDeletedUsers(now time.Time) (int64, error)
+	PurgeTime(user *models.User) *time.Time
+
+	//个人资料相关（actor 为当前操作者，非管理员只能修改自己）
+	ChangePassword(actor *models.User, targetID int, currentPassword, newPassword string) error
+	RequestEmailChange(actor *models.User, targetID int, newEmail string) error
+	ConfirmEmailChange(token string) (*models.User, error)
+	GetPendingEmailChange(userID int) (*models.EmailVerification, error)
+	RecordLogin(id int) error
+	IsPasswordExpired(user *models.User) bool
+
+	//有效权限和可执行的操作
+	GetEffectivePermissions(actor *models.User, userID int) (*EffectivePermissions, error)
+	GetUserActions(actor *models.User, users []*models.User) (map[int]UserActions, error)
+
+	//模拟登录
+	// 会话的切换由调用方传入的函数完成，切换成功后才记为成功
+	StartImpersonation(actor *models.User, targetID int, switchSession func(target *models.User) error) (*models.User, error)
+	StopImpersonation(actor *models.User, restoreSession func() error) error
 
 	//统计相关
 	GetUserStats() (map[string]interface{}, error)
@@ -29,70 +88,118 @@ type UserService interface {
 
 // userServiceImpl 是 UserService 接口的具体实现
 type userServiceImpl struct {
-	userRepo interfaces.UserRepository
+	*serviceCore
 }
 
-// NewUserService 创建一个新的用户服务实例
-func NewUserService(userRepo interfaces.UserRepository) UserService {
-	return &userServiceImpl{
-		userRepo: userRepo,
-	}
+// NewUserService 创建一个新的用户服务实例，修改数据和登录时会记录审计事件，启用追踪时每个方法记录一个 span
+// deps 中的依赖需已全部就绪，通常通过 NewService 填充默认实现后调用
+func NewUserService(deps *ServiceDependencies) UserService {
+	return newTracingService(newAuditingService(&userServiceImpl{newServiceCore(deps)}, newAuditor(deps)))
 }
 
-// RegisterUser 注册一个新用户
-func (s *userServiceImpl) RegisterUser(username, password, email string) error {
-	//验证输入
-	if username == "" {
-		return errors.NewValidationError("username", "用户名不能为空")
-	}
-	if len(username) < 3 || len(username) > 20 {
-		return errors.NewValidationError("username", "用户名长度必须在3到20个字符之间")
-	}
-	if password == "" {
-		return errors.NewValidationError("password", "密码不能为空")
+// ForOrganization 返回限定在指定组织内的服务副本，orgID 为 0 时不限定
+func (s *userServiceImpl) ForOrganization(orgID int) UserService {
+	return &userServiceImpl{s.forOrganization(orgID)}
+}
+
+// ForRequest 实现本身不使用请求信息，审计事件由 auditingService 记录
+func (s *userServiceImpl) ForRequest(meta audit.Meta) UserService {
+	return s
+}
+
+// WithContext 返回所有仓库都在 ctx 中执行语句的服务副本
+func (s *userServiceImpl) WithContext(ctx context.Context) UserService {
+	return &userServiceImpl{s.withContext(ctx)}
+}
+
+// RegisterUser 注册一个新用户，行为取决于配置的注册方式（见 config.RegistrationMode）
+// 仅限邀请时 invitationToken 必须有效，注册邮箱和角色以邀请为准；其他方式忽略该参数
+// 新用户加入服务限定的组织（未限定时加入默认组织），通过邀请注册时加入邀请所属的组织
+func (s *userServiceImpl) RegisterUser(username, plainPassword, email, invitationToken string) (*models.User, error) {
+	role := models.RoleUser //默认角色
+	status := models.StatusActive
+
+	switch s.cfg.RegistrationMode {
+	case models.RegistrationClosed:
+		return nil, errors.NewForbiddenError("当前不开放注册")
+	case models.RegistrationApproval:
+		// 审批通过之前不能登录
+		status = models.StatusPending
+	}
+
+	// 仅限邀请时必须提供邀请；其他方式下也接受邀请，邮箱和角色以邀请为准，且无需审批
+	var invitation *models.Invitation
+	if invitationToken != "" || s.cfg.RegistrationMode == models.RegistrationInvite {
+		inv, err := s.VerifyInvitation(invitationToken)
+		if err != nil {
+			return nil, err
+		}
+		invitation = inv
+		email = inv.Email
+		role = inv.Role
+		status = models.StatusActive
 	}
-	if len(password) < 6 || len(password) > 20 {
-		return errors.NewValidationError("password", "密码长度必须在6到20个字符之间")
+
+	//验证输入
+	if err := validateUsername(username); err != nil {
+		return nil, err
 	}
 	if email == "" {
-		return errors.NewValidationError("email", "邮箱不能为空")
+		return nil, errors.NewValidationError("email", "邮箱不能为空")
+	}
+	if err := s.passwordPolicy.Validate(plainPassword, password.Context{Username: username, Email: email}); err != nil {
+		return nil, err
 	}
 	// 检查用户名是否已存在
-	exists, err := s.userRepo.Exists(username)
+	exists, err := s.usernameTaken(username)
 	if err != nil {
-		return errors.NewInternalError(err)
+		return nil, errors.NewInternalError(err)
 	}
 	if exists {
-		return errors.NewConflictError("用户名已存在")
+		return nil, errors.NewConflictError("用户名已存在")
 	}
 
 	//检查邮箱是否已经被使用
-	emailExists, err := s.userRepo.ExistsByEmail(email)
+	emailExists, err := s.emailTaken(email, 0)
 	if err != nil {
-		return errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
+		return nil, errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
 	}
 
 	if emailExists {
-		return errors.NewConflictError("邮箱已被使用")
+		return nil, errors.NewConflictError("邮箱已被使用")
 	}
 
 	//创建新用户
 	user := &models.User{
 		Username: username,
 		Email:    email,
-		Role:     "user", //默认角色
+		Roles:    []string{role},
+		Status:   status,
 	}
 
-	//设置密码(使用bcrypt加密)
-	if err := user.SetPassword(password); err != nil {
-		return errors.NewInternalError(fmt.Errorf("设置密码失败: %w", err))
+	//设置密码(使用配置的哈希算法)
+	hashedPassword, err := s.passwordHasher.Hash(plainPassword)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("设置密码失败: %w", err))
 	}
+	user.Password = hashedPassword
 
-	//保存到数据库
-	if err := s.userRepo.Create(user); err != nil {
-		return errors.NewInternalError(fmt.Errorf("保存用户失败: %w", err))
+	//保存到数据库；邀请只能使用一次，创建用户与标记邀请已接受在同一事务中完成
+	if invitation != nil {
+		err = s.userRepo.ForOrganization(invitation.OrgID).CreateWithInvitation(user, invitation.ID)
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.NewConflictError("邀请已被使用、已撤销或已过期")
+		}
+	} else {
+		err = s.userRepo.Create(user)
 	}
-	return nil
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("保存用户失败: %w", err))
+	}
+
+	//初始密码也计入密码历史
+	s.recordPasswordHistory(user.ID, user.Password)
+	return user, nil
 }
 
 // AuthenticateUser 用户认证
@@ -112,9 +219,30 @@ func (s *userServiceImpl) AuthenticateUser(username, password string) (*models.U
 	if user == nil {
 		return nil, errors.NewUnauthorizedError("用户不存在")
 	}
-	if !user.CheckPassword(password) {
+	ok, err := s.passwordHasher.Verify(user.Password, password)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("校验密码失败: %w", err))
+	}
+	if !ok {
 		return nil, errors.NewUnauthorizedError("密码错误")
 	}
+
+	// 密码正确后才提示账户状态，避免泄露账户信息
+	if err := checkCanLogin(user); err != nil {
+		return nil, err
+	}
+
+	// 哈希算法或参数已过时，趁持有明文密码时透明升级（失败不影响本次登录）
+	if s.passwordHasher.NeedsRehash(user.Password) {
+		if hashedPassword, err := s.passwordHasher.Hash(password); err != nil {
+			s.log().Warn("重新哈希用户密码失败", "user", user.Username, "error", err)
+		} else if err := s.userRepo.UpdatePasswordHash(user.ID, hashedPassword); err != nil {
+			s.log().Warn("保存升级后的密码哈希失败", "user", user.Username, "error", err)
+		} else {
+			user.Password = hashedPassword
+			s.log().Info("用户的密码哈希已升级", "user", user.Username)
+		}
+	}
 	return user, nil
 }
 
@@ -158,85 +286,510 @@ func (s *userServiceImpl) GetAllUsers() ([]*models.User, error) {
 	return users, nil
 }
 
-// UpdateUser 更新用户信息
-func (s *userServiceImpl) UpdateUser(id int, email, role string) error {
+// UpdateUser 更新用户的邮箱和角色，修改角色需要 users:manage 权限
+// 使用户成为管理员的修改需要审批时，保存为变更申请并返回，批准后才生效
+func (s *userServiceImpl) UpdateUser(actor *models.User, id int, email string, roles []string) (*models.ChangeRequest, error) {
+	existingUser, roles, promotes, err := s.checkUserUpdate(actor, id, email, roles)
+	if err != nil {
+		return nil, err
+	}
+	if promotes && s.requiresApproval(models.ChangeActionPromoteAdmin) {
+		return s.createChangeRequest(actor, existingUser, models.ChangeActionPromoteAdmin, email, roles)
+	}
+
+	//更新用户信息
+	if err := s.userRepo.UpdateEmailAndRoles(id, email, roles); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("更新用户信息失败: %w", err))
+	}
+
+	return nil, nil
+}
+
+// checkUserUpdate 校验 actor 能否将用户的邮箱和角色修改为 email 和 roles（批准变更申请时也会再次校验）
+// 返回修改前的用户、修改后的角色（角色为空时沿用原角色），以及修改是否使用户获得分配角色的权限
+func (s *serviceCore) checkUserUpdate(actor *models.User, id int, email string, roles []string) (*models.User, []string, bool, error) {
 	if id <= 0 {
-		return errors.NewValidationError("id", "无效的用户ID")
+		return nil, nil, false, errors.NewValidationError("id", "无效的用户ID")
 	}
 	if email == "" {
-		return errors.NewValidationError("email", "邮箱不能为空")
-	}
-	if role != "user" && role != "admin" {
-		return errors.NewValidationError("role", "无效的角色")
+		return nil, nil, false, errors.NewValidationError("email", "邮箱不能为空")
 	}
 
 	// 检查用户是否存在
 	existingUser, err := s.userRepo.GetByID(id)
 	if err != nil {
-		return errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+		return nil, nil, false, errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
 	}
 	if existingUser == nil {
-		return errors.NewNotFoundError("用户")
+		return nil, nil, false, errors.NewNotFoundError("用户")
+	}
+	if err := s.authorizeOver(actor, existingUser, models.PermUsersUpdate); err != nil {
+		return nil, nil, false, err
+	}
+
+	// 角色未变化时不要求分配角色的权限
+	promotes := false
+	if len(roles) == 0 {
+		roles = existingUser.Roles
+	}
+	if !sameRoles(roles, existingUser.Roles) {
+		newRoles, permissions, err := s.resolveRoles(actor, roles)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if err := requirePermission(actor, models.PermUsersManage); err != nil {
+			return nil, nil, false, err
+		}
+		// 不能让系统失去最后一个可以分配角色的管理员（通过用户组继承管理权限的用户不受影响）
+		inherited, err := s.inheritedPermissions(existingUser)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if _, ok := inherited[models.PermUsersManage]; !ok && !permissions[models.PermUsersManage] {
+			if err := s.ensureNotLastActiveAdmin(existingUser, "不能移除最后一个管理员的管理权限"); err != nil {
+				return nil, nil, false, err
+			}
+		}
+		promotes = !existingUser.IsAdmin() && permissions[models.PermUsersManage]
+		roles = newRoles
 	}
 
 	// 如果邮箱改变了，检查新邮箱是否已被使用
 	if existingUser.Email != email {
-		emailUser, err := s.userRepo.GetByEmail(email)
+		emailTaken, err := s.emailTaken(email, id)
 		if err != nil {
-			return errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
+			return nil, nil, false, errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
 		}
 
-		if emailUser != nil && emailUser.ID != id {
-			return errors.NewConflictError("邮箱已被其他用户使用")
+		if emailTaken {
+			return nil, nil, false, errors.NewConflictError("邮箱已被其他用户使用")
 		}
 	}
+	return existingUser, roles, promotes, nil
+}
 
-	//更新用户信息
-	if err := s.userRepo.UpdateEmailAndRole(id, email, role); err != nil {
-		return errors.NewInternalError(fmt.Errorf("更新用户信息失败: %w", err))
+// DeleteUser 删除用户（移入回收站，保留期内可以恢复），需要 users:delete 权限或对该用户的委派授权
+// 删除管理员需要审批时，保存为变更申请并返回，批准后才生效
+func (s *userServiceImpl) DeleteUser(actor *models.User, id int) (*models.ChangeRequest, error) {
+	user, err := s.checkUserDelete(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin() && s.requiresApproval(models.ChangeActionDeleteAdmin) {
+		return s.createChangeRequest(actor, user, models.ChangeActionDeleteAdmin, "", nil)
 	}
 
-	return nil
+	//删除用户
+	if err := s.userRepo.Delete(id); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("删除用户失败: %w", err))
+	}
+
+	return nil, nil
 }
 
-// DeleteUser 删除用户
-func (s *userServiceImpl) DeleteUser(id int) error {
+// checkUserDelete 校验 actor 能否删除用户（批准变更申请时也会再次校验），返回要删除的用户
+func (s *serviceCore) checkUserDelete(actor *models.User, id int) (*models.User, error) {
 	//验证输入
 	if id <= 0 {
-		return errors.NewValidationError("id", "无效的用户ID")
+		return nil, errors.NewValidationError("id", "无效的用户ID")
+	}
+	if err := requireNotImpersonated(actor); err != nil {
+		return nil, err
+	}
+	if actor.ID == id {
+		return nil, errors.NewForbiddenError("不能删除自己")
 	}
 
 	//检查用户存在
 	user, err := s.userRepo.GetByID(id)
 	if err != nil {
-		return errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+		return nil, errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
 	}
 	if user == nil {
-		return errors.NewNotFoundError("用户")
+		return nil, errors.NewNotFoundError("用户")
+	}
+	if err := s.authorizeOver(actor, user, models.PermUsersDelete); err != nil {
+		return nil, err
 	}
 
 	//防止删除最后一个管理员
-	if user.Role == "admin" {
-		adminCount, err := s.userRepo.CountByRole("admin")
-		if err != nil {
-			return errors.NewInternalError(fmt.Errorf("查询管理员数量失败: %w", err))
+	if err := s.ensureNotLastActiveAdmin(user, "不能删除最后一个管理员"); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword 修改密码，需要提供当前密码进行确认
+func (s *userServiceImpl) ChangePassword(actor *models.User, targetID int, currentPassword, newPassword string) error {
+	user, err := s.getModifiableUser(actor, targetID)
+	if err != nil {
+		return err
+	}
+
+	if currentPassword == "" {
+		return errors.NewValidationError("current_password", "请输入当前密码")
+	}
+	ok, err := s.passwordHasher.Verify(user.Password, currentPassword)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("校验密码失败: %w", err))
+	}
+	if !ok {
+		return errors.NewValidationError("current_password", "当前密码错误")
+	}
+	if currentPassword == newPassword {
+		return errors.NewValidationError("password", "新密码不能与当前密码相同")
+	}
+	return s.setPassword(user, newPassword)
+}
+
+// setPassword 校验策略与密码历史后保存新密码（修改、重置密码共用）
+func (s *userServiceImpl) setPassword(user *models.User, newPassword string) error {
+	if err := s.passwordPolicy.Validate(newPassword, password.Context{Username: user.Username, Email: user.Email}); err != nil {
+		return err
+	}
+	if err := s.checkPasswordReuse(user, newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(newPassword)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("设置密码失败: %w", err))
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return errors.NewInternalError(fmt.Errorf("更新密码失败: %w", err))
+	}
+
+	s.recordPasswordHistory(user.ID, hashedPassword)
+	return nil
+}
+
+// checkPasswordReuse 检查新密码是否与当前密码或最近使用过的密码相同
+func (s *userServiceImpl) checkPasswordReuse(user *models.User, newPassword string) error {
+	size := s.cfg.PasswordHistorySize
+	if size <= 0 {
+		return nil
+	}
+
+	// 当前密码可能早于密码历史功能上线，没有出现在历史中，因此单独检查
+	hashes := []string{user.Password}
+	recent, err := s.historyRepo.GetRecent(user.ID, size)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("获取密码历史失败: %w", err))
+	}
+	hashes = append(hashes, recent...)
+
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
 		}
-		if adminCount <= 1 {
-			return errors.NewForbiddenError("不能删除最后一个管理员")
+		// 无法识别的旧哈希不影响修改
+		if same, _ := s.passwordHasher.Verify(hash, newPassword); same {
+			return errors.NewValidationError("password", fmt.Sprintf("不能使用最近%d次用过的密码", size))
 		}
 	}
+	return nil
+}
 
-	//删除用户
-	if err := s.userRepo.Delete(id); err != nil {
-		return errors.NewInternalError(fmt.Errorf("删除用户失败: %w", err))
+// recordPasswordHistory 记录密码历史并清理超出保留数量的记录（失败只记录日志）
+func (s *userServiceImpl) recordPasswordHistory(userID int, hashedPassword string) {
+	size := s.cfg.PasswordHistorySize
+	if size <= 0 {
+		return
+	}
+	if err := s.historyRepo.Add(userID, hashedPassword); err != nil {
+		s.log().Warn("记录密码历史失败", "user_id", userID, "error", err)
+		return
+	}
+	if err := s.historyRepo.Prune(userID, size); err != nil {
+		s.log().Warn("清理密码历史失败", "user_id", userID, "error", err)
+	}
+}
+
+// IsPasswordExpired 判断用户密码是否已超过有效期
+func (s *userServiceImpl) IsPasswordExpired(user *models.User) bool {
+	if user == nil {
+		return false
+	}
+
+	maxAge := s.cfg.PasswordMaxAge
+	if user.IsAdmin() && s.cfg.AdminPasswordMaxAge > 0 {
+		maxAge = s.cfg.AdminPasswordMaxAge
+	}
+	if maxAge <= 0 {
+		return false
+	}
+	return time.Since(user.PasswordSetAt()) > maxAge
+}
+
+// RequestEmailChange 申请修改邮箱，向新邮箱发送验证链接，验证通过后才真正生效
+func (s *userServiceImpl) RequestEmailChange(actor *models.User, targetID int, newEmail string) error {
+	user, err := s.getModifiableUser(actor, targetID)
+	if err != nil {
+		return err
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if newEmail == "" {
+		return errors.NewValidationError("email", "邮箱不能为空")
+	}
+	if !strings.Contains(newEmail, "@") {
+		return errors.NewValidationError("email", "邮箱格式不正确")
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return errors.NewValidationError("email", "新邮箱与当前邮箱相同")
+	}
+
+	emailExists, err := s.emailTaken(newEmail, user.ID)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
+	}
+	if emailExists {
+		return errors.NewConflictError("邮箱已被使用")
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("生成验证令牌失败: %w", err))
+	}
+
+	// 每个用户同一时间只保留一条待验证记录
+	if err := s.verificationRepo.DeleteByUserID(user.ID); err != nil {
+		return errors.NewInternalError(fmt.Errorf("清理旧验证记录失败: %w", err))
+	}
+	verification := &models.EmailVerification{
+		UserID:    user.ID,
+		Email:     newEmail,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.cfg.EmailVerificationTTL),
+	}
+	if err := s.verificationRepo.Create(verification); err != nil {
+		return errors.NewInternalError(fmt.Errorf("保存验证记录失败: %w", err))
+	}
+
+	link := s.cfg.BaseURL + "/profile/verify-email?token=" + token
+	msg := &mail.Message{
+		To:      newEmail,
+		Subject: "请验证您的新邮箱",
+		Body: fmt.Sprintf("%s，您好：\n\n您申请将账户邮箱修改为 %s，请在 %s 前打开以下链接完成验证：\n\n%s\n\n如果这不是您本人的操作，请忽略本邮件。\n",
+			user.Username, newEmail, verification.ExpiresAt.Format("2006-01-02 15:04"), link),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return errors.NewInternalError(fmt.Errorf("发送验证邮件失败: %w", err))
+	}
+	return nil
+}
+
+// ConfirmEmailChange 使用邮件中的令牌确认邮箱变更
+func (s *userServiceImpl) ConfirmEmailChange(token string) (*models.User, error) {
+	if token == "" {
+		return nil, errors.NewValidationError("token", "验证链接无效")
 	}
 
+	verification, err := s.verificationRepo.GetByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询验证记录失败: %w", err))
+	}
+	if verification == nil {
+		return nil, errors.NewValidationError("token", "验证链接无效或已被使用")
+	}
+	if verification.IsExpired() {
+		return nil, errors.NewValidationError("token", "验证链接已过期，请重新申请")
+	}
+
+	user, err := s.userRepo.GetByID(verification.UserID)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取用户失败: %w", err))
+	}
+	if user == nil {
+		return nil, errors.NewNotFoundError("用户")
+	}
+
+	// 发出验证邮件后邮箱可能已被他人占用
+	emailTaken, err := s.emailTaken(verification.Email, user.ID)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("检查邮箱失败: %w", err))
+	}
+	if emailTaken {
+		return nil, errors.NewConflictError("邮箱已被其他用户使用")
+	}
+
+	if err := s.userRepo.UpdateEmail(user.ID, verification.Email); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("更新邮箱失败: %w", err))
+	}
+	if err := s.verificationRepo.DeleteByUserID(user.ID); err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("清理验证记录失败: %w", err))
+	}
+
+	user.Email = verification.Email
+	return user, nil
+}
+
+// GetPendingEmailChange 获取用户待验证的邮箱变更，没有时返回 nil
+func (s *userServiceImpl) GetPendingEmailChange(userID int) (*models.EmailVerification, error) {
+	verification, err := s.verificationRepo.GetPendingByUserID(userID)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("查询验证记录失败: %w", err))
+	}
+	return verification, nil
+}
+
+// RecordLogin 记录用户登录时间
+func (s *userServiceImpl) RecordLogin(id int) error {
+	if err := s.userRepo.UpdateLastLogin(id, time.Now()); err != nil {
+		return errors.NewInternalError(fmt.Errorf("记录登录时间失败: %w", err))
+	}
 	return nil
 }
 
-// IsAdmin 检查用户是否为管理员
-func (s *userServiceImpl) IsAdmin(user *models.User) bool {
-	return user != nil && user.IsAdmin()
+// getModifiableUser 获取 actor 有权修改密码和邮箱的目标用户：拥有 users:update 权限可修改任何人，否则只能修改自己
+// 模拟登录期间不能修改密码和邮箱
+func (s *userServiceImpl) getModifiableUser(actor *models.User, targetID int) (*models.User, error) {
+	if err := requireNotImpersonated(actor); err != nil {
+		return nil, err
+	}
+	if targetID <= 0 {
+		return nil, errors.NewValidationError("id", "无效的用户ID")
+	}
+	if actor.ID != targetID && !rbac.Can(actor, models.PermUsersUpdate, nil) {
+		return nil, errors.NewForbiddenError("只能修改自己的账户")
+	}
+
+	user, err := s.userRepo.GetByID(targetID)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("获取用户失败: %w", err))
+	}
+	if user == nil {
+		return nil, errors.NewNotFoundError("用户")
+	}
+	if !rbac.Can(actor, models.PermUsersUpdate, user) {
+		return nil, errors.NewForbiddenError("只能修改自己的账户")
+	}
+	return user, nil
+}
+
+// ChangeStatus 修改账户状态（启用、停用、锁定等），需要 users:status 权限或对该用户的委派授权
+// 删除账户请使用 DeleteUser
+func (s *userServiceImpl) ChangeStatus(actor *models.User, id int, status, reason string) error {
+	if actor == nil {
+		return errors.NewUnauthorizedError("")
+	}
+	if id <= 0 {
+		return errors.NewValidationError("id", "无效的用户ID")
+	}
+	if id == actor.ID {
+		return errors.NewForbiddenError("不能修改自己的账户状态")
+	}
+	if !models.IsValidStatus(status) || status == models.StatusDeleted {
+		return errors.NewValidationError("status", "无效的账户状态")
+	}
+
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > 255 {
+		return errors.NewValidationError("reason", "原因不能超过255个字符")
+	}
+	if status != models.StatusActive && reason == "" {
+		return errors.NewValidationError("reason", "请填写原因")
+	}
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("查询用户失败: %w", err))
+	}
+	if user == nil {
+		return errors.NewNotFoundError("用户")
+	}
+	if err := s.authorizeOver(actor, user, models.PermUsersStatus); err != nil {
+		return err
+	}
+	if user.Status == status {
+		return errors.NewConflictError("账户已经是" + models.StatusLabel(status) + "状态")
+	}
+	if !models.CanTransition(user.Status, status) {
+		return errors.NewConflictError(fmt.Sprintf("账户不能从%s变为%s",
+			models.StatusLabel(user.Status), models.StatusLabel(status)))
+	}
+
+	// 不能让系统失去最后一个可用的管理员
+	if status != models.StatusActive {
+		if err := s.ensureNotLastActiveAdmin(user, "不能停用最后一个管理员"); err != nil {
+			return err
+		}
+	}
+
+	if err := s.userRepo.UpdateStatus(id, status, reason); err != nil {
+		return errors.NewInternalError(fmt.Errorf("更新账户状态失败: %w", err))
+	}
+	return nil
+}
+
+// usernameTaken 检查用户名是否已被占用
+// 回收站中的用户是否继续占用用户名由 SoftDeleteReserveUsername 决定
+func (s *userServiceImpl) usernameTaken(username string) (bool, error) {
+	exists, err := s.userRepo.Exists(username)
+	if err != nil || exists || !s.cfg.SoftDeleteReserveUsername {
+		return exists, err
+	}
+	return s.userRepo.ExistsDeleted(username)
+}
+
+// emailTaken 检查邮箱是否已被 exceptID 以外的用户占用
+// 回收站中的用户是否继续占用邮箱由 SoftDeleteReserveEmail 决定
+func (s *serviceCore) emailTaken(email string, exceptID int) (bool, error) {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return false, err
+	}
+	if user != nil && user.ID != exceptID {
+		return true, nil
+	}
+	if !s.cfg.SoftDeleteReserveEmail {
+		return false, nil
+	}
+	return s.userRepo.ExistsDeletedByEmail(email)
+}
+
+// ensureNotLastActiveAdmin 当 user 是最后一个正常状态的管理员时返回禁止错误
+func (s *serviceCore) ensureNotLastActiveAdmin(user *models.User, message string) error {
+	if !user.IsAdmin() || !user.IsActive() {
+		return nil
+	}
+
+	adminCount, err := s.userRepo.CountByPermissionAndStatus(models.PermUsersManage, models.StatusActive)
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("查询管理员数量失败: %w", err))
+	}
+	if adminCount <= 1 {
+		return errors.NewForbiddenError(message)
+	}
+	return nil
+}
+
+// requireOutranks 只有同样可以管理组织的管理员才能修改、停用、删除可以管理组织的管理员，
+// 组织管理员不能影响管理所有组织的超级管理员
+func requireOutranks(actor, target *models.User) error {
+	if actor == nil {
+		return errors.NewUnauthorizedError("")
+	}
+	if target.HasPermission(models.PermOrgsManage) && !actor.HasPermission(models.PermOrgsManage) {
+		return errors.NewForbiddenError("不能管理拥有" + models.PermissionLabel(models.PermOrgsManage) + "权限的用户")
+	}
+	return nil
+}
+
+// checkCanLogin 检查账户状态是否允许登录
+func checkCanLogin(user *models.User) error {
+	switch user.Status {
+	case models.StatusActive:
+		return nil
+	case models.StatusPending:
+		return errors.NewForbiddenError("账户正在等待管理员审批")
+	case models.StatusDisabled:
+		return errors.NewForbiddenError("账户已被停用")
+	case models.StatusLocked:
+		return errors.NewForbiddenError("账户已被锁定，请联系管理员")
+	default:
+		return errors.NewUnauthorizedError("用户不存在")
+	}
 }
 
 // GetUserStats 获取用户统计信息
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"user-management-system/config"
+	"user-management-system/errors"
+	"user-management-system/models"
+	"user-management-system/password"
+	"user-management-system/repository/interfaces"
+)
+
+// registrationUserRepo 记录注册时创建的用户，createErr 为 CreateWithInvitation 返回的错误
+type registrationUserRepo struct {
+	interfaces.UserRepository
+	created      *models.User
+	invitationID int
+	createErr    error
+}
+
+func (r *registrationUserRepo) ForOrganization(orgID int) interfaces.UserRepository {
+	return r
+}
+
+func (r *registrationUserRepo) Exists(username string) (bool, error) {
+	return false, nil
+}
+
+func (r *registrationUserRepo) GetByEmail(email string) (*models.User, error) {
+	return nil, nil
+}
+
+func (r *registrationUserRepo) CreateWithInvitation(user *models.User, invitationID int) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	user.ID = 7
+	r.created = user
+	r.invitationID = invitationID
+	return nil
+}
+
+// fakeInvitationRepo 只有一份仍然有效的邀请
+type fakeInvitationRepo struct {
+	interfaces.InvitationRepository
+	invitation *models.Invitation
+}
+
+func (r *fakeInvitationRepo) GetByTokenHash(tokenHash string) (*models.Invitation, error) {
+	if tokenHash != hashToken("token") {
+		return nil, nil
+	}
+	return r.invitation, nil
+}
+
+// fakeHistoryRepo 丢弃密码历史
+type fakeHistoryRepo struct {
+	interfaces.PasswordHistoryRepository
+}
+
+func (r *fakeHistoryRepo) Add(userID int, hashedPassword string) error {
+	return nil
+}
+
+// newRegistrationService 创建仅限邀请注册的用户服务
+func newRegistrationService(users *registrationUserRepo) *userServiceImpl {
+	return &userServiceImpl{&serviceCore{
+		userRepo:    users,
+		historyRepo: &fakeHistoryRepo{},
+		invitationRepo: &fakeInvitationRepo{invitation: &models.Invitation{
+			ID: 3, Email: "carol@example.com", Role: "viewer", OrgID: 2, ExpiresAt: time.Now().Add(time.Hour),
+		}},
+		passwordPolicy: password.NewPolicy(),
+		passwordHasher: &password.BcryptHasher{Cost: 4},
+		cfg:            &config.Config{RegistrationMode: models.RegistrationInvite},
+	}}
+}
+
+func TestRegisterUserAcceptsInvitationWithUser(t *testing.T) {
+	users := &registrationUserRepo{}
+	s := newRegistrationService(users)
+
+	user, err := s.RegisterUser("carol", "correct horse battery staple", "ignored@example.com", "token")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	if users.created != user || users.invitationID != 3 {
+		t.Fatalf("用户应与邀请 3 一起创建，实际邀请为 %d", users.invitationID)
+	}
+	if user.Email != "carol@example.com" || len(user.Roles) != 1 || user.Roles[0] != "viewer" {
+		t.Errorf("邮箱和角色应以邀请为准，实际为 %s %v", user.Email, user.Roles)
+	}
+}
+
+// TestRegisterUserFailsWhenInvitationAlreadyUsed 并发注册时邀请可能在校验之后被另一个请求用掉，
+// 此时标记邀请的语句影响0行，整个注册失败，不会留下拥有预分配角色的用户
+func TestRegisterUserFailsWhenInvitationAlreadyUsed(t *testing.T) {
+	users := &registrationUserRepo{createErr: sql.ErrNoRows}
+	s := newRegistrationService(users)
+
+	_, err := s.RegisterUser("carol", "correct horse battery staple", "", "token")
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Type != errors.ConflictError {
+		t.Fatalf("期望冲突错误，实际为 %v", err)
+	}
+	if users.created != nil {
+		t.Error("邀请已被使用时不应创建用户")
+	}
+}
@@ -27,7 +27,7 @@ func GenerateCSRFToken(session *Session) (string, error) {
 	token := base64.StdEncoding.EncodeToString(b)
 
 	//将令牌存储到会话中
-	session.Data[CSRFTokenKey] = token
+	session.Set(CSRFTokenKey, token)
 
 	return token, nil
 }
@@ -35,7 +35,8 @@ func GenerateCSRFToken(session *Session) (string, error) {
 // GetCSRFToken 从会话中获取CSRF令牌，如果不存在则生成一个新令牌
 func GetCSRFToken(session *Session) (string, error) {
 	// 检查会话中是否已存在令牌
-	if token, ok := session.Data[CSRFTokenKey].(string); ok {
+	value, _ := session.Get(CSRFTokenKey)
+	if token, ok := value.(string); ok {
 		return token, nil
 	}
 
@@ -46,7 +47,8 @@ func GetCSRFToken(session *Session) (string, error) {
 // ValidateCSRFToken 验证请求中的CSRF令牌是否与会话中的令牌匹配
 func ValidateCSRFToken(r *http.Request, session *Session) error {
 	//从会话中获取令牌
-	sessionToken, ok := session.Data[CSRFTokenKey].(string)
+	value, _ := session.Get(CSRFTokenKey)
+	sessionToken, ok := value.(string)
 	if !ok {
 		return errors.New("会话中没有CSRF令牌")
 	}
@@ -1,14 +1,70 @@
 package session
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"user-management-system/errors"
 	"user-management-system/models"
 	"user-management-system/repository/interfaces"
+	"user-management-system/tenant"
 )
 
+// flashKey 是存储在会话中的一次性提示消息的键名
+const flashKey = "flash"
+
+// mustChangePasswordKey 是存储在会话中的强制修改密码原因的键名
+const mustChangePasswordKey = "must_change_password"
+
+// RequirePasswordChange 标记会话必须先修改密码才能访问其他页面，reason 会展示给用户
+func RequirePasswordChange(session *Session, reason string) {
+	session.Set(mustChangePasswordKey, reason)
+}
+
+// PasswordChangeReason 返回会话被要求修改密码的原因，未被要求时 ok 为 false
+func PasswordChangeReason(session *Session) (reason string, ok bool) {
+	value, _ := session.Get(mustChangePasswordKey)
+	reason, ok = value.(string)
+	return reason, ok
+}
+
+// organizationKey 是存储在会话中的当前组织ID的键名
+const organizationKey = "organization_id"
+
+// SetOrganization 记录会话当前所在的组织，之后不带子域名或路径前缀的请求都使用该组织
+// 组织没有变化时不写入会话
+func SetOrganization(session *Session, orgID int) {
+	if current, ok := OrganizationID(session); ok && current == orgID {
+		return
+	}
+	session.Set(organizationKey, orgID)
+}
+
+// OrganizationID 返回会话当前所在的组织ID，未记录时 ok 为 false
+func OrganizationID(session *Session) (orgID int, ok bool) {
+	value, _ := session.Get(organizationKey)
+	orgID, ok = value.(int)
+	return orgID, ok
+}
+
+// impersonatorKey 是存储在会话中的模拟者用户ID的键名，存在时会话的 UserID 为被模拟的用户
+const impersonatorKey = "impersonator_id"
+
+// ImpersonatorID 返回正在模拟登录的管理员的用户ID，不是模拟登录时 ok 为 false
+func ImpersonatorID(session *Session) (userID int, ok bool) {
+	value, _ := session.Get(impersonatorKey)
+	userID, ok = value.(int)
+	return userID, ok
+}
+
+// Flash 一次性提示消息
+type Flash struct {
+	Kind    string // success 或 error
+	Message string
+}
+
 // Helper 会话辅助器，封装常用操作
 type Helper struct {
 	manager        *Manager
@@ -23,7 +79,64 @@ func NewHelper(manager *Manager, userRepo interfaces.UserRepository) *Helper {
 	}
 }
 
-// GetCurrentUser 从请求中获取当前登录用户
+// userCacheKey 请求上下文中用户缓存的键
+type userCacheKey struct{}
+
+// userCacheEntry 用户缓存的键：用户ID和计算角色所用的组织ID
+type userCacheEntry struct {
+	userID int
+	orgID  int
+}
+
+// userCache 一次请求内已加载的用户，组织中间件、认证中间件和处理器共用，同一用户只查询一次数据库
+type userCache struct {
+	mu    sync.Mutex
+	users map[userCacheEntry]*models.User
+}
+
+// WithUserCache 返回带有用户缓存的上下文，由组织中间件在每个请求开始时调用
+// 上下文中没有缓存时 LoadUser 每次都查询数据库
+func WithUserCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, userCacheKey{}, &userCache{users: make(map[userCacheEntry]*models.User)})
+}
+
+// LoadUser 加载用户 userID，角色和权限按组织 orgID 计算，用户不存在时返回 nil
+// 同一请求中重复加载时直接使用缓存；返回的是副本，调用方修改它不影响缓存
+func (h *Helper) LoadUser(ctx context.Context, userID, orgID int) (*models.User, error) {
+	cache, _ := ctx.Value(userCacheKey{}).(*userCache)
+	key := userCacheEntry{userID: userID, orgID: orgID}
+	if cache != nil {
+		cache.mu.Lock()
+		user, ok := cache.users[key]
+		cache.mu.Unlock()
+		if ok {
+			return copyUser(user), nil
+		}
+	}
+
+	user, err := h.userRepository.WithRolesIn(orgID).GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		cache.mu.Lock()
+		cache.users[key] = user
+		cache.mu.Unlock()
+	}
+	return copyUser(user), nil
+}
+
+// copyUser 返回用户的浅拷贝，nil 时返回 nil
+func copyUser(user *models.User) *models.User {
+	if user == nil {
+		return nil
+	}
+	copied := *user
+	return &copied
+}
+
+// GetCurrentUser 从请求中获取当前登录用户，角色和权限按请求所在的组织计算
+// 同一请求中多次调用只查询一次数据库（见 WithUserCache）
 func (h *Helper) GetCurrentUser(r *http.Request) (*models.User, error) {
 	// 获取会话
 	session, err := h.manager.GetSession(r)
@@ -32,10 +145,12 @@ func (h *Helper) GetCurrentUser(r *http.Request) (*models.User, error) {
 	}
 
 	// 从会话中获取用户ID
-	userID := session.UserID
+	userID := session.UserID()
 
-	// 根据用户ID获取用户信息
-	user, err := h.userRepository.GetByID(userID)
+	// 根据用户ID获取用户信息，角色只在分配它的组织中生效
+	ctx := r.Context()
+	orgID := tenant.ID(ctx)
+	user, err := h.LoadUser(ctx, userID, orgID)
 	if err != nil {
 		return nil, errors.NewInternalError(fmt.Errorf("获取用户信息失败: %w", err))
 	}
@@ -44,26 +159,125 @@ func (h *Helper) GetCurrentUser(r *http.Request) (*models.User, error) {
 		return nil, errors.NewNotFoundError("用户")
 	}
 
+	// 账户被停用、锁定等情况下，已有会话立即失效
+	if !user.IsActive() {
+		return nil, errors.NewUnauthorizedError("账户已" + user.StatusLabel())
+	}
+
+	// 模拟登录时同时加载管理员本人，管理员的账户失效后模拟登录随之失效
+	if impersonatorID, ok := ImpersonatorID(session); ok {
+		impersonator, err := h.LoadUser(ctx, impersonatorID, orgID)
+		if err != nil {
+			return nil, errors.NewInternalError(fmt.Errorf("获取用户信息失败: %w", err))
+		}
+		if impersonator == nil || !impersonator.IsActive() {
+			return nil, errors.NewUnauthorizedError("模拟登录的管理员账户已失效")
+		}
+		user.Impersonator = impersonator
+
+		// 每个页面的提示条中都有结束模拟登录的表单，需要 CSRF 令牌
+		if user.CSRFToken, err = GetCSRFToken(session); err != nil {
+			return nil, errors.NewInternalError(fmt.Errorf("获取CSRF令牌失败: %w", err))
+		}
+	}
+
+	user.CurrentOrg = tenant.FromContext(ctx)
 	return user, nil
 }
 
 // Login 处理用户登录，创建会话
-func (h *Helper) Login(w http.ResponseWriter, r *http.Request, userID int, remember bool) error {
+func (h *Helper) Login(w http.ResponseWriter, r *http.Request, userID int, remember bool) (*Session, error) {
 	// 重要：先销毁旧会话，防止会话固定攻击
 	h.manager.DestroySession(w, r)
 	// 创建新会话
-	_, err := h.manager.CreateSession(w, userID, remember)
+	session, err := h.manager.CreateSession(w, userID, remember)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Errorf("创建会话失败: %w", err))
+	}
+	return session, nil
+}
+
+// StartImpersonation 让当前会话改为以 targetID 的身份继续，原来的用户记为模拟者
+// 调用方负责检查权限；已经处于模拟登录的会话不能再次模拟
+func (h *Helper) StartImpersonation(r *http.Request, targetID int) error {
+	session, err := h.manager.GetSession(r)
 	if err != nil {
-		return errors.NewInternalError(fmt.Errorf("创建会话失败: %w", err))
+		return errors.NewUnauthorizedError("会话无效或已过期")
 	}
+	// 检查和切换在同一次加锁中完成，并行的请求不会重复切换
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if _, ok := session.data[impersonatorKey]; ok {
+		return errors.NewConflictError("请先结束当前的模拟登录")
+	}
+
+	session.data[impersonatorKey] = session.userID
+	session.userID = targetID
 	return nil
 }
 
+// StopImpersonation 结束模拟登录，会话恢复为模拟者本人，返回被模拟的用户ID
+func (h *Helper) StopImpersonation(r *http.Request) (int, error) {
+	session, err := h.manager.GetSession(r)
+	if err != nil {
+		return 0, errors.NewUnauthorizedError("会话无效或已过期")
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	impersonatorID, ok := session.data[impersonatorKey].(int)
+	if !ok {
+		return 0, errors.NewConflictError("当前不是模拟登录")
+	}
+
+	targetID := session.userID
+	session.userID = impersonatorID
+	delete(session.data, impersonatorKey)
+	return targetID, nil
+}
+
 // Logout 处理用户登出，销毁会话
 func (h *Helper) Logout(w http.ResponseWriter, r *http.Request) {
 	h.manager.DestroySession(w, r)
 }
 
+// LogoutOtherSessions 注销用户在其他设备上的会话，保留当前请求的会话
+func (h *Helper) LogoutOtherSessions(r *http.Request, userID int) int {
+	currentSID := ""
+	if session, err := h.manager.GetSession(r); err == nil {
+		currentSID = session.ID
+	}
+	return h.manager.DestroyUserSessions(userID, currentSID)
+}
+
+// SetFlash 保存一条一次性提示消息，在下一次页面渲染时显示
+func (h *Helper) SetFlash(r *http.Request, kind, message string) {
+	session, err := h.manager.GetSession(r)
+	if err != nil {
+		return
+	}
+	session.Set(flashKey, &Flash{Kind: kind, Message: message})
+}
+
+// PopFlash 取出并清除一次性提示消息，没有时返回 nil
+func (h *Helper) PopFlash(r *http.Request) *Flash {
+	session, err := h.manager.GetSession(r)
+	if err != nil {
+		return nil
+	}
+	value, _ := session.Pop(flashKey)
+	flash, _ := value.(*Flash)
+	return flash
+}
+
+// ClearPasswordChange 清除当前会话的强制修改密码标记
+func (h *Helper) ClearPasswordChange(r *http.Request) {
+	session, err := h.manager.GetSession(r)
+	if err != nil {
+		return
+	}
+	session.Delete(mustChangePasswordKey)
+}
+
 // RequireLogin 检查用户是否已登录
 func (h *Helper) RequireLogin(r *http.Request) (*Session, error) {
 	session, err := h.manager.GetSession(r)
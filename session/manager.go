@@ -1,9 +1,11 @@
 package session
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -26,7 +28,7 @@ CSRF:
 */
 
 /*
-Session.Data 存储:
+Session 的会话数据（Get、Set）存储:
 CSRF令牌
 用户偏好设置（通过辅助函数设置)
 可能的其他用途（虽然当前项目未明确使用）
@@ -44,12 +46,53 @@ Redis存储：使用Redis等内存数据库存储会话
 */
 
 // Session 表示一个用户会话
+// 同一浏览器的并行请求共享同一个会话，用户ID和会话数据只能通过加锁的方法访问
 type Session struct {
-	ID        string                 // 会话唯一标识符 随机的sid
-	UserID    int                    // 关联的用户ID 数据库中的id
-	Data      map[string]interface{} // 会话数据存储 存储数据+CSRF令牌
-	CreatedAt time.Time              // 会话创建时间
-	ExpiresAt time.Time              // 会话过期时间
+	ID        string    // 会话唯一标识符 随机的sid
+	CreatedAt time.Time // 会话创建时间
+	ExpiresAt time.Time // 会话过期时间
+
+	mu     sync.RWMutex
+	userID int                    // 关联的用户ID 数据库中的id
+	data   map[string]interface{} // 会话数据存储 存储数据+CSRF令牌
+}
+
+// UserID 返回会话关联的用户ID（模拟登录时为被模拟的用户）
+func (s *Session) UserID() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userID
+}
+
+// Get 返回会话数据中 key 对应的值
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.data[key]
+	return value, ok
+}
+
+// Set 保存会话数据
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+}
+
+// Delete 删除会话数据
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+}
+
+// Pop 取出并删除会话数据
+func (s *Session) Pop(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.data[key]
+	delete(s.data, key)
+	return value, ok
 }
 
 // Manager 会话管理器，负责创建、获取和销毁会话
@@ -106,15 +149,15 @@ func (manager *Manager) CreateSession(w http.ResponseWriter, userID int, remembe
 	// 创建新会话
 	session := &Session{
 		ID:        sid,
-		UserID:    userID,
-		Data:      make(map[string]interface{}),
+		userID:    userID,
+		data:      make(map[string]interface{}),
 		CreatedAt: time.Now(),
 		ExpiresAt: expiresAt,
 	}
 
 	// 立即生成 CSRF token
 	token := generateCSRFTokenDirect()
-	session.Data[CSRFTokenKey] = token
+	session.data[CSRFTokenKey] = token
 
 	// 存储会话
 	manager.sessions[sid] = session
@@ -188,6 +231,54 @@ func (manager *Manager) DestroySession(w http.ResponseWriter, r *http.Request) {
 	http.SetCookie(w, &expiredCookie)
 }
 
+// DestroyUserSessions 销毁指定用户的所有会话（exceptSID 对应的会话除外）
+// 用于修改密码等场景，让其他设备上的登录失效
+func (manager *Manager) DestroyUserSessions(userID int, exceptSID string) int {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	count := 0
+	for sid, session := range manager.sessions {
+		if session.UserID() == userID && sid != exceptSID {
+			delete(manager.sessions, sid)
+			count++
+		}
+	}
+	return count
+}
+
+// ActiveCount 返回未过期的会话数
+func (manager *Manager) ActiveCount() int {
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	now := time.Now()
+	count := 0
+	for _, session := range manager.sessions {
+		if session.ExpiresAt.After(now) {
+			count++
+		}
+	}
+	return count
+}
+
+// Ping 检查会话存储能否在 ctx 结束前响应：存储的锁被长时间占用时，所有需要会话的请求都会挂起
+func (manager *Manager) Ping(ctx context.Context) error {
+	for !manager.lock.TryRLock() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("会话存储没有响应: %w", ctx.Err())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	defer manager.lock.RUnlock()
+
+	if manager.sessions == nil {
+		return errors.New("会话存储未初始化")
+	}
+	return nil
+}
+
 // GC 垃圾收集，清理过期的会话
 func (manager *Manager) GC() {
 	for {
@@ -0,0 +1,198 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"user-management-system/models"
+	"user-management-system/repository/interfaces"
+	"user-management-system/tenant"
+)
+
+// newTestSession 创建会话并返回带有会话 Cookie 的请求
+func newTestSession(t *testing.T, m *Manager, userID int) (*Session, *http.Request) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	sess, err := m.CreateSession(rec, userID, false)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return sess, r
+}
+
+// TestConcurrentSessionAccess 同一会话的并行请求同时读写会话数据（go test -race 下检查数据竞争）
+func TestConcurrentSessionAccess(t *testing.T) {
+	m := NewManager("session_id", time.Hour)
+	sess, r := newTestSession(t, m, 1)
+	h := &Helper{manager: m}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				SetOrganization(sess, i%2+1)
+				OrganizationID(sess)
+				PasswordChangeReason(sess)
+				ImpersonatorID(sess)
+				h.SetFlash(r, "success", "ok")
+				h.PopFlash(r)
+				GetCSRFToken(sess)
+				sess.UserID()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSetOrganizationOnlyWritesOnChange(t *testing.T) {
+	m := NewManager("session_id", time.Hour)
+	sess, _ := newTestSession(t, m, 1)
+
+	SetOrganization(sess, 3)
+	if id, ok := OrganizationID(sess); !ok || id != 3 {
+		t.Fatalf("OrganizationID = %d, %v", id, ok)
+	}
+
+	// 持有读锁时，组织未变化的 SetOrganization 不会等待写锁
+	sess.mu.RLock()
+	done := make(chan struct{})
+	go func() {
+		SetOrganization(sess, 3)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("SetOrganization with an unchanged org should not write")
+	}
+	sess.mu.RUnlock()
+}
+
+func TestImpersonation(t *testing.T) {
+	m := NewManager("session_id", time.Hour)
+	sess, r := newTestSession(t, m, 1)
+	h := &Helper{manager: m}
+
+	if err := h.StartImpersonation(r, 2); err != nil {
+		t.Fatalf("StartImpersonation: %v", err)
+	}
+	if id, ok := ImpersonatorID(sess); !ok || id != 1 || sess.UserID() != 2 {
+		t.Fatalf("after start: impersonator=%d,%v user=%d", id, ok, sess.UserID())
+	}
+	if err := h.StartImpersonation(r, 3); err == nil {
+		t.Error("nested impersonation should fail")
+	}
+
+	target, err := h.StopImpersonation(r)
+	if err != nil || target != 2 {
+		t.Fatalf("StopImpersonation = %d, %v", target, err)
+	}
+	if _, ok := ImpersonatorID(sess); ok || sess.UserID() != 1 {
+		t.Errorf("after stop: user=%d", sess.UserID())
+	}
+	if _, err := h.StopImpersonation(r); err == nil {
+		t.Error("stopping without impersonation should fail")
+	}
+}
+
+// orgRolesRepo 按组织返回不同角色的用户仓库，模拟角色只在分配它的组织中生效
+type orgRolesRepo struct {
+	interfaces.UserRepository
+	byOrg map[int]*models.User // 组织ID -> 在该组织中加载到的用户
+	orgID int
+	loads *int // GetByID 的调用次数，为 nil 时不统计
+}
+
+func (r *orgRolesRepo) WithRolesIn(orgID int) interfaces.UserRepository {
+	return &orgRolesRepo{byOrg: r.byOrg, orgID: orgID, loads: r.loads}
+}
+
+func (r *orgRolesRepo) GetByID(id int) (*models.User, error) {
+	if r.loads != nil {
+		*r.loads++
+	}
+	user, ok := r.byOrg[r.orgID]
+	if !ok || user.ID != id {
+		return nil, nil
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// TestGetCurrentUserUsesRolesOfCurrentOrganization 当前用户的权限按请求所在的组织计算，
+// 在组织 A 中的管理权限不会带到组织 B
+func TestGetCurrentUserUsesRolesOfCurrentOrganization(t *testing.T) {
+	m := NewManager("session_id", time.Hour)
+	_, r := newTestSession(t, m, 1)
+	repo := &orgRolesRepo{byOrg: map[int]*models.User{
+		1: {ID: 1, Username: "alice", Status: models.StatusActive, Roles: []string{models.RoleOrgAdmin}, Permissions: []string{models.PermUsersManage}},
+		2: {ID: 1, Username: "alice", Status: models.StatusActive, Roles: []string{models.RoleUser}, Permissions: []string{models.PermUsersView}},
+	}}
+	h := NewHelper(m, repo)
+
+	for orgID, manages := range map[int]bool{1: true, 2: false} {
+		org := &models.Organization{ID: orgID, Slug: fmt.Sprintf("org%d", orgID)}
+		user, err := h.GetCurrentUser(r.WithContext(tenant.WithOrganization(r.Context(), org)))
+		if err != nil {
+			t.Fatalf("组织 %d: GetCurrentUser: %v", orgID, err)
+		}
+		if got := user.HasPermission(models.PermUsersManage); got != manages {
+			t.Errorf("组织 %d: users:manage = %v，期望 %v", orgID, got, manages)
+		}
+		if user.CurrentOrg != org {
+			t.Errorf("组织 %d: CurrentOrg = %v", orgID, user.CurrentOrg)
+		}
+	}
+}
+
+// TestGetCurrentUserLoadsOncePerRequest 同一请求中多次获取当前用户只查询一次仓库，
+// 不同请求之间、不同组织之间不共用缓存
+func TestGetCurrentUserLoadsOncePerRequest(t *testing.T) {
+	m := NewManager("session_id", time.Hour)
+	_, r := newTestSession(t, m, 1)
+	loads := 0
+	repo := &orgRolesRepo{loads: &loads, byOrg: map[int]*models.User{
+		1: {ID: 1, Username: "alice", Status: models.StatusActive, Permissions: []string{models.PermUsersManage}},
+		2: {ID: 1, Username: "alice", Status: models.StatusActive},
+	}}
+	h := NewHelper(m, repo)
+
+	inOrg := func(r *http.Request, orgID int) *http.Request {
+		return r.WithContext(tenant.WithOrganization(r.Context(), &models.Organization{ID: orgID}))
+	}
+	cached := r.WithContext(WithUserCache(r.Context()))
+	for i := 0; i < 3; i++ {
+		user, err := h.GetCurrentUser(inOrg(cached, 1))
+		if err != nil {
+			t.Fatalf("GetCurrentUser: %v", err)
+		}
+		// 调用方修改返回的用户不影响缓存
+		user.Permissions = nil
+	}
+	if loads != 1 {
+		t.Errorf("同一请求加载了 %d 次，期望 1 次", loads)
+	}
+	if user, _ := h.GetCurrentUser(inOrg(cached, 1)); !user.HasPermission(models.PermUsersManage) {
+		t.Error("缓存的用户被调用方修改")
+	}
+
+	if _, err := h.GetCurrentUser(inOrg(cached, 2)); err != nil {
+		t.Fatalf("GetCurrentUser: %v", err)
+	}
+	if _, err := h.GetCurrentUser(inOrg(r.WithContext(WithUserCache(r.Context())), 1)); err != nil {
+		t.Fatalf("GetCurrentUser: %v", err)
+	}
+	if loads != 3 {
+		t.Errorf("另一个组织和另一个请求共加载了 %d 次，期望 2 次", loads-1)
+	}
+}
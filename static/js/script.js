@@ -10,6 +10,7 @@ document.addEventListener('DOMContentLoaded', function() {
     // 搜索输入框
     const searchInput = document.getElementById('searchInput');
     const filterRole = document.getElementById('filterRole');
+    const filterStatus = document.getElementById('filterStatus');
     const usersTableBody = document.getElementById('usersTableBody');
     const emptyState = document.getElementById('emptyState');
 
@@ -19,18 +20,21 @@ document.addEventListener('DOMContentLoaded', function() {
 
         const searchTerm = searchInput.value.toLowerCase();
         const roleFilter = filterRole ? filterRole.value : 'all';
+        const statusFilter = filterStatus ? filterStatus.value : 'all';
         const rows = usersTableBody.getElementsByClassName('user-row');
         let visibleCount = 0;
 
         for (let row of rows) {
             const username = row.querySelector('.user-info span:last-child').textContent.toLowerCase();
             const email = row.cells[2].textContent.toLowerCase();
-            const role = row.getAttribute('data-role');
+            const roles = (row.getAttribute('data-roles') || '').split(' ');
+            const status = row.getAttribute('data-status');
 
             const matchesSearch = username.includes(searchTerm) || email.includes(searchTerm);
-            const matchesRole = roleFilter === 'all' || role === roleFilter;
+            const matchesRole = roleFilter === 'all' || roles.includes(roleFilter);
+            const matchesStatus = statusFilter === 'all' || status === statusFilter;
 
-            if (matchesSearch && matchesRole) {
+            if (matchesSearch && matchesRole && matchesStatus) {
                 row.style.display = '';
                 row.style.animation = 'fadeIn 0.3s ease-out';
                 visibleCount++;
@@ -63,6 +67,10 @@ document.addEventListener('DOMContentLoaded', function() {
         filterRole.addEventListener('change', filterUsers);
     }
 
+    if (filterStatus) {
+        filterStatus.addEventListener('change', filterUsers);
+    }
+
     // 密码强度检测
     const passwordInput = document.getElementById('password');
     if (passwordInput && document.querySelector('.password-strength')) {
@@ -73,8 +81,8 @@ document.addEventListener('DOMContentLoaded', function() {
             if (!strengthFill) return;
 
             let strength = 0;
-            if (password.length >= 6) strength++;
-            if (password.length >= 10) strength++;
+            if (password.length >= 8) strength++;
+            if (password.length >= 16) strength++;
             if (/[A-Z]/.test(password) && /[a-z]/.test(password)) strength++;
             if (/[0-9]/.test(password)) strength++;
             if (/[^A-Za-z0-9]/.test(password)) strength++;
@@ -136,6 +144,34 @@ document.addEventListener('DOMContentLoaded', function() {
         if (modal && e.target === modal) {
             closeModal();
         }
+        const statusModal = document.getElementById('statusModal');
+        if (statusModal && e.target === statusModal) {
+            closeStatusModal();
+        }
+        const createModal = document.getElementById('createModal');
+        if (createModal && e.target === createModal) {
+            closeCreateModal();
+        }
+        const resetModal = document.getElementById('resetModal');
+        if (resetModal && e.target === resetModal) {
+            closeResetModal();
+        }
+        const inviteModal = document.getElementById('inviteModal');
+        if (inviteModal && e.target === inviteModal) {
+            closeInviteModal();
+        }
+        const rejectModal = document.getElementById('rejectModal');
+        if (rejectModal && e.target === rejectModal) {
+            closeRejectModal();
+        }
+        const roleModal = document.getElementById('roleModal');
+        if (roleModal && e.target === roleModal) {
+            closeRoleModal();
+        }
+        const groupModal = document.getElementById('groupModal');
+        if (groupModal && e.target === groupModal) {
+            closeGroupModal();
+        }
     });
 
     // 添加按钮悬停效果
@@ -153,7 +189,7 @@ document.addEventListener('DOMContentLoaded', function() {
     // 统计管理员数量（如果在用户页面）
     const adminCountEl = document.getElementById('adminCount');
     if (adminCountEl) {
-        const adminCount = document.querySelectorAll('.user-row[data-role="admin"]').length;
+        const adminCount = document.querySelectorAll('.user-row[data-admin="true"]').length;
         adminCountEl.textContent = adminCount;
 
         // 添加数字动画
@@ -213,7 +249,7 @@ function closeModal() {
 
 function confirmDelete(username) {
     // 创建自定义确认对话框
-    const confirmed = confirm(`确定要删除用户 "${username}" 吗？此操作不可撤销。`);
+    const confirmed = confirm(`确定要删除用户 "${username}" 吗？用户将被移入回收站，保留期内可以恢复。`);
 
     if (confirmed) {
         // 添加删除动画
@@ -0,0 +1,31 @@
+// Package tenant 在请求上下文中传递当前组织（租户）
+// 组织由 middleware.TenantMiddleware 按子域名、路径前缀或会话解析后写入上下文
+package tenant
+
+import (
+	"context"
+
+	"user-management-system/models"
+)
+
+// contextKey 上下文键的私有类型，避免与其他包的键冲突
+type contextKey struct{}
+
+// WithOrganization 返回携带当前组织的上下文
+func WithOrganization(ctx context.Context, org *models.Organization) context.Context {
+	return context.WithValue(ctx, contextKey{}, org)
+}
+
+// FromContext 返回上下文中的当前组织，未解析组织时返回 nil
+func FromContext(ctx context.Context) *models.Organization {
+	org, _ := ctx.Value(contextKey{}).(*models.Organization)
+	return org
+}
+
+// ID 返回上下文中当前组织的ID，未解析组织时返回 0（即不限定组织）
+func ID(ctx context.Context) int {
+	if org := FromContext(ctx); org != nil {
+		return org.ID
+	}
+	return 0
+}
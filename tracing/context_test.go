@@ -0,0 +1,81 @@
+package tracing
+
+import "testing"
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name    string
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", true, false},
+		{"other flags keep sampled bit", "00-" + traceID + "-" + spanID + "-03", true, true},
+		{"surrounding spaces", "  00-" + traceID + "-" + spanID + "-01 ", true, true},
+		{"future version with extra fields", "cc-" + traceID + "-" + spanID + "-01-extra", true, true},
+		{"version 00 with extra fields", "00-" + traceID + "-" + spanID + "-01-extra", false, false},
+		{"version ff", "ff-" + traceID + "-" + spanID + "-01", false, false},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01", false, false},
+		{"all-zero trace id", "00-00000000000000000000000000000000-" + spanID + "-01", false, false},
+		{"all-zero span id", "00-" + traceID + "-0000000000000000-01", false, false},
+		{"short trace id", "00-4bf92f3577b34da6-" + spanID + "-01", false, false},
+		{"non-hex flags", "00-" + traceID + "-" + spanID + "-zz", false, false},
+		{"missing field", "00-" + traceID + "-" + spanID, false, false},
+		{"empty", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("ParseTraceparent(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID {
+				t.Errorf("ids = %s/%s, want %s/%s", sc.TraceID, sc.SpanID, traceID, spanID)
+			}
+			if sc.Sampled != tt.sampled {
+				t.Errorf("sampled = %v, want %v", sc.Sampled, tt.sampled)
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	for _, sampled := range []bool{true, false} {
+		sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: sampled}
+		parsed, ok := ParseTraceparent(sc.Traceparent())
+		if !ok {
+			t.Fatalf("ParseTraceparent(%q) failed", sc.Traceparent())
+		}
+		if parsed != sc {
+			t.Errorf("round trip = %+v, want %+v", parsed, sc)
+		}
+	}
+}
+
+func TestSampleBound(t *testing.T) {
+	var low, high TraceID
+	high[8] = 0xff
+
+	always := &tracer{sampleBound: sampleBound(1)}
+	never := &tracer{sampleBound: sampleBound(0)}
+	half := &tracer{sampleBound: sampleBound(0.5)}
+
+	if !always.sample(high) || !always.sample(low) {
+		t.Error("ratio 1 should sample every trace")
+	}
+	if never.sample(low) || never.sample(high) {
+		t.Error("ratio 0 should sample no trace")
+	}
+	// 采样只取决于追踪 ID，同一追踪在各服务中的决定一致
+	if !half.sample(low) || half.sample(high) {
+		t.Error("ratio 0.5 should sample the lower half of trace ids")
+	}
+}
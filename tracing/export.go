@@ -0,0 +1,322 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"user-management-system/logger"
+)
+
+// Exporter 把结束的 span 发送到追踪后端
+type Exporter interface {
+	Export(ctx context.Context, spans []*SpanData) error
+}
+
+// 批量导出的参数
+const (
+	maxQueueSize   = 2048            // 等待导出的 span 上限，超过时丢弃新的 span
+	maxBatchSize   = 512             // 每批导出的 span 数
+	exportInterval = 5 * time.Second // 不满一批时的导出间隔
+	exportTimeout  = 10 * time.Second
+)
+
+// batchProcessor 在后台批量导出 span，不阻塞请求处理
+type batchProcessor struct {
+	exporter Exporter
+	queue    chan *SpanData
+	done     chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int // 队列已满时丢弃的 span 数，下次导出时记录日志
+}
+
+// newBatchProcessor 创建并启动批量导出
+func newBatchProcessor(exporter Exporter) *batchProcessor {
+	p := &batchProcessor{exporter: exporter, queue: make(chan *SpanData, maxQueueSize), done: make(chan struct{})}
+	go p.run()
+	return p
+}
+
+// enqueue 把 span 加入导出队列，队列已满或已关闭时丢弃
+func (p *batchProcessor) enqueue(span *SpanData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- span:
+	default:
+		p.dropped++
+	}
+}
+
+// run 攒够一批或到达间隔时导出，队列关闭后导出剩余的 span
+func (p *batchProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*SpanData, 0, maxBatchSize)
+	for {
+		select {
+		case span, ok := <-p.queue:
+			if !ok {
+				p.export(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= maxBatchSize {
+				p.export(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.export(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// export 导出一批 span，失败时只记录日志
+func (p *batchProcessor) export(batch []*SpanData) {
+	p.mu.Lock()
+	dropped := p.dropped
+	p.dropped = 0
+	p.mu.Unlock()
+	if dropped > 0 {
+		logger.Warn("追踪队列已满，部分 span 被丢弃", "dropped", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	if err := p.exporter.Export(ctx, batch); err != nil {
+		logger.Warn("导出追踪数据失败", "spans", len(batch), "error", err)
+	}
+}
+
+// shutdown 停止接收新的 span，等待已有的 span 导出完成或 ctx 结束
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// StdoutExporter 每个 span 输出一行 JSON，用于在没有追踪后端时本地查看
+type StdoutExporter struct {
+	mu          sync.Mutex
+	w           io.Writer
+	serviceName string
+}
+
+// NewStdoutExporter 创建输出到 w 的导出器
+func NewStdoutExporter(w io.Writer, serviceName string) *StdoutExporter {
+	return &StdoutExporter{w: w, serviceName: serviceName}
+}
+
+// stdoutSpan 标准输出中一个 span 的格式
+type stdoutSpan struct {
+	Service    string                 `json:"service"`
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Start      time.Time              `json:"start"`
+	DurationMS float64                `json:"duration_ms"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Export 实现 Exporter 接口
+func (e *StdoutExporter) Export(ctx context.Context, spans []*SpanData) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, s := range spans {
+		out := stdoutSpan{
+			Service:    e.serviceName,
+			Name:       s.Name,
+			Kind:       s.Kind.String(),
+			TraceID:    s.TraceID.String(),
+			SpanID:     s.SpanID.String(),
+			Start:      s.Start,
+			DurationMS: float64(s.End.Sub(s.Start).Microseconds()) / 1000,
+			Error:      s.Error,
+		}
+		if s.ParentID.IsValid() {
+			out.ParentID = s.ParentID.String()
+		}
+		if len(s.Attributes) > 0 {
+			out.Attributes = make(map[string]interface{}, len(s.Attributes))
+			for _, a := range s.Attributes {
+				out.Attributes[a.Key] = a.Value
+			}
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+// OTLPExporter 以 OTLP/HTTP（JSON 编码）发送 span
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter 创建发送到 endpoint（如 http://localhost:4318/v1/traces）的导出器
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	return &OTLPExporter{endpoint: endpoint, serviceName: serviceName, client: &http.Client{Timeout: exportTimeout}}
+}
+
+// OTLP JSON 编码的结构，字段名见 opentelemetry-proto 的 JSON 映射
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		TraceState        string         `json:"traceState,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 0：未设置，1：成功，2：错误
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // int64 在 JSON 映射中为字符串
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// otlpValue 把属性值转换为 OTLP 的 AnyValue
+func otlpValue(v interface{}) otlpAnyValue {
+	switch v := v.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return otlpAnyValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpAnyValue{IntValue: &s}
+	case float64:
+		return otlpAnyValue{DoubleValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+// otlpAttributes 转换属性列表
+func otlpAttributes(attrs []Attr) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		out = append(out, otlpKeyValue{Key: a.Key, Value: otlpValue(a.Value)})
+	}
+	return out
+}
+
+// Export 实现 Exporter 接口
+func (e *OTLPExporter) Export(ctx context.Context, spans []*SpanData) error {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: "user-management-system/tracing"}}
+	for _, s := range spans {
+		out := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			TraceState:        s.TraceState,
+			Name:              s.Name,
+			Kind:              int(s.Kind),
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status:            otlpStatus{Code: 1},
+		}
+		if s.ParentID.IsValid() {
+			out.ParentSpanID = s.ParentID.String()
+		}
+		if s.Error != "" {
+			out.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+		scope.Spans = append(scope.Spans, out)
+	}
+
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]Attr{{Key: "service.name", Value: e.serviceName}})},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OTLP 接收端返回 %s", resp.Status)
+	}
+	return nil
+}
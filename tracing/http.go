@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// W3C Trace Context 请求头
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTracestateLength 接受的 tracestate 最大长度，超过时丢弃（规范允许）
+const maxTracestateLength = 512
+
+// Extract 从请求头中读取上游的追踪信息，之后在返回的上下文上 Start 的 span 以上游 span 为父 span
+// 请求头不存在或格式不正确时返回原上下文（开始新的追踪）
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, ok := ParseTraceparent(header.Get(TraceparentHeader))
+	if !ok {
+		return ctx
+	}
+	if state := header.Get(TracestateHeader); len(state) <= maxTracestateLength {
+		sc.TraceState = state
+	}
+	return ContextWithRemoteParent(ctx, sc)
+}
+
+// Inject 把上下文中的追踪信息写入发出请求的请求头
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(TracestateHeader, sc.TraceState)
+	}
+}
+
+// Transport 为发出的 HTTP 请求记录 span，并通过 traceparent 头把追踪信息传给下游服务
+// 用法：client := &http.Client{Transport: tracing.Transport{}}
+type Transport struct {
+	Base http.RoundTripper // 为空时使用 http.DefaultTransport
+}
+
+// RoundTrip 实现 http.RoundTripper 接口
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := Start(req.Context(), "HTTP "+req.Method, KindClient,
+		Attr{Key: "http.request.method", Value: req.Method},
+		Attr{Key: "server.address", Value: req.URL.Host},
+		Attr{Key: "url.full", Value: redactedURL(req)},
+	)
+	if span == nil {
+		return base.RoundTrip(req)
+	}
+
+	// RoundTripper 不能修改传入的请求
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.EndWithError(err)
+		return nil, err
+	}
+	span.SetAttributes(Attr{Key: "http.response.status_code", Value: resp.StatusCode})
+	if resp.StatusCode >= 500 {
+		span.SetError(httpStatusError(resp.StatusCode))
+	}
+	span.End()
+	return resp, nil
+}
+
+// redactedURL 去掉用户名、密码和查询参数，避免把凭据写入追踪数据
+func redactedURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// httpStatusError 以状态码作为 span 的错误信息
+type httpStatusError int
+
+func (e httpStatusError) Error() string {
+	return "HTTP " + strconv.Itoa(int(e)) + " " + http.StatusText(int(e))
+}
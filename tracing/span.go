@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SpanKind span 的类型，与 OpenTelemetry 的定义一致
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1 // 进程内部的操作（如服务方法）
+	KindServer   SpanKind = 2 // 处理收到的请求
+	KindClient   SpanKind = 3 // 发出的请求（如 SQL 查询、HTTP 调用）
+)
+
+// String 返回类型名称
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// Attr span 的一个属性，值为 string、bool、int、int64 或 float64
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// Span 一次操作的追踪记录；nil 的 Span 可以安全调用所有方法（未启用追踪时 Start 返回 nil）
+type Span struct {
+	mu        sync.Mutex
+	name      string
+	kind      SpanKind
+	sc        SpanContext
+	parent    SpanID
+	start     time.Time
+	end       time.Time
+	attrs     []Attr
+	errorText string // 不为空时状态为错误
+	ended     bool
+}
+
+// SpanData 结束后交给导出器的 span 数据
+type SpanData struct {
+	Name       string
+	Kind       SpanKind
+	TraceID    TraceID
+	SpanID     SpanID
+	ParentID   SpanID // 根 span 为全零
+	TraceState string
+	Start      time.Time
+	End        time.Time
+	Attributes []Attr
+	Error      string // 为空表示成功
+}
+
+// spanKey span 在上下文中的键
+type spanKey struct{}
+
+// remoteKey 从请求头中解析出的远端父 span 在上下文中的键
+type remoteKey struct{}
+
+// ContextWithSpan 返回带有 span 的上下文，之后在该上下文上 Start 的 span 以它为父 span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 返回上下文中的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent 返回以远端 span（如请求头中的 traceparent）为父 span 的上下文
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext 返回上下文中的 span 的追踪信息，没有时返回远端父 span 的信息
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// Start 开始一个 span，父 span 取自 ctx；返回的上下文带有新的 span
+// 未启用追踪时返回原上下文和 nil；父 span 未被采样时新 span 也不采样（只传递追踪 ID，不导出）
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...Attr) (context.Context, *Span) {
+	t := current()
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	span := &Span{name: name, kind: kind, start: time.Now(), attrs: attrs}
+	if parent.IsValid() {
+		span.sc = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled, TraceState: parent.TraceState}
+		span.parent = parent.SpanID
+	} else {
+		traceID := newTraceID()
+		span.sc = SpanContext{TraceID: traceID, SpanID: newSpanID(), Sampled: t.sample(traceID)}
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// SpanContext 返回 span 的追踪信息
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// IsRecording 是否会被导出，用于避免构造开销较大的属性
+func (s *Span) IsRecording() bool {
+	return s != nil && s.sc.Sampled
+}
+
+// SetName 修改 span 名称（如路由匹配之后）
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttributes 添加属性
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// SetError 把 span 的状态设为错误，err 为 nil 时不做修改
+func (s *Span) SetError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	s.errorText = err.Error()
+	s.mu.Unlock()
+}
+
+// End 结束 span 并交给导出器，重复调用时只有第一次有效
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	data := &SpanData{
+		Name:       s.name,
+		Kind:       s.kind,
+		TraceID:    s.sc.TraceID,
+		SpanID:     s.sc.SpanID,
+		ParentID:   s.parent,
+		TraceState: s.sc.TraceState,
+		Start:      s.start,
+		End:        s.end,
+		Attributes: s.attrs,
+		Error:      s.errorText,
+	}
+	s.mu.Unlock()
+
+	if t := current(); t != nil {
+		t.processor.enqueue(data)
+	}
+}
+
+// EndWithError 设置错误状态（err 不为 nil 时）并结束 span
+func (s *Span) EndWithError(err error) {
+	s.SetError(err)
+	s.End()
+}
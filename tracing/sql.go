@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"strings"
+)
+
+// maxStatementLength 记录的 SQL 最大长度
+const maxStatementLength = 2048
+
+// SanitizeSQL 返回可以写入追踪数据的 SQL：字符串和数字字面量替换为 ?，连续的空白合并为一个空格
+// 参数本来就通过占位符传递，这里再去掉拼接在语句中的值（如组织 ID），避免敏感数据和过多的不同语句
+func SanitizeSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i)
+			c = '?'
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			for i+1 < len(query) && (isIdentChar(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			c = '?'
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			// 行注释
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteByte(c)
+		if b.Len() >= maxStatementLength {
+			break
+		}
+	}
+	return b.String()
+}
+
+// SQLOperation 返回语句的第一个关键字（SELECT、INSERT 等），用作 span 名称
+func SQLOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// skipQuoted 跳过从 start 开始的引号字符串（支持重复引号和反斜杠转义），返回结束引号的位置
+func skipQuoted(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"placeholders unchanged", "SELECT id FROM users WHERE username = ?", "SELECT id FROM users WHERE username = ?"},
+		{"string literal", "SELECT * FROM users WHERE email = 'a@b.com'", "SELECT * FROM users WHERE email = ?"},
+		{"double quoted literal", `SELECT * FROM users WHERE name = "bob"`, "SELECT * FROM users WHERE name = ?"},
+		{"doubled quote", "SELECT 'it''s'", "SELECT ?"},
+		{"backslash escape", `SELECT 'a\'b' FROM t`, "SELECT ? FROM t"},
+		{"numbers", "SELECT * FROM users WHERE org_id = 42 AND score > 1.5", "SELECT * FROM users WHERE org_id = ? AND score > ?"},
+		{"negative number keeps sign", "SELECT -7", "SELECT -?"},
+		{"digits inside identifiers", "SELECT col1, t2.x FROM table3", "SELECT col1, t2.x FROM table3"},
+		{"whitespace collapsed", "SELECT\n\t id\r\n  FROM   users  ", "SELECT id FROM users"},
+		{"line comment removed", "SELECT id -- secret 123\nFROM users", "SELECT id FROM users"},
+		{"IN list", "DELETE FROM t WHERE id IN (1, 2, 3)", "DELETE FROM t WHERE id IN (?, ?, ?)"},
+		{"unterminated string", "SELECT 'abc", "SELECT ?"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeSQL(tt.query); got != tt.want {
+				t.Errorf("SanitizeSQL(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeSQLTruncates(t *testing.T) {
+	query := "SELECT " + strings.Repeat("a, ", 2000) + "b FROM t"
+	if got := SanitizeSQL(query); len(got) != maxStatementLength {
+		t.Errorf("len = %d, want %d", len(got), maxStatementLength)
+	}
+}
+
+func TestSQLOperation(t *testing.T) {
+	tests := map[string]string{
+		"select id from users":        "SELECT",
+		"\n  INSERT INTO t VALUES ()": "INSERT",
+		"":                            "",
+	}
+	for query, want := range tests {
+		if got := SQLOperation(query); got != want {
+			t.Errorf("SQLOperation(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package tracing 分布式追踪：为 HTTP 请求、服务方法和 SQL 查询记录 span，
+// 通过 W3C Trace Context（traceparent、tracestate 头）与上下游服务关联
+//
+// 只实现了本项目需要的部分，不依赖 OpenTelemetry SDK；导出格式与 OTLP/HTTP（JSON 编码）兼容，
+// 可以直接发送给 OpenTelemetry Collector、Jaeger 等，也可以输出到标准输出在本地查看。
+//
+// span 的父子关系通过 context.Context 传递：
+//
+//	ctx, span := tracing.Start(ctx, "SendMail", tracing.KindClient)
+//	defer span.End()
+//
+// 未启用追踪时 Start 返回 nil 的 *Span，所有方法都可以安全调用。
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"user-management-system/config"
+)
+
+// 导出方式
+const (
+	ExporterNone   = "none"   // 不启用追踪
+	ExporterStdout = "stdout" // 每个 span 一行 JSON 输出到标准输出
+	ExporterOTLP   = "otlp"   // 以 OTLP/HTTP（JSON 编码）发送给 Endpoint
+)
+
+// Options 追踪配置
+type Options struct {
+	Exporter    string  // none、stdout 或 otlp，为空时为 none
+	Endpoint    string  // OTLP 接收地址，如 http://localhost:4318/v1/traces
+	SampleRatio float64 // 没有上游追踪信息时的采样比例（0～1），有上游信息时沿用上游的采样决定
+	ServiceName string  // 导出的 service.name
+}
+
+// OptionsFromConfig 根据应用配置生成追踪配置
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		Exporter:    cfg.TraceExporter,
+		Endpoint:    cfg.TraceEndpoint,
+		SampleRatio: cfg.TraceSampleRatio,
+		ServiceName: cfg.TraceServiceName,
+	}
+}
+
+// tracer 全局的追踪状态
+type tracer struct {
+	sampleBound uint64 // 追踪 ID 后8字节小于该值时采样
+	processor   *batchProcessor
+}
+
+var (
+	mu     sync.RWMutex
+	global *tracer
+)
+
+// current 返回全局的追踪状态，未启用时返回 nil
+func current() *tracer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return global
+}
+
+// Enabled 是否启用了追踪
+func Enabled() bool {
+	return current() != nil
+}
+
+// Init 按 opts 启用追踪；Exporter 为 none 时不启用
+// 重复调用时先关闭之前的导出器
+func Init(opts Options) error {
+	var exporter Exporter
+	switch opts.Exporter {
+	case ExporterNone, "":
+	case ExporterStdout:
+		exporter = NewStdoutExporter(os.Stdout, opts.ServiceName)
+	case ExporterOTLP:
+		if opts.Endpoint == "" {
+			return fmt.Errorf("未配置 OTLP 接收地址")
+		}
+		exporter = NewOTLPExporter(opts.Endpoint, opts.ServiceName)
+	default:
+		return fmt.Errorf("未知的追踪导出方式: %s", opts.Exporter)
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return fmt.Errorf("追踪采样比例必须在0到1之间: %v", opts.SampleRatio)
+	}
+
+	var t *tracer
+	if exporter != nil {
+		t = &tracer{sampleBound: sampleBound(opts.SampleRatio), processor: newBatchProcessor(exporter)}
+	}
+
+	mu.Lock()
+	old := global
+	global = t
+	mu.Unlock()
+
+	if old != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		old.processor.shutdown(ctx)
+	}
+	return nil
+}
+
+// Shutdown 导出尚未发送的 span 并停止追踪，ctx 控制等待的最长时间
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	t := global
+	global = nil
+	mu.Unlock()
+
+	if t == nil {
+		return nil
+	}
+	return t.processor.shutdown(ctx)
+}
+
+// sampleBound 把采样比例换算为追踪 ID 的阈值，同一追踪在各服务中的采样决定一致
+func sampleBound(ratio float64) uint64 {
+	if ratio >= 1 {
+		return ^uint64(0)
+	}
+	return uint64(ratio * (1 << 63) * 2)
+}
+
+// sample 判断新的追踪是否采样
+func (t *tracer) sample(traceID TraceID) bool {
+	if t.sampleBound == ^uint64(0) {
+		return true
+	}
+	return binary.BigEndian.Uint64(traceID[8:]) < t.sampleBound
+}
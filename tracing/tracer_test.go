@@ -0,0 +1,264 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingExporter 在内存中保存导出的 span
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []*SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// enableTracing 以 exporter 启用追踪，返回的函数关闭追踪并等待导出完成
+func enableTracing(t *testing.T, ratio float64) (*recordingExporter, func() []*SpanData) {
+	t.Helper()
+	exporter := &recordingExporter{}
+	mu.Lock()
+	global = &tracer{sampleBound: sampleBound(ratio), processor: newBatchProcessor(exporter)}
+	mu.Unlock()
+
+	flush := func() []*SpanData {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+		exporter.mu.Lock()
+		defer exporter.mu.Unlock()
+		return exporter.spans
+	}
+	t.Cleanup(func() { Shutdown(context.Background()) })
+	return exporter, flush
+}
+
+func TestStartDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "op", KindInternal)
+	if span != nil || ctx != context.Background() {
+		t.Fatal("Start should return nil span when tracing is disabled")
+	}
+	// nil span 的方法都可以安全调用
+	span.SetAttributes(Attr{Key: "k", Value: "v"})
+	span.SetName("x")
+	span.EndWithError(errors.New("boom"))
+	if span.IsRecording() || span.SpanContext().IsValid() {
+		t.Error("nil span should not record")
+	}
+}
+
+func TestSpanNesting(t *testing.T) {
+	_, flush := enableTracing(t, 1)
+
+	ctx, root := Start(context.Background(), "root", KindServer)
+	childCtx, child := Start(ctx, "child", KindInternal, Attr{Key: "k", Value: 1})
+	_, grandchild := Start(childCtx, "grandchild", KindClient)
+	grandchild.EndWithError(errors.New("query failed"))
+	child.End()
+	child.End() // 重复结束只导出一次
+	root.End()
+
+	spans := flush()
+	if len(spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(spans))
+	}
+	byName := map[string]*SpanData{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	r, c, g := byName["root"], byName["child"], byName["grandchild"]
+	if r.ParentID.IsValid() {
+		t.Error("root span should have no parent")
+	}
+	if c.TraceID != r.TraceID || g.TraceID != r.TraceID {
+		t.Error("child spans should share the root trace id")
+	}
+	if c.ParentID != r.SpanID || g.ParentID != c.SpanID {
+		t.Error("parent ids do not follow the context chain")
+	}
+	if g.Error != "query failed" || c.Error != "" {
+		t.Errorf("errors = %q/%q", g.Error, c.Error)
+	}
+	if len(c.Attributes) != 1 || c.Attributes[0].Value != 1 {
+		t.Errorf("child attributes = %v", c.Attributes)
+	}
+}
+
+func TestUnsampledParentIsNotExported(t *testing.T) {
+	_, flush := enableTracing(t, 1)
+
+	parent := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: false}
+	ctx, span := Start(ContextWithRemoteParent(context.Background(), parent), "op", KindServer)
+	if span.IsRecording() {
+		t.Error("span with unsampled parent should not record")
+	}
+	// 仍然传递追踪 ID，下游看到同一个追踪
+	if got := SpanContextFromContext(ctx); got.TraceID != parent.TraceID || got.Sampled {
+		t.Errorf("span context = %+v", got)
+	}
+	span.End()
+
+	if spans := flush(); len(spans) != 0 {
+		t.Errorf("exported %d spans, want 0", len(spans))
+	}
+}
+
+func TestExtractAndInject(t *testing.T) {
+	_, flush := enableTracing(t, 1)
+
+	in := http.Header{}
+	in.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Set(TracestateHeader, "vendor=value")
+
+	ctx, span := Start(Extract(context.Background(), in), "server", KindServer)
+	out := http.Header{}
+	Inject(ctx, out)
+	span.End()
+
+	sc, ok := ParseTraceparent(out.Get(TraceparentHeader))
+	if !ok {
+		t.Fatalf("injected traceparent %q is invalid", out.Get(TraceparentHeader))
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !sc.Sampled {
+		t.Errorf("injected trace = %+v, want upstream trace id, sampled", sc)
+	}
+	if sc.SpanID != span.SpanContext().SpanID {
+		t.Error("injected span id should be the current span")
+	}
+	if got := out.Get(TracestateHeader); got != "vendor=value" {
+		t.Errorf("tracestate = %q, want passed through", got)
+	}
+
+	spans := flush()
+	if len(spans) != 1 || spans[0].ParentID.String() != "00f067aa0ba902b7" {
+		t.Errorf("server span should have the upstream span as parent: %+v", spans)
+	}
+}
+
+func TestExtractIgnoresInvalidHeaders(t *testing.T) {
+	ctx := context.Background()
+	in := http.Header{}
+	in.Set(TraceparentHeader, "garbage")
+	if got := Extract(ctx, in); got != ctx {
+		t.Error("invalid traceparent should leave the context unchanged")
+	}
+
+	in.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Set(TracestateHeader, string(make([]byte, maxTracestateLength+1)))
+	if sc := SpanContextFromContext(Extract(ctx, in)); !sc.IsValid() || sc.TraceState != "" {
+		t.Errorf("oversized tracestate should be dropped: %+v", sc)
+	}
+}
+
+func TestTransportPropagates(t *testing.T) {
+	_, flush := enableTracing(t, 1)
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(TraceparentHeader)
+	}))
+	defer server.Close()
+
+	ctx, parent := Start(context.Background(), "parent", KindInternal)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/path?secret=1", nil)
+	resp, err := (&http.Client{Transport: Transport{}}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if req.Header.Get(TraceparentHeader) != "" {
+		t.Error("Transport must not modify the caller's request")
+	}
+	sc, ok := ParseTraceparent(got)
+	if !ok || sc.TraceID != parent.SpanContext().TraceID {
+		t.Fatalf("downstream traceparent = %q, want parent trace", got)
+	}
+
+	var client *SpanData
+	for _, s := range flush() {
+		if s.Kind == KindClient {
+			client = s
+		}
+	}
+	if client == nil || client.SpanID != sc.SpanID {
+		t.Fatal("downstream should see the client span as parent")
+	}
+	for _, a := range client.Attributes {
+		if a.Key == "url.full" && a.Value != server.URL+"/path" {
+			t.Errorf("url.full = %v, want query removed", a.Value)
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+	}))
+	defer server.Close()
+
+	start := time.Unix(1700000000, 0)
+	span := &SpanData{
+		Name: "SELECT", Kind: KindClient,
+		TraceID: newTraceID(), SpanID: newSpanID(), ParentID: newSpanID(),
+		Start: start, End: start.Add(time.Millisecond),
+		Attributes: []Attr{{Key: "db.rows", Value: int64(3)}, {Key: "ok", Value: true}},
+		Error:      "timeout",
+	}
+	if err := NewOTLPExporter(server.URL, "svc").Export(context.Background(), []*SpanData{span}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	service := rs["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	if service["key"] != "service.name" || service["value"].(map[string]interface{})["stringValue"] != "svc" {
+		t.Errorf("resource attributes = %v", service)
+	}
+	out := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	if out["traceId"] != span.TraceID.String() || out["parentSpanId"] != span.ParentID.String() {
+		t.Errorf("ids = %v/%v", out["traceId"], out["parentSpanId"])
+	}
+	if out["kind"] != float64(KindClient) || out["startTimeUnixNano"] != "1700000000000000000" {
+		t.Errorf("kind/start = %v/%v", out["kind"], out["startTimeUnixNano"])
+	}
+	if status := out["status"].(map[string]interface{}); status["code"] != float64(2) || status["message"] != "timeout" {
+		t.Errorf("status = %v", status)
+	}
+	// int64 在 JSON 映射中编码为字符串
+	attr := out["attributes"].([]interface{})[0].(map[string]interface{})
+	if attr["value"].(map[string]interface{})["intValue"] != "3" {
+		t.Errorf("int attribute = %v", attr)
+	}
+}
+
+func TestOTLPExporterReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := NewOTLPExporter(server.URL, "svc").Export(context.Background(), []*SpanData{{TraceID: newTraceID(), SpanID: newSpanID()}})
+	if err == nil {
+		t.Error("Export should fail on a non-2xx response")
+	}
+}